@@ -43,6 +43,22 @@ takes advantage of more heuristic techniques to speed up alignment, and is bette
 at finding similar sequences in large database, sacrificing precision for faster
 results.
 
+Both support linear gap penalties, where every base of a gap costs the same,
+and affine gap penalties, where opening a gap costs more than extending it
+(Gotoh's algorithm), which is what EMBOSS needle and water use.
+
+Between global and local alignments, SemiGlobalAlignment, OverlapAlignment
+and GlocalAlignment leave the ends of one or both sequences unaligned for
+free, for finding primers in reads or checking the overlaps of fragments.
+
+For finding barcodes and primers in many reads, MyersPattern searches for the
+best edit distance match of a pattern with bit-parallel operations, which is
+orders of magnitude faster than filling an alignment matrix.
+
+For long sequences, like nanopore reads of whole plasmids,
+BandedNeedlemanWunschAlignment and HirschbergAlignment align globally without
+keeping whole matrices in memory.
+
 Both are "dynamic programming algorithms" which is a fancy 1980's term for they use
 matrices. If you're familiar with kernel operations, linear filters, or whatever term
 ML researchers are using nowadays for, "slide a window over a matrix and determine that
@@ -66,13 +82,26 @@ Tim
 package align
 
 import (
+	"fmt"
+	"math"
+
 	"github.com/koeng101/dnadesign/lib/align/matrix"
 )
 
 // Scoring is a struct that holds the scoring matrix for match, mismatch, and gap penalties.
+//
+// Gaps cost GapPenalty per base (linear gaps), unless GapOpenPenalty or
+// GapExtendPenalty are set (affine gaps), in which case the first base of a
+// gap costs GapOpenPenalty and each base after it costs GapExtendPenalty.
+// Affine gaps favor a few long gaps over many scattered short gaps, which is
+// closer to how insertions and deletions happen in nanopore reads and protein
+// homologs. Linear gaps are affine gaps where opening and extending a gap cost
+// the same.
 type Scoring struct {
 	SubstitutionMatrix *matrix.SubstitutionMatrix
 	GapPenalty         int
+	GapOpenPenalty     int
+	GapExtendPenalty   int
 }
 
 // NewScoring returns a new Scoring struct with default values for DNA.
@@ -86,6 +115,30 @@ func NewScoring(substitutionMatrix *matrix.SubstitutionMatrix, gapPenalty int) (
 	}, nil
 }
 
+// NewAffineScoring returns a new Scoring struct with affine gap penalties: a
+// gap of length k costs gapOpenPenalty + (k-1)*gapExtendPenalty, like the gap
+// penalties of EMBOSS needle and water. Penalties are integers, so EMBOSS's
+// default gap open of 10 and gap extend of 0.5 can only be used with a
+// substitution matrix and penalties scaled by 2.
+//
+// Extending a gap can't cost more than opening it: the best gap would then be
+// many gaps of one base, which Gotoh's algorithm and HirschbergAlignment
+// don't agree on, so those penalties return an error.
+func NewAffineScoring(substitutionMatrix *matrix.SubstitutionMatrix, gapOpenPenalty int, gapExtendPenalty int) (Scoring, error) {
+	if substitutionMatrix == nil {
+		substitutionMatrix = matrix.Default
+	}
+	if abs(gapExtendPenalty) > abs(gapOpenPenalty) {
+		return Scoring{}, fmt.Errorf("gap extend penalty %d costs more than gap open penalty %d", gapExtendPenalty, gapOpenPenalty)
+	}
+	return Scoring{
+		SubstitutionMatrix: substitutionMatrix,
+		GapPenalty:         gapOpenPenalty,
+		GapOpenPenalty:     gapOpenPenalty,
+		GapExtendPenalty:   gapExtendPenalty,
+	}, nil
+}
+
 func (s Scoring) Score(a, b byte) (int, error) {
 	matchScore, err := s.SubstitutionMatrix.Score(string(a), string(b))
 	if err != nil {
@@ -94,141 +147,258 @@ func (s Scoring) Score(a, b byte) (int, error) {
 	return matchScore, nil
 }
 
+// gapPenalties returns the penalty of the first base of a gap and of each base
+// after it.
+func (s Scoring) gapPenalties() (int, int) {
+	if s.GapOpenPenalty == 0 && s.GapExtendPenalty == 0 {
+		return s.GapPenalty, s.GapPenalty
+	}
+	return s.GapOpenPenalty, s.GapExtendPenalty
+}
+
+// negativeInfinity is the score of impossible alignments. It is far enough
+// from math.MinInt that adding penalties to it doesn't overflow.
+const negativeInfinity = math.MinInt / 2
+
 // NeedlemanWunsch performs global alignment between two strings using the Needleman-Wunsch algorithm.
 // It returns the final score and the optimal alignments of the two strings in O(nm) time and O(nm) space.
+// Affine gap penalties use the three matrices of Gotoh's algorithm.
 // https://en.wikipedia.org/wiki/Needleman-Wunsch_algorithm
 func NeedlemanWunsch(stringA string, stringB string, scoring Scoring) (int, string, string, error) {
-	// Get the M and N dimensions of the matrix. The M x N matrix is standard linear algebra notation.
-	// But I added columns and rows to the variable name to make it more clear what the dimensions are.
-	columnLengthM, rowLengthN := len(stringA), len(stringB)
+	alignment, err := gotoh(stringA, stringB, scoring, globalMode)
+	return alignment.Score, alignment.AlignedA, alignment.AlignedB, err
+}
 
-	// Initialize the matrix columns
-	matrix := make([][]int, columnLengthM+1) // matrix is a 2D slice of ints.
+// NeedlemanWunschAlignment is NeedlemanWunsch, returning an Alignment.
+func NeedlemanWunschAlignment(stringA string, stringB string, scoring Scoring) (Alignment, error) {
+	return gotoh(stringA, stringB, scoring, globalMode)
+}
 
-	// Initialize the matrix rows.
-	for columnM := range matrix {
-		matrix[columnM] = make([]int, rowLengthN+1)
-	}
+// SmithWaterman performs local alignment between two strings using the Smith-Waterman algorithm.
+// It returns the max score and optimal local alignments between two strings alignments of the two strings in O(nm) time and O(nm) space.
+// Affine gap penalties use the three matrices of Gotoh's algorithm.
+// https://en.wikipedia.org/wiki/Smith-Waterman_algorithm
+func SmithWaterman(stringA string, stringB string, scoring Scoring) (int, string, string, error) {
+	alignment, err := gotoh(stringA, stringB, scoring, localMode)
+	return alignment.Score, alignment.AlignedA, alignment.AlignedB, err
+}
 
-	// Fill in the first column with gap penalties.
-	for columnM := 1; columnM <= columnLengthM; columnM++ {
-		matrix[columnM][0] = matrix[columnM-1][0] + scoring.GapPenalty
-	}
+// SmithWatermanAlignment is SmithWaterman, returning an Alignment with where
+// the local alignment starts and ends in both strings.
+func SmithWatermanAlignment(stringA string, stringB string, scoring Scoring) (Alignment, error) {
+	return gotoh(stringA, stringB, scoring, localMode)
+}
 
-	// Fill in the first row with gap penalties.
-	for rowN := 1; rowN <= rowLengthN; rowN++ {
-		matrix[0][rowN] = matrix[0][rowN-1] + scoring.GapPenalty
-	}
+/******************************************************************************
 
-	// Fill in the rest of the matrix.
-	for columnM := 1; columnM <= columnLengthM; columnM++ {
-		for rowN := 1; rowN <= rowLengthN; rowN++ {
-			// Calculate the scores for scoring.Match/mismatch and gap.
-			var score, err = scoring.Score(stringA[columnM-1], stringB[rowN-1])
-			if err != nil {
-				return 0, "", "", err
-			}
-			matrix[columnM][rowN] = max(
-				matrix[columnM-1][rowN-1]+score,
-				max(matrix[columnM-1][rowN]+scoring.GapPenalty, matrix[columnM][rowN-1]+scoring.GapPenalty),
-			)
-		}
+Gotoh's algorithm begins here
+
+Needleman-Wunsch and Smith-Waterman fill a single matrix, where each cell is
+the best score of aligning the start of stringA to the start of stringB. With
+affine gaps, the cost of a gap depends on whether the gap is opened or
+extended, so a single matrix isn't enough. Gotoh's algorithm fills three:
+
+	best    best score of any alignment ending at the cell
+	gapInB  best score of alignments ending with a base of stringA against a gap
+	gapInA  best score of alignments ending with a base of stringB against a gap
+
+and the traceback switches between the matrices when gaps are opened. Gotoh,
+O. (1982) An improved algorithm for matching biological sequences. J. Mol.
+Biol. 162:705-708.
+
+With linear gaps, gapInB and gapInA are always a gap away from best, so the
+algorithm gives the same scores and alignments as the single matrix.
+
+******************************************************************************/
+
+// traceback states of Gotoh's algorithm.
+const (
+	stateBest = iota
+	stateGapInB
+	stateGapInA
+)
+
+// diagonalBand is the diagonals of the matrices of an alignment that are
+// filled in, from low to high. The diagonal of a cell is rowN - columnM.
+type diagonalBand struct {
+	low  int
+	high int
+}
+
+// fullBand returns the band of all diagonals of the matrices of two strings.
+func fullBand(stringA string, stringB string) diagonalBand {
+	return diagonalBand{low: -len(stringA), high: len(stringB)}
+}
+
+// bandedMatrix is a matrix that only stores the cells of a diagonal band. The
+// cells outside of the band are negativeInfinity.
+type bandedMatrix struct {
+	rows   [][]int
+	starts []int // first rowN stored of each columnM
+}
+
+// newBandedMatrix returns the matrix of a band of two strings.
+func newBandedMatrix(columnLengthM int, rowLengthN int, band diagonalBand) bandedMatrix {
+	matrix := bandedMatrix{rows: make([][]int, columnLengthM+1), starts: make([]int, columnLengthM+1)}
+	for columnM := range matrix.rows {
+		start, end := max(0, columnM+band.low), min(rowLengthN, columnM+band.high)
+		matrix.starts[columnM] = start
+		matrix.rows[columnM] = make([]int, max(0, end-start+1))
 	}
+	return matrix
+}
 
-	// Traceback to find the optimal alignment.
-	var alignA, alignB []rune
-	columnM, rowN := columnLengthM, rowLengthN
-	for columnM > 0 && rowN > 0 {
-		var matchScore, err = scoring.Score(stringA[columnM-1], stringB[rowN-1])
-		if err != nil {
-			return 0, "", "", err
-		}
-		if matrix[columnM][rowN] == matrix[columnM-1][rowN-1]+matchScore {
-			alignA = append(alignA, rune(stringA[columnM-1]))
-			alignB = append(alignB, rune(stringB[rowN-1]))
-			columnM--
-			rowN--
-		} else if matrix[columnM][rowN] == matrix[columnM-1][rowN]+scoring.GapPenalty {
-			alignA = append(alignA, rune(stringA[columnM-1]))
-			alignB = append(alignB, '-')
-			columnM--
-		} else {
-			alignA = append(alignA, '-')
-			alignB = append(alignB, rune(stringB[rowN-1]))
-			rowN--
-		}
+// get returns the score of a cell.
+func (matrix bandedMatrix) get(columnM int, rowN int) int {
+	index := rowN - matrix.starts[columnM]
+	if index < 0 || index >= len(matrix.rows[columnM]) {
+		return negativeInfinity
 	}
+	return matrix.rows[columnM][index]
+}
 
-	// Reverse the alignments to get the optimal alignment.
-	alignA = reverseRuneArray(alignA)
-	alignB = reverseRuneArray(alignB)
-	return matrix[columnLengthM][rowLengthN], string(alignA), string(alignB), nil
+// set sets the score of a cell of the band.
+func (matrix bandedMatrix) set(columnM int, rowN int, score int) {
+	matrix.rows[columnM][rowN-matrix.starts[columnM]] = score
 }
 
-// SmithWaterman performs local alignment between two strings using the Smith-Waterman algorithm.
-// It returns the max score and optimal local alignments between two strings alignments of the two strings in O(nm) time and O(nm) space.
-// https://en.wikipedia.org/wiki/Smith-Waterman_algorithm
-func SmithWaterman(stringA string, stringB string, scoring Scoring) (int, string, string, error) {
-	columnLengthM, rowLengthN := len(stringA), len(stringB)
+// alignmentMode is the ends of the strings of an alignment that can be left
+// unaligned without gap penalties. Local alignments can start and end
+// anywhere, and stop where their score drops to 0.
+type alignmentMode struct {
+	local      bool
+	freeStartA bool
+	freeEndA   bool
+	freeStartB bool
+	freeEndB   bool
+}
 
-	// Initialize the alignment matrix
-	matrix := make([][]int, columnLengthM+1)
-	for columnM := 0; columnM <= columnLengthM; columnM++ {
-		matrix[columnM] = make([]int, rowLengthN+1)
-	}
+var (
+	globalMode = alignmentMode{}
+	localMode  = alignmentMode{local: true, freeStartA: true, freeEndA: true, freeStartB: true, freeEndB: true}
+)
 
-	// Initialize variables to keep track of the maximum score and its position
-	maxScore := 0
-	maxScoreRow := 0
-	maxScoreCol := 0
+// gotoh aligns two strings in an alignment mode.
+func gotoh(stringA string, stringB string, scoring Scoring, mode alignmentMode) (Alignment, error) {
+	return bandedGotoh(stringA, stringB, scoring, mode, fullBand(stringA, stringB))
+}
 
-	// Fill the alignment matrix
+// bandedGotoh aligns two strings in an alignment mode, only filling in the
+// cells of a diagonal band of the matrices. The band must have a path between
+// the starts and ends of the alignment.
+func bandedGotoh(stringA string, stringB string, scoring Scoring, mode alignmentMode, band diagonalBand) (Alignment, error) {
+	openPenalty, extendPenalty := scoring.gapPenalties()
+	columnLengthM, rowLengthN := len(stringA), len(stringB)
+	best := newBandedMatrix(columnLengthM, rowLengthN, band)
+	gapInB := newBandedMatrix(columnLengthM, rowLengthN, band)
+	gapInA := newBandedMatrix(columnLengthM, rowLengthN, band)
+
+	// Fill in the first column and row with gap penalties. Alignments that
+	// start anywhere in a string don't start with gaps in the other.
+	gapInB.set(0, 0, negativeInfinity)
+	gapInA.set(0, 0, negativeInfinity)
+	for columnM := 1; columnM <= min(columnLengthM, -band.low); columnM++ {
+		gapInA.set(columnM, 0, negativeInfinity)
+		gapInB.set(columnM, 0, openPenalty+(columnM-1)*extendPenalty)
+		best.set(columnM, 0, gapInB.get(columnM, 0))
+		if mode.freeStartA {
+			gapInB.set(columnM, 0, negativeInfinity)
+			best.set(columnM, 0, 0)
+		}
+	}
+	for rowN := 1; rowN <= min(rowLengthN, band.high); rowN++ {
+		gapInB.set(0, rowN, negativeInfinity)
+		gapInA.set(0, rowN, openPenalty+(rowN-1)*extendPenalty)
+		best.set(0, rowN, gapInA.get(0, rowN))
+		if mode.freeStartB {
+			gapInA.set(0, rowN, negativeInfinity)
+			best.set(0, rowN, 0)
+		}
+	}
+
+	// Fill in the rest of the matrices, keeping track of the maximum score
+	// of local alignments.
+	maxScore, maxScoreColumn, maxScoreRow := 0, 0, 0
 	for columnM := 1; columnM <= columnLengthM; columnM++ {
-		for rowN := 1; rowN <= rowLengthN; rowN++ {
-			var matchScore, err = scoring.Score(stringA[columnM-1], stringB[rowN-1])
+		for rowN := max(1, columnM+band.low); rowN <= min(rowLengthN, columnM+band.high); rowN++ {
+			matchScore, err := scoring.Score(stringA[columnM-1], stringB[rowN-1])
 			if err != nil {
-				return 0, "", "", err
+				return Alignment{}, err
 			}
-			diagScore := matrix[columnM-1][rowN-1] + matchScore
-			upScore := matrix[columnM-1][rowN] + scoring.GapPenalty
-			leftScore := matrix[columnM][rowN-1] + scoring.GapPenalty
-			matrix[columnM][rowN] = max(0, max(diagScore, max(upScore, leftScore)))
-
-			if matrix[columnM][rowN] > maxScore {
-				maxScore = matrix[columnM][rowN]
-				maxScoreRow = columnM
-				maxScoreCol = rowN
+			gapInB.set(columnM, rowN, max(best.get(columnM-1, rowN)+openPenalty, gapInB.get(columnM-1, rowN)+extendPenalty))
+			gapInA.set(columnM, rowN, max(best.get(columnM, rowN-1)+openPenalty, gapInA.get(columnM, rowN-1)+extendPenalty))
+			score := max(best.get(columnM-1, rowN-1)+matchScore, max(gapInB.get(columnM, rowN), gapInA.get(columnM, rowN)))
+			if mode.local {
+				score = max(0, score)
+				if score > maxScore {
+					maxScore, maxScoreColumn, maxScoreRow = score, columnM, rowN
+				}
 			}
+			best.set(columnM, rowN, score)
 		}
 	}
-
-	// Traceback to construct the aligned strings
-	alignA := ""
-	alignB := ""
-	columnM := maxScoreRow
-	rowN := maxScoreCol
-	for matrix[columnM][rowN] > 0 {
-		var matchScore, err = scoring.Score(stringA[columnM-1], stringB[rowN-1])
-		if err != nil {
-			return 0, "", "", err
+	// Alignments that end anywhere in a string end at the best score of the
+	// last row or column, preferring to align both strings to their ends.
+	if !mode.local {
+		maxScore, maxScoreColumn, maxScoreRow = best.get(columnLengthM, rowLengthN), columnLengthM, rowLengthN
+		for columnM := 0; mode.freeEndA && columnM < columnLengthM; columnM++ {
+			if best.get(columnM, rowLengthN) > maxScore {
+				maxScore, maxScoreColumn, maxScoreRow = best.get(columnM, rowLengthN), columnM, rowLengthN
+			}
 		}
-		if matrix[columnM][rowN] == matrix[columnM-1][rowN-1]+matchScore {
-			alignA = string(stringA[columnM-1]) + alignA
-			alignB = string(stringB[rowN-1]) + alignB
-			columnM--
-			rowN--
-		} else if matrix[columnM][rowN] == matrix[columnM-1][rowN]+scoring.GapPenalty {
-			alignA = string(stringA[columnM-1]) + alignA
-			alignB = "-" + alignB
+		for rowN := 0; mode.freeEndB && rowN < rowLengthN; rowN++ {
+			if best.get(columnLengthM, rowN) > maxScore {
+				maxScore, maxScoreColumn, maxScoreRow = best.get(columnLengthM, rowN), columnLengthM, rowN
+			}
+		}
+	}
+
+	// Traceback to find the optimal alignment.
+	var alignA, alignB []rune
+	columnM, rowN := maxScoreColumn, maxScoreRow
+	state := stateBest
+	for columnM > 0 || rowN > 0 {
+		switch state {
+		case stateBest:
+			if (mode.local && best.get(columnM, rowN) == 0) || (mode.freeStartA && rowN == 0) || (mode.freeStartB && columnM == 0) {
+				return newAlignment(maxScore, stringA, stringB, alignA, alignB, columnM, rowN, maxScoreColumn, maxScoreRow), nil
+			}
+			if columnM > 0 && rowN > 0 {
+				matchScore, err := scoring.Score(stringA[columnM-1], stringB[rowN-1])
+				if err != nil {
+					return Alignment{}, err
+				}
+				if best.get(columnM, rowN) == best.get(columnM-1, rowN-1)+matchScore {
+					alignA = append(alignA, rune(stringA[columnM-1]))
+					alignB = append(alignB, rune(stringB[rowN-1]))
+					columnM--
+					rowN--
+					continue
+				}
+			}
+			if columnM > 0 && best.get(columnM, rowN) == gapInB.get(columnM, rowN) {
+				state = stateGapInB
+			} else {
+				state = stateGapInA
+			}
+		case stateGapInB:
+			if gapInB.get(columnM, rowN) == best.get(columnM-1, rowN)+openPenalty {
+				state = stateBest
+			}
+			alignA = append(alignA, rune(stringA[columnM-1]))
+			alignB = append(alignB, '-')
 			columnM--
-		} else if matrix[columnM][rowN] == matrix[columnM][rowN-1]+scoring.GapPenalty {
-			alignA = "-" + alignA
-			alignB = string(stringB[rowN-1]) + alignB
+		case stateGapInA:
+			if gapInA.get(columnM, rowN) == best.get(columnM, rowN-1)+openPenalty {
+				state = stateBest
+			}
+			alignA = append(alignA, '-')
+			alignB = append(alignB, rune(stringB[rowN-1]))
 			rowN--
 		}
 	}
 
-	return maxScore, alignA, alignB, nil
+	return newAlignment(maxScore, stringA, stringB, alignA, alignB, 0, 0, maxScoreColumn, maxScoreRow), nil
 }
 
 func reverseRuneArray(runes []rune) []rune { // wasn't able to find a built-in reverse function for runes
@@ -246,3 +416,10 @@ func max(a, b int) int { // funny enough Go's built-in max only handles floats?
 	}
 	return b
 }
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -1,6 +1,9 @@
 package align_test
 
 import (
+	"math"
+	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/koeng101/dnadesign/lib/align"
@@ -290,3 +293,284 @@ func TestSmithWaterman(t *testing.T) {
 		t.Errorf("Alignment is %s, expected G", alignN)
 	}
 }
+
+func TestAffineGaps(t *testing.T) {
+	alphabet := alphabet.NewAlphabet([]string{"-", "A", "C", "G", "T"})
+	subMatrix, err := matrix.NewSubstitutionMatrix(alphabet, alphabet, matrix.NUC_4)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	affine, err := align.NewAffineScoring(subMatrix, -10, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	linear, err := align.NewScoring(subMatrix, -10)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+
+	// A single gap of 7 bases costs -10 + 6*-1 with affine gaps, and 7*-10
+	// with linear gaps.
+	a := "ACGTACGTTTTTTTTACGTACGT"
+	b := "ACGTACGTACGTACGT"
+	for _, test := range []struct {
+		name     string
+		align    func(string, string, align.Scoring) (int, string, string, error)
+		scoring  align.Scoring
+		score    int
+		expected string
+	}{
+		{"NeedlemanWunsch affine", align.NeedlemanWunsch, affine, 64, "ACGTACG-------TACGTACGT"},
+		{"NeedlemanWunsch linear", align.NeedlemanWunsch, linear, 10, "ACGTACG-------TACGTACGT"},
+		{"SmithWaterman affine", align.SmithWaterman, affine, 64, "ACGTACG-------TACGTACGT"},
+	} {
+		score, alignA, alignB, err := test.align(a, b, test.scoring)
+		if err != nil {
+			t.Errorf("%s: error: %s", test.name, err)
+		}
+		if score != test.score || alignA != a || alignB != test.expected {
+			t.Errorf("%s: got score %d, A: %s, B: %s, expected score %d, A: %s, B: %s", test.name, score, alignA, alignB, test.score, a, test.expected)
+		}
+	}
+
+	// Global alignments start and end with gaps when they need to.
+	score, alignA, alignB, err := align.NeedlemanWunsch("G", "GATTACA", affine)
+	if err != nil {
+		t.Errorf("error: %s", err)
+	}
+	if score != -10 || alignA != "G------" || alignB != "GATTACA" {
+		t.Errorf("got score %d, A: %s, B: %s, expected score -10, A: G------, B: GATTACA", score, alignA, alignB)
+	}
+	score, alignA, alignB, err = align.NeedlemanWunsch("T", "GAT", affine)
+	if err != nil {
+		t.Errorf("error: %s", err)
+	}
+	if score != -6 || alignA != "--T" || alignB != "GAT" {
+		t.Errorf("got score %d, A: %s, B: %s, expected score -6, A: --T, B: GAT", score, alignA, alignB)
+	}
+}
+
+// affineScore scores an alignment with affine gap penalties.
+func affineScore(t *testing.T, alignA string, alignB string, scoring align.Scoring) int {
+	t.Helper()
+	var score int
+	for i := range alignA {
+		switch {
+		case alignA[i] == '-':
+			score += scoring.GapExtendPenalty
+			if i == 0 || alignA[i-1] != '-' {
+				score += scoring.GapOpenPenalty - scoring.GapExtendPenalty
+			}
+		case alignB[i] == '-':
+			score += scoring.GapExtendPenalty
+			if i == 0 || alignB[i-1] != '-' {
+				score += scoring.GapOpenPenalty - scoring.GapExtendPenalty
+			}
+		default:
+			matchScore, err := scoring.Score(alignA[i], alignB[i])
+			if err != nil {
+				t.Fatalf("error: %s", err)
+			}
+			score += matchScore
+		}
+	}
+	return score
+}
+
+// bestAffineScore returns the best score of all global alignments of two
+// strings, by trying every alignment.
+func bestAffineScore(t *testing.T, a string, b string, alignA string, alignB string, scoring align.Scoring) int {
+	t.Helper()
+	if a == "" && b == "" {
+		return affineScore(t, alignA, alignB, scoring)
+	}
+	best := math.MinInt
+	if a != "" && b != "" {
+		best = max(best, bestAffineScore(t, a[1:], b[1:], alignA+a[:1], alignB+b[:1], scoring))
+	}
+	if a != "" {
+		best = max(best, bestAffineScore(t, a[1:], b, alignA+a[:1], alignB+"-", scoring))
+	}
+	if b != "" {
+		best = max(best, bestAffineScore(t, a, b[1:], alignA+"-", alignB+b[:1], scoring))
+	}
+	return best
+}
+
+func TestNewAffineScoringPenalties(t *testing.T) {
+	// gap extensions that cost more than gap openings are rejected.
+	if _, err := align.NewAffineScoring(nil, -2, -3); err == nil {
+		t.Errorf("expected an error for a gap extend penalty above the gap open penalty")
+	}
+	// equal penalties are linear gaps, which every aligner agrees on.
+	scoring, err := align.NewAffineScoring(nil, -3, -3)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	a, b := "GATTACAGATTACA", "GACATTACGATA"
+	gotoh, err := align.NeedlemanWunschAlignment(a, b, scoring)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	hirschberg, err := align.HirschbergAlignment(a, b, scoring)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	if gotoh.Score != hirschberg.Score {
+		t.Errorf("got NeedlemanWunsch score %d and Hirschberg score %d", gotoh.Score, hirschberg.Score)
+	}
+}
+
+func TestAffineGapsExhaustive(t *testing.T) {
+	scoring, err := align.NewAffineScoring(nil, -3, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	random := rand.New(rand.NewSource(1))
+	randomSequence := func() string {
+		sequence := make([]byte, random.Intn(6))
+		for i := range sequence {
+			sequence[i] = "ACGT"[random.Intn(4)]
+		}
+		return string(sequence)
+	}
+	for i := 0; i < 200; i++ {
+		a, b := randomSequence(), randomSequence()
+
+		score, alignA, alignB, err := align.NeedlemanWunsch(a, b, scoring)
+		if err != nil {
+			t.Fatalf("error: %s", err)
+		}
+		if expected := bestAffineScore(t, a, b, "", "", scoring); score != expected {
+			t.Errorf("NeedlemanWunsch(%s, %s) got score %d, expected %d", a, b, score, expected)
+		}
+		if strings.ReplaceAll(alignA, "-", "") != a || strings.ReplaceAll(alignB, "-", "") != b || affineScore(t, alignA, alignB, scoring) != score {
+			t.Errorf("NeedlemanWunsch(%s, %s) got alignment A: %s, B: %s that doesn't score %d", a, b, alignA, alignB, score)
+		}
+
+		// the best local alignment is the best global alignment of any
+		// substrings.
+		score, alignA, alignB, err = align.SmithWaterman(a, b, scoring)
+		if err != nil {
+			t.Fatalf("error: %s", err)
+		}
+		expected := 0
+		for startA := 0; startA < len(a); startA++ {
+			for endA := startA + 1; endA <= len(a); endA++ {
+				for startB := 0; startB < len(b); startB++ {
+					for endB := startB + 1; endB <= len(b); endB++ {
+						expected = max(expected, bestAffineScore(t, a[startA:endA], b[startB:endB], "", "", scoring))
+					}
+				}
+			}
+		}
+		if score != expected {
+			t.Errorf("SmithWaterman(%s, %s) got score %d, expected %d", a, b, score, expected)
+		}
+		if !strings.Contains(a, strings.ReplaceAll(alignA, "-", "")) || !strings.Contains(b, strings.ReplaceAll(alignB, "-", "")) || affineScore(t, alignA, alignB, scoring) != score {
+			t.Errorf("SmithWaterman(%s, %s) got alignment A: %s, B: %s that doesn't score %d", a, b, alignA, alignB, score)
+		}
+	}
+}
+
+// TestNeedlemanWunschEndGaps checks that global alignments with linear gap
+// penalties include the gaps at both ends of the strings. Before affine gaps,
+// NeedlemanWunsch("ACGT", "CGT") returned CGT and CGT, dropping the leading A.
+func TestNeedlemanWunschEndGaps(t *testing.T) {
+	alphabet := alphabet.NewAlphabet([]string{"-", "A", "C", "G", "T"})
+	subMatrix, err := matrix.NewSubstitutionMatrix(alphabet, alphabet, matrix.NUC_4)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	scoring, err := align.NewScoring(subMatrix, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	for _, test := range []struct {
+		a, b           string
+		alignA, alignB string
+	}{
+		{"ACGT", "CGT", "ACGT", "-CGT"},
+		{"CGT", "ACGT", "-CGT", "ACGT"},
+		{"ACGT", "ACG", "ACGT", "ACG-"},
+		{"ACG", "ACGT", "ACG-", "ACGT"},
+		{"AACGTA", "CGT", "AACGTA", "--CGT-"},
+	} {
+		_, alignA, alignB, err := align.NeedlemanWunsch(test.a, test.b, scoring)
+		if err != nil {
+			t.Fatalf("error: %s", err)
+		}
+		if alignA != test.alignA || alignB != test.alignB {
+			t.Errorf("NeedlemanWunsch(%q, %q): expected %s/%s, got %s/%s", test.a, test.b, test.alignA, test.alignB, alignA, alignB)
+		}
+	}
+}
+
+// scaledScoring returns affine scoring with a substitution matrix and gap
+// penalties scaled by 2, so that EMBOSS's default gap open of 10 and gap
+// extend of 0.5 are integers.
+func scaledScoring(t *testing.T, letters []string, substitutions [][]int) align.Scoring {
+	t.Helper()
+	scaled := make([][]int, len(substitutions))
+	for i, row := range substitutions {
+		scaled[i] = make([]int, len(row))
+		for j, score := range row {
+			scaled[i][j] = 2 * score
+		}
+	}
+	residues := alphabet.NewAlphabet(letters)
+	subMatrix, err := matrix.NewSubstitutionMatrix(residues, residues, scaled)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	scoring, err := align.NewAffineScoring(subMatrix, -20, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	return scoring
+}
+
+// TestStandardMatrices aligns DNA with EDNAFULL and proteins with BLOSUM62,
+// with EMBOSS's default gap penalties scaled by 2. The expected scores are
+// worked out by hand or by trying every alignment, not taken from EMBOSS
+// runs.
+func TestStandardMatrices(t *testing.T) {
+	ednafull := scaledScoring(t, strings.Split("-ACMGRSVTWYHKDBN", ""), matrix.NUC_4_4)
+	blosum62 := scaledScoring(t, strings.Split("-ABCDEFGHIJKLMNPQRSTVWXYZ*", ""), matrix.BLOSUM62)
+
+	// 12 matches of 10, and a gap of 2 bases costing 20 + 1.
+	dna, err := align.NeedlemanWunschAlignment("GATTACAGATTACA", "GATTACATTACA", ednafull)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	if dna.Score != 99 || dna.Matches() != 12 || dna.Gaps() != 2 || dna.GapOpenings() != 1 || affineScore(t, dna.AlignedA, dna.AlignedB, ednafull) != 99 {
+		t.Errorf("unexpected EDNAFULL global alignment with score %d:\n%s", dna.Score, dna)
+	}
+
+	// GATTACA is flanked by mismatches, which local alignments leave out.
+	dna, err = align.SmithWatermanAlignment("CCCCGATTACACCCC", "TTTTGATTACATTTT", ednafull)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	if dna.Score != 70 || dna.AlignedA != "GATTACA" || dna.StartA != 4 || dna.StartB != 4 {
+		t.Errorf("unexpected EDNAFULL local alignment with score %d:\n%s", dna.Score, dna)
+	}
+
+	a, b := "HEAGAWGHEE", "PAWHEAE"
+	protein, err := align.NeedlemanWunschAlignment(a, b, blosum62)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	if expected := bestAffineScore(t, a, b, "", "", blosum62); protein.Score != expected || affineScore(t, protein.AlignedA, protein.AlignedB, blosum62) != expected {
+		t.Errorf("expected BLOSUM62 global alignment with score %d, got score %d:\n%s", expected, protein.Score, protein)
+	}
+
+	// A, W, H and E score 4, 11, 8 and 5, and the gap 10, all scaled by 2.
+	protein, err = align.SmithWatermanAlignment(a, b, blosum62)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	if protein.Score != 36 || protein.AlignedA != "AWGHE" || protein.AlignedB != "AW-HE" {
+		t.Errorf("unexpected BLOSUM62 local alignment with score %d:\n%s", protein.Score, protein)
+	}
+}
@@ -0,0 +1,172 @@
+package align
+
+import (
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+Alignment results begin here
+
+NeedlemanWunsch and SmithWaterman return the gapped strings of an alignment,
+which is enough to print it, but not to know where a local alignment is. An
+Alignment also has the coordinates of the aligned region of both strings, so
+you can find where a primer or a barcode is in a read, and summarizes the
+alignment the way alignment tools do: its CIGAR string, identity, and number
+of matches, mismatches and gaps.
+
+CIGAR strings follow the SAM specification, with stringA as the query (the
+read) and stringB as the reference, so an alignment of a read to a reference
+can be written to sam.Alignment.CIGAR with sam.Alignment.POS = StartB + 1.
+Bases of stringA outside of local alignments are soft clipped.
+
+******************************************************************************/
+
+// Alignment is an alignment of stringA to stringB. Coordinates are 0-based,
+// with exclusive ends.
+type Alignment struct {
+	Score    int
+	AlignedA string // aligned region of stringA, with gaps
+	AlignedB string // aligned region of stringB, with gaps
+	StartA   int    // start of the aligned region of stringA
+	EndA     int    // end of the aligned region of stringA
+	StartB   int    // start of the aligned region of stringB
+	EndB     int    // end of the aligned region of stringB
+	LengthA  int    // length of stringA
+	LengthB  int    // length of stringB
+}
+
+// newAlignment returns the Alignment of the traceback of an alignment, which
+// is in reverse.
+func newAlignment(score int, stringA string, stringB string, alignA []rune, alignB []rune, startA int, startB int, endA int, endB int) Alignment {
+	return Alignment{
+		Score:    score,
+		AlignedA: string(reverseRuneArray(alignA)),
+		AlignedB: string(reverseRuneArray(alignB)),
+		StartA:   startA,
+		EndA:     endA,
+		StartB:   startB,
+		EndB:     endB,
+		LengthA:  len(stringA),
+		LengthB:  len(stringB),
+	}
+}
+
+// column returns the CIGAR operation of a column of an alignment: M for
+// aligned bases, I for bases of stringA against a gap, and D for bases of
+// stringB against a gap.
+func (alignment Alignment) column(i int) byte {
+	switch {
+	case alignment.AlignedB[i] == '-':
+		return 'I'
+	case alignment.AlignedA[i] == '-':
+		return 'D'
+	default:
+		return 'M'
+	}
+}
+
+// isMatch returns whether a column of an alignment has identical bases.
+func (alignment Alignment) isMatch(i int) bool {
+	return alignment.column(i) == 'M' && strings.EqualFold(alignment.AlignedA[i:i+1], alignment.AlignedB[i:i+1])
+}
+
+// Matches returns the number of identical aligned bases.
+func (alignment Alignment) Matches() int {
+	var matches int
+	for i := range alignment.AlignedA {
+		if alignment.isMatch(i) {
+			matches++
+		}
+	}
+	return matches
+}
+
+// Mismatches returns the number of aligned bases that aren't identical.
+func (alignment Alignment) Mismatches() int {
+	var mismatches int
+	for i := range alignment.AlignedA {
+		if alignment.column(i) == 'M' && !alignment.isMatch(i) {
+			mismatches++
+		}
+	}
+	return mismatches
+}
+
+// Gaps returns the number of bases aligned to a gap, in both strings.
+func (alignment Alignment) Gaps() int {
+	return strings.Count(alignment.AlignedA, "-") + strings.Count(alignment.AlignedB, "-")
+}
+
+// GapOpenings returns the number of gaps, in both strings.
+func (alignment Alignment) GapOpenings() int {
+	var gaps int
+	for i := range alignment.AlignedA {
+		operation := alignment.column(i)
+		if operation != 'M' && (i == 0 || alignment.column(i-1) != operation) {
+			gaps++
+		}
+	}
+	return gaps
+}
+
+// Identity returns the percentage of columns of an alignment with identical
+// bases, like EMBOSS needle and water, or 0 for empty alignments.
+func (alignment Alignment) Identity() float64 {
+	if len(alignment.AlignedA) == 0 {
+		return 0
+	}
+	return 100 * float64(alignment.Matches()) / float64(len(alignment.AlignedA))
+}
+
+// CIGAR returns the CIGAR string of the alignment of stringA, the query, to
+// stringB, the reference. Bases of stringA outside of the alignment are soft
+// clipped. Empty alignments have an unavailable CIGAR (*).
+func (alignment Alignment) CIGAR() string {
+	if len(alignment.AlignedA) == 0 {
+		return "*"
+	}
+	var cigar strings.Builder
+	writeOperation := func(length int, operation byte) {
+		if length > 0 {
+			cigar.WriteString(strconv.Itoa(length))
+			cigar.WriteByte(operation)
+		}
+	}
+	writeOperation(alignment.StartA, 'S')
+	length := 0
+	for i := range alignment.AlignedA {
+		length++
+		if i == len(alignment.AlignedA)-1 || alignment.column(i+1) != alignment.column(i) {
+			writeOperation(length, alignment.column(i))
+			length = 0
+		}
+	}
+	writeOperation(alignment.LengthA-alignment.EndA, 'S')
+	return cigar.String()
+}
+
+// MatchLine returns the line between the aligned strings of a printed
+// alignment, with | for identical bases, . for mismatches and spaces for
+// gaps.
+func (alignment Alignment) MatchLine() string {
+	matchLine := make([]byte, len(alignment.AlignedA))
+	for i := range matchLine {
+		switch {
+		case alignment.isMatch(i):
+			matchLine[i] = '|'
+		case alignment.column(i) == 'M':
+			matchLine[i] = '.'
+		default:
+			matchLine[i] = ' '
+		}
+	}
+	return string(matchLine)
+}
+
+// String returns the alignment printed on three lines: the aligned region of
+// stringA, the match line, and the aligned region of stringB.
+func (alignment Alignment) String() string {
+	return alignment.AlignedA + "\n" + alignment.MatchLine() + "\n" + alignment.AlignedB
+}
@@ -0,0 +1,132 @@
+package align_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/koeng101/dnadesign/lib/align"
+	"github.com/koeng101/dnadesign/lib/align/matrix"
+	"github.com/koeng101/dnadesign/lib/alphabet"
+	"github.com/koeng101/dnadesign/lib/bio/sam"
+)
+
+func TestAlignment(t *testing.T) {
+	alphabet := alphabet.NewAlphabet([]string{"-", "A", "C", "G", "T"})
+	subMatrix, err := matrix.NewSubstitutionMatrix(alphabet, alphabet, [][]int{
+		/*       - A C G T */
+		/* - */ {0, 0, 0, 0, 0},
+		/* A */ {0, 3, -3, -3, -3},
+		/* C */ {0, -3, 3, -3, -3},
+		/* G */ {0, -3, -3, 3, -3},
+		/* T */ {0, -3, -3, -3, 3},
+	})
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	scoring, err := align.NewScoring(subMatrix, -2)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	nucScoring, err := align.NewScoring(nil, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+
+	for _, test := range []struct {
+		name        string
+		align       func(string, string, align.Scoring) (align.Alignment, error)
+		a, b        string
+		scoring     align.Scoring
+		expected    align.Alignment
+		cigar       string
+		matches     int
+		mismatches  int
+		gaps        int
+		gapOpenings int
+		identity    float64
+		matchLine   string
+	}{
+		{
+			name:  "local",
+			align: align.SmithWatermanAlignment,
+			a:     "TGTTACGG", b: "GGTTGACTA",
+			scoring:  scoring,
+			expected: align.Alignment{Score: 13, AlignedA: "GTT-AC", AlignedB: "GTTGAC", StartA: 1, EndA: 6, StartB: 1, EndB: 7, LengthA: 8, LengthB: 9},
+			cigar:    "1S3M1D2M2S",
+			matches:  5, mismatches: 0, gaps: 1, gapOpenings: 1,
+			identity:  500.0 / 6,
+			matchLine: "||| ||",
+		},
+		{
+			name:  "global",
+			align: align.NeedlemanWunschAlignment,
+			a:     "GATTACA", b: "GCATGCT",
+			scoring:  nucScoring,
+			expected: align.Alignment{Score: 0, AlignedA: "G-ATTACA", AlignedB: "GCA-TGCT", StartA: 0, EndA: 7, StartB: 0, EndB: 7, LengthA: 7, LengthB: 7},
+			cigar:    "1M1D1M1I4M",
+			matches:  4, mismatches: 2, gaps: 2, gapOpenings: 2,
+			identity:  50,
+			matchLine: "| | |.|.",
+		},
+		{
+			name:  "no alignment",
+			align: align.SmithWatermanAlignment,
+			a:     "AAAA", b: "CCCC",
+			scoring:  scoring,
+			expected: align.Alignment{LengthA: 4, LengthB: 4},
+			cigar:    "*",
+		},
+	} {
+		alignment, err := test.align(test.a, test.b, test.scoring)
+		if err != nil {
+			t.Errorf("%s: error: %s", test.name, err)
+			continue
+		}
+		if alignment != test.expected {
+			t.Errorf("%s: got %+v, expected %+v", test.name, alignment, test.expected)
+		}
+		if alignment.CIGAR() != test.cigar {
+			t.Errorf("%s: got CIGAR %s, expected %s", test.name, alignment.CIGAR(), test.cigar)
+		}
+		if alignment.Matches() != test.matches || alignment.Mismatches() != test.mismatches || alignment.Gaps() != test.gaps || alignment.GapOpenings() != test.gapOpenings {
+			t.Errorf("%s: got %d matches, %d mismatches, %d gaps and %d gap openings, expected %d, %d, %d and %d", test.name, alignment.Matches(), alignment.Mismatches(), alignment.Gaps(), alignment.GapOpenings(), test.matches, test.mismatches, test.gaps, test.gapOpenings)
+		}
+		if math.Abs(alignment.Identity()-test.identity) > 1e-9 {
+			t.Errorf("%s: got identity %f, expected %f", test.name, alignment.Identity(), test.identity)
+		}
+		if alignment.MatchLine() != test.matchLine {
+			t.Errorf("%s: got match line %q, expected %q", test.name, alignment.MatchLine(), test.matchLine)
+		}
+	}
+}
+
+func TestAlignmentCIGAR(t *testing.T) {
+	// CIGARs of local alignments of a read to a reference cover the whole
+	// read and the aligned region of the reference.
+	scoring, err := align.NewAffineScoring(nil, -3, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	read := "TTTTGGATCCAAGCTTGAATTCAAAA"
+	reference := "CCCCCCCCGGATCCAAGCTTTTGAATTCCCCCCCC"
+	alignment, err := align.SmithWatermanAlignment(read, reference, scoring)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	cigar, err := sam.ParseCigar(alignment.CIGAR())
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	if cigar.QueryLength() != len(read) {
+		t.Errorf("CIGAR %s has query length %d, expected %d", cigar, cigar.QueryLength(), len(read))
+	}
+	if cigar.ReferenceLength() != alignment.EndB-alignment.StartB {
+		t.Errorf("CIGAR %s has reference length %d, expected %d", cigar, cigar.ReferenceLength(), alignment.EndB-alignment.StartB)
+	}
+	if reference[alignment.StartB:alignment.StartB+4] != "GGAT" || read[alignment.StartA:alignment.EndA] != "GGATCCAAGCTTGAATTC" {
+		t.Errorf("got alignment of %s to %s", read[alignment.StartA:alignment.EndA], reference[alignment.StartB:alignment.EndB])
+	}
+	if alignment.CIGAR() != "4S10M2D8M4S" {
+		t.Errorf("got CIGAR %s, expected 4S10M2D8M4S", alignment.CIGAR())
+	}
+}
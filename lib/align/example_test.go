@@ -109,3 +109,117 @@ func ExampleSmithWaterman_matrix_nuc_4() {
 
 	// Output: score: 15, A: GATTAC, B: GCATGC
 }
+
+func ExampleNewAffineScoring() {
+	a := "ACGTACGTTTTTTTTACGTACGT"
+	b := "ACGTACGTACGTACGT"
+
+	alphabet := alphabet.NewAlphabet([]string{"-", "A", "C", "G", "T"})
+	subMatrix, err := matrix.NewSubstitutionMatrix(alphabet, alphabet, matrix.NUC_4)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// gaps cost 10 to open and 1 to extend.
+	scoring, err := align.NewAffineScoring(subMatrix, -10, -1)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	score, alignA, alignB, err := align.NeedlemanWunsch(a, b, scoring)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("score: %d\n%s\n%s", score, alignA, alignB)
+
+	// Output: score: 64
+	// ACGTACGTTTTTTTTACGTACGT
+	// ACGTACG-------TACGTACGT
+}
+
+func ExampleSmithWatermanAlignment() {
+	read := "TTTTGGATCCAAGCTTGAATTCAAAA"
+	reference := "CCCCCCCCGGATCCAAGCTTTTGAATTCCCCCCCC"
+
+	scoring, err := align.NewAffineScoring(nil, -3, -1)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	alignment, err := align.SmithWatermanAlignment(read, reference, scoring)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("read %d-%d aligns to reference %d-%d\n", alignment.StartA, alignment.EndA, alignment.StartB, alignment.EndB)
+	fmt.Printf("CIGAR: %s, identity: %.1f%%\n", alignment.CIGAR(), alignment.Identity())
+	fmt.Println(alignment)
+
+	// Output: read 4-22 aligns to reference 8-28
+	// CIGAR: 4S10M2D8M4S, identity: 90.0%
+	// GGATCCAAGC--TTGAATTC
+	// ||||||||||  ||||||||
+	// GGATCCAAGCTTTTGAATTC
+}
+
+func ExampleOverlapAlignment() {
+	// the end of one Gibson fragment overlaps the start of the next.
+	fragmentA := "AAAATTTTGGATCCAAGC"
+	fragmentB := "GGATCCAAGCCCCCGGGG"
+
+	scoring, err := align.NewAffineScoring(nil, -3, -1)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	alignment, err := align.OverlapAlignment(fragmentA, fragmentB, scoring)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("overlap: %s, identity: %.1f%%", fragmentA[alignment.StartA:alignment.EndA], alignment.Identity())
+
+	// Output: overlap: GGATCCAAGC, identity: 100.0%
+}
+
+func ExampleMyersPattern_Search() {
+	// compile a barcode once to search it in many reads.
+	barcode := align.NewMyersPattern("GAAGCTCAAGCGTAAGCGGA")
+	reads := []string{
+		"TTCAGCACCTGAAGCTCAAGCGTAAGCGGAGAACCACAAC",
+		"TTCAGCACCTGAAGCTCAAGGTAAGCGGAGAACCACAAC", // deletion
+		"TTCAGCACCTCCTAGGGAAACAGCTATGACCATGAACCAC",
+	}
+	for _, read := range reads {
+		match := barcode.Search(read)
+		fmt.Printf("distance: %d, end: %d\n", match.Distance, match.End)
+	}
+
+	// Output: distance: 0, end: 30
+	// distance: 1, end: 29
+	// distance: 8, end: 18
+}
+
+func ExampleHirschbergAlignment() {
+	read := "GATTACAGATTACAGATTACAGATTACA"
+	reference := "GATTACAGATTCAGATTACAGGATTACA"
+
+	scoring, err := align.NewAffineScoring(nil, -3, -1)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	alignment, err := align.HirschbergAlignment(read, reference, scoring)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("score: %d, CIGAR: %s\n", alignment.Score, alignment.CIGAR())
+
+	// Output: score: 21, CIGAR: 11M1I10M1D6M
+}
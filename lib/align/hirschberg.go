@@ -0,0 +1,252 @@
+package align
+
+/******************************************************************************
+
+Long global alignments begin here
+
+NeedlemanWunsch keeps three matrices of len(stringA)*len(stringB) scores to
+trace back the alignment, so aligning a 10kb nanopore read to its 10kb
+reference takes gigabytes of memory. There are two ways around that.
+
+When the strings are nearly identical, like a read and its reference, the
+best alignment stays close to the diagonal of the matrices, so
+BandedNeedlemanWunschAlignment only fills in the diagonals near it. That
+takes memory and time proportional to the length of the strings times the
+width of the band, but finds the best alignment only if it stays within the
+band.
+
+HirschbergAlignment always finds the best alignment, without keeping the
+matrices. It scores the first half of stringA against stringB forwards and
+the second half backwards, keeping a single row of scores each, which tells
+it where the best alignment crosses the middle of stringA. It then aligns
+both halves the same way, recursively. That takes memory proportional to the
+length of the strings, and about twice the time of NeedlemanWunsch.
+
+Affine gaps need more care, since a gap can cross the middle of stringA, so
+HirschbergAlignment uses the extension of Myers and Miller. Myers, E.W. and
+Miller, W. (1988) Optimal alignments in linear space. CABIOS 4(1):11-17.
+
+******************************************************************************/
+
+// DefaultBandWidth is the minimum number of diagonals of
+// BandedNeedlemanWunschAlignment on each side of the diagonals between the
+// starts and ends of both strings, when it picks the band width.
+var DefaultBandWidth = 32
+
+// BandedNeedlemanWunschAlignment is NeedlemanWunschAlignment for nearly
+// identical strings, like a read and its reference, that only considers
+// alignments within bandWidth diagonals of the diagonals between the starts
+// and the ends of both strings. A bandWidth of 0 or less picks the larger of
+// DefaultBandWidth and the difference of the lengths of the strings. Memory is
+// proportional to the length of stringA times the sum of the difference of
+// the lengths of the strings and twice the bandWidth.
+func BandedNeedlemanWunschAlignment(stringA string, stringB string, scoring Scoring, bandWidth int) (Alignment, error) {
+	lengthDifference := len(stringB) - len(stringA)
+	if bandWidth <= 0 {
+		bandWidth = max(DefaultBandWidth, max(lengthDifference, -lengthDifference))
+	}
+	band := diagonalBand{low: min(0, lengthDifference) - bandWidth, high: max(0, lengthDifference) + bandWidth}
+	return bandedGotoh(stringA, stringB, scoring, globalMode, band)
+}
+
+// HirschbergAlignment is NeedlemanWunschAlignment in memory proportional to
+// the length of the strings, for long strings. It finds an alignment of the
+// same score as NeedlemanWunschAlignment, but may place gaps differently
+// when there are many best alignments.
+func HirschbergAlignment(stringA string, stringB string, scoring Scoring) (Alignment, error) {
+	openPenalty, extendPenalty := scoring.gapPenalties()
+	// a gap of length k scores gapOpen + k*gapExtend.
+	aligner := hirschberg{scoring: scoring, gapOpen: openPenalty - extendPenalty, gapExtend: extendPenalty}
+	if err := aligner.align(stringA, stringB, aligner.gapOpen, aligner.gapOpen); err != nil {
+		return Alignment{}, err
+	}
+	score, err := aligner.score()
+	if err != nil {
+		return Alignment{}, err
+	}
+	return Alignment{
+		Score:    score,
+		AlignedA: string(aligner.alignA),
+		AlignedB: string(aligner.alignB),
+		EndA:     len(stringA),
+		EndB:     len(stringB),
+		LengthA:  len(stringA),
+		LengthB:  len(stringB),
+	}, nil
+}
+
+// hirschberg builds an alignment from left to right.
+type hirschberg struct {
+	scoring   Scoring
+	gapOpen   int
+	gapExtend int
+	alignA    []byte
+	alignB    []byte
+}
+
+// gap returns the score of a gap.
+func (aligner *hirschberg) gap(length int) int {
+	if length == 0 {
+		return 0
+	}
+	return aligner.gapOpen + length*aligner.gapExtend
+}
+
+// appendColumns appends columns to the alignment.
+func (aligner *hirschberg) appendColumns(alignA string, alignB string) {
+	aligner.alignA = append(aligner.alignA, alignA...)
+	aligner.alignB = append(aligner.alignB, alignB...)
+}
+
+// appendGaps appends bases of stringA against gaps, or gaps against bases of
+// stringB.
+func (aligner *hirschberg) appendGaps(stringA string, stringB string) {
+	for i := 0; i < len(stringA); i++ {
+		aligner.appendColumns(stringA[i:i+1], "-")
+	}
+	for i := 0; i < len(stringB); i++ {
+		aligner.appendColumns("-", stringB[i:i+1])
+	}
+}
+
+// scoreRows returns the last row of the scores of stringA against the
+// prefixes of stringB, best and ending with a base of stringA against a gap.
+// startGapOpen is the open score of a gap in stringB at the start, which is
+// 0 if it continues a gap.
+func (aligner *hirschberg) scoreRows(stringA string, stringB string, startGapOpen int) ([]int, []int, error) {
+	best := make([]int, len(stringB)+1)
+	gapInB := make([]int, len(stringB)+1)
+	gap := aligner.gapOpen
+	for rowN := 1; rowN <= len(stringB); rowN++ {
+		gap += aligner.gapExtend
+		best[rowN] = gap
+		gapInB[rowN] = gap + aligner.gapOpen
+	}
+	gap = startGapOpen
+	for columnM := 1; columnM <= len(stringA); columnM++ {
+		diagonal := best[0]
+		gap += aligner.gapExtend
+		left := gap
+		best[0] = left
+		gapInA := gap + aligner.gapOpen
+		for rowN := 1; rowN <= len(stringB); rowN++ {
+			matchScore, err := aligner.scoring.Score(stringA[columnM-1], stringB[rowN-1])
+			if err != nil {
+				return nil, nil, err
+			}
+			gapInA = max(gapInA, left+aligner.gapOpen) + aligner.gapExtend
+			gapInB[rowN] = max(gapInB[rowN], best[rowN]+aligner.gapOpen) + aligner.gapExtend
+			left = max(diagonal+matchScore, max(gapInA, gapInB[rowN]))
+			diagonal, best[rowN] = best[rowN], left
+		}
+	}
+	gapInB[0] = best[0]
+	return best, gapInB, nil
+}
+
+// align appends the best alignment of stringA and stringB. startGapOpen and
+// endGapOpen are the open scores of gaps in stringB at the start and end of
+// the alignment, which are 0 if they continue a gap.
+func (aligner *hirschberg) align(stringA string, stringB string, startGapOpen int, endGapOpen int) error {
+	lengthM, lengthN := len(stringA), len(stringB)
+	switch {
+	case lengthM == 0 || lengthN == 0:
+		aligner.appendGaps(stringA, stringB)
+		return nil
+	case lengthM == 1:
+		// either stringA is against a gap, next to the gap that is free to
+		// open, or it is aligned to a base of stringB.
+		bestScore := max(startGapOpen, endGapOpen) + aligner.gapExtend + aligner.gap(lengthN)
+		bestRowN := 0
+		for rowN := 1; rowN <= lengthN; rowN++ {
+			matchScore, err := aligner.scoring.Score(stringA[0], stringB[rowN-1])
+			if err != nil {
+				return err
+			}
+			if score := aligner.gap(rowN-1) + matchScore + aligner.gap(lengthN-rowN); score > bestScore {
+				bestScore, bestRowN = score, rowN
+			}
+		}
+		switch {
+		case bestRowN > 0:
+			aligner.appendGaps("", stringB[:bestRowN-1])
+			aligner.appendColumns(stringA, stringB[bestRowN-1:bestRowN])
+			aligner.appendGaps("", stringB[bestRowN:])
+		case startGapOpen >= endGapOpen:
+			aligner.appendGaps(stringA, "")
+			aligner.appendGaps("", stringB)
+		default:
+			aligner.appendGaps("", stringB)
+			aligner.appendGaps(stringA, "")
+		}
+		return nil
+	}
+
+	// find where the best alignment crosses the middle of stringA, either
+	// between two bases of stringA or within a gap in stringB.
+	middleM := lengthM / 2
+	forwardBest, forwardGapInB, err := aligner.scoreRows(stringA[:middleM], stringB, startGapOpen)
+	if err != nil {
+		return err
+	}
+	reverseBest, reverseGapInB, err := aligner.scoreRows(reverse(stringA[middleM:]), reverse(stringB), endGapOpen)
+	if err != nil {
+		return err
+	}
+	bestScore, bestRowN, withinGap := negativeInfinity, 0, false
+	for rowN := 0; rowN <= lengthN; rowN++ {
+		if score := forwardBest[rowN] + reverseBest[lengthN-rowN]; score > bestScore {
+			bestScore, bestRowN, withinGap = score, rowN, false
+		}
+		if score := forwardGapInB[rowN] + reverseGapInB[lengthN-rowN] - aligner.gapOpen; score > bestScore {
+			bestScore, bestRowN, withinGap = score, rowN, true
+		}
+	}
+
+	if !withinGap {
+		if err := aligner.align(stringA[:middleM], stringB[:bestRowN], startGapOpen, aligner.gapOpen); err != nil {
+			return err
+		}
+		return aligner.align(stringA[middleM:], stringB[bestRowN:], aligner.gapOpen, endGapOpen)
+	}
+	if err := aligner.align(stringA[:middleM-1], stringB[:bestRowN], startGapOpen, 0); err != nil {
+		return err
+	}
+	aligner.appendGaps(stringA[middleM-1:middleM+1], "")
+	return aligner.align(stringA[middleM+1:], stringB[bestRowN:], 0, endGapOpen)
+}
+
+// score returns the score of the alignment.
+func (aligner *hirschberg) score() (int, error) {
+	var score int
+	for i := range aligner.alignA {
+		switch {
+		case aligner.alignA[i] == '-':
+			score += aligner.gapExtend
+			if i == 0 || aligner.alignA[i-1] != '-' {
+				score += aligner.gapOpen
+			}
+		case aligner.alignB[i] == '-':
+			score += aligner.gapExtend
+			if i == 0 || aligner.alignB[i-1] != '-' {
+				score += aligner.gapOpen
+			}
+		default:
+			matchScore, err := aligner.scoring.Score(aligner.alignA[i], aligner.alignB[i])
+			if err != nil {
+				return 0, err
+			}
+			score += matchScore
+		}
+	}
+	return score, nil
+}
+
+// reverse returns a string backwards.
+func reverse(sequence string) string {
+	reversed := make([]byte, len(sequence))
+	for i := range reversed {
+		reversed[i] = sequence[len(sequence)-1-i]
+	}
+	return string(reversed)
+}
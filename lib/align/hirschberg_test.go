@@ -0,0 +1,137 @@
+package align_test
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/koeng101/dnadesign/lib/align"
+)
+
+// mutate returns a sequence with random substitutions, insertions and
+// deletions.
+func mutate(random *rand.Rand, sequence string, edits int) string {
+	mutated := []byte(sequence)
+	for edit := 0; edit < edits && len(mutated) > 0; edit++ {
+		position := random.Intn(len(mutated))
+		base := "ACGT"[random.Intn(4)]
+		switch random.Intn(3) {
+		case 0:
+			mutated[position] = base
+		case 1:
+			mutated = append(mutated[:position], append([]byte{base}, mutated[position:]...)...)
+		default:
+			mutated = append(mutated[:position], mutated[position+1:]...)
+		}
+	}
+	return string(mutated)
+}
+
+func randomDNA(random *rand.Rand, length int) string {
+	sequence := make([]byte, length)
+	for i := range sequence {
+		sequence[i] = "ACGT"[random.Intn(4)]
+	}
+	return string(sequence)
+}
+
+func TestLongGlobalAlignments(t *testing.T) {
+	linear, err := align.NewScoring(nil, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	affine, err := align.NewAffineScoring(nil, -3, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		scoring := linear
+		if i%2 == 1 {
+			scoring = affine
+		}
+		// unrelated strings, and similar strings.
+		a := randomDNA(random, random.Intn(30))
+		b := randomDNA(random, random.Intn(30))
+		if i%3 == 0 {
+			b = mutate(random, a, random.Intn(6))
+		}
+		expected, err := align.NeedlemanWunschAlignment(a, b, scoring)
+		if err != nil {
+			t.Fatalf("error: %s", err)
+		}
+
+		// wide enough bands give the best alignment.
+		banded, err := align.BandedNeedlemanWunschAlignment(a, b, scoring, max(len(a), len(b)))
+		if err != nil {
+			t.Fatalf("error: %s", err)
+		}
+		if banded != expected {
+			t.Errorf("BandedNeedlemanWunschAlignment(%s, %s) got %+v, expected %+v", a, b, banded, expected)
+		}
+
+		hirschberg, err := align.HirschbergAlignment(a, b, scoring)
+		if err != nil {
+			t.Fatalf("error: %s", err)
+		}
+		if hirschberg.Score != expected.Score {
+			t.Errorf("HirschbergAlignment(%s, %s) got score %d, expected %d", a, b, hirschberg.Score, expected.Score)
+		}
+		if strings.ReplaceAll(hirschberg.AlignedA, "-", "") != a || strings.ReplaceAll(hirschberg.AlignedB, "-", "") != b {
+			t.Errorf("HirschbergAlignment(%s, %s) got alignment of other strings A: %s, B: %s", a, b, hirschberg.AlignedA, hirschberg.AlignedB)
+		}
+		if scoring == affine && affineScore(t, hirschberg.AlignedA, hirschberg.AlignedB, scoring) != expected.Score {
+			t.Errorf("HirschbergAlignment(%s, %s) got alignment A: %s, B: %s that doesn't score %d", a, b, hirschberg.AlignedA, hirschberg.AlignedB, expected.Score)
+		}
+	}
+
+	// a read of a plasmid with nanopore-like errors.
+	reference := randomDNA(random, 1000)
+	read := mutate(random, reference, 50)
+	expected, err := align.NeedlemanWunschAlignment(read, reference, affine)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	banded, err := align.BandedNeedlemanWunschAlignment(read, reference, affine, 0)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	hirschberg, err := align.HirschbergAlignment(read, reference, affine)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	if banded.Score != expected.Score || hirschberg.Score != expected.Score {
+		t.Errorf("got banded score %d and Hirschberg score %d, expected %d", banded.Score, hirschberg.Score, expected.Score)
+	}
+	if banded.CIGAR() != expected.CIGAR() {
+		t.Errorf("got banded CIGAR %s, expected %s", banded.CIGAR(), expected.CIGAR())
+	}
+}
+
+func TestBandedNeedlemanWunschAlignment(t *testing.T) {
+	scoring, err := align.NewAffineScoring(nil, -3, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	// an insertion and a deletion of 10 bases take the alignment 10
+	// diagonals away from the diagonal, outside of a band of 5 diagonals but
+	// within the band of DefaultBandWidth diagonals.
+	a := "GATTACAGATTACAGATTACA" + "CCCCCCCCCC" + "TGACCTAGGTCATCGATTGCA" + "GATTACAGATTACAGATTACA"
+	b := "GATTACAGATTACAGATTACA" + "TGACCTAGGTCATCGATTGCA" + "GGGGGGGGGG" + "GATTACAGATTACAGATTACA"
+	expected, err := align.NeedlemanWunschAlignment(a, b, scoring)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	for _, test := range []struct {
+		bandWidth int
+		best      bool
+	}{{0, true}, {5, false}, {10, true}} {
+		alignment, err := align.BandedNeedlemanWunschAlignment(a, b, scoring, test.bandWidth)
+		if err != nil {
+			t.Fatalf("error: %s", err)
+		}
+		if (alignment == expected) != test.best {
+			t.Errorf("band width %d got %+v, expected %+v", test.bandWidth, alignment, expected)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package align
+
+/******************************************************************************
+
+End gap free alignment modes begin here
+
+Global alignments penalize gaps at the ends of both strings, and local
+alignments don't align the ends at all. Many alignments are in between, where
+the ends of one or both strings are left unaligned without gap penalties:
+
+	SemiGlobalAlignment aligns all of a query, like a primer or an adapter,
+	to anywhere in a target, like a read:
+
+		    GGATCCAAGC
+		TTTTGGATCCAAGCAAAA
+
+	OverlapAlignment aligns the end of stringA to the start of stringB, like
+	the overlap of two Gibson fragments:
+
+		AAAATTTTGGATCCAAGC
+		        GGATCCAAGCCCCCGGGG
+
+	GlocalAlignment aligns two strings end to end, but without penalizing
+	gaps at their ends, like EMBOSS needle, so one string can contain the
+	other or they can overlap either way.
+
+All of them use the same Scoring as NeedlemanWunsch and SmithWaterman,
+including affine gaps, and return an Alignment with where the alignment
+starts and ends in both strings.
+
+******************************************************************************/
+
+var (
+	semiGlobalMode = alignmentMode{freeStartB: true, freeEndB: true}
+	overlapMode    = alignmentMode{freeStartA: true, freeEndB: true}
+	glocalMode     = alignmentMode{freeStartA: true, freeEndA: true, freeStartB: true, freeEndB: true}
+)
+
+// SemiGlobalAlignment aligns all of a query to any region of a target,
+// without penalizing the unaligned ends of the target, like finding a primer
+// in a read.
+func SemiGlobalAlignment(query string, target string, scoring Scoring) (Alignment, error) {
+	return gotoh(query, target, scoring, semiGlobalMode)
+}
+
+// OverlapAlignment aligns a suffix of stringA to a prefix of stringB, without
+// penalizing the unaligned start of stringA and end of stringB, like checking
+// the overlap of two Gibson fragments.
+func OverlapAlignment(stringA string, stringB string, scoring Scoring) (Alignment, error) {
+	return gotoh(stringA, stringB, scoring, overlapMode)
+}
+
+// GlocalAlignment aligns two strings end to end without penalizing gaps at
+// their ends, so one string can contain the other, or they can overlap either
+// way.
+func GlocalAlignment(stringA string, stringB string, scoring Scoring) (Alignment, error) {
+	return gotoh(stringA, stringB, scoring, glocalMode)
+}
@@ -0,0 +1,135 @@
+package align_test
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/koeng101/dnadesign/lib/align"
+)
+
+func TestAlignmentModes(t *testing.T) {
+	scoring, err := align.NewAffineScoring(nil, -3, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	for _, test := range []struct {
+		name     string
+		align    func(string, string, align.Scoring) (align.Alignment, error)
+		a, b     string
+		expected align.Alignment
+		cigar    string
+	}{
+		{
+			name:  "semi-global primer in read",
+			align: align.SemiGlobalAlignment,
+			a:     "GGATCCAAGC", b: "TTTTGGATCCAAGCAAAA",
+			expected: align.Alignment{Score: 10, AlignedA: "GGATCCAAGC", AlignedB: "GGATCCAAGC", StartA: 0, EndA: 10, StartB: 4, EndB: 14, LengthA: 10, LengthB: 18},
+			cigar:    "10M",
+		},
+		{
+			name:  "semi-global aligns all of the query",
+			align: align.SemiGlobalAlignment,
+			a:     "CCGGATCC", b: "TTTTGGATCCAAAA",
+			expected: align.Alignment{Score: 4, AlignedA: "CCGGATCC", AlignedB: "TTGGATCC", StartA: 0, EndA: 8, StartB: 2, EndB: 10, LengthA: 8, LengthB: 14},
+			cigar:    "8M",
+		},
+		{
+			name:  "overlap of Gibson fragments",
+			align: align.OverlapAlignment,
+			a:     "AAAATTTTGGATCCAAGC", b: "GGATCCAAGCCCCCGGGG",
+			expected: align.Alignment{Score: 10, AlignedA: "GGATCCAAGC", AlignedB: "GGATCCAAGC", StartA: 8, EndA: 18, StartB: 0, EndB: 10, LengthA: 18, LengthB: 18},
+			cigar:    "8S10M",
+		},
+		{
+			name:  "glocal containment",
+			align: align.GlocalAlignment,
+			a:     "TTTTGGATCCAAGCAAAA", b: "GGATCCAAGC",
+			expected: align.Alignment{Score: 10, AlignedA: "GGATCCAAGC", AlignedB: "GGATCCAAGC", StartA: 4, EndA: 14, StartB: 0, EndB: 10, LengthA: 18, LengthB: 10},
+			cigar:    "4S10M4S",
+		},
+		{
+			name:  "glocal overlap",
+			align: align.GlocalAlignment,
+			a:     "GGATCCAAGCCCCCGGGG", b: "AAAATTTTGGATCCAAGC",
+			expected: align.Alignment{Score: 10, AlignedA: "GGATCCAAGC", AlignedB: "GGATCCAAGC", StartA: 0, EndA: 10, StartB: 8, EndB: 18, LengthA: 18, LengthB: 18},
+			cigar:    "10M8S",
+		},
+	} {
+		alignment, err := test.align(test.a, test.b, scoring)
+		if err != nil {
+			t.Errorf("%s: error: %s", test.name, err)
+			continue
+		}
+		if alignment != test.expected {
+			t.Errorf("%s: got %+v, expected %+v", test.name, alignment, test.expected)
+		}
+		if alignment.CIGAR() != test.cigar {
+			t.Errorf("%s: got CIGAR %s, expected %s", test.name, alignment.CIGAR(), test.cigar)
+		}
+	}
+}
+
+func TestAlignmentModesExhaustive(t *testing.T) {
+	scoring, err := align.NewAffineScoring(nil, -3, -1)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	// each mode is the best global alignment of the regions of the strings
+	// that the mode allows.
+	for _, test := range []struct {
+		name  string
+		align func(string, string, align.Scoring) (align.Alignment, error)
+		// allowed returns whether a mode can align a region of stringA to a
+		// region of stringB.
+		allowed func(startA, endA, lengthA, startB, endB, lengthB int) bool
+	}{
+		{"SemiGlobalAlignment", align.SemiGlobalAlignment, func(startA, endA, lengthA, startB, endB, lengthB int) bool {
+			return startA == 0 && endA == lengthA
+		}},
+		{"OverlapAlignment", align.OverlapAlignment, func(startA, endA, lengthA, startB, endB, lengthB int) bool {
+			return endA == lengthA && startB == 0
+		}},
+		{"GlocalAlignment", align.GlocalAlignment, func(startA, endA, lengthA, startB, endB, lengthB int) bool {
+			return (startA == 0 || startB == 0) && (endA == lengthA || endB == lengthB)
+		}},
+	} {
+		random := rand.New(rand.NewSource(1))
+		randomSequence := func() string {
+			sequence := make([]byte, random.Intn(6))
+			for i := range sequence {
+				sequence[i] = "ACGT"[random.Intn(4)]
+			}
+			return string(sequence)
+		}
+		for i := 0; i < 100; i++ {
+			a, b := randomSequence(), randomSequence()
+			alignment, err := test.align(a, b, scoring)
+			if err != nil {
+				t.Fatalf("error: %s", err)
+			}
+			expected := math.MinInt
+			for startA := 0; startA <= len(a); startA++ {
+				for endA := startA; endA <= len(a); endA++ {
+					for startB := 0; startB <= len(b); startB++ {
+						for endB := startB; endB <= len(b); endB++ {
+							if test.allowed(startA, endA, len(a), startB, endB, len(b)) {
+								expected = max(expected, bestAffineScore(t, a[startA:endA], b[startB:endB], "", "", scoring))
+							}
+						}
+					}
+				}
+			}
+			if alignment.Score != expected {
+				t.Errorf("%s(%s, %s) got score %d, expected %d", test.name, a, b, alignment.Score, expected)
+			}
+			if !test.allowed(alignment.StartA, alignment.EndA, len(a), alignment.StartB, alignment.EndB, len(b)) ||
+				strings.ReplaceAll(alignment.AlignedA, "-", "") != a[alignment.StartA:alignment.EndA] ||
+				strings.ReplaceAll(alignment.AlignedB, "-", "") != b[alignment.StartB:alignment.EndB] ||
+				affineScore(t, alignment.AlignedA, alignment.AlignedB, scoring) != alignment.Score {
+				t.Errorf("%s(%s, %s) got alignment %+v that doesn't score %d", test.name, a, b, alignment, alignment.Score)
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package align
+
+/******************************************************************************
+
+Bit-parallel approximate string search begins here
+
+SmithWaterman fills a matrix of len(pattern)*len(text) cells one cell at a
+time, which is slow when you're looking for dozens of barcodes or primers in
+millions of reads. When all you need is how many edits (mismatches,
+insertions and deletions) away the best match of a pattern is, and where it
+ends, Myers' algorithm computes a whole column of the edit distance matrix at
+once with bitwise operations on machine words, one bit per base of the
+pattern.
+
+The trick is that neighbouring cells of an edit distance matrix differ by -1,
+0 or +1, so a column can be stored as two bit vectors: the positions where the
+distance goes up by one (Pv) and where it goes down by one (Mv) from the cell
+above. Moving to the next base of the text is a dozen bitwise operations on
+those vectors, so patterns of up to 64 bases are searched in O(n) time.
+Longer patterns are split into blocks of 64 bases, which pass the difference
+of their last cell down to the next block.
+
+The matrix is that of semi-global alignment: the pattern is fully aligned, and
+matches start and end anywhere in the text, so the distance at each base of
+the text is the edit distance of the pattern to the best substring of the text
+ending there.
+
+Patterns can have IUPAC codes for degenerate bases, like N in barcodes with
+random bases, and match any base of the text they share a base with. Matching
+is case insensitive, and U matches T.
+
+Myers, G. (1999) A fast bit-vector algorithm for approximate string matching
+based on dynamic programming. J. ACM 46(3):395-415.
+Hyyrö, H. (2003) A bit-vector algorithm for computing Levenshtein and
+Damerau edit distances. Nordic Journal of Computing 10:29-39.
+
+******************************************************************************/
+
+// wordSize is the number of bases of a pattern in a block.
+const wordSize = 64
+
+// iupacBases maps nucleotide IUPAC codes to the bases they match, as bits of
+// A, C, G and T.
+var iupacBases = [256]uint8{
+	'A': 0b0001, 'C': 0b0010, 'G': 0b0100, 'T': 0b1000, 'U': 0b1000,
+	'R': 0b0101, 'Y': 0b1010, 'S': 0b0110, 'W': 0b1001, 'K': 0b1100, 'M': 0b0011,
+	'B': 0b1110, 'D': 0b1101, 'H': 0b1011, 'V': 0b0111, 'N': 0b1111,
+}
+
+// toUpper returns the upper case of an ASCII letter.
+func toUpper(character byte) byte {
+	if 'a' <= character && character <= 'z' {
+		return character - 'a' + 'A'
+	}
+	return character
+}
+
+// iupacMatch returns whether a base of a pattern matches a base of a text.
+func iupacMatch(patternBase byte, textBase byte) bool {
+	patternBase, textBase = toUpper(patternBase), toUpper(textBase)
+	return patternBase == textBase || iupacBases[patternBase]&iupacBases[textBase] != 0
+}
+
+// MyersMatch is a match of a pattern in a text.
+type MyersMatch struct {
+	Distance int // edit distance of the pattern to the match
+	End      int // end of the match in the text, exclusive
+}
+
+// MyersPattern is a pattern compiled for bit-parallel search, which can be
+// reused to search many texts, like barcodes in reads.
+type MyersPattern struct {
+	length int
+	// peq has, for each base of a text, the bases of the pattern that match
+	// it, one bit per base in blocks of 64 bases.
+	peq [256][]uint64
+}
+
+// NewMyersPattern compiles a pattern for bit-parallel search.
+func NewMyersPattern(pattern string) *MyersPattern {
+	blocks := (len(pattern) + wordSize - 1) / wordSize
+	myersPattern := &MyersPattern{length: len(pattern)}
+	for textBase := range myersPattern.peq {
+		myersPattern.peq[textBase] = make([]uint64, blocks)
+		for i := 0; i < len(pattern); i++ {
+			if iupacMatch(pattern[i], byte(textBase)) {
+				myersPattern.peq[textBase][i/wordSize] |= 1 << (i % wordSize)
+			}
+		}
+	}
+	return myersPattern
+}
+
+// Search returns the best match of the pattern in a text, with the lowest
+// edit distance and the first end. Empty patterns match at the start of the
+// text.
+func (pattern *MyersPattern) Search(text string) MyersMatch {
+	best := MyersMatch{Distance: pattern.length}
+	pattern.scan(text, func(end int, distance int) {
+		if distance < best.Distance {
+			best = MyersMatch{Distance: distance, End: end}
+		}
+	})
+	return best
+}
+
+// SearchAll returns the matches of the pattern in a text with an edit
+// distance of at most maxDistance, by end. Since a match can be extended by
+// an insertion, neighbouring ends of a good match often match too.
+func (pattern *MyersPattern) SearchAll(text string, maxDistance int) []MyersMatch {
+	var matches []MyersMatch
+	if pattern.length <= maxDistance {
+		matches = append(matches, MyersMatch{Distance: pattern.length, End: 0})
+	}
+	pattern.scan(text, func(end int, distance int) {
+		if distance <= maxDistance {
+			matches = append(matches, MyersMatch{Distance: distance, End: end})
+		}
+	})
+	return matches
+}
+
+// scan calls found with the edit distance of the pattern to the best
+// substring of the text ending at each end.
+func (pattern *MyersPattern) scan(text string, found func(end int, distance int)) {
+	switch {
+	case pattern.length == 0:
+		for end := 1; end <= len(text); end++ {
+			found(end, 0)
+		}
+	case pattern.length <= wordSize:
+		pattern.scanWord(text, found)
+	default:
+		pattern.scanBlocks(text, found)
+	}
+}
+
+// scanWord is scan for patterns of up to 64 bases, which fit in a single
+// word.
+func (pattern *MyersPattern) scanWord(text string, found func(end int, distance int)) {
+	last := uint64(1) << (pattern.length - 1)
+	pv, mv := ^uint64(0), uint64(0)
+	distance := pattern.length
+	for i := 0; i < len(text); i++ {
+		eq := pattern.peq[text[i]][0]
+		xv := eq | mv
+		xh := (((eq & pv) + pv) ^ pv) | eq
+		ph := mv | ^(xh | pv)
+		mh := pv & xh
+		if ph&last != 0 {
+			distance++
+		} else if mh&last != 0 {
+			distance--
+		}
+		// matches start anywhere in the text, so the first row of the
+		// matrix is 0 and doesn't shift a difference into the column.
+		ph <<= 1
+		mh <<= 1
+		pv = mh | ^(xv | ph)
+		mv = ph & xv
+		found(i+1, distance)
+	}
+}
+
+// scanBlocks is scan for patterns of more than 64 bases, split in blocks of
+// 64 bases. Each block takes the difference of the last cell of the block
+// above it in the column, and passes its own to the block below it.
+func (pattern *MyersPattern) scanBlocks(text string, found func(end int, distance int)) {
+	blocks := len(pattern.peq[0])
+	pv := make([]uint64, blocks)
+	mv := make([]uint64, blocks)
+	for block := range pv {
+		pv[block] = ^uint64(0)
+	}
+	distance := pattern.length
+	for i := 0; i < len(text); i++ {
+		peq := pattern.peq[text[i]]
+		horizontal := 0
+		for block := 0; block < blocks; block++ {
+			// the difference out of a block is read from its last base.
+			last := uint64(1) << (wordSize - 1)
+			if block == blocks-1 {
+				last = uint64(1) << ((pattern.length - 1) % wordSize)
+			}
+			eq := peq[block]
+			xv := eq | mv[block]
+			if horizontal < 0 {
+				eq |= 1
+			}
+			xh := (((eq & pv[block]) + pv[block]) ^ pv[block]) | eq
+			ph := mv[block] | ^(xh | pv[block])
+			mh := pv[block] & xh
+			horizontalOut := 0
+			if ph&last != 0 {
+				horizontalOut = 1
+			} else if mh&last != 0 {
+				horizontalOut = -1
+			}
+			ph <<= 1
+			mh <<= 1
+			if horizontal < 0 {
+				mh |= 1
+			} else if horizontal > 0 {
+				ph |= 1
+			}
+			pv[block] = mh | ^(xv | ph)
+			mv[block] = ph & xv
+			horizontal = horizontalOut
+		}
+		distance += horizontal
+		found(i+1, distance)
+	}
+}
+
+// MyersSearch returns the best match of a pattern in a text, with the lowest
+// edit distance and the first end. To search many texts for the same
+// pattern, compile it once with NewMyersPattern.
+func MyersSearch(pattern string, text string) MyersMatch {
+	return NewMyersPattern(pattern).Search(text)
+}
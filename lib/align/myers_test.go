@@ -0,0 +1,115 @@
+package align_test
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/koeng101/dnadesign/lib/align"
+)
+
+// searchDistances returns the edit distance of a pattern to the best
+// substring of a text ending at each position of the text, by filling the
+// whole edit distance matrix. Sequences are ACGT and N.
+func searchDistances(pattern string, text string) []int {
+	column := make([]int, len(pattern)+1)
+	for i := range column {
+		column[i] = i
+	}
+	distances := []int{len(pattern)}
+	for j := 0; j < len(text); j++ {
+		diagonal := column[0]
+		column[0] = 0
+		for i := 1; i <= len(pattern); i++ {
+			substitution := 1
+			if pattern[i-1] == text[j] || pattern[i-1] == 'N' || text[j] == 'N' {
+				substitution = 0
+			}
+			diagonal, column[i] = column[i], min(diagonal+substitution, column[i]+1, column[i-1]+1)
+		}
+		distances = append(distances, column[len(pattern)])
+	}
+	return distances
+}
+
+func TestMyersSearch(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		pattern  string
+		text     string
+		expected align.MyersMatch
+	}{
+		{"exact", "GGATCC", "AAAAGGATCCAAAA", align.MyersMatch{Distance: 0, End: 10}},
+		{"mismatch", "GGATCC", "AAAAGGTTCCAAAA", align.MyersMatch{Distance: 1, End: 10}},
+		{"insertion", "GGATCC", "AAAAGGAATCCAAAA", align.MyersMatch{Distance: 1, End: 11}},
+		{"deletion", "GGATCC", "AAAAGGTCCAAAA", align.MyersMatch{Distance: 1, End: 9}},
+		{"IUPAC pattern", "GGNNCC", "AAAAGGATCCAAAA", align.MyersMatch{Distance: 0, End: 10}},
+		{"IUPAC text", "GGATCC", "AAAAGGRTCCAAAA", align.MyersMatch{Distance: 0, End: 10}},
+		{"lower case", "ggatcc", "AAAAGGATCCAAAA", align.MyersMatch{Distance: 0, End: 10}},
+		{"RNA", "GGAUCC", "AAAAGGATCCAAAA", align.MyersMatch{Distance: 0, End: 10}},
+		{"no match", "GGATCC", "", align.MyersMatch{Distance: 6, End: 0}},
+		{"empty pattern", "", "AAAA", align.MyersMatch{Distance: 0, End: 0}},
+		{"long pattern", strings.Repeat("GATTACA", 20), "TT" + strings.Repeat("GATTACA", 10) + "C" + strings.Repeat("GATTACA", 10) + "TT", align.MyersMatch{Distance: 1, End: 143}},
+	} {
+		if match := align.MyersSearch(test.pattern, test.text); match != test.expected {
+			t.Errorf("%s: got %+v, expected %+v", test.name, match, test.expected)
+		}
+	}
+}
+
+func TestMyersSearchAll(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomSequence := func(length int, bases string) string {
+		sequence := make([]byte, length)
+		for i := range sequence {
+			sequence[i] = bases[random.Intn(len(bases))]
+		}
+		return string(sequence)
+	}
+	// patterns of one word, of exactly one word and of many blocks.
+	for _, patternLength := range []int{1, 7, 20, 63, 64, 65, 128, 150, 200} {
+		for i := 0; i < 10; i++ {
+			pattern := randomSequence(patternLength, "ACGTACGTACGTN")
+			// texts with copies of the pattern with a few edits.
+			text := randomSequence(random.Intn(50), "ACGT")
+			for copies := 0; copies < 3; copies++ {
+				mutated := []byte(pattern)
+				for edit := 0; edit < random.Intn(5); edit++ {
+					mutated[random.Intn(len(mutated))] = "ACGT"[random.Intn(4)]
+				}
+				text += string(mutated) + randomSequence(random.Intn(50), "ACGT")
+			}
+			expected := searchDistances(pattern, text)
+			matches := align.NewMyersPattern(pattern).SearchAll(text, len(pattern))
+			if len(matches) != len(expected) {
+				t.Fatalf("pattern of %d bases: got %d matches, expected %d", patternLength, len(matches), len(expected))
+			}
+			for _, match := range matches {
+				if match.Distance != expected[match.End] {
+					t.Errorf("pattern %s: got distance %d at %d, expected %d", pattern, match.Distance, match.End, expected[match.End])
+				}
+			}
+		}
+	}
+}
+
+// barcode and read of the barcoding benchmarks: a 40 base barcode and primer
+// in the 120 bases at the edge of a read.
+var (
+	benchmarkBarcode = "GAAGCTCAAGCGTAAGCGGAGAACCACAACCTTAACCTGA"
+	benchmarkRead    = "TGTCCCTGTACTTCGTTCAGTTACGTATTGCTAAGGTTAACACAAAGACACCGACAACTTTCTTCAGCACCTGAAGCTCAAGCGTAAGCGGAGAACCACAACCTTAACCTGACGGCCAG"
+)
+
+func BenchmarkMyersSearch(b *testing.B) {
+	pattern := align.NewMyersPattern(benchmarkBarcode)
+	for i := 0; i < b.N; i++ {
+		pattern.Search(benchmarkRead)
+	}
+}
+
+func BenchmarkSmithWaterman(b *testing.B) {
+	scoring, _ := align.NewScoring(nil, -1)
+	for i := 0; i < b.N; i++ {
+		_, _, _, _ = align.SmithWaterman(benchmarkRead, benchmarkBarcode, scoring)
+	}
+}
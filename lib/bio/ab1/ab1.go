@@ -0,0 +1,278 @@
+/*
+Package ab1 provides a parser for ABI Sanger sequencing chromatograms.
+
+Sanger sequencers, like the Applied Biosystems 3730, save each read as an .ab1
+file. Besides the base calls, .ab1 files hold the quality of each base call,
+the location of each base call in the trace, and the fluorescence traces of
+the four dyes, which are needed to check a base call by eye.
+
+.ab1 files are in the Applied Biosystems Genetic Analysis Data File Format
+(ABIF). ABIF is a binary format with a directory of tagged entries, each
+pointing to the data of the entry somewhere in the file. All integers are big
+endian. The file starts with a header:
+
+	magic number  "ABIF"
+	version       uint16
+	directory     28 byte entry pointing to the directory
+
+and each entry of the directory is 28 bytes:
+
+	name          4 characters, like "PBAS"
+	number        int32, since the same name is used for several entries
+	element type  int16, like 2 for characters or 4 for int16s
+	element size  int16
+	elements      int32
+	data size     int32
+	data offset   int32, or the data itself if it is 4 bytes or less
+	data handle   int32, unused
+
+This package reads the entries with the base calls (PBAS), qualities (PCON),
+peak locations (PLOC), traces (DATA) and sample name (SMPL), and keeps all
+entries for anything else. The format is described by Applied Biosystems at:
+https://projects.nfstc.org/workshops/resources/articles/ABIF_File_Format.pdf
+
+The test file, data/puc19_m13rev.ab1, was written to that description
+rather than by a sequencer.
+
+Sanger reads have low quality at their start and end, which should be trimmed
+before the read is aligned to a reference. MottTrim trims reads with the
+modified Mott algorithm of the phred basecaller.
+*/
+package ab1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/koeng101/dnadesign/lib/bio/fastq"
+)
+
+// entrySize is the size of directory entries.
+const entrySize = 28
+
+// headerSize is the size of the magic number, version and root entry.
+const headerSize = 6 + entrySize
+
+var magic = []byte("ABIF")
+
+// Element types of entries.
+const (
+	typeChar    = 2
+	typeShort   = 4
+	typePString = 18
+	typeCString = 19
+)
+
+// DefaultMottCutoff is the error probability cutoff of MottTrim used by
+// phred, which is about a quality of 13.
+const DefaultMottCutoff = 0.05
+
+// Entry is an entry of the directory of an ABIF file.
+type Entry struct {
+	Name        string
+	Number      int
+	ElementType int
+	ElementSize int
+	NumElements int
+	Data        []byte
+}
+
+// Trace is a Sanger sequencing read.
+type Trace struct {
+	Name          string         // sample name
+	Well          string         // well of the sample in the sequencing plate
+	Sequence      string         // base calls
+	Quality       []uint8        // phred quality of each base call
+	PeakLocations []int          // location of each base call in the traces
+	Traces        map[byte][]int // fluorescence of each base, by base
+	Entries       []Entry        // all entries of the file
+}
+
+// Parse parses an .ab1 file.
+func Parse(r io.Reader) (Trace, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Trace{}, err
+	}
+	if len(data) < headerSize || !bytes.Equal(data[:len(magic)], magic) {
+		return Trace{}, errors.New("Not an ABIF file. ABIF files start with ABIF")
+	}
+	root, err := parseEntry(data, data[6:headerSize])
+	if err != nil {
+		return Trace{}, fmt.Errorf("Failed to read directory: %w", err)
+	}
+	if root.ElementSize != entrySize || len(root.Data) != root.NumElements*entrySize {
+		return Trace{}, fmt.Errorf("Invalid directory of %d entries of %d bytes", root.NumElements, root.ElementSize)
+	}
+
+	var trace Trace
+	for i := 0; i < root.NumElements; i++ {
+		entry, err := parseEntry(data, root.Data[i*entrySize:(i+1)*entrySize])
+		if err != nil {
+			return Trace{}, fmt.Errorf("Failed to read directory entry %d: %w", i, err)
+		}
+		trace.Entries = append(trace.Entries, entry)
+	}
+
+	trace.Name = trace.text("SMPL", 1)
+	trace.Well = trace.text("TUBE", 1)
+	// Entries numbered 2 are the base calls of the basecaller, and entries
+	// numbered 1 are the base calls edited by the user, if any.
+	for _, number := range []int{2, 1} {
+		if _, ok := trace.Entry("PBAS", number); ok {
+			trace.Sequence = trace.text("PBAS", number)
+			trace.PeakLocations = trace.shorts("PLOC", number)
+			if quality, ok := trace.Entry("PCON", number); ok {
+				trace.Quality = quality.Data
+			}
+			break
+		}
+	}
+	if len(trace.PeakLocations) != len(trace.Sequence) {
+		return Trace{}, fmt.Errorf("Got %d peak locations for %d base calls", len(trace.PeakLocations), len(trace.Sequence))
+	}
+	if trace.Quality != nil && len(trace.Quality) != len(trace.Sequence) {
+		return Trace{}, fmt.Errorf("Got %d qualities for %d base calls", len(trace.Quality), len(trace.Sequence))
+	}
+
+	// The analyzed traces are entries 9 to 12, in the order of the bases
+	// of the FWO_ entry.
+	baseOrder := trace.text("FWO_", 1)
+	if len(baseOrder) == 4 {
+		trace.Traces = make(map[byte][]int)
+		for i := 0; i < 4; i++ {
+			trace.Traces[baseOrder[i]] = trace.shorts("DATA", 9+i)
+		}
+	}
+	return trace, nil
+}
+
+// parseEntry parses the 28 bytes of a directory entry of an ABIF file.
+func parseEntry(data []byte, entryData []byte) (Entry, error) {
+	entry := Entry{
+		Name:        string(entryData[:4]),
+		Number:      int(int32(binary.BigEndian.Uint32(entryData[4:]))),
+		ElementType: int(int16(binary.BigEndian.Uint16(entryData[8:]))),
+		ElementSize: int(int16(binary.BigEndian.Uint16(entryData[10:]))),
+		NumElements: int(int32(binary.BigEndian.Uint32(entryData[12:]))),
+	}
+	size := int(int32(binary.BigEndian.Uint32(entryData[16:])))
+	if size < 0 {
+		return entry, fmt.Errorf("Entry %s%d has a negative size", entry.Name, entry.Number)
+	}
+	// Data of 4 bytes or less is stored in place of its offset.
+	if size <= 4 {
+		entry.Data = entryData[20 : 20+size]
+		return entry, nil
+	}
+	offset := int(int32(binary.BigEndian.Uint32(entryData[20:])))
+	if offset < 0 || offset+size > len(data) {
+		return entry, fmt.Errorf("Entry %s%d of %d bytes at byte %d is outside of the file: %w", entry.Name, entry.Number, size, offset, io.ErrUnexpectedEOF)
+	}
+	entry.Data = data[offset : offset+size]
+	return entry, nil
+}
+
+// Entry returns the directory entry with a name and number, like PBAS2.
+func (trace *Trace) Entry(name string, number int) (Entry, bool) {
+	for _, entry := range trace.Entries {
+		if entry.Name == name && entry.Number == number {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// text returns the text of an entry of characters or strings.
+func (trace *Trace) text(name string, number int) string {
+	entry, ok := trace.Entry(name, number)
+	if !ok {
+		return ""
+	}
+	switch entry.ElementType {
+	case typePString:
+		if len(entry.Data) == 0 || int(entry.Data[0]) >= len(entry.Data) {
+			return ""
+		}
+		return string(entry.Data[1 : 1+int(entry.Data[0])])
+	case typeCString:
+		return strings.TrimRight(string(entry.Data), "\x00")
+	case typeChar:
+		return string(entry.Data)
+	}
+	return ""
+}
+
+// shorts returns the values of an entry of int16s.
+func (trace *Trace) shorts(name string, number int) []int {
+	entry, ok := trace.Entry(name, number)
+	if !ok || entry.ElementType != typeShort {
+		return nil
+	}
+	values := make([]int, len(entry.Data)/2)
+	for i := range values {
+		values[i] = int(int16(binary.BigEndian.Uint16(entry.Data[i*2:])))
+	}
+	return values
+}
+
+// ToFastq converts a trace to a fastq read, named after the sample.
+func (trace Trace) ToFastq() fastq.Read {
+	quality := make([]byte, len(trace.Sequence))
+	for i := range quality {
+		// traces without qualities get the lowest quality.
+		if trace.Quality != nil {
+			quality[i] = min(trace.Quality[i], '~'-'!')
+		}
+		quality[i] += '!'
+	}
+	read := fastq.Read{Identifier: trace.Name, Optionals: make(map[string]string), Sequence: trace.Sequence, Quality: string(quality)}
+	if trace.Well != "" {
+		read.Optionals["well"] = trace.Well
+	}
+	return read
+}
+
+// MottTrim returns the start and end of the high quality part of a read with
+// the modified Mott algorithm, which phred uses to trim reads. Each base
+// scores cutoff minus its error probability, and the high quality part of the
+// read is the part with the highest total score. It is
+// sequence[start:end], and is empty if all bases have an error probability
+// above cutoff.
+func MottTrim(quality []uint8, cutoff float64) (int, int) {
+	var score, bestScore float64
+	var start, bestStart, bestEnd int
+	for i, phred := range quality {
+		score += cutoff - math.Pow(10, -float64(phred)/10)
+		if score <= 0 {
+			score = 0
+			start = i + 1
+			continue
+		}
+		if score > bestScore {
+			bestScore = score
+			bestStart, bestEnd = start, i+1
+		}
+	}
+	return bestStart, bestEnd
+}
+
+// Trim returns the trace with the low quality base calls at the start and end
+// of the read removed by MottTrim. The traces are kept whole, so the peak
+// locations of the trimmed base calls still point into them. Traces without
+// qualities can't be trimmed, and are returned unchanged.
+func (trace Trace) Trim(cutoff float64) Trace {
+	if len(trace.Quality) == 0 {
+		return trace
+	}
+	start, end := MottTrim(trace.Quality, cutoff)
+	trace.Sequence = trace.Sequence[start:end]
+	trace.Quality = trace.Quality[start:end]
+	trace.PeakLocations = trace.PeakLocations[start:end]
+	return trace
+}
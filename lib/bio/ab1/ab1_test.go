@@ -0,0 +1,245 @@
+package ab1
+
+import (
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func readFile(t *testing.T, path string) Trace {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	trace, err := Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trace
+}
+
+// buildFile builds an ABIF file with the data of entries after the header,
+// followed by the directory.
+func buildFile(entries []Entry) []byte {
+	file := make([]byte, 128)
+	offsets := make([]int, len(entries))
+	for i, entry := range entries {
+		offsets[i] = len(file)
+		if len(entry.Data) > 4 {
+			file = append(file, entry.Data...)
+		}
+	}
+	appendEntry := func(buffer []byte, entry Entry, offset int) []byte {
+		buffer = append(buffer, entry.Name...)
+		buffer = binary.BigEndian.AppendUint32(buffer, uint32(entry.Number))
+		buffer = binary.BigEndian.AppendUint16(buffer, uint16(entry.ElementType))
+		buffer = binary.BigEndian.AppendUint16(buffer, uint16(entry.ElementSize))
+		buffer = binary.BigEndian.AppendUint32(buffer, uint32(entry.NumElements))
+		buffer = binary.BigEndian.AppendUint32(buffer, uint32(len(entry.Data)))
+		if len(entry.Data) <= 4 {
+			var data [4]byte
+			copy(data[:], entry.Data)
+			buffer = append(buffer, data[:]...)
+		} else {
+			buffer = binary.BigEndian.AppendUint32(buffer, uint32(offset))
+		}
+		return binary.BigEndian.AppendUint32(buffer, 0)
+	}
+	var directory []byte
+	for i, entry := range entries {
+		directory = appendEntry(directory, entry, offsets[i])
+	}
+	root := appendEntry([]byte("ABIF\x00\x65"), Entry{Name: "tdir", Number: 1, ElementType: 1023, ElementSize: entrySize, NumElements: len(entries), Data: directory}, len(file))
+	copy(file, root)
+	return append(file, directory...)
+}
+
+// TestParse parses puc19_m13rev.ab1, which was written with the entries of a
+// 3730 file (PBAS2, PCON2, PLOC2 and DATA9-12) rather than by a sequencer.
+func TestParse(t *testing.T) {
+	trace := readFile(t, "data/puc19_m13rev.ab1")
+	if trace.Name != "pUC19_M13rev" || trace.Well != "A01" {
+		t.Errorf("unexpected name %s or well %s", trace.Name, trace.Well)
+	}
+	if len(trace.Sequence) != 293 || !strings.HasPrefix(trace.Sequence, "GCNTCNGGNTCGTATG") {
+		t.Errorf("unexpected sequence %s", trace.Sequence)
+	}
+	if len(trace.Quality) != 293 || trace.Quality[0] != 12 || trace.Quality[25] != 42 {
+		t.Errorf("unexpected quality %v", trace.Quality)
+	}
+	if diff := cmp.Diff([]int{20, 32, 44}, trace.PeakLocations[:3]); diff != "" {
+		t.Errorf("unexpected peak locations (-want +got):\n%s", diff)
+	}
+	if len(trace.Traces) != 4 || len(trace.Traces['A']) != 3556 {
+		t.Fatalf("unexpected traces")
+	}
+	// the peak of each high quality base call is the highest trace.
+	for i := 25; i < 250; i++ {
+		peak := trace.PeakLocations[i]
+		called := trace.Traces[trace.Sequence[i]][peak]
+		for base, values := range trace.Traces {
+			if base != trace.Sequence[i] && values[peak] >= called {
+				t.Fatalf("base call %d is %c, but the %c trace is higher", i, trace.Sequence[i], base)
+			}
+		}
+	}
+	// 4 byte entries are stored in the directory.
+	entry, ok := trace.Entry("FWO_", 1)
+	if !ok || string(entry.Data) != "GATC" {
+		t.Errorf("unexpected FWO_ entry %+v", entry)
+	}
+	if _, ok := trace.Entry("PBAS", 3); ok {
+		t.Errorf("expected no PBAS3 entry")
+	}
+}
+
+func TestParseEditedBaseCalls(t *testing.T) {
+	// files without base calls of the basecaller use the edited base calls.
+	file := buildFile([]Entry{
+		{Name: "PBAS", Number: 1, ElementType: typeChar, ElementSize: 1, NumElements: 5, Data: []byte("ATGCA")},
+		{Name: "PLOC", Number: 1, ElementType: typeShort, ElementSize: 2, NumElements: 5, Data: []byte{0, 1, 0, 2, 0, 3, 0, 4, 0, 5}},
+		{Name: "SMPL", Number: 1, ElementType: typeCString, ElementSize: 1, NumElements: 5, Data: []byte("test\x00")},
+	})
+	trace, err := Parse(strings.NewReader(string(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.Sequence != "ATGCA" || trace.Name != "test" || trace.Quality != nil || trace.Traces != nil {
+		t.Errorf("unexpected trace %+v", trace)
+	}
+	if read := trace.ToFastq(); read.Quality != "!!!!!" {
+		t.Errorf("expected lowest quality for base calls without quality, got %s", read.Quality)
+	}
+}
+
+func TestParseEntryChoices(t *testing.T) {
+	// puc19_m13rev.ab1 has the same base calls in PBAS1 and PBAS2, and its
+	// bases in GATC order, so this file has different ones.
+	shorts := func(values ...int16) []byte {
+		var data []byte
+		for _, value := range values {
+			data = binary.BigEndian.AppendUint16(data, uint16(value))
+		}
+		return data
+	}
+	file := buildFile([]Entry{
+		{Name: "PBAS", Number: 1, ElementType: typeChar, ElementSize: 1, NumElements: 3, Data: []byte("AAA")},
+		{Name: "PBAS", Number: 2, ElementType: typeChar, ElementSize: 1, NumElements: 3, Data: []byte("ATG")},
+		{Name: "PCON", Number: 1, ElementType: typeChar, ElementSize: 1, NumElements: 3, Data: []byte{1, 1, 1}},
+		{Name: "PCON", Number: 2, ElementType: typeChar, ElementSize: 1, NumElements: 3, Data: []byte{20, 30, 40}},
+		{Name: "PLOC", Number: 1, ElementType: typeShort, ElementSize: 2, NumElements: 3, Data: shorts(1, 1, 1)},
+		{Name: "PLOC", Number: 2, ElementType: typeShort, ElementSize: 2, NumElements: 3, Data: shorts(0, 1, 2)},
+		{Name: "FWO_", Number: 1, ElementType: typeChar, ElementSize: 1, NumElements: 4, Data: []byte("ACGT")},
+		{Name: "DATA", Number: 9, ElementType: typeShort, ElementSize: 2, NumElements: 3, Data: shorts(9, 0, 0)},
+		{Name: "DATA", Number: 10, ElementType: typeShort, ElementSize: 2, NumElements: 3, Data: shorts(0, 0, 10)},
+		{Name: "DATA", Number: 11, ElementType: typeShort, ElementSize: 2, NumElements: 3, Data: shorts(0, 0, 11)},
+		{Name: "DATA", Number: 12, ElementType: typeShort, ElementSize: 2, NumElements: 3, Data: shorts(0, 12, 0)},
+		{Name: "SMPL", Number: 1, ElementType: typePString, ElementSize: 1, NumElements: 5, Data: []byte("\x04test")},
+		{Name: "TUBE", Number: 1, ElementType: typeCString, ElementSize: 1, NumElements: 4, Data: []byte("A01\x00")},
+	})
+	trace, err := Parse(strings.NewReader(string(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the base calls of the basecaller are used over edited ones.
+	if trace.Sequence != "ATG" || string(trace.Quality) != string([]byte{20, 30, 40}) {
+		t.Errorf("expected the base calls of PBAS2 and PCON2, got %s %v", trace.Sequence, trace.Quality)
+	}
+	if diff := cmp.Diff([]int{0, 1, 2}, trace.PeakLocations); diff != "" {
+		t.Errorf("expected the peak locations of PLOC2 (-want +got):\n%s", diff)
+	}
+	// DATA9 to DATA12 are the traces of the bases of FWO_, in order.
+	expectedTraces := map[byte][]int{'A': {9, 0, 0}, 'C': {0, 0, 10}, 'G': {0, 0, 11}, 'T': {0, 12, 0}}
+	if diff := cmp.Diff(expectedTraces, trace.Traces); diff != "" {
+		t.Errorf("expected the traces of DATA9-12 in FWO_ order (-want +got):\n%s", diff)
+	}
+	if trace.Name != "test" || trace.Well != "A01" {
+		t.Errorf("unexpected pString name %q or cString well %q", trace.Name, trace.Well)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	valid := buildFile([]Entry{
+		{Name: "PBAS", Number: 2, ElementType: typeChar, ElementSize: 1, NumElements: 5, Data: []byte("ATGCA")},
+		{Name: "PLOC", Number: 2, ElementType: typeShort, ElementSize: 2, NumElements: 5, Data: []byte{0, 1, 0, 2, 0, 3, 0, 4, 0, 5}},
+	})
+	if _, err := Parse(strings.NewReader(string(valid))); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name string
+		file []byte
+	}{
+		{"empty", nil},
+		{"not abif", []byte(strings.Repeat("FASTA", 30))},
+		{"truncated", valid[:len(valid)-10]},
+		{"truncated data", valid[:130]},
+		{"missing peak locations", buildFile([]Entry{
+			{Name: "PBAS", Number: 2, ElementType: typeChar, ElementSize: 1, NumElements: 5, Data: []byte("ATGCA")},
+		})},
+		{"missing qualities", buildFile([]Entry{
+			{Name: "PBAS", Number: 2, ElementType: typeChar, ElementSize: 1, NumElements: 5, Data: []byte("ATGCA")},
+			{Name: "PCON", Number: 2, ElementType: typeChar, ElementSize: 1, NumElements: 4, Data: []byte{20, 20, 20, 20}},
+			{Name: "PLOC", Number: 2, ElementType: typeShort, ElementSize: 2, NumElements: 5, Data: []byte{0, 1, 0, 2, 0, 3, 0, 4, 0, 5}},
+		})},
+	} {
+		if _, err := Parse(strings.NewReader(string(test.file))); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestToFastq(t *testing.T) {
+	trace := Trace{Name: "sample", Well: "B02", Sequence: "ATGC", Quality: []uint8{0, 20, 40, 99}}
+	read := trace.ToFastq()
+	if read.Identifier != "sample" || read.Sequence != "ATGC" || read.Optionals["well"] != "B02" {
+		t.Errorf("unexpected read %+v", read)
+	}
+	// qualities above 93 can't be written in fastq files.
+	if read.Quality != "!5I~" {
+		t.Errorf("unexpected quality %s", read.Quality)
+	}
+}
+
+func TestMottTrim(t *testing.T) {
+	for _, test := range []struct {
+		quality    []uint8
+		start, end int
+	}{
+		{[]uint8{40, 40, 40}, 0, 3},
+		{[]uint8{5, 5, 40, 40, 40, 5, 5}, 2, 5},
+		// a single low quality base doesn't split a high quality read.
+		{[]uint8{40, 40, 40, 40, 10, 40, 40, 40, 40}, 0, 9},
+		{[]uint8{5, 5, 5}, 0, 0},
+		{nil, 0, 0},
+	} {
+		start, end := MottTrim(test.quality, DefaultMottCutoff)
+		if start != test.start || end != test.end {
+			t.Errorf("MottTrim(%v): expected %d-%d, got %d-%d", test.quality, test.start, test.end, start, end)
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	trace := readFile(t, "data/puc19_m13rev.ab1")
+	trimmed := trace.Trim(DefaultMottCutoff)
+	if trimmed.Sequence != trace.Sequence[11:254] || len(trimmed.Quality) != len(trimmed.Sequence) || trimmed.PeakLocations[0] != trace.PeakLocations[11] {
+		t.Errorf("unexpected trimmed trace %s", trimmed.Sequence)
+	}
+	if strings.Contains(trimmed.Sequence, "N") {
+		t.Errorf("trimmed sequence should have no N base calls")
+	}
+	if len(trace.Sequence) != 293 {
+		t.Errorf("trimming should not change the original trace")
+	}
+
+	trace.Quality = nil
+	if untrimmed := trace.Trim(DefaultMottCutoff); untrimmed.Sequence != trace.Sequence {
+		t.Errorf("traces without qualities should not be trimmed, got %s", untrimmed.Sequence)
+	}
+}
@@ -0,0 +1,35 @@
+package ab1_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/koeng101/dnadesign/lib/align"
+	"github.com/koeng101/dnadesign/lib/bio"
+	"github.com/koeng101/dnadesign/lib/bio/ab1"
+)
+
+func Example_trimAndAlign() {
+	file, _ := os.Open("data/puc19_m13rev.ab1")
+	defer file.Close()
+	trace, _ := ab1.Parse(file)
+
+	// Sanger reads start and end with low quality base calls, which are
+	// trimmed before aligning the read to the expected sequence.
+	trimmed := trace.Trim(ab1.DefaultMottCutoff)
+	read := trimmed.ToFastq()
+	fmt.Println(read.Identifier, len(trace.Sequence), "bp,", len(read.Sequence), "bp after trimming")
+
+	plasmidFile, _ := os.Open("../genbank/data/puc19.gbk")
+	defer plasmidFile.Close()
+	parser := bio.NewGenbankParser(plasmidFile)
+	plasmid, _ := parser.Next()
+
+	scoring, _ := align.NewScoring(nil, -1)
+	score, alignedRead, alignedPlasmid, _ := align.SmithWaterman(read.Sequence, strings.ToUpper(plasmid.Sequence), scoring)
+	fmt.Println("score", score, "identical:", alignedRead == alignedPlasmid)
+	// Output:
+	// pUC19_M13rev 293 bp, 243 bp after trimming
+	// score 243 identical: true
+}
@@ -0,0 +1,771 @@
+/*
+Package bam implements a BAM file parser and writer.
+
+BAM is the binary equivalent of SAM. It stores exactly the same data as a
+SAM file, but with numbers, CIGAR operations, and sequences packed into
+binary, and the whole file compressed with BGZF (see the bgzf package). BAM
+files are typically what aligners and samtools produce, since they are much
+smaller and faster to process than SAM.
+
+This package reads and writes BAM files using the same Header and Alignment
+types as the sam package, so that BAM and SAM files can be used
+interchangeably with the rest of the library. Converting between the two is
+as simple as parsing one and writing the other.
+
+Spec: section 4.2 of http://samtools.github.io/hts-specs/SAMv1.pdf
+Spec(locally): `dnadesign/lib/bio/sam/SAMv1.pdf`
+*/
+package bam
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/koeng101/dnadesign/lib/bio/bgzf"
+	"github.com/koeng101/dnadesign/lib/bio/sam"
+)
+
+// magic is the first four bytes of every decompressed BAM file.
+var magic = []byte("BAM\x01")
+
+const (
+	cigarOperations   = "MIDNSHP=X"
+	sequenceAlphabet  = "=ACMGRSVTWYHKDBN"
+	fixedRecordLength = 32 // Length of the fixed-size portion of an alignment record.
+)
+
+// Reference is a reference sequence from the BAM reference dictionary.
+// Alignments refer to references by their index in this list.
+type Reference struct {
+	Name   string
+	Length int32
+}
+
+/******************************************************************************
+
+BAM parser
+
+******************************************************************************/
+
+// Parser is a BAM file parser that provides sample control over reading BAM
+// alignments. It should be initialized with NewParser.
+type Parser struct {
+	reader     io.Reader
+	header     sam.Header
+	references []Reference
+	record     []byte
+	alignment  uint
+}
+
+// Header returns the parsed sam header.
+func (p *Parser) Header() (sam.Header, error) {
+	return p.header, nil
+}
+
+// References returns the reference dictionary of the BAM file.
+func (p *Parser) References() []Reference {
+	return p.references
+}
+
+// NewParser creates a parser from an io.Reader of BGZF compressed BAM data.
+// Like sam.NewParser, the header is read before the parser is returned.
+func NewParser(r io.Reader) (*Parser, sam.Header, error) {
+	return NewParserFromDecompressed(bgzf.NewReader(r))
+}
+
+// NewParserFromDecompressed creates a parser from an io.Reader of BAM data
+// that has already been decompressed. This is useful if the BGZF stream is
+// being decompressed elsewhere, for example in parallel.
+func NewParserFromDecompressed(r io.Reader) (*Parser, sam.Header, error) {
+	parser := &Parser{reader: r}
+	fileMagic := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, fileMagic); err != nil {
+		return parser, sam.Header{}, fmt.Errorf("Failed to read BAM magic: %w", err)
+	}
+	if !bytes.Equal(fileMagic, magic) {
+		return parser, sam.Header{}, fmt.Errorf("Invalid BAM magic. Expected %q, got %q", magic, fileMagic)
+	}
+
+	// The header is stored as plain SAM header text.
+	headerText, err := parser.readLengthPrefixed()
+	if err != nil {
+		return parser, sam.Header{}, fmt.Errorf("Failed to read BAM header text: %w", err)
+	}
+	// Some writers pad the header text with NULs.
+	header, err := sam.ParseHeader(string(bytes.TrimRight(headerText, "\x00")))
+	if err != nil {
+		return parser, sam.Header{}, err
+	}
+
+	// The reference dictionary follows the header text.
+	var referenceCount int32
+	if err = binary.Read(r, binary.LittleEndian, &referenceCount); err != nil {
+		return parser, sam.Header{}, fmt.Errorf("Failed to read BAM reference count: %w", err)
+	}
+	if referenceCount < 0 {
+		return parser, sam.Header{}, fmt.Errorf("Invalid BAM reference count: %d", referenceCount)
+	}
+	for i := int32(0); i < referenceCount; i++ {
+		name, err := parser.readLengthPrefixed()
+		if err != nil {
+			return parser, sam.Header{}, fmt.Errorf("Failed to read name of reference %d: %w", i, err)
+		}
+		var length int32
+		if err = binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return parser, sam.Header{}, fmt.Errorf("Failed to read length of reference %d: %w", i, err)
+		}
+		parser.references = append(parser.references, Reference{Name: string(bytes.TrimRight(name, "\x00")), Length: length})
+	}
+
+	// SAM headers are not required to have @SQ lines in BAM files, since
+	// the reference dictionary is stored separately. If they are missing,
+	// we add them so that the header can be written as a valid SAM file.
+	if len(header.SQ) == 0 {
+		for _, reference := range parser.references {
+			header.SQ = append(header.SQ, map[string]string{"SN": reference.Name, "LN": strconv.Itoa(int(reference.Length))})
+		}
+	}
+	parser.header = header
+	return parser, header, nil
+}
+
+// readLengthPrefixed reads an int32 length followed by that many bytes.
+func (p *Parser) readLengthPrefixed() ([]byte, error) {
+	var length int32
+	if err := binary.Read(p.reader, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("invalid length: %d", length)
+	}
+	data := make([]byte, length)
+	_, err := io.ReadFull(p.reader, data)
+	return data, err
+}
+
+// referenceName returns the name of the reference at refID, or "*" if the
+// refID is -1.
+func (p *Parser) referenceName(refID int32) (string, error) {
+	if refID == -1 {
+		return "*", nil
+	}
+	if refID < 0 || int(refID) >= len(p.references) {
+		return "", fmt.Errorf("reference ID %d out of range of %d references", refID, len(p.references))
+	}
+	return p.references[refID].Name, nil
+}
+
+// Next parses the next alignment from a parser. Returns an `io.EOF` upon EOF.
+func (p *Parser) Next() (sam.Alignment, error) {
+	var blockSize int32
+	if err := binary.Read(p.reader, binary.LittleEndian, &blockSize); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return sam.Alignment{}, fmt.Errorf("Truncated BAM record after alignment %d: %w", p.alignment, err)
+		}
+		return sam.Alignment{}, err
+	}
+	p.alignment++
+	if blockSize < fixedRecordLength {
+		return sam.Alignment{}, fmt.Errorf("Alignment %d had error: block size %d is smaller than minimum of %d", p.alignment, blockSize, fixedRecordLength)
+	}
+	if cap(p.record) < int(blockSize) {
+		p.record = make([]byte, blockSize)
+	}
+	record := p.record[:blockSize]
+	if _, err := io.ReadFull(p.reader, record); err != nil {
+		return sam.Alignment{}, fmt.Errorf("Alignment %d had error: truncated record: %w", p.alignment, io.ErrUnexpectedEOF)
+	}
+	alignment, err := p.decodeAlignment(record)
+	if err != nil {
+		return sam.Alignment{}, fmt.Errorf("Alignment %d had error: %w", p.alignment, err)
+	}
+	return alignment, nil
+}
+
+// decodeAlignment decodes a single BAM record, without its block_size
+// prefix, into a sam.Alignment.
+func (p *Parser) decodeAlignment(record []byte) (sam.Alignment, error) {
+	var alignment sam.Alignment
+	var err error
+	refID := int32(binary.LittleEndian.Uint32(record[0:4]))
+	pos := int32(binary.LittleEndian.Uint32(record[4:8]))
+	readNameLength := int(record[8])
+	alignment.MAPQ = record[9]
+	// record[10:12] is the bin, which is only used for indexing.
+	cigarLength := int(binary.LittleEndian.Uint16(record[12:14]))
+	alignment.FLAG = binary.LittleEndian.Uint16(record[14:16])
+	sequenceLength := int(int32(binary.LittleEndian.Uint32(record[16:20])))
+	nextRefID := int32(binary.LittleEndian.Uint32(record[20:24]))
+	nextPos := int32(binary.LittleEndian.Uint32(record[24:28]))
+	alignment.TLEN = int32(binary.LittleEndian.Uint32(record[28:32]))
+
+	if sequenceLength < 0 {
+		return alignment, fmt.Errorf("invalid sequence length: %d", sequenceLength)
+	}
+	variableLength := readNameLength + 4*cigarLength + (sequenceLength+1)/2 + sequenceLength
+	if fixedRecordLength+variableLength > len(record) {
+		return alignment, fmt.Errorf("record of %d bytes too short for its contents", len(record))
+	}
+
+	alignment.RNAME, err = p.referenceName(refID)
+	if err != nil {
+		return alignment, err
+	}
+	alignment.RNEXT, err = p.referenceName(nextRefID)
+	if err != nil {
+		return alignment, err
+	}
+	if nextRefID != -1 && nextRefID == refID {
+		alignment.RNEXT = "="
+	}
+	// BAM positions are 0-based, while SAM positions are 1-based. Unmapped
+	// reads have a position of -1, which becomes 0 in SAM.
+	alignment.POS = pos + 1
+	alignment.PNEXT = nextPos + 1
+
+	offset := fixedRecordLength
+	alignment.QNAME = string(bytes.TrimRight(record[offset:offset+readNameLength], "\x00"))
+	offset += readNameLength
+
+	// CIGAR
+	if cigarLength == 0 {
+		alignment.CIGAR = "*"
+	} else {
+		var cigar strings.Builder
+		for i := 0; i < cigarLength; i++ {
+			operation := binary.LittleEndian.Uint32(record[offset : offset+4])
+			offset += 4
+			if int(operation&0xf) >= len(cigarOperations) {
+				return alignment, fmt.Errorf("invalid CIGAR operation: %d", operation&0xf)
+			}
+			cigar.WriteString(strconv.FormatUint(uint64(operation>>4), 10))
+			cigar.WriteByte(cigarOperations[operation&0xf])
+		}
+		alignment.CIGAR = cigar.String()
+	}
+
+	// SEQ is packed two bases to a byte, high nibble first.
+	if sequenceLength == 0 {
+		alignment.SEQ = "*"
+	} else {
+		sequence := make([]byte, sequenceLength)
+		for i := range sequence {
+			packed := record[offset+i/2]
+			if i%2 == 0 {
+				sequence[i] = sequenceAlphabet[packed>>4]
+			} else {
+				sequence[i] = sequenceAlphabet[packed&0xf]
+			}
+		}
+		alignment.SEQ = string(sequence)
+	}
+	offset += (sequenceLength + 1) / 2
+
+	// QUAL is stored as raw phred scores. A missing QUAL is stored as 0xff.
+	if sequenceLength == 0 || record[offset] == 0xff {
+		alignment.QUAL = "*"
+	} else {
+		quality := make([]byte, sequenceLength)
+		for i := range quality {
+			quality[i] = record[offset+i] + 33
+		}
+		alignment.QUAL = string(quality)
+	}
+	offset += sequenceLength
+
+	alignment.Optionals, err = decodeOptionals(record[offset:])
+	if err != nil {
+		return alignment, err
+	}
+
+	// CIGARs with more than 65535 operations are stored in the CG tag, with a
+	// placeholder of <sequence length>S<reference length>N in the CIGAR
+	// field.
+	if cigarLength == 2 && len(alignment.SEQ) > 0 && strings.HasPrefix(alignment.CIGAR, strconv.Itoa(sequenceLength)+"S") {
+		for i, optional := range alignment.Optionals {
+			if optional.Tag == "CG" && optional.Type == 'B' {
+				alignment.CIGAR, err = cigarFromArray(optional.Data)
+				if err != nil {
+					return alignment, err
+				}
+				alignment.Optionals = append(alignment.Optionals[:i], alignment.Optionals[i+1:]...)
+				break
+			}
+		}
+	}
+	return alignment, nil
+}
+
+// cigarFromArray converts a CG:B:I array of encoded CIGAR operations to a
+// CIGAR string.
+func cigarFromArray(data string) (string, error) {
+	values := strings.Split(data, ",")
+	var cigar strings.Builder
+	for _, value := range values[1:] {
+		operation, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid CG tag value %q: %w", value, err)
+		}
+		if int(operation&0xf) >= len(cigarOperations) {
+			return "", fmt.Errorf("invalid CIGAR operation in CG tag: %d", operation&0xf)
+		}
+		cigar.WriteString(strconv.FormatUint(operation>>4, 10))
+		cigar.WriteByte(cigarOperations[operation&0xf])
+	}
+	return cigar.String(), nil
+}
+
+// typeSizes is the size in bytes of each fixed-size auxiliary field type.
+var typeSizes = map[byte]int{'A': 1, 'c': 1, 'C': 1, 's': 2, 'S': 2, 'i': 4, 'I': 4, 'f': 4}
+
+// decodeOptionals decodes the binary auxiliary fields at the end of a BAM
+// record into their SAM text representation. All integer types become SAM
+// type 'i', as SAM text has only one integer type.
+func decodeOptionals(data []byte) ([]sam.Optional, error) {
+	var optionals []sam.Optional
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, errors.New("truncated auxiliary field")
+		}
+		tag := string(data[0:2])
+		valueType := data[2]
+		data = data[3:]
+		switch valueType {
+		case 'Z', 'H':
+			end := bytes.IndexByte(data, 0)
+			if end == -1 {
+				return nil, fmt.Errorf("auxiliary field %s is not NUL terminated", tag)
+			}
+			optionals = append(optionals, sam.Optional{Tag: tag, Type: rune(valueType), Data: string(data[:end])})
+			data = data[end+1:]
+		case 'B':
+			if len(data) < 5 {
+				return nil, fmt.Errorf("truncated auxiliary array %s", tag)
+			}
+			subtype := data[0]
+			count := int(binary.LittleEndian.Uint32(data[1:5]))
+			size, ok := typeSizes[subtype]
+			if !ok || subtype == 'A' {
+				return nil, fmt.Errorf("invalid auxiliary array subtype %q for %s", subtype, tag)
+			}
+			data = data[5:]
+			if count < 0 || count*size > len(data) {
+				return nil, fmt.Errorf("truncated auxiliary array %s", tag)
+			}
+			var array strings.Builder
+			array.WriteByte(subtype)
+			for i := 0; i < count; i++ {
+				array.WriteByte(',')
+				array.WriteString(decodeNumber(subtype, data[i*size:(i+1)*size]))
+			}
+			optionals = append(optionals, sam.Optional{Tag: tag, Type: 'B', Data: array.String()})
+			data = data[count*size:]
+		default:
+			size, ok := typeSizes[valueType]
+			if !ok {
+				return nil, fmt.Errorf("invalid auxiliary field type %q for %s", valueType, tag)
+			}
+			if len(data) < size {
+				return nil, fmt.Errorf("truncated auxiliary field %s", tag)
+			}
+			optional := sam.Optional{Tag: tag, Type: 'i', Data: decodeNumber(valueType, data[:size])}
+			switch valueType {
+			case 'A':
+				optional.Type = 'A'
+			case 'f':
+				optional.Type = 'f'
+			}
+			optionals = append(optionals, optional)
+			data = data[size:]
+		}
+	}
+	return optionals, nil
+}
+
+// decodeNumber decodes a single little-endian value of the given auxiliary
+// type into text.
+func decodeNumber(valueType byte, data []byte) string {
+	switch valueType {
+	case 'A':
+		return string(data[0:1])
+	case 'c':
+		return strconv.FormatInt(int64(int8(data[0])), 10)
+	case 'C':
+		return strconv.FormatUint(uint64(data[0]), 10)
+	case 's':
+		return strconv.FormatInt(int64(int16(binary.LittleEndian.Uint16(data))), 10)
+	case 'S':
+		return strconv.FormatUint(uint64(binary.LittleEndian.Uint16(data)), 10)
+	case 'i':
+		return strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(data))), 10)
+	case 'I':
+		return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data)), 10)
+	default: // 'f'
+		return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(data))), 'g', -1, 32)
+	}
+}
+
+/******************************************************************************
+
+BAM writer
+
+******************************************************************************/
+
+// Writer writes sam.Alignments as a BAM file. It should be initialized with
+// NewWriter, and must be closed with Close to write the BGZF EOF marker.
+type Writer struct {
+	writer     *bgzf.Writer
+	references map[string]int32
+	buffer     bytes.Buffer
+}
+
+// NewWriter creates a BAM writer and writes the header to w. The reference
+// dictionary is built from the @SQ lines of the header, and every alignment
+// written must refer to one of those references (or "*").
+func NewWriter(w io.Writer, header sam.Header) (*Writer, error) {
+	writer := &Writer{writer: bgzf.NewWriter(w), references: make(map[string]int32)}
+
+	var headerText bytes.Buffer
+	if _, err := header.WriteTo(&headerText); err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	buffer.Write(magic)
+	_ = binary.Write(&buffer, binary.LittleEndian, int32(headerText.Len()))
+	buffer.Write(headerText.Bytes())
+	_ = binary.Write(&buffer, binary.LittleEndian, int32(len(header.SQ)))
+	for index, sq := range header.SQ {
+		name, ok := sq["SN"]
+		if !ok {
+			return nil, fmt.Errorf("@SQ line %d has no SN tag", index+1)
+		}
+		length, err := strconv.ParseInt(sq["LN"], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("@SQ line %d has invalid LN tag: %w", index+1, err)
+		}
+		if _, exists := writer.references[name]; exists {
+			return nil, fmt.Errorf("Non-unique @SQ SN: %s", name)
+		}
+		writer.references[name] = int32(index)
+		_ = binary.Write(&buffer, binary.LittleEndian, int32(len(name)+1))
+		buffer.WriteString(name)
+		buffer.WriteByte(0)
+		_ = binary.Write(&buffer, binary.LittleEndian, int32(length))
+	}
+	if _, err := writer.writer.Write(buffer.Bytes()); err != nil {
+		return nil, err
+	}
+	// htslib always starts alignments in a new block, so that the start of
+	// the alignments can be found without decompressing the header.
+	if err := writer.writer.Flush(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// referenceID returns the index of a reference name in the dictionary.
+func (w *Writer) referenceID(name string) (int32, error) {
+	if name == "*" {
+		return -1, nil
+	}
+	refID, ok := w.references[name]
+	if !ok {
+		return 0, fmt.Errorf("reference %q not found in header", name)
+	}
+	return refID, nil
+}
+
+// Write encodes a single alignment and writes it.
+func (w *Writer) Write(alignment sam.Alignment) error {
+	record, err := w.encodeAlignment(alignment)
+	if err != nil {
+		return fmt.Errorf("Failed to encode alignment %s: %w", alignment.QNAME, err)
+	}
+	_, err = w.writer.Write(record)
+	return err
+}
+
+// Close flushes any buffered alignments and writes the BGZF EOF marker. It
+// does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	return w.writer.Close()
+}
+
+// encodeAlignment encodes a sam.Alignment as a BAM record, including its
+// block_size prefix.
+func (w *Writer) encodeAlignment(alignment sam.Alignment) ([]byte, error) {
+	refID, err := w.referenceID(alignment.RNAME)
+	if err != nil {
+		return nil, err
+	}
+	nextRefID := refID
+	if alignment.RNEXT != "=" {
+		nextRefID, err = w.referenceID(alignment.RNEXT)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(alignment.QNAME) > 254 {
+		return nil, fmt.Errorf("QNAME longer than 254 characters")
+	}
+
+	cigar, referenceLength, err := encodeCigar(alignment.CIGAR)
+	if err != nil {
+		return nil, err
+	}
+	if len(cigar) > math.MaxUint16 {
+		return nil, fmt.Errorf("CIGAR has %d operations, more than the maximum of %d", len(cigar), math.MaxUint16)
+	}
+
+	sequence := alignment.SEQ
+	if sequence == "*" {
+		sequence = ""
+	}
+	quality := alignment.QUAL
+	if quality != "*" && len(quality) != len(sequence) {
+		return nil, fmt.Errorf("QUAL length %d does not match SEQ length %d", len(quality), len(sequence))
+	}
+
+	pos := alignment.POS - 1
+	end := pos + referenceLength
+	if referenceLength == 0 {
+		end = pos + 1
+	}
+
+	buffer := &w.buffer
+	buffer.Reset()
+	_ = binary.Write(buffer, binary.LittleEndian, int32(0)) // placeholder for block_size
+	_ = binary.Write(buffer, binary.LittleEndian, refID)
+	_ = binary.Write(buffer, binary.LittleEndian, pos)
+	buffer.WriteByte(byte(len(alignment.QNAME) + 1))
+	buffer.WriteByte(alignment.MAPQ)
+	_ = binary.Write(buffer, binary.LittleEndian, reg2bin(pos, end))
+	_ = binary.Write(buffer, binary.LittleEndian, uint16(len(cigar)))
+	_ = binary.Write(buffer, binary.LittleEndian, alignment.FLAG)
+	_ = binary.Write(buffer, binary.LittleEndian, int32(len(sequence)))
+	_ = binary.Write(buffer, binary.LittleEndian, nextRefID)
+	_ = binary.Write(buffer, binary.LittleEndian, alignment.PNEXT-1)
+	_ = binary.Write(buffer, binary.LittleEndian, alignment.TLEN)
+	buffer.WriteString(alignment.QNAME)
+	buffer.WriteByte(0)
+	_ = binary.Write(buffer, binary.LittleEndian, cigar)
+
+	packed := make([]byte, (len(sequence)+1)/2)
+	for i := 0; i < len(sequence); i++ {
+		code := strings.IndexByte(sequenceAlphabet, upper(sequence[i]))
+		if code == -1 {
+			code = 15 // N
+		}
+		if i%2 == 0 {
+			packed[i/2] = byte(code) << 4
+		} else {
+			packed[i/2] |= byte(code)
+		}
+	}
+	buffer.Write(packed)
+	for i := 0; i < len(sequence); i++ {
+		if quality == "*" {
+			buffer.WriteByte(0xff)
+		} else {
+			buffer.WriteByte(quality[i] - 33)
+		}
+	}
+
+	for _, optional := range alignment.Optionals {
+		if err := encodeOptional(buffer, optional); err != nil {
+			return nil, err
+		}
+	}
+
+	record := buffer.Bytes()
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(record)-4))
+	return record, nil
+}
+
+// upper converts an ASCII letter to uppercase.
+func upper(b byte) byte {
+	if 'a' <= b && b <= 'z' {
+		return b - 'a' + 'A'
+	}
+	return b
+}
+
+// encodeCigar encodes a CIGAR string into BAM CIGAR operations, and returns
+// the number of reference bases the CIGAR spans.
+func encodeCigar(cigar string) ([]uint32, int32, error) {
+	if cigar == "*" || cigar == "" {
+		return nil, 0, nil
+	}
+	var operations []uint32
+	var referenceLength int32
+	var length uint64
+	var hasLength bool
+	for i := 0; i < len(cigar); i++ {
+		character := cigar[i]
+		if '0' <= character && character <= '9' {
+			length = length*10 + uint64(character-'0')
+			hasLength = true
+			if length > 1<<28-1 {
+				return nil, 0, fmt.Errorf("CIGAR operation too long in %s", cigar)
+			}
+			continue
+		}
+		operation := strings.IndexByte(cigarOperations, character)
+		if operation == -1 || !hasLength {
+			return nil, 0, fmt.Errorf("invalid CIGAR: %s", cigar)
+		}
+		switch character {
+		case 'M', 'D', 'N', '=', 'X':
+			referenceLength += int32(length)
+		}
+		operations = append(operations, uint32(length)<<4|uint32(operation))
+		length = 0
+		hasLength = false
+	}
+	if hasLength {
+		return nil, 0, fmt.Errorf("invalid CIGAR: %s", cigar)
+	}
+	return operations, referenceLength, nil
+}
+
+// reg2bin calculates the BAI bin of an alignment spanning the 0-based,
+// half-open region [beg, end). This is a direct translation of the C code
+// given in section 5.3 of the SAM spec.
+func reg2bin(beg, end int32) uint16 {
+	end--
+	switch {
+	case beg>>14 == end>>14:
+		return uint16(((1<<15)-1)/7 + (beg >> 14))
+	case beg>>17 == end>>17:
+		return uint16(((1<<12)-1)/7 + (beg >> 17))
+	case beg>>20 == end>>20:
+		return uint16(((1<<9)-1)/7 + (beg >> 20))
+	case beg>>23 == end>>23:
+		return uint16(((1<<6)-1)/7 + (beg >> 23))
+	case beg>>26 == end>>26:
+		return uint16(((1<<3)-1)/7 + (beg >> 26))
+	}
+	return 0
+}
+
+// encodeOptional encodes a single SAM optional field into its binary
+// representation. Integers are stored in the smallest type that fits them,
+// as done by htslib.
+func encodeOptional(buffer *bytes.Buffer, optional sam.Optional) error {
+	if len(optional.Tag) != 2 {
+		return fmt.Errorf("invalid optional tag: %q", optional.Tag)
+	}
+	buffer.WriteString(optional.Tag)
+	switch optional.Type {
+	case 'A':
+		if len(optional.Data) != 1 {
+			return fmt.Errorf("optional %s of type A must be a single character, got %q", optional.Tag, optional.Data)
+		}
+		buffer.WriteByte('A')
+		buffer.WriteByte(optional.Data[0])
+	case 'i':
+		value, err := strconv.ParseInt(optional.Data, 10, 64)
+		if err != nil {
+			return fmt.Errorf("optional %s: %w", optional.Tag, err)
+		}
+		valueType := smallestIntegerType(value)
+		if valueType == 0 {
+			return fmt.Errorf("optional %s: integer %d out of range", optional.Tag, value)
+		}
+		buffer.WriteByte(valueType)
+		if err = encodeNumber(buffer, valueType, optional.Data); err != nil {
+			return fmt.Errorf("optional %s: %w", optional.Tag, err)
+		}
+	case 'f':
+		buffer.WriteByte('f')
+		if err := encodeNumber(buffer, 'f', optional.Data); err != nil {
+			return fmt.Errorf("optional %s: %w", optional.Tag, err)
+		}
+	case 'Z', 'H':
+		buffer.WriteByte(byte(optional.Type))
+		buffer.WriteString(optional.Data)
+		buffer.WriteByte(0)
+	case 'B':
+		values := strings.Split(optional.Data, ",")
+		subtype := values[0]
+		if len(subtype) != 1 || !strings.Contains("cCsSiIf", subtype) {
+			return fmt.Errorf("optional %s has invalid array subtype %q", optional.Tag, subtype)
+		}
+		buffer.WriteByte('B')
+		buffer.WriteByte(subtype[0])
+		_ = binary.Write(buffer, binary.LittleEndian, int32(len(values)-1))
+		for _, value := range values[1:] {
+			if err := encodeNumber(buffer, subtype[0], value); err != nil {
+				return fmt.Errorf("optional %s: %w", optional.Tag, err)
+			}
+		}
+	default:
+		return fmt.Errorf("optional %s has unknown type %q", optional.Tag, optional.Type)
+	}
+	return nil
+}
+
+// smallestIntegerType returns the smallest BAM integer type that can hold
+// value, or 0 if no type can.
+func smallestIntegerType(value int64) byte {
+	switch {
+	case value >= 0 && value <= math.MaxUint8:
+		return 'C'
+	case value >= math.MinInt8 && value < 0:
+		return 'c'
+	case value >= 0 && value <= math.MaxUint16:
+		return 'S'
+	case value >= math.MinInt16 && value < 0:
+		return 's'
+	case value >= 0 && value <= math.MaxUint32:
+		return 'I'
+	case value >= math.MinInt32 && value < 0:
+		return 'i'
+	}
+	return 0
+}
+
+// encodeNumber parses a number from text and writes it in the given
+// auxiliary type.
+func encodeNumber(buffer *bytes.Buffer, valueType byte, text string) error {
+	if valueType == 'f' {
+		value, err := strconv.ParseFloat(text, 32)
+		if err != nil {
+			return err
+		}
+		return binary.Write(buffer, binary.LittleEndian, float32(value))
+	}
+	bitSize := typeSizes[valueType] * 8
+	if valueType == 'C' || valueType == 'S' || valueType == 'I' {
+		value, err := strconv.ParseUint(text, 10, bitSize)
+		if err != nil {
+			return err
+		}
+		switch valueType {
+		case 'C':
+			return buffer.WriteByte(uint8(value))
+		case 'S':
+			return binary.Write(buffer, binary.LittleEndian, uint16(value))
+		default:
+			return binary.Write(buffer, binary.LittleEndian, uint32(value))
+		}
+	}
+	value, err := strconv.ParseInt(text, 10, bitSize)
+	if err != nil {
+		return err
+	}
+	switch valueType {
+	case 'c':
+		return buffer.WriteByte(byte(int8(value)))
+	case 's':
+		return binary.Write(buffer, binary.LittleEndian, int16(value))
+	default:
+		return binary.Write(buffer, binary.LittleEndian, int32(value))
+	}
+}
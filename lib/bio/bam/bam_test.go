@@ -0,0 +1,243 @@
+package bam
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/koeng101/dnadesign/lib/bio/bgzf"
+	"github.com/koeng101/dnadesign/lib/bio/sam"
+)
+
+// readSam reads all alignments from a SAM file.
+func readSam(t *testing.T, path string) (sam.Header, []sam.Alignment) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %s", path, err)
+	}
+	defer file.Close()
+	parser, header, err := sam.NewParser(file, sam.DefaultMaxLineSize*4)
+	if err != nil {
+		t.Fatalf("Failed to parse header of %s: %s", path, err)
+	}
+	var alignments []sam.Alignment
+	for {
+		alignment, err := parser.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("Failed to parse %s: %s", path, err)
+			}
+			break
+		}
+		alignments = append(alignments, alignment)
+	}
+	return header, alignments
+}
+
+// normalizeFloats formats type 'f' optionals the way they are formatted
+// when read from a BAM file. BAM stores floats in binary, so SAM text such
+// as "0.0490" is read back as "0.049".
+func normalizeFloats(alignments []sam.Alignment) {
+	for _, alignment := range alignments {
+		for i, optional := range alignment.Optionals {
+			if optional.Type == 'f' {
+				value, _ := strconv.ParseFloat(optional.Data, 32)
+				alignment.Optionals[i].Data = strconv.FormatFloat(value, 'g', -1, 32)
+			}
+		}
+	}
+}
+
+// readBam reads all alignments from a BAM stream.
+func readBam(t *testing.T, r io.Reader) (sam.Header, []sam.Alignment) {
+	t.Helper()
+	parser, header, err := NewParser(r)
+	if err != nil {
+		t.Fatalf("Failed to parse BAM header: %s", err)
+	}
+	var alignments []sam.Alignment
+	for {
+		alignment, err := parser.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("Failed to parse BAM: %s", err)
+			}
+			break
+		}
+		alignments = append(alignments, alignment)
+	}
+	return header, alignments
+}
+
+func TestParse(t *testing.T) {
+	file, err := os.Open("data/aln.bam")
+	if err != nil {
+		t.Fatalf("Failed to open aln.bam: %s", err)
+	}
+	defer file.Close()
+	header, alignments := readBam(t, file)
+	expectedHeader, expectedAlignments := readSam(t, "../sam/data/aln.sam")
+	normalizeFloats(expectedAlignments)
+	if diff := cmp.Diff(expectedHeader, header); diff != "" {
+		t.Errorf("Header mismatch (-sam +bam):\n%s", diff)
+	}
+	if diff := cmp.Diff(expectedAlignments, alignments); diff != "" {
+		t.Errorf("Alignment mismatch (-sam +bam):\n%s", diff)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	header, alignments := readSam(t, "../sam/data/aln.sam")
+	var buffer bytes.Buffer
+	writer, err := NewWriter(&buffer, header)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %s", err)
+	}
+	for _, alignment := range alignments {
+		if err := writer.Write(alignment); err != nil {
+			t.Fatalf("Failed to write alignment: %s", err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	newHeader, newAlignments := readBam(t, &buffer)
+	normalizeFloats(alignments)
+	if diff := cmp.Diff(header, newHeader); diff != "" {
+		t.Errorf("Header mismatch (-original +roundtrip):\n%s", diff)
+	}
+	if diff := cmp.Diff(alignments, newAlignments); diff != "" {
+		t.Errorf("Alignment mismatch (-original +roundtrip):\n%s", diff)
+	}
+}
+
+func TestOptionalTypes(t *testing.T) {
+	header := sam.Header{SQ: []map[string]string{{"SN": "chr1", "LN": "1000"}}}
+	alignment := sam.Alignment{
+		QNAME: "read1", FLAG: 0, RNAME: "chr1", POS: 10, MAPQ: 60, CIGAR: "2S3M1I2M",
+		RNEXT: "=", PNEXT: 100, TLEN: -5, SEQ: "ACGTNacgt", QUAL: "*",
+		Optionals: []sam.Optional{
+			{Tag: "tp", Type: 'A', Data: "P"},
+			{Tag: "c1", Type: 'i', Data: "-100"},
+			{Tag: "s1", Type: 'i', Data: "-30000"},
+			{Tag: "S1", Type: 'i', Data: "60000"},
+			{Tag: "i1", Type: 'i', Data: "-2000000000"},
+			{Tag: "I1", Type: 'i', Data: "4000000000"},
+			{Tag: "de", Type: 'f', Data: "0.0345"},
+			{Tag: "MD", Type: 'Z', Data: "3^A:B2"},
+			{Tag: "H1", Type: 'H', Data: "1AE301"},
+			{Tag: "B1", Type: 'B', Data: "c,-1,2,-3"},
+			{Tag: "B2", Type: 'B', Data: "I,4000000000"},
+			{Tag: "B3", Type: 'B', Data: "f,1.5,-0.25"},
+			{Tag: "B4", Type: 'B', Data: "s"},
+		},
+	}
+	var buffer bytes.Buffer
+	writer, err := NewWriter(&buffer, header)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %s", err)
+	}
+	if err = writer.Write(alignment); err != nil {
+		t.Fatalf("Failed to write alignment: %s", err)
+	}
+	_ = writer.Close()
+	_, alignments := readBam(t, &buffer)
+	// BAM sequences are always uppercase.
+	alignment.SEQ = "ACGTNACGT"
+	if diff := cmp.Diff([]sam.Alignment{alignment}, alignments); diff != "" {
+		t.Errorf("Alignment mismatch (-original +roundtrip):\n%s", diff)
+	}
+}
+
+// TestDecodeRecord checks decoding against a record packed by hand according
+// to the layout in section 4.2 of the SAM spec.
+func TestDecodeRecord(t *testing.T) {
+	var record bytes.Buffer
+	put := func(values ...any) {
+		for _, value := range values {
+			_ = binary.Write(&record, binary.LittleEndian, value)
+		}
+	}
+	put(int32(0), int32(99), uint8(3), uint8(30), uint16(4681), uint16(1), uint16(16), int32(3), int32(-1), int32(-1), int32(0))
+	record.WriteString("r1\x00")
+	put(uint32(3<<4 | 0)) // 3M
+	record.Write([]byte{0x12, 0x40})
+	record.Write([]byte{30, 31, 32})
+	record.WriteString("NMC\x02")
+
+	parser := &Parser{references: []Reference{{Name: "chr1", Length: 1000}}}
+	alignment, err := parser.decodeAlignment(record.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to decode record: %s", err)
+	}
+	expected := sam.Alignment{
+		QNAME: "r1", FLAG: 16, RNAME: "chr1", POS: 100, MAPQ: 30, CIGAR: "3M",
+		RNEXT: "*", PNEXT: 0, TLEN: 0, SEQ: "ACG", QUAL: "?@A",
+		Optionals: []sam.Optional{{Tag: "NM", Type: 'i', Data: "2"}},
+	}
+	if diff := cmp.Diff(expected, alignment); diff != "" {
+		t.Errorf("Alignment mismatch (-expected +got):\n%s", diff)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	// Not a BAM file.
+	var buffer bytes.Buffer
+	writer := bgzf.NewWriter(&buffer)
+	_, _ = writer.Write([]byte("@HD\tVN:1.6\n"))
+	_ = writer.Close()
+	if _, _, err := NewParser(&buffer); err == nil || !strings.Contains(err.Error(), "Invalid BAM magic") {
+		t.Errorf("Expected invalid magic error, got: %v", err)
+	}
+
+	// Alignment referencing a reference missing from the header.
+	header := sam.Header{SQ: []map[string]string{{"SN": "chr1", "LN": "1000"}}}
+	buffer.Reset()
+	bamWriter, _ := NewWriter(&buffer, header)
+	err := bamWriter.Write(sam.Alignment{QNAME: "r", RNAME: "chr2", CIGAR: "*", RNEXT: "*", SEQ: "*", QUAL: "*"})
+	if err == nil {
+		t.Errorf("Expected error writing alignment with unknown reference")
+	}
+
+	// Truncated BAM file.
+	buffer.Reset()
+	header, alignments := readSam(t, "../sam/data/aln.sam")
+	var decompressed bytes.Buffer
+	bamWriter, _ = NewWriter(&buffer, header)
+	_ = bamWriter.Write(alignments[0])
+	_ = bamWriter.Close()
+	_, _ = io.Copy(&decompressed, bgzf.NewReader(&buffer))
+	parser, _, err := NewParserFromDecompressed(bytes.NewReader(decompressed.Bytes()[:decompressed.Len()-10]))
+	if err != nil {
+		t.Fatalf("Failed to parse header: %s", err)
+	}
+	if _, err = parser.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF on truncated record, got: %v", err)
+	}
+}
+
+func TestReg2bin(t *testing.T) {
+	for _, test := range []struct {
+		beg, end int32
+		bin      uint16
+	}{
+		{-1, 0, 4680}, // unmapped reads without coordinates
+		{0, 1, 4681},
+		{0, 16384, 4681},
+		{0, 16385, 585},
+		{100000, 100100, 4687},
+		{0, 1 << 29, 0},
+	} {
+		if bin := reg2bin(test.beg, test.end); bin != test.bin {
+			t.Errorf("reg2bin(%d, %d) = %d, expected %d", test.beg, test.end, bin, test.bin)
+		}
+	}
+}
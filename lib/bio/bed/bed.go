@@ -0,0 +1,544 @@
+/*
+Package bed contains BED parsers and writers.
+
+BED (Browser Extensible Data) is a tab-delimited text format for genomic
+intervals, like primer binding sites, amplicons, or peaks from a ChIP-seq
+experiment. Each line is a single interval, with 3 required columns and 9
+optional columns:
+
+	chrom	chromStart	chromEnd	name	score	strand	thickStart	thickEnd	itemRgb	blockCount	blockSizes	blockStarts
+	pUC19	145	210	amp1	0	+
+
+Files using the first N columns are called BEDN files. Unlike most formats
+in bioinformatics, BED coordinates are 0-based and the end is exclusive,
+which is the same as slices in Go and genbank.Location.
+
+Many formats are built on top of BED by adding extra columns. These are
+called BEDN+M files, with N standard columns and M extra columns. The most
+common is narrowPeak, a BED6+4 format output by peak callers like MACS2,
+which has its own struct in this package.
+
+This package provides a parser and writer for BED files, as well as
+functions to convert BED records to genbank features and to get the
+sequences of BED records.
+
+Spec: https://samtools.github.io/hts-specs/BEDv1.pdf
+*/
+package bed
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/koeng101/dnadesign/lib/bio/fasta"
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+	"github.com/koeng101/dnadesign/lib/transform"
+)
+
+/******************************************************************************
+
+BED structs begin here
+
+******************************************************************************/
+
+// Record is a single BED line.
+type Record struct {
+	Chrom       string // Name of the sequence, such as a fasta identifier.
+	ChromStart  int    // 0-based start of the interval.
+	ChromEnd    int    // End of the interval, which is not included in the interval.
+	Name        string
+	Score       int    // Score from 0 to 1000.
+	Strand      byte   // +, -, or . for no strand.
+	ThickStart  int    // Start of the thickly drawn part, like the start codon of a gene.
+	ThickEnd    int    // End of the thickly drawn part, like the stop codon of a gene.
+	ItemRgb     string // Display color, such as 255,0,0, or 0.
+	BlockSizes  []int  // Sizes of each block, like the exons of a gene.
+	BlockStarts []int  // Starts of each block, relative to ChromStart.
+
+	// Fields is the number of standard BED columns of the record, from 3 to
+	// 12. If it is 0, the writer uses the fewest columns that hold every set
+	// value.
+	Fields int
+	// Extra contains the values of any columns after the standard BED
+	// columns, such as the last 4 columns of a narrowPeak file.
+	Extra []string
+}
+
+// Header contains the browser and track lines, as well as comments, at the
+// start of a BED file.
+type Header struct {
+	Lines []string
+}
+
+// WriteTo writes the header lines to an io.Writer.
+func (header *Header) WriteTo(w io.Writer) (int64, error) {
+	var writtenBytes int64
+	for _, line := range header.Lines {
+		newWrittenBytes, err := w.Write([]byte(line + "\n"))
+		writtenBytes += int64(newWrittenBytes)
+		if err != nil {
+			return writtenBytes, err
+		}
+	}
+	return writtenBytes, nil
+}
+
+// isHeaderLine returns true for browser, track, and comment lines.
+func isHeaderLine(line string) bool {
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, "track") || strings.HasPrefix(line, "browser")
+}
+
+// Validate checks that the record is a valid BED record.
+func (record *Record) Validate() error {
+	if record.Chrom == "" {
+		return errors.New("chrom is empty")
+	}
+	if record.ChromStart < 0 || record.ChromStart > record.ChromEnd {
+		return fmt.Errorf("invalid interval [%d, %d)", record.ChromStart, record.ChromEnd)
+	}
+	switch record.Strand {
+	case 0, '+', '-', '.':
+	default:
+		return fmt.Errorf("invalid strand %q, should be +, -, or .", record.Strand)
+	}
+	if record.ThickStart != 0 || record.ThickEnd != 0 {
+		if record.ThickStart < record.ChromStart || record.ThickEnd > record.ChromEnd || record.ThickStart > record.ThickEnd {
+			return fmt.Errorf("thick interval [%d, %d) is not within [%d, %d)", record.ThickStart, record.ThickEnd, record.ChromStart, record.ChromEnd)
+		}
+	}
+	if len(record.BlockSizes) != len(record.BlockStarts) {
+		return fmt.Errorf("got %d block sizes but %d block starts", len(record.BlockSizes), len(record.BlockStarts))
+	}
+	if len(record.BlockStarts) == 0 {
+		return nil
+	}
+	if record.BlockStarts[0] != 0 {
+		return errors.New("first block must start at chromStart")
+	}
+	var previousEnd int
+	for i, start := range record.BlockStarts {
+		if record.BlockSizes[i] < 0 || start < previousEnd {
+			return fmt.Errorf("block %d overlaps the block before it or has negative size", i)
+		}
+		previousEnd = start + record.BlockSizes[i]
+	}
+	if record.ChromStart+previousEnd != record.ChromEnd {
+		return errors.New("last block must end at chromEnd")
+	}
+	return nil
+}
+
+/******************************************************************************
+
+Parser
+
+******************************************************************************/
+
+// Parser is a BED parser. It should be initialized with NewParser or
+// NewParserWithFields.
+type Parser struct {
+	reader  bufio.Reader
+	line    uint
+	fields  int
+	header  Header
+	pending string // first data line, read while parsing the header.
+	atEOF   bool
+}
+
+// Header returns the browser, track, and comment lines at the start of the
+// file.
+func (parser *Parser) Header() (Header, error) {
+	return parser.header, nil
+}
+
+// NewParser creates a parser from an io.Reader for BED data. Every column of
+// a line, up to 12, is parsed as a standard BED column, and any further
+// columns are stored in Extra. For BEDN+M files, like narrowPeak, use
+// NewParserWithFields.
+func NewParser(r io.Reader, maxLineSize int) (*Parser, error) {
+	return NewParserWithFields(r, maxLineSize, 0)
+}
+
+// NewParserWithFields creates a parser from an io.Reader for BED data with a
+// given number of standard BED columns. Columns after those are stored in
+// Extra. For example, narrowPeak files have 6 standard columns. If fields is
+// 0, it works like NewParser.
+func NewParserWithFields(r io.Reader, maxLineSize int, fields int) (*Parser, error) {
+	if fields != 0 && (fields < 3 || fields > 12) {
+		return nil, fmt.Errorf("BED files have 3 to 12 standard columns, got %d", fields)
+	}
+	parser := &Parser{reader: *bufio.NewReaderSize(r, maxLineSize), fields: fields}
+	for {
+		line, err := parser.readLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return parser, nil
+			}
+			return parser, err
+		}
+		if !isHeaderLine(line) {
+			parser.pending = line
+			return parser, nil
+		}
+		parser.header.Lines = append(parser.header.Lines, line)
+	}
+}
+
+// readLine reads the next non-empty line.
+func (parser *Parser) readLine() (string, error) {
+	for {
+		if parser.atEOF {
+			return "", io.EOF
+		}
+		lineBytes, err := parser.reader.ReadSlice('\n')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return "", err
+			}
+			parser.atEOF = true
+		}
+		parser.line++
+		line := strings.TrimRight(string(lineBytes), "\r\n")
+		if strings.TrimSpace(line) != "" {
+			return line, nil
+		}
+	}
+}
+
+// Next parses the next record from the parser. Returns an `io.EOF` upon EOF.
+// Track, browser, and comment lines after the header are skipped.
+func (parser *Parser) Next() (Record, error) {
+	line := parser.pending
+	parser.pending = ""
+	for line == "" || isHeaderLine(line) {
+		var err error
+		line, err = parser.readLine()
+		if err != nil {
+			return Record{}, err
+		}
+	}
+	record, err := parseRecord(line, parser.fields)
+	if err != nil {
+		return Record{}, fmt.Errorf("line %d: %w", parser.line, err)
+	}
+	return record, nil
+}
+
+// parseIntList parses a comma separated list of integers, like 10,20,30,.
+func parseIntList(text string) ([]int, error) {
+	text = strings.TrimSuffix(text, ",")
+	if text == "" {
+		return nil, nil
+	}
+	var values []int
+	for _, value := range strings.Split(text, ",") {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, number)
+	}
+	return values, nil
+}
+
+// parseRecord parses a single BED line with the given number of standard
+// columns. If fields is 0, up to 12 columns are standard.
+func parseRecord(line string, fields int) (Record, error) {
+	// BED files are supposed to be tab-delimited, but many are
+	// space-delimited, which is fine as long as names have no spaces.
+	columns := strings.Split(line, "\t")
+	if len(columns) < 3 {
+		columns = strings.Fields(line)
+	}
+	if len(columns) < 3 {
+		return Record{}, fmt.Errorf("expected at least 3 columns, got %d", len(columns))
+	}
+	if fields == 0 {
+		fields = min(len(columns), 12)
+	}
+	if len(columns) < fields {
+		return Record{}, fmt.Errorf("expected at least %d columns, got %d", fields, len(columns))
+	}
+	record := Record{Chrom: columns[0], Fields: fields}
+	if len(columns) > fields {
+		record.Extra = columns[fields:]
+	}
+
+	var err error
+	integers := []struct {
+		column int
+		value  *int
+		name   string
+	}{
+		{1, &record.ChromStart, "chromStart"},
+		{2, &record.ChromEnd, "chromEnd"},
+		{4, &record.Score, "score"},
+		{6, &record.ThickStart, "thickStart"},
+		{7, &record.ThickEnd, "thickEnd"},
+	}
+	for _, integer := range integers {
+		if integer.column >= fields {
+			break
+		}
+		*integer.value, err = strconv.Atoi(columns[integer.column])
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid %s: %w", integer.name, err)
+		}
+	}
+	if fields > 3 {
+		record.Name = columns[3]
+	}
+	if fields > 5 {
+		if len(columns[5]) != 1 {
+			return Record{}, fmt.Errorf("invalid strand %q, should be +, -, or .", columns[5])
+		}
+		record.Strand = columns[5][0]
+	}
+	if fields > 8 {
+		record.ItemRgb = columns[8]
+	}
+	if fields > 9 {
+		if fields != 12 {
+			return Record{}, fmt.Errorf("blocks require blockCount, blockSizes, and blockStarts, got %d columns", fields)
+		}
+		blockCount, err := strconv.Atoi(columns[9])
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid blockCount: %w", err)
+		}
+		record.BlockSizes, err = parseIntList(columns[10])
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid blockSizes: %w", err)
+		}
+		record.BlockStarts, err = parseIntList(columns[11])
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid blockStarts: %w", err)
+		}
+		if len(record.BlockSizes) != blockCount {
+			return Record{}, fmt.Errorf("blockCount is %d, but got %d block sizes", blockCount, len(record.BlockSizes))
+		}
+	}
+	return record, record.Validate()
+}
+
+/******************************************************************************
+
+Writer
+
+******************************************************************************/
+
+// fieldCount returns the number of standard columns to write.
+func (record *Record) fieldCount() int {
+	switch {
+	case record.Fields != 0:
+		return record.Fields
+	case len(record.BlockSizes) > 0:
+		return 12
+	case record.ItemRgb != "":
+		return 9
+	case record.ThickStart != 0 || record.ThickEnd != 0:
+		return 8
+	case record.Strand != 0 || record.Score != 0:
+		return 6
+	case record.Name != "":
+		return 4
+	}
+	return 3
+}
+
+// joinInts formats integers as a comma separated list.
+func joinInts(values []int) string {
+	text := make([]string, len(values))
+	for i, value := range values {
+		text[i] = strconv.Itoa(value)
+	}
+	return strings.Join(text, ",")
+}
+
+// WriteTo writes the record as a single BED line.
+func (record *Record) WriteTo(w io.Writer) (int64, error) {
+	fields := record.fieldCount()
+	name := record.Name
+	if name == "" {
+		name = "."
+	}
+	strand := "."
+	if record.Strand != 0 {
+		strand = string(record.Strand)
+	}
+	itemRgb := record.ItemRgb
+	if itemRgb == "" {
+		itemRgb = "0"
+	}
+	columns := []string{
+		record.Chrom,
+		strconv.Itoa(record.ChromStart),
+		strconv.Itoa(record.ChromEnd),
+		name,
+		strconv.Itoa(record.Score),
+		strand,
+		strconv.Itoa(record.ThickStart),
+		strconv.Itoa(record.ThickEnd),
+		itemRgb,
+		strconv.Itoa(len(record.BlockSizes)),
+		joinInts(record.BlockSizes),
+		joinInts(record.BlockStarts),
+	}
+	line := strings.Join(append(columns[:fields], record.Extra...), "\t") + "\n"
+	newWrittenBytes, err := w.Write([]byte(line))
+	return int64(newWrittenBytes), err
+}
+
+/******************************************************************************
+
+narrowPeak
+
+narrowPeak is a BED6+4 format used for peaks of signal enrichment, such as
+the output of MACS2 on ChIP-seq data. The 4 extra columns are the signal
+value, the p-value and q-value (as -log10), and the offset of the peak
+summit from chromStart. Missing values are -1.
+
+Spec: https://genome.ucsc.edu/FAQ/FAQformat.html#format12
+
+******************************************************************************/
+
+// NarrowPeak is a single narrowPeak record.
+type NarrowPeak struct {
+	Record
+	SignalValue float64 // Overall enrichment of the region.
+	PValue      float64 // -log10 p-value, or -1 if not calculated.
+	QValue      float64 // -log10 q-value, or -1 if not calculated.
+	Peak        int     // Offset of the peak summit from ChromStart, or -1 if not calculated.
+}
+
+// ParseNarrowPeak converts a BED6+4 record, as returned by a parser from
+// NewParserWithFields with 6 fields, into a NarrowPeak.
+func ParseNarrowPeak(record Record) (NarrowPeak, error) {
+	if record.Fields != 6 || len(record.Extra) != 4 {
+		return NarrowPeak{}, fmt.Errorf("narrowPeak records have 6 standard and 4 extra columns, got %d and %d", record.Fields, len(record.Extra))
+	}
+	peak := NarrowPeak{Record: record}
+	peak.Record.Extra = nil
+	var err error
+	for i, value := range []*float64{&peak.SignalValue, &peak.PValue, &peak.QValue} {
+		*value, err = strconv.ParseFloat(record.Extra[i], 64)
+		if err != nil {
+			return NarrowPeak{}, fmt.Errorf("invalid narrowPeak column %d: %w", i+7, err)
+		}
+	}
+	peak.Peak, err = strconv.Atoi(record.Extra[3])
+	if err != nil {
+		return NarrowPeak{}, fmt.Errorf("invalid narrowPeak peak: %w", err)
+	}
+	return peak, nil
+}
+
+// WriteTo writes the narrowPeak as a single BED6+4 line.
+func (peak *NarrowPeak) WriteTo(w io.Writer) (int64, error) {
+	record := peak.Record
+	record.Fields = 6
+	record.Extra = []string{
+		strconv.FormatFloat(peak.SignalValue, 'f', -1, 64),
+		strconv.FormatFloat(peak.PValue, 'f', -1, 64),
+		strconv.FormatFloat(peak.QValue, 'f', -1, 64),
+		strconv.Itoa(peak.Peak),
+	}
+	return record.WriteTo(w)
+}
+
+/******************************************************************************
+
+Conversion to genbank and sequences
+
+******************************************************************************/
+
+// Location returns the genbank.Location of the record. Records with blocks
+// become join(...) locations, and records on the minus strand become
+// complement(...) locations.
+func (record *Record) Location() genbank.Location {
+	location := genbank.Location{Start: record.ChromStart, End: record.ChromEnd}
+	if len(record.BlockSizes) > 1 {
+		location = genbank.Location{Join: true}
+		for i, start := range record.BlockStarts {
+			blockStart := record.ChromStart + start
+			location.SubLocations = append(location.SubLocations, genbank.Location{Start: blockStart, End: blockStart + record.BlockSizes[i]})
+		}
+	}
+	location.Complement = record.Strand == '-'
+	return location
+}
+
+// Feature converts the record into a genbank.Feature of the given type,
+// such as primer_bind or misc_feature. The name of the record becomes the
+// label qualifier.
+func (record *Record) Feature(featureType string) genbank.Feature {
+	feature := genbank.Feature{Type: featureType, Location: record.Location(), Attributes: genbank.NewMultiMap[string, string]()}
+	if record.Name != "" && record.Name != "." {
+		genbank.Put(feature.Attributes, "label", record.Name)
+	}
+	return feature
+}
+
+// Sequence returns the sequence of the record from the sequence it is on.
+// The blocks of records with blocks are joined together, and the sequence
+// of records on the minus strand is reverse complemented. Invalid records
+// return the error of Validate.
+func (record *Record) Sequence(sequence fasta.Record) (string, error) {
+	if err := record.Validate(); err != nil {
+		return "", err
+	}
+	fields := strings.Fields(sequence.Identifier)
+	if len(fields) == 0 || fields[0] != record.Chrom {
+		return "", fmt.Errorf("record is on %q, but got sequence %q", record.Chrom, sequence.Identifier)
+	}
+	if record.ChromEnd > len(sequence.Sequence) {
+		return "", fmt.Errorf("interval [%d, %d) is out of range for %q of length %d", record.ChromStart, record.ChromEnd, record.Chrom, len(sequence.Sequence))
+	}
+	var builder strings.Builder
+	if len(record.BlockSizes) == 0 {
+		builder.WriteString(sequence.Sequence[record.ChromStart:record.ChromEnd])
+	}
+	for i, start := range record.BlockStarts {
+		blockStart := record.ChromStart + start
+		builder.WriteString(sequence.Sequence[blockStart : blockStart+record.BlockSizes[i]])
+	}
+	if record.Strand == '-' {
+		return transform.ReverseComplement(builder.String()), nil
+	}
+	return builder.String(), nil
+}
+
+// Extract returns the sequences of the records as fasta records, like
+// `bedtools getfasta -s -name`. Each record is named by its name, or by its
+// interval if it has no name.
+func Extract(records []Record, sequences []fasta.Record) ([]fasta.Record, error) {
+	sequenceMap := make(map[string]fasta.Record, len(sequences))
+	for _, sequence := range sequences {
+		if fields := strings.Fields(sequence.Identifier); len(fields) > 0 {
+			sequenceMap[fields[0]] = sequence
+		}
+	}
+	output := make([]fasta.Record, len(records))
+	for i, record := range records {
+		sequence, ok := sequenceMap[record.Chrom]
+		if !ok {
+			return nil, fmt.Errorf("sequence %q of record %d not found", record.Chrom, i)
+		}
+		extracted, err := record.Sequence(sequence)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		identifier := record.Name
+		if identifier == "" || identifier == "." {
+			identifier = fmt.Sprintf("%s:%d-%d", record.Chrom, record.ChromStart, record.ChromEnd)
+			if record.Strand == '+' || record.Strand == '-' {
+				identifier += "(" + string(record.Strand) + ")"
+			}
+		}
+		output[i] = fasta.Record{Identifier: identifier, Sequence: extracted}
+	}
+	return output, nil
+}
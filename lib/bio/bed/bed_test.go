@@ -0,0 +1,223 @@
+package bed
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/koeng101/dnadesign/lib/bio/fasta"
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+const maxLineSize = 2 * 32 * 1024
+
+var sequences = []fasta.Record{
+	{Identifier: "chr1 test chromosome", Sequence: "AAAAGGGGCCCCTTTTAAAAGGGGCCCCTTTTAAAAGGGG"},
+	{Identifier: "chr2", Sequence: "ATGCATGCAT"},
+}
+
+func parseFile(t *testing.T, path string, fields int) (Header, []Record) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %s", path, err)
+	}
+	defer file.Close()
+	parser, err := NewParserWithFields(file, maxLineSize, fields)
+	if err != nil {
+		t.Fatalf("Failed to create parser: %s", err)
+	}
+	header, _ := parser.Header()
+	var records []Record
+	for {
+		record, err := parser.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("Got unknown error: %s", err)
+			}
+			break
+		}
+		records = append(records, record)
+	}
+	return header, records
+}
+
+func TestParse(t *testing.T) {
+	header, records := parseFile(t, "data/amplicons.bed", 0)
+	if len(header.Lines) != 3 || !strings.HasPrefix(header.Lines[1], "track") {
+		t.Errorf("Failed to parse header: %v", header.Lines)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if records[1].Name != "amp2" || records[1].Score != 500 || records[1].Strand != '-' || records[1].Fields != 6 {
+		t.Errorf("Failed to parse BED6 record: %+v", records[1])
+	}
+
+	_, records = parseFile(t, "data/genes.bed", 0)
+	gene := records[0]
+	if gene.ThickStart != 8 || gene.ThickEnd != 30 || gene.ItemRgb != "255,0,0" || len(gene.BlockSizes) != 2 || gene.BlockStarts[1] != 15 {
+		t.Errorf("Failed to parse BED12 record: %+v", gene)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	for _, path := range []string{"data/amplicons.bed", "data/genes.bed"} {
+		data, _ := os.ReadFile(path)
+		header, records := parseFile(t, path, 0)
+		var buffer bytes.Buffer
+		_, _ = header.WriteTo(&buffer)
+		for _, record := range records {
+			_, _ = record.WriteTo(&buffer)
+		}
+		// Block lists are written without trailing commas.
+		expected := strings.ReplaceAll(string(data), ",\t", "\t")
+		expected = strings.ReplaceAll(expected, ",\n", "\n")
+		if buffer.String() != expected {
+			t.Errorf("Round trip of %s does not match. Got:\n%s", path, buffer.String())
+		}
+	}
+
+	// Without Fields, the fewest columns holding every value are written.
+	var buffer bytes.Buffer
+	_, _ = (&Record{Chrom: "chr1", ChromStart: 1, ChromEnd: 5}).WriteTo(&buffer)
+	_, _ = (&Record{Chrom: "chr1", ChromStart: 1, ChromEnd: 5, Name: "a"}).WriteTo(&buffer)
+	_, _ = (&Record{Chrom: "chr1", ChromStart: 1, ChromEnd: 5, Strand: '-'}).WriteTo(&buffer)
+	expected := "chr1\t1\t5\nchr1\t1\t5\ta\nchr1\t1\t5\t.\t0\t-\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buffer.String())
+	}
+}
+
+func TestNarrowPeak(t *testing.T) {
+	data, _ := os.ReadFile("data/peaks.narrowPeak")
+	_, records := parseFile(t, "data/peaks.narrowPeak", 6)
+	var buffer bytes.Buffer
+	for _, record := range records {
+		peak, err := ParseNarrowPeak(record)
+		if err != nil {
+			t.Fatalf("Failed to parse narrowPeak: %s", err)
+		}
+		_, _ = peak.WriteTo(&buffer)
+	}
+	if buffer.String() != string(data) {
+		t.Errorf("Round trip does not match. Got:\n%s", buffer.String())
+	}
+	peak, _ := ParseNarrowPeak(records[0])
+	if peak.SignalValue != 182.8 || peak.PValue != -1 || peak.QValue != 4.3 || peak.Peak != 11 || peak.Name != "peak1" {
+		t.Errorf("Failed to parse narrowPeak: %+v", peak)
+	}
+
+	// Without fields, narrowPeak columns are read as standard columns.
+	if _, err := NewParser(bytes.NewReader(data), maxLineSize); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	parser, _ := NewParser(bytes.NewReader(data), maxLineSize)
+	if _, err := parser.Next(); err == nil {
+		t.Errorf("Expected error parsing narrowPeak as BED10")
+	}
+	if _, err := ParseNarrowPeak(Record{Chrom: "chr1", Fields: 6}); err == nil {
+		t.Errorf("Expected error converting record without extra columns")
+	}
+}
+
+func TestSequence(t *testing.T) {
+	_, records := parseFile(t, "data/amplicons.bed", 0)
+	extracted, err := Extract(records, sequences)
+	if err != nil {
+		t.Fatalf("Failed to extract: %s", err)
+	}
+	expected := []fasta.Record{
+		{Identifier: "amp1", Sequence: "AAAAGGGGCC"},
+		{Identifier: "amp2", Sequence: "AAAGGGGCCC"},
+		{Identifier: "chr2:2-8", Sequence: "GCATGC"},
+	}
+	for i := range expected {
+		if extracted[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], extracted[i])
+		}
+	}
+
+	_, records = parseFile(t, "data/genes.bed", 0)
+	sequence, err := records[0].Sequence(sequences[0])
+	if err != nil || sequence != "TTAAAAGGGGCCCCTAAGGGGCCCC" {
+		t.Errorf("Wrong spliced sequence %s, err: %v", sequence, err)
+	}
+
+	if _, err := records[0].Sequence(sequences[1]); err == nil {
+		t.Errorf("Expected error getting sequence from wrong chrom")
+	}
+	outOfRange := Record{Chrom: "chr2", ChromStart: 5, ChromEnd: 20}
+	if _, err := outOfRange.Sequence(sequences[1]); err == nil {
+		t.Errorf("Expected error for out of range interval")
+	}
+	for name, invalid := range map[string]Record{
+		"negative start":       {Chrom: "chr2", ChromStart: -1, ChromEnd: 5},
+		"start after end":      {Chrom: "chr2", ChromStart: 8, ChromEnd: 5},
+		"block past chromEnd":  {Chrom: "chr2", ChromStart: 0, ChromEnd: 5, BlockSizes: []int{2, 10}, BlockStarts: []int{0, 3}},
+		"missing block starts": {Chrom: "chr2", ChromStart: 0, ChromEnd: 5, BlockSizes: []int{2, 2}},
+	} {
+		if _, err := invalid.Sequence(sequences[1]); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := Extract([]Record{{Chrom: "chr3", ChromEnd: 1}}, sequences); err == nil {
+		t.Errorf("Expected error for missing sequence")
+	}
+}
+
+func TestFeature(t *testing.T) {
+	_, records := parseFile(t, "data/genes.bed", 0)
+	feature := records[0].Feature("mRNA")
+	if location := genbank.BuildLocationString(feature.Location); location != "complement(join(5..14,20..34))" {
+		t.Errorf("Expected complement(join(5..14,20..34)), got %s", location)
+	}
+	if feature.Attributes["label"][0] != "gene1" {
+		t.Errorf("Expected label gene1, got %v", feature.Attributes)
+	}
+
+	// The feature sequence matches the BED sequence.
+	parent := genbank.Genbank{Sequence: sequences[0].Sequence}
+	_ = parent.AddFeature(&feature)
+	sequence, _ := parent.Features[0].GetSequence()
+	expected, _ := records[0].Sequence(sequences[0])
+	if sequence != expected {
+		t.Errorf("Feature sequence %s does not match BED sequence %s", sequence, expected)
+	}
+
+	amplicon := Record{Chrom: "chr1", ChromStart: 0, ChromEnd: 10}
+	if location := genbank.BuildLocationString(amplicon.Location()); location != "1..10" {
+		t.Errorf("Expected 1..10, got %s", location)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, line := range []string{
+		"chr1\t0\n",
+		"chr1\tzero\t10\n",
+		"chr1\t0\tten\n",
+		"chr1\t10\t0\n",
+		"chr1\t0\t10\ta\tmany\n",
+		"chr1\t0\t10\ta\t0\tx\n",
+		"chr1\t0\t10\ta\t0\t+\t5\t20\n",
+		"chr1\t0\t10\ta\t0\t+\t0\t10\t0\t2\n",
+		"chr1\t0\t10\ta\t0\t+\t0\t10\t0\t2\t5,\t0,\n",
+		"chr1\t0\t10\ta\t0\t+\t0\t10\t0\t2\t5,5\t1,5\n",
+		"chr1\t0\t10\ta\t0\t+\t0\t10\t0\t2\t5,4\t0,5\n",
+		"chr1\t0\t10\ta\t0\t+\t0\t10\t0\t2\t6,5\t0,5\n",
+	} {
+		parser, err := NewParser(strings.NewReader(line), maxLineSize)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if _, err := parser.Next(); err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("Expected error parsing %q, got %v", line, err)
+		}
+	}
+	if _, err := NewParserWithFields(strings.NewReader(""), maxLineSize, 13); err == nil {
+		t.Errorf("Expected error for 13 fields")
+	}
+}
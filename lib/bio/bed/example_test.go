@@ -0,0 +1,37 @@
+package bed_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koeng101/dnadesign/lib/bio/bed"
+	"github.com/koeng101/dnadesign/lib/bio/fasta"
+)
+
+func ExampleExtract() {
+	// Amplicons of a primer panel, one on each strand.
+	file := strings.NewReader("pUC19\t0\t12\tamp1\t0\t+\npUC19\t6\t18\tamp2\t0\t-\n")
+	parser, _ := bed.NewParser(file, 2*32*1024)
+	amplicon1, _ := parser.Next()
+	amplicon2, _ := parser.Next()
+
+	plasmid := fasta.Record{Identifier: "pUC19", Sequence: "TCGCGCGTTTCGGTGATGACGG"}
+	amplicons, _ := bed.Extract([]bed.Record{amplicon1, amplicon2}, []fasta.Record{plasmid})
+	for _, amplicon := range amplicons {
+		fmt.Println(amplicon.Identifier, amplicon.Sequence)
+	}
+	// Output:
+	// amp1 TCGCGCGTTTCG
+	// amp2 CATCACCGAAAC
+}
+
+func ExampleParseNarrowPeak() {
+	file := strings.NewReader("chr1\t9\t30\tpeak1\t1000\t.\t182.8\t-1\t4.3\t11\n")
+	// narrowPeak files have 6 standard BED columns and 4 extra columns.
+	parser, _ := bed.NewParserWithFields(file, 2*32*1024, 6)
+	record, _ := parser.Next()
+	peak, _ := bed.ParseNarrowPeak(record)
+
+	fmt.Println(peak.Name, peak.ChromStart+peak.Peak, peak.SignalValue)
+	// Output: peak1 20 182.8
+}
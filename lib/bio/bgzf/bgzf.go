@@ -0,0 +1,428 @@
+/*
+Package bgzf implements reading and writing of BGZF compressed files.
+
+BGZF (Blocked GNU Zip Format) is a variant of gzip used by BAM, bgzipped
+FASTA/FASTQ, VCF, and many other bioinformatics formats. A BGZF file is a
+series of concatenated gzip members (blocks), each holding at most 64kB of
+uncompressed data, with the compressed size of every block stored in a gzip
+extra field. Since every block is a valid gzip member, a BGZF file can be
+read by any gzip reader, but knowing block boundaries allows for random
+access and for decompressing blocks in parallel.
+
+A BGZF file ends with a special empty block, the EOF marker, which lets
+readers detect truncated files. Reader and ParallelReader return
+ErrMissingEOFMarker when a stream ends without it.
+
+Spec: section 4.1 of http://samtools.github.io/hts-specs/SAMv1.pdf
+*/
+package bgzf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+)
+
+const (
+	// MaxBlockSize is the maximum size of a single compressed BGZF block.
+	MaxBlockSize = 0x10000
+	// MaxDataSize is the maximum number of uncompressed bytes written into a
+	// single BGZF block. This is the same value used by htslib, and is
+	// small enough that even incompressible data fits into MaxBlockSize.
+	MaxDataSize = 0xff00
+
+	fixedHeaderSize = 12 // Size of the gzip header before its extra subfields.
+	footerSize      = 8  // Size of the CRC32 and ISIZE footer.
+)
+
+// EOFMarker is the empty BGZF block that terminates every BGZF file.
+var EOFMarker = []byte{
+	0x1f, 0x8b, 0x08, 0x04, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0xff, 0x06, 0x00, 0x42, 0x43, 0x02, 0x00,
+	0x1b, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00,
+}
+
+// ErrNotBgzf is returned when a block does not have a valid BGZF header.
+var ErrNotBgzf = errors.New("bgzf: invalid BGZF block header")
+
+// ErrMissingEOFMarker is returned when a BGZF stream ends without an EOF
+// marker, which usually means that the file was truncated.
+var ErrMissingEOFMarker = errors.New("bgzf: missing EOF marker, the file may be truncated")
+
+/******************************************************************************
+
+Block level functions
+
+******************************************************************************/
+
+// ReadBlock reads a single raw (still compressed) BGZF block, including its
+// header and footer, from r. It returns io.EOF if r is empty.
+func ReadBlock(r io.Reader) ([]byte, error) {
+	header := make([]byte, fixedHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("bgzf: truncated block header: %w", err)
+		}
+		return nil, err
+	}
+	if !isGzipWithExtra(header) {
+		return nil, ErrNotBgzf
+	}
+	extraLength := int(binary.LittleEndian.Uint16(header[10:12]))
+	header = append(header, make([]byte, extraLength)...)
+	if _, err := io.ReadFull(r, header[fixedHeaderSize:]); err != nil {
+		return nil, fmt.Errorf("bgzf: truncated block header: %w", io.ErrUnexpectedEOF)
+	}
+	blockSize, err := blockSize(header)
+	if err != nil {
+		return nil, err
+	}
+	block := make([]byte, blockSize)
+	copy(block, header)
+	if _, err := io.ReadFull(r, block[len(header):]); err != nil {
+		return nil, fmt.Errorf("bgzf: truncated block: %w", io.ErrUnexpectedEOF)
+	}
+	return block, nil
+}
+
+// isGzipWithExtra returns whether the start of a block is a gzip header with
+// extra fields.
+func isGzipWithExtra(header []byte) bool {
+	return len(header) >= fixedHeaderSize && header[0] == 0x1f && header[1] == 0x8b && header[2] == 0x08 && header[3]&0x04 != 0
+}
+
+// IsBgzf returns whether the start of a block, including all of its extra
+// fields, is a BGZF header: a gzip header with a BC extra subfield.
+func IsBgzf(header []byte) bool {
+	_, err := blockSize(header)
+	return err == nil
+}
+
+// blockSize returns the total size of a BGZF block from its header, which
+// must include all of its extra fields. The block size is stored in the BC
+// extra subfield, which can be anywhere among the extra subfields.
+func blockSize(header []byte) (int, error) {
+	if !isGzipWithExtra(header) {
+		return 0, ErrNotBgzf
+	}
+	extraLength := int(binary.LittleEndian.Uint16(header[10:12]))
+	if len(header) < fixedHeaderSize+extraLength {
+		return 0, ErrNotBgzf
+	}
+	extra := header[fixedHeaderSize : fixedHeaderSize+extraLength]
+	// every subfield is a 2 byte identifier, a 2 byte length, and its data.
+	for len(extra) >= 4 {
+		subfieldLength := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+subfieldLength {
+			return 0, ErrNotBgzf
+		}
+		if extra[0] == 'B' && extra[1] == 'C' && subfieldLength == 2 {
+			size := int(binary.LittleEndian.Uint16(extra[4:6])) + 1
+			if size < fixedHeaderSize+extraLength+footerSize {
+				return 0, ErrNotBgzf
+			}
+			return size, nil
+		}
+		extra = extra[4+subfieldLength:]
+	}
+	return 0, ErrNotBgzf
+}
+
+// DecompressBlock decompresses a raw BGZF block, as returned by ReadBlock,
+// and checks its CRC32 and size.
+func DecompressBlock(block []byte) ([]byte, error) {
+	if !isGzipWithExtra(block) {
+		return nil, ErrNotBgzf
+	}
+	dataStart := fixedHeaderSize + int(binary.LittleEndian.Uint16(block[10:12]))
+	if len(block) < dataStart+footerSize {
+		return nil, ErrNotBgzf
+	}
+	footer := block[len(block)-footerSize:]
+	crc := binary.LittleEndian.Uint32(footer[0:4])
+	size := binary.LittleEndian.Uint32(footer[4:8])
+	if size > MaxBlockSize {
+		return nil, fmt.Errorf("bgzf: block claims %d uncompressed bytes, more than maximum of %d", size, MaxBlockSize)
+	}
+	data := make([]byte, size)
+	decompressor := flate.NewReader(bytes.NewReader(block[dataStart : len(block)-footerSize]))
+	defer decompressor.Close()
+	if _, err := io.ReadFull(decompressor, data); err != nil {
+		return nil, fmt.Errorf("bgzf: failed to decompress block: %w", err)
+	}
+	if crc32.ChecksumIEEE(data) != crc {
+		return nil, errors.New("bgzf: block checksum mismatch")
+	}
+	return data, nil
+}
+
+// CompressBlock compresses up to MaxDataSize bytes of data into a single
+// BGZF block using the given flate compression level.
+func CompressBlock(data []byte, level int) ([]byte, error) {
+	if len(data) > MaxDataSize {
+		return nil, fmt.Errorf("bgzf: cannot compress %d bytes into a single block, maximum is %d", len(data), MaxDataSize)
+	}
+	var buffer bytes.Buffer
+	buffer.Write([]byte{0x1f, 0x8b, 0x08, 0x04, 0, 0, 0, 0, 0, 0xff, 0x06, 0x00, 'B', 'C', 0x02, 0x00, 0, 0})
+	compressor, err := flate.NewWriter(&buffer, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = compressor.Write(data); err != nil {
+		return nil, err
+	}
+	if err = compressor.Close(); err != nil {
+		return nil, err
+	}
+	footer := make([]byte, footerSize)
+	binary.LittleEndian.PutUint32(footer[0:4], crc32.ChecksumIEEE(data))
+	binary.LittleEndian.PutUint32(footer[4:8], uint32(len(data)))
+	buffer.Write(footer)
+
+	block := buffer.Bytes()
+	if len(block) > MaxBlockSize {
+		return nil, fmt.Errorf("bgzf: compressed block of %d bytes exceeds maximum of %d", len(block), MaxBlockSize)
+	}
+	binary.LittleEndian.PutUint16(block[16:18], uint16(len(block)-1))
+	return block, nil
+}
+
+/******************************************************************************
+
+Reader
+
+******************************************************************************/
+
+// Reader is an io.Reader that decompresses a BGZF stream block by block. It
+// should be initialized with NewReader.
+type Reader struct {
+	reader    *bufio.Reader
+	block     []byte // decompressed data of the current block
+	offset    int    // read offset within the current block
+	eofMarker bool   // whether the last block read was the EOF marker
+	err       error
+}
+
+// NewReader returns a Reader that decompresses BGZF data from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReaderSize(r, MaxBlockSize)}
+}
+
+// Read implements io.Reader. Read returns io.EOF once every block in the
+// underlying stream has been read, or ErrMissingEOFMarker if the stream
+// doesn't end with the EOF marker.
+func (r *Reader) Read(p []byte) (int, error) {
+	for r.offset == len(r.block) {
+		if r.err != nil {
+			return 0, r.err
+		}
+		block, err := ReadBlock(r.reader)
+		if err != nil {
+			r.err = endOfStream(err, r.eofMarker)
+			return 0, r.err
+		}
+		r.eofMarker = bytes.Equal(block, EOFMarker)
+		r.block, r.err = DecompressBlock(block)
+		r.offset = 0
+		if r.err != nil {
+			return 0, r.err
+		}
+	}
+	n := copy(p, r.block[r.offset:])
+	r.offset += n
+	return n, nil
+}
+
+// endOfStream returns the error of a reader when reading a block fails,
+// which is ErrMissingEOFMarker if the stream ended without an EOF marker.
+func endOfStream(err error, eofMarker bool) error {
+	if errors.Is(err, io.EOF) && !eofMarker {
+		return ErrMissingEOFMarker
+	}
+	return err
+}
+
+/******************************************************************************
+
+Writer
+
+******************************************************************************/
+
+// Writer is an io.WriteCloser that compresses data into BGZF blocks. Close
+// must be called to flush the final block and write the EOF marker. It
+// should be initialized with NewWriter or NewWriterLevel.
+type Writer struct {
+	writer io.Writer
+	level  int
+	buffer []byte
+	closed bool
+}
+
+// NewWriter returns a Writer that writes BGZF data to w using the default
+// compression level.
+func NewWriter(w io.Writer) *Writer {
+	writer, _ := NewWriterLevel(w, flate.DefaultCompression)
+	return writer
+}
+
+// NewWriterLevel returns a Writer that writes BGZF data to w using the given
+// flate compression level.
+func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return nil, fmt.Errorf("bgzf: invalid compression level: %d", level)
+	}
+	return &Writer{writer: w, level: level, buffer: make([]byte, 0, MaxDataSize)}, nil
+}
+
+// Write implements io.Writer. Data is buffered until a full block is
+// available, at which point the block is compressed and written.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("bgzf: write to closed writer")
+	}
+	var written int
+	for len(p) > 0 {
+		n := copy(w.buffer[len(w.buffer):cap(w.buffer)], p)
+		w.buffer = w.buffer[:len(w.buffer)+n]
+		p = p[n:]
+		written += n
+		if len(w.buffer) == MaxDataSize {
+			if err := w.Flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Flush compresses and writes any buffered data as a block. Flushing ends
+// the current block early, which is useful for aligning records to block
+// boundaries.
+func (w *Writer) Flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	block, err := CompressBlock(w.buffer, w.level)
+	if err != nil {
+		return err
+	}
+	w.buffer = w.buffer[:0]
+	_, err = w.writer.Write(block)
+	return err
+}
+
+// Close flushes any buffered data and writes the BGZF EOF marker. It does
+// not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	w.closed = true
+	_, err := w.writer.Write(EOFMarker)
+	return err
+}
+
+/******************************************************************************
+
+Parallel reader
+
+Each BGZF block is an independent gzip member, so blocks can be decompressed
+at the same time. ParallelReader reads a batch of raw blocks, decompresses
+the whole batch concurrently, and then serves the decompressed data in
+order. No goroutines outlive a call to Read, so a ParallelReader that is
+abandoned halfway through a file doesn't leak anything.
+
+******************************************************************************/
+
+// ParallelReader is an io.Reader that decompresses a BGZF stream using
+// multiple goroutines. It should be initialized with NewParallelReader.
+type ParallelReader struct {
+	reader    *bufio.Reader
+	workers   int
+	blocks    [][]byte // decompressed blocks of the current batch
+	offset    int      // read offset within blocks[0]
+	eofMarker bool     // whether the last block read was the EOF marker
+	err       error
+}
+
+// NewParallelReader returns a ParallelReader that decompresses BGZF data
+// from r with the given number of workers. If workers is less than 1, a
+// single worker is used.
+func NewParallelReader(r io.Reader, workers int) *ParallelReader {
+	if workers < 1 {
+		workers = 1
+	}
+	return &ParallelReader{reader: bufio.NewReaderSize(r, MaxBlockSize), workers: workers}
+}
+
+// Read implements io.Reader. Read returns io.EOF once every block in the
+// underlying stream has been read, or ErrMissingEOFMarker if the stream
+// doesn't end with the EOF marker.
+func (r *ParallelReader) Read(p []byte) (int, error) {
+	for {
+		// Drop finished blocks. Empty blocks, like the EOF marker, are
+		// dropped immediately.
+		for len(r.blocks) > 0 && r.offset == len(r.blocks[0]) {
+			r.blocks = r.blocks[1:]
+			r.offset = 0
+		}
+		if len(r.blocks) > 0 {
+			break
+		}
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.readBatch()
+	}
+	n := copy(p, r.blocks[0][r.offset:])
+	r.offset += n
+	return n, nil
+}
+
+// readBatch reads up to r.workers raw blocks and decompresses them
+// concurrently. Any error is stored in r.err, and only the blocks before the
+// error are kept.
+func (r *ParallelReader) readBatch() {
+	var rawBlocks [][]byte
+	for len(rawBlocks) < r.workers {
+		block, err := ReadBlock(r.reader)
+		if err != nil {
+			r.err = endOfStream(err, r.eofMarker)
+			break
+		}
+		r.eofMarker = bytes.Equal(block, EOFMarker)
+		rawBlocks = append(rawBlocks, block)
+	}
+
+	blocks := make([][]byte, len(rawBlocks))
+	errs := make([]error, len(rawBlocks))
+	var wg sync.WaitGroup
+	for i := range rawBlocks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			blocks[i], errs[i] = DecompressBlock(rawBlocks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			blocks = blocks[:i]
+			r.err = err
+			break
+		}
+	}
+	r.blocks = blocks
+	r.offset = 0
+}
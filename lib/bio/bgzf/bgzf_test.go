@@ -0,0 +1,214 @@
+package bgzf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadWrite(t *testing.T) {
+	// Write enough data to span several blocks.
+	data := []byte(strings.Repeat("ATGCGTAGCTAGCTAGCTGATCGATCGTAGCTAGCTAGCTAGCTAGCATCGACTAGCTACG\n", 5000))
+	var buffer bytes.Buffer
+	writer := NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatalf("Failed to write: %s", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close: %s", err)
+	}
+	if !bytes.HasSuffix(buffer.Bytes(), EOFMarker) {
+		t.Errorf("Written file does not end with the EOF marker")
+	}
+
+	output, err := io.ReadAll(NewReader(bytes.NewReader(buffer.Bytes())))
+	if err != nil {
+		t.Fatalf("Failed to read: %s", err)
+	}
+	if !bytes.Equal(data, output) {
+		t.Errorf("Round tripped data does not match input")
+	}
+
+	// Every BGZF file is a valid multi-member gzip file.
+	gzipReader, err := gzip.NewReader(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to open as gzip: %s", err)
+	}
+	output, err = io.ReadAll(gzipReader)
+	if err != nil {
+		t.Fatalf("Failed to read as gzip: %s", err)
+	}
+	if !bytes.Equal(data, output) {
+		t.Errorf("gzip reader output does not match input")
+	}
+}
+
+func TestEOFMarkerIsValidGzip(t *testing.T) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(EOFMarker))
+	if err != nil {
+		t.Fatalf("EOF marker is not valid gzip: %s", err)
+	}
+	output, err := io.ReadAll(gzipReader)
+	if err != nil || len(output) != 0 {
+		t.Errorf("EOF marker should decompress to nothing. Got %q, %v", output, err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	// Plain gzip (no BC extra field) is not BGZF.
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write([]byte("ATGC"))
+	_ = gzipWriter.Close()
+	_, err := io.ReadAll(NewReader(&gzipped))
+	if !errors.Is(err, ErrNotBgzf) {
+		t.Errorf("Expected ErrNotBgzf, got: %v", err)
+	}
+
+	// Truncated block
+	block, _ := CompressBlock([]byte("ATGC"), 6)
+	_, err = io.ReadAll(NewReader(bytes.NewReader(block[:len(block)-3])))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got: %v", err)
+	}
+
+	// Corrupt checksum
+	block[len(block)-8] ^= 0xff
+	_, err = io.ReadAll(NewReader(bytes.NewReader(block)))
+	if err == nil {
+		t.Errorf("Expected checksum error")
+	}
+}
+
+func TestMissingEOFMarker(t *testing.T) {
+	data := []byte(strings.Repeat("GATTACA\n", 20000))
+	var buffer bytes.Buffer
+	writer := NewWriter(&buffer)
+	_, _ = writer.Write(data)
+	_ = writer.Close()
+	file := buffer.Bytes()
+	firstBlock, _ := ReadBlock(bytes.NewReader(file))
+
+	readers := map[string]func(io.Reader) io.Reader{
+		"reader":          func(r io.Reader) io.Reader { return NewReader(r) },
+		"parallel reader": func(r io.Reader) io.Reader { return NewParallelReader(r, 4) },
+	}
+	for name, newReader := range readers {
+		// Files cut at a block boundary are only truncated if the EOF
+		// marker is missing.
+		for _, truncated := range [][]byte{file[:len(file)-len(EOFMarker)], firstBlock} {
+			_, err := io.ReadAll(newReader(bytes.NewReader(truncated)))
+			if !errors.Is(err, ErrMissingEOFMarker) {
+				t.Errorf("%s: Expected ErrMissingEOFMarker, got: %v", name, err)
+			}
+		}
+
+		// Concatenated files have EOF markers in the middle.
+		concatenated := append(bytes.Clone(file), file...)
+		output, err := io.ReadAll(newReader(bytes.NewReader(concatenated)))
+		if err != nil {
+			t.Errorf("%s: Failed to read concatenated files: %s", name, err)
+		}
+		if !bytes.Equal(output, append(bytes.Clone(data), data...)) {
+			t.Errorf("%s: Output of concatenated files does not match input", name)
+		}
+	}
+}
+
+func TestCompressBlockTooLarge(t *testing.T) {
+	if _, err := CompressBlock(make([]byte, MaxDataSize+1), 6); err == nil {
+		t.Errorf("Expected error when compressing more than MaxDataSize")
+	}
+	if _, err := NewWriterLevel(io.Discard, 42); err == nil {
+		t.Errorf("Expected error on invalid compression level")
+	}
+}
+
+func TestParallelReader(t *testing.T) {
+	data := []byte(strings.Repeat("GATTACAGATTACAGATTACAGATTACA\n", 20000))
+	var buffer bytes.Buffer
+	writer := NewWriter(&buffer)
+	_, _ = writer.Write(data)
+	_ = writer.Close()
+	for _, workers := range []int{0, 1, 3, 16} {
+		output, err := io.ReadAll(NewParallelReader(bytes.NewReader(buffer.Bytes()), workers))
+		if err != nil {
+			t.Fatalf("Failed to read with %d workers: %s", workers, err)
+		}
+		if !bytes.Equal(data, output) {
+			t.Errorf("Output with %d workers does not match input", workers)
+		}
+	}
+
+	// Errors in a block are returned after the data of the blocks before it.
+	corrupt := bytes.Clone(buffer.Bytes())
+	firstBlock, _ := ReadBlock(bytes.NewReader(corrupt))
+	corrupt[len(firstBlock)+20] ^= 0xff
+	output, err := io.ReadAll(NewParallelReader(bytes.NewReader(corrupt), 4))
+	if err == nil {
+		t.Errorf("Expected error from corrupt block")
+	}
+	if !bytes.Equal(output, data[:MaxDataSize]) {
+		t.Errorf("Expected only the first block to be read, got %d bytes", len(output))
+	}
+}
+
+// withSubfield returns a block with an extra subfield added before or after
+// its BC subfield.
+func withSubfield(block []byte, before bool) []byte {
+	subfield := []byte{'X', 'Y', 3, 0, 'a', 'b', 'c'}
+	bcSubfield := block[12:18]
+	var header []byte
+	header = append(header, block[:12]...)
+	if before {
+		header = append(append(header, subfield...), bcSubfield...)
+	} else {
+		header = append(append(header, bcSubfield...), subfield...)
+	}
+	header[10] += byte(len(subfield))
+	extended := append(header, block[18:]...)
+	blockSize := len(extended) - 1
+	bcStart := 12
+	if before {
+		bcStart += len(subfield)
+	}
+	extended[bcStart+4], extended[bcStart+5] = byte(blockSize), byte(blockSize>>8)
+	return extended
+}
+
+func TestExtraSubfields(t *testing.T) {
+	// the BGZF spec allows other extra subfields next to BC.
+	for _, before := range []bool{true, false} {
+		first, _ := CompressBlock([]byte("ATGC"), 6)
+		second, _ := CompressBlock([]byte("GATTACA"), 6)
+		input := append(append(withSubfield(first, before), withSubfield(second, before)...), EOFMarker...)
+		if !IsBgzf(input) {
+			t.Errorf("Expected block with extra subfield to be BGZF")
+		}
+		for name, reader := range map[string]io.Reader{
+			"Reader":         NewReader(bytes.NewReader(input)),
+			"ParallelReader": NewParallelReader(bytes.NewReader(input), 2),
+		} {
+			output, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("%s: got error: %s", name, err)
+			}
+			if string(output) != "ATGCGATTACA" {
+				t.Errorf("%s: expected ATGCGATTACA, got %q", name, output)
+			}
+		}
+	}
+
+	// extra subfields without BC are not BGZF.
+	block, _ := CompressBlock([]byte("ATGC"), 6)
+	block[12], block[13] = 'X', 'Y'
+	if IsBgzf(block) {
+		t.Errorf("Expected block without BC subfield not to be BGZF")
+	}
+	if _, err := ReadBlock(bytes.NewReader(block)); !errors.Is(err, ErrNotBgzf) {
+		t.Errorf("Expected ErrNotBgzf, got: %v", err)
+	}
+}
@@ -6,25 +6,40 @@ The poly bio package provides a consistent way to work with each of these file
 formats. The underlying data returned by each parser is as raw as we can return
 while still being easy to use for downstream applications, and should be
 immediately recognizable as the original format.
+
+Biological files are very often compressed with gzip or BGZF (block gzip).
+Every parser constructor in this package detects compressed input by its
+magic bytes and transparently decompresses it, so compressed and
+uncompressed files can be passed to the same constructors.
 */
 package bio
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
+	"encoding/binary"
 	"errors"
 	"io"
 	"math"
+	"runtime"
 
+	"github.com/koeng101/dnadesign/lib/bio/bam"
+	"github.com/koeng101/dnadesign/lib/bio/bed"
+	"github.com/koeng101/dnadesign/lib/bio/bgzf"
+	"github.com/koeng101/dnadesign/lib/bio/embl"
 	"github.com/koeng101/dnadesign/lib/bio/errgroup"
 	"github.com/koeng101/dnadesign/lib/bio/fasta"
 	"github.com/koeng101/dnadesign/lib/bio/fastq"
 	"github.com/koeng101/dnadesign/lib/bio/genbank"
+	"github.com/koeng101/dnadesign/lib/bio/gff"
 	"github.com/koeng101/dnadesign/lib/bio/pileup"
 	"github.com/koeng101/dnadesign/lib/bio/sam"
 	"github.com/koeng101/dnadesign/lib/bio/slow5"
+	"github.com/koeng101/dnadesign/lib/bio/snapgene"
 	"github.com/koeng101/dnadesign/lib/bio/uniprot"
 	"github.com/koeng101/dnadesign/lib/bio/uniref"
+	"github.com/koeng101/dnadesign/lib/bio/vcf"
 )
 
 // Format is a enum of different parser formats.
@@ -37,6 +52,13 @@ const (
 	Slow5
 	Sam
 	Pileup
+	Bam
+	Vcf
+	Gff
+	Bed
+	Embl
+	Blow5
+	Snapgene
 )
 
 // DefaultMaxLineLength variables are defined for performance reasons. While
@@ -53,6 +75,10 @@ var DefaultMaxLengths = map[Format]int{
 	Slow5:   128 * 1024 * 1024, // 128mb is used because slow5 lines can be massive, since a single read can be many millions of base pairs.
 	Sam:     defaultMaxLineLength,
 	Pileup:  defaultMaxLineLength,
+	Vcf:     vcf.DefaultMaxLineSize, // VCF lines grow with the number of samples, and can have thousands.
+	Gff:     defaultMaxLineLength,
+	Bed:     defaultMaxLineLength,
+	Embl:    defaultMaxLineLength,
 }
 
 /******************************************************************************
@@ -64,12 +90,12 @@ Lower level interfaces
 
 // DataTypes defines the possible data types returned by every parser.
 type DataTypes interface {
-	genbank.Genbank | fasta.Record | fastq.Read | slow5.Read | sam.Alignment | pileup.Line | uniprot.Entry | uniref.Entry
+	genbank.Genbank | fasta.Record | fastq.Read | slow5.Read | sam.Alignment | pileup.Line | uniprot.Entry | uniref.Entry | vcf.Record | gff.Gff | bed.Record
 }
 
 // HeaderTypes defines the possible header types returned by every parser.
 type HeaderTypes interface {
-	genbank.Header | fasta.Header | fastq.Header | slow5.Header | sam.Header | pileup.Header | uniprot.Header | uniref.Header
+	genbank.Header | fasta.Header | fastq.Header | slow5.Header | sam.Header | pileup.Header | uniprot.Header | uniref.Header | vcf.Header | gff.Header | bed.Header | embl.Header | snapgene.Header
 }
 
 // ParserInterface is a generic interface that all parsers must support. It is
@@ -106,7 +132,7 @@ func NewFastaParser(r io.Reader) *Parser[fasta.Record, fasta.Header] {
 // NewFastaParserWithMaxLineLength initiates a new FASTA parser from an
 // io.Reader and a user-given maxLineLength.
 func NewFastaParserWithMaxLineLength(r io.Reader, maxLineLength int) *Parser[fasta.Record, fasta.Header] {
-	return &Parser[fasta.Record, fasta.Header]{ParserInterface: fasta.NewParser(r, maxLineLength)}
+	return &Parser[fasta.Record, fasta.Header]{ParserInterface: fasta.NewParser(newDecompressingReader(r), maxLineLength)}
 }
 
 // NewFastqParser initiates a new FASTQ parser from an io.Reader.
@@ -117,7 +143,7 @@ func NewFastqParser(r io.Reader) *Parser[fastq.Read, fastq.Header] {
 // NewFastqParserWithMaxLineLength initiates a new FASTQ parser from an
 // io.Reader and a user-given maxLineLength.
 func NewFastqParserWithMaxLineLength(r io.Reader, maxLineLength int) *Parser[fastq.Read, fastq.Header] {
-	return &Parser[fastq.Read, fastq.Header]{ParserInterface: fastq.NewParser(r, maxLineLength)}
+	return &Parser[fastq.Read, fastq.Header]{ParserInterface: fastq.NewParser(newDecompressingReader(r), maxLineLength)}
 }
 
 // NewGenbankParser initiates a new Genbank parser form an io.Reader.
@@ -128,7 +154,7 @@ func NewGenbankParser(r io.Reader) *Parser[genbank.Genbank, genbank.Header] {
 // NewGenbankParserWithMaxLineLength initiates a new Genbank parser from an
 // io.Reader and a user-given maxLineLength.
 func NewGenbankParserWithMaxLineLength(r io.Reader, maxLineLength int) *Parser[genbank.Genbank, genbank.Header] {
-	return &Parser[genbank.Genbank, genbank.Header]{ParserInterface: genbank.NewParser(r, maxLineLength)}
+	return &Parser[genbank.Genbank, genbank.Header]{ParserInterface: genbank.NewParser(newDecompressingReader(r), maxLineLength)}
 }
 
 // NewSlow5Parser initiates a new SLOW5 parser from an io.Reader.
@@ -139,7 +165,15 @@ func NewSlow5Parser(r io.Reader) (*Parser[slow5.Read, slow5.Header], error) {
 // NewSlow5ParserWithMaxLineLength initiates a new SLOW5 parser from an
 // io.Reader and a user-given maxLineLength.
 func NewSlow5ParserWithMaxLineLength(r io.Reader, maxLineLength int) (*Parser[slow5.Read, slow5.Header], error) {
-	parser, err := slow5.NewParser(r, maxLineLength)
+	parser, err := slow5.NewParser(newDecompressingReader(r), maxLineLength)
+	return &Parser[slow5.Read, slow5.Header]{ParserInterface: parser}, err
+}
+
+// NewBlow5Parser initiates a new blow5 parser from an io.Reader. blow5 is
+// the binary version of slow5, so the parser yields the same reads and header
+// as a slow5 parser. No maxLineLength is necessary.
+func NewBlow5Parser(r io.Reader) (*Parser[slow5.Read, slow5.Header], error) {
+	parser, err := slow5.NewBlow5Parser(newDecompressingReader(r))
 	return &Parser[slow5.Read, slow5.Header]{ParserInterface: parser}, err
 }
 
@@ -151,7 +185,16 @@ func NewSamParser(r io.Reader) (*Parser[sam.Alignment, sam.Header], error) {
 // NewSamParserWithMaxLineLength initiates a new SAM parser from an io.Reader
 // and a user-given maxLineLength.
 func NewSamParserWithMaxLineLength(r io.Reader, maxLineLength int) (*Parser[sam.Alignment, sam.Header], error) {
-	parser, _, err := sam.NewParser(r, maxLineLength)
+	parser, _, err := sam.NewParser(newDecompressingReader(r), maxLineLength)
+	return &Parser[sam.Alignment, sam.Header]{ParserInterface: parser}, err
+}
+
+// NewBamParser initiates a new BAM parser from an io.Reader. BAM files are
+// binary and BGZF compressed, so no maxLineLength is necessary. The returned
+// parser yields the same alignments and header as a SAM parser. Blocks are
+// decompressed in parallel.
+func NewBamParser(r io.Reader) (*Parser[sam.Alignment, sam.Header], error) {
+	parser, _, err := bam.NewParserFromDecompressed(newDecompressingReader(r))
 	return &Parser[sam.Alignment, sam.Header]{ParserInterface: parser}, err
 }
 
@@ -163,24 +206,179 @@ func NewPileupParser(r io.Reader) *Parser[pileup.Line, pileup.Header] {
 // NewPileupParserWithMaxLineLength initiates a new Pileup parser from an
 // io.Reader and a user-given maxLineLength.
 func NewPileupParserWithMaxLineLength(r io.Reader, maxLineLength int) *Parser[pileup.Line, pileup.Header] {
-	return &Parser[pileup.Line, pileup.Header]{ParserInterface: pileup.NewParser(r, maxLineLength)}
+	return &Parser[pileup.Line, pileup.Header]{ParserInterface: pileup.NewParser(newDecompressingReader(r), maxLineLength)}
+}
+
+// NewVcfParser initiates a new VCF parser from an io.Reader.
+func NewVcfParser(r io.Reader) (*Parser[vcf.Record, vcf.Header], error) {
+	return NewVcfParserWithMaxLineLength(r, DefaultMaxLengths[Vcf])
+}
+
+// NewVcfParserWithMaxLineLength initiates a new VCF parser from an io.Reader
+// and a user-given maxLineLength.
+func NewVcfParserWithMaxLineLength(r io.Reader, maxLineLength int) (*Parser[vcf.Record, vcf.Header], error) {
+	parser, err := vcf.NewParser(newDecompressingReader(r), maxLineLength)
+	return &Parser[vcf.Record, vcf.Header]{ParserInterface: parser}, err
+}
+
+// NewGffParser initiates a new GFF3 parser from an io.Reader. Each GFF3 file
+// is parsed into a single gff.Gff.
+func NewGffParser(r io.Reader) *Parser[gff.Gff, gff.Header] {
+	return NewGffParserWithMaxLineLength(r, DefaultMaxLengths[Gff])
+}
+
+// NewGffParserWithMaxLineLength initiates a new GFF3 parser from an io.Reader
+// and a user-given maxLineLength.
+func NewGffParserWithMaxLineLength(r io.Reader, maxLineLength int) *Parser[gff.Gff, gff.Header] {
+	return &Parser[gff.Gff, gff.Header]{ParserInterface: gff.NewParser(newDecompressingReader(r), maxLineLength)}
+}
+
+// NewBedParser initiates a new BED parser from an io.Reader. For BEDN+M
+// files, like narrowPeak, use bed.NewParserWithFields.
+func NewBedParser(r io.Reader) (*Parser[bed.Record, bed.Header], error) {
+	return NewBedParserWithMaxLineLength(r, DefaultMaxLengths[Bed])
+}
+
+// NewBedParserWithMaxLineLength initiates a new BED parser from an io.Reader
+// and a user-given maxLineLength.
+func NewBedParserWithMaxLineLength(r io.Reader, maxLineLength int) (*Parser[bed.Record, bed.Header], error) {
+	parser, err := bed.NewParser(newDecompressingReader(r), maxLineLength)
+	return &Parser[bed.Record, bed.Header]{ParserInterface: parser}, err
+}
+
+// NewEmblParser initiates a new EMBL parser from an io.Reader. EMBL records
+// are parsed into genbank.Genbank structs.
+func NewEmblParser(r io.Reader) *Parser[genbank.Genbank, embl.Header] {
+	return NewEmblParserWithMaxLineLength(r, DefaultMaxLengths[Embl])
+}
+
+// NewEmblParserWithMaxLineLength initiates a new EMBL parser from an
+// io.Reader and a user-given maxLineLength.
+func NewEmblParserWithMaxLineLength(r io.Reader, maxLineLength int) *Parser[genbank.Genbank, embl.Header] {
+	return &Parser[genbank.Genbank, embl.Header]{ParserInterface: embl.NewParser(newDecompressingReader(r), maxLineLength)}
+}
+
+// NewSnapgeneParser initiates a new SnapGene parser from an io.Reader.
+// SnapGene .dna files hold a single sequence, which is parsed into a
+// genbank.Genbank struct. No maxLineLength is necessary.
+func NewSnapgeneParser(r io.Reader) *Parser[genbank.Genbank, snapgene.Header] {
+	return &Parser[genbank.Genbank, snapgene.Header]{ParserInterface: snapgene.NewParser(newDecompressingReader(r))}
 }
 
 // NewUniprotParser initiates a new Uniprot parser from an io.Reader. No
 // maxLineLength is necessary.
 func NewUniprotParser(r io.Reader) *Parser[uniprot.Entry, uniprot.Header] {
-	return &Parser[uniprot.Entry, uniprot.Header]{ParserInterface: uniprot.NewParser(r)}
+	return &Parser[uniprot.Entry, uniprot.Header]{ParserInterface: uniprot.NewParser(newDecompressingReader(r))}
 }
 
 // NewUnirefParser initiates a new Uniref parser from an io.Reader. No
 // maxLineLength is necessary.
 func NewUnirefParser(r io.Reader) (*Parser[uniref.Entry, uniref.Header], error) {
-	parser, err := uniref.NewParser(r)
+	parser, err := uniref.NewParser(newDecompressingReader(r))
 	return &Parser[uniref.Entry, uniref.Header]{ParserInterface: parser}, err
 }
 
 /******************************************************************************
 
+Compressed input
+
+Almost every sequencing or genome file we receive is compressed, usually with
+gzip or BGZF. BGZF is a series of small gzip members, so it can be read with
+any gzip reader, but recognizing it lets us decompress its blocks in
+parallel, which is significantly faster on large files.
+
+Compression is detected from the magic bytes at the start of the input,
+rather than the file name, since most inputs here are io.Readers without
+names.
+
+******************************************************************************/
+
+// Compression is an enum of the compression formats that can be detected.
+type Compression int
+
+const (
+	Uncompressed Compression = iota
+	Gzip
+	Bgzf
+)
+
+// DetectCompression peeks at the beginning of r to determine how it is
+// compressed. It returns a reader that must be used instead of r, since the
+// peeked bytes have been consumed from r.
+func DetectCompression(r io.Reader) (io.Reader, Compression, error) {
+	// the buffer fits the largest gzip header with extra subfields.
+	bufferedReader := bufio.NewReaderSize(r, 12+0xffff)
+	// A gzip header is 12 bytes long before its extra subfields. If the
+	// input is shorter than that, it can still be (empty) gzip.
+	magic, err := bufferedReader.Peek(12)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return bufferedReader, Uncompressed, err
+	}
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return bufferedReader, Uncompressed, nil
+	}
+	// BGZF files are gzip files with the FEXTRA flag set, and a "BC" extra
+	// subfield among their extra subfields.
+	if len(magic) == 12 && magic[3]&0x04 != 0 {
+		header, err := bufferedReader.Peek(12 + int(binary.LittleEndian.Uint16(magic[10:12])))
+		if err != nil && !errors.Is(err, io.EOF) {
+			return bufferedReader, Uncompressed, err
+		}
+		if bgzf.IsBgzf(header) {
+			return bufferedReader, Bgzf, nil
+		}
+	}
+	return bufferedReader, Gzip, nil
+}
+
+// NewDecompressedReader returns a reader of the decompressed contents of r.
+// gzip (including multi-member gzip) and BGZF are detected and decompressed,
+// while uncompressed input is returned as-is. BGZF input is decompressed in
+// parallel using all available CPUs.
+func NewDecompressedReader(r io.Reader) (io.Reader, error) {
+	reader, compression, err := DetectCompression(r)
+	if err != nil {
+		return reader, err
+	}
+	switch compression {
+	case Bgzf:
+		return bgzf.NewParallelReader(reader, runtime.GOMAXPROCS(0)), nil
+	case Gzip:
+		// gzip.Reader reads concatenated gzip members by default.
+		return gzip.NewReader(reader)
+	default:
+		return reader, nil
+	}
+}
+
+// decompressingReader delays detecting compression until its first Read, so
+// that constructors of parsers that do not read their input right away do
+// not block, and do not need to return an error. Any error while detecting
+// compression is returned from Read.
+type decompressingReader struct {
+	reader   io.Reader
+	detected bool
+	err      error
+}
+
+func newDecompressingReader(r io.Reader) *decompressingReader {
+	return &decompressingReader{reader: r}
+}
+
+// Read implements io.Reader.
+func (d *decompressingReader) Read(p []byte) (int, error) {
+	if !d.detected {
+		d.reader, d.err = NewDecompressedReader(d.reader)
+		d.detected = true
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.reader.Read(p)
+}
+
+/******************************************************************************
+
 Parser higher-level functions
 
 ******************************************************************************/
@@ -207,10 +405,15 @@ func (p *Parser[Data, Header]) Next() (Data, error) {
 //	FASTA
 //	FASTQ
 //	Pileup
+//	GFF3
 //
 // The following file formats do have a useful header:
 //
 //	SLOW5
+//	SAM
+//	BAM
+//	VCF
+//	BED
 func (p *Parser[Data, Header]) Header() (Header, error) {
 	return p.ParserInterface.Header()
 }
@@ -0,0 +1,109 @@
+package bio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/koeng101/dnadesign/lib/bio/bgzf"
+)
+
+const testFasta = ">seq1\nATGCATGC\n>seq2\nGATTACA\n"
+
+func gzipCompress(data string) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, _ = writer.Write([]byte(data))
+	_ = writer.Close()
+	return buffer.Bytes()
+}
+
+func bgzfCompress(data string) []byte {
+	var buffer bytes.Buffer
+	writer := bgzf.NewWriter(&buffer)
+	_, _ = writer.Write([]byte(data))
+	_ = writer.Close()
+	return buffer.Bytes()
+}
+
+// bgzfWithSubfield returns a BGZF file of a single block with an extra
+// subfield before its BC subfield.
+func bgzfWithSubfield(data string) []byte {
+	block, _ := bgzf.CompressBlock([]byte(data), 6)
+	extended := append(append(append([]byte{}, block[:12]...), 'X', 'Y', 2, 0, 'a', 'b'), block[12:]...)
+	extended[10] += 6
+	blockSize := len(extended) - 1
+	extended[22], extended[23] = byte(blockSize), byte(blockSize>>8)
+	return append(extended, bgzf.EOFMarker...)
+}
+
+func TestDetectCompression(t *testing.T) {
+	// Multi-member gzip, as produced by `cat a.gz b.gz`
+	multiMember := append(gzipCompress(testFasta[:14]), gzipCompress(testFasta[14:])...)
+	for _, test := range []struct {
+		name        string
+		input       []byte
+		compression Compression
+	}{
+		{"plain", []byte(testFasta), Uncompressed},
+		{"empty", []byte{}, Uncompressed},
+		{"gzip", gzipCompress(testFasta), Gzip},
+		{"multi-member gzip", multiMember, Gzip},
+		{"bgzf", bgzfCompress(testFasta), Bgzf},
+		{"bgzf with extra subfield", bgzfWithSubfield(testFasta), Bgzf},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			reader, compression, err := DetectCompression(bytes.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+			if compression != test.compression {
+				t.Errorf("Expected compression %d, got %d", test.compression, compression)
+			}
+			// The returned reader still contains the peeked bytes.
+			data, _ := io.ReadAll(reader)
+			if !bytes.Equal(data, test.input) {
+				t.Errorf("Returned reader lost data")
+			}
+
+			decompressed, err := NewDecompressedReader(bytes.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+			data, err = io.ReadAll(decompressed)
+			if err != nil {
+				t.Fatalf("Got error: %s", err)
+			}
+			if len(test.input) != 0 && string(data) != testFasta {
+				t.Errorf("Expected %q, got %q", testFasta, data)
+			}
+		})
+	}
+}
+
+func TestCompressedParsers(t *testing.T) {
+	for name, input := range map[string][]byte{
+		"plain": []byte(testFasta),
+		"gzip":  gzipCompress(testFasta),
+		"bgzf":  bgzfCompress(testFasta),
+	} {
+		records, err := NewFastaParser(bytes.NewReader(input)).Parse()
+		if err != nil {
+			t.Errorf("%s: Got error: %s", name, err)
+		}
+		if len(records) != 2 || records[1].Sequence != "GATTACA" {
+			t.Errorf("%s: Got unexpected records: %v", name, records)
+		}
+	}
+}
+
+func TestCompressedParserError(t *testing.T) {
+	// A valid gzip magic number followed by garbage should be reported when
+	// parsing, not when constructing the parser.
+	parser := NewFastaParser(strings.NewReader("\x1f\x8b\x08\x00garbage"))
+	if _, err := parser.Parse(); err == nil {
+		t.Errorf("Expected error parsing corrupt gzip input")
+	}
+}
@@ -0,0 +1,735 @@
+/*
+Package embl provides an EMBL flat file parser and writer.
+
+EMBL is the flat file format of the European Nucleotide Archive. It holds the
+same data as GenBank files, with a different layout: every line starts with a
+two letter code, like ID, DE, or FT, and sections are separated by XX lines.
+The feature table uses the same keys, qualifiers, and locations as GenBank.
+
+Since the data model is the same, this package reads and writes EMBL records
+as genbank.Genbank structs, so that EMBL input works with everything in the
+library that takes genbank records.
+
+EMBL fields are stored in genbank fields as follows:
+
+	ID	Locus name, molecule type, topology, division (converted to its GenBank
+		equivalent), and sequence length, with the sequence version in Version
+	AC	Accession, space separated
+	DT	Date, one line per DT line, and the last date in Locus.ModificationDate
+	DE	Definition
+	KW	Keywords
+	OS	Source, and Organism without the common name
+	OC	Taxonomy
+	RN-RL	References. RX lines other than PUBMED are not kept.
+	CC	Other["COMMENT"]
+	FT	Features
+	SQ	Sequence and BaseCount
+
+Every other line code, like DR, is kept in Other under its code, one line per
+line of the record.
+
+Specification: https://ftp.ebi.ac.uk/pub/databases/embl/doc/usrman.txt
+*/
+package embl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+// Header is a blank struct, needed for compatibility with bio parsers. It
+// contains nothing.
+type Header struct{}
+
+// WriteTo is a blank function, needed for compatibility with bio parsers. It
+// doesn't do anything.
+func (header *Header) WriteTo(w io.Writer) (int64, error) {
+	return 0, nil
+}
+
+// emblToGenbankDivisions converts EMBL taxonomic divisions to their GenBank
+// equivalents. Divisions that are the same in both are not listed.
+var emblToGenbankDivisions = map[string]string{
+	"FUN": "PLN",
+	"HUM": "PRI",
+	"MUS": "ROD",
+	"PRO": "BCT",
+	"TGN": "SYN",
+	"UNC": "UNA",
+}
+
+// genbankToEmblDivisions converts GenBank divisions to their EMBL
+// equivalents. GenBank divisions for sequencing methods, like EST, have no
+// EMBL division and are written as UNC.
+var genbankToEmblDivisions = map[string]string{
+	"PRI": "HUM",
+	"BCT": "PRO",
+	"UNA": "UNC",
+	"EST": "UNC",
+	"PAT": "UNC",
+	"STS": "UNC",
+	"GSS": "UNC",
+	"HTG": "UNC",
+	"HTC": "UNC",
+	"":    "UNC",
+}
+
+var (
+	dateRegex  = regexp.MustCompile(`^\d{2}-[A-Z]{3}-\d{4}`)
+	rangeRegex = regexp.MustCompile(`(\d+) to (\d+)`)
+)
+
+// Parser is an EMBL parser created on an io.Reader.
+type Parser struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewParser returns a Parser that reads EMBL records from r.
+func NewParser(r io.Reader, maxLineSize int) *Parser {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, maxLineSize), maxLineSize)
+	return &Parser{scanner: scanner}
+}
+
+// Header returns an empty header, since EMBL files have none.
+func (parser *Parser) Header() (Header, error) {
+	return Header{}, nil
+}
+
+// Next returns the next record of the file. It returns io.EOF after the last
+// record.
+func (parser *Parser) Next() (genbank.Genbank, error) {
+	var lines []string
+	var start int
+	for parser.scanner.Scan() {
+		parser.line++
+		line := strings.TrimRight(parser.scanner.Text(), " \r")
+		if lines == nil {
+			// skip anything before the ID line of the record.
+			if strings.HasPrefix(line, "ID ") {
+				lines = []string{line}
+				start = parser.line
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "//") {
+			return parseRecord(lines, start)
+		}
+		lines = append(lines, line)
+	}
+	if err := parser.scanner.Err(); err != nil {
+		return genbank.Genbank{}, err
+	}
+	if lines != nil {
+		return genbank.Genbank{}, fmt.Errorf("line %d: unexpected end of file, record starting on line %d has no //", parser.line, start)
+	}
+	return genbank.Genbank{}, io.EOF
+}
+
+// lineCode splits an EMBL line into its two letter code and its content,
+// which starts at the sixth character.
+func lineCode(line string) (string, string) {
+	if len(line) < 2 {
+		return line, ""
+	}
+	if len(line) <= 5 {
+		return line[:2], ""
+	}
+	return line[:2], line[5:]
+}
+
+// parseRecord parses the lines of a record, from the ID line to the line
+// before //.
+func parseRecord(lines []string, start int) (genbank.Genbank, error) {
+	sequence := genbank.Genbank{Meta: genbank.Meta{Other: make(map[string]string)}}
+	var accessions, dates, definition, keywords, taxonomy, featureLines []string
+	var sequenceBuilder strings.Builder
+	var reference *genbank.Reference
+	var referenceField string
+	inSequence := false
+
+	for i, line := range lines {
+		lineNumber := start + i
+		code, content := lineCode(line)
+		if inSequence {
+			if strings.TrimSpace(code) != "" {
+				return sequence, fmt.Errorf("line %d: unexpected %s line in sequence", lineNumber, code)
+			}
+			for _, character := range line {
+				if ('a' <= character && character <= 'z') || ('A' <= character && character <= 'Z') {
+					sequenceBuilder.WriteRune(character)
+				}
+			}
+			continue
+		}
+		// reference lines are saved when the reference ends.
+		if reference != nil && !strings.HasPrefix(code, "R") {
+			sequence.Meta.References = append(sequence.Meta.References, *reference)
+			reference = nil
+		}
+
+		switch code {
+		case "ID":
+			if err := parseID(&sequence, content); err != nil {
+				return sequence, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+		case "AC":
+			for _, accession := range strings.Split(content, ";") {
+				if accession = strings.TrimSpace(accession); accession != "" {
+					accessions = append(accessions, accession)
+				}
+			}
+		case "SV":
+			sequence.Meta.Version = strings.TrimSpace(content)
+		case "DT":
+			dates = append(dates, content)
+		case "DE":
+			definition = append(definition, content)
+		case "KW":
+			keywords = append(keywords, content)
+		case "OS":
+			sequence.Meta.Source = joinLines(sequence.Meta.Source, content, " ")
+		case "OC":
+			taxonomy = append(taxonomy, content)
+		case "RN":
+			reference = &genbank.Reference{}
+			referenceField = ""
+		case "RC", "RP", "RX", "RG", "RA", "RT", "RL":
+			if reference == nil {
+				return sequence, fmt.Errorf("line %d: %s line outside of a reference", lineNumber, code)
+			}
+			addReferenceLine(reference, code, content, referenceField == code)
+			referenceField = code
+		case "CC":
+			sequence.Meta.Other["COMMENT"] = joinLines(sequence.Meta.Other["COMMENT"], content, "\n")
+		case "FT":
+			featureLines = append(featureLines, content)
+		case "SQ":
+			inSequence = true
+		case "XX", "FH":
+		default:
+			if len(code) != 2 || strings.TrimSpace(code) != code {
+				return sequence, fmt.Errorf("line %d: invalid line code %q", lineNumber, code)
+			}
+			sequence.Meta.Other[code] = joinLines(sequence.Meta.Other[code], content, "\n")
+		}
+	}
+	if reference != nil {
+		sequence.Meta.References = append(sequence.Meta.References, *reference)
+	}
+
+	sequence.Meta.Accession = strings.Join(accessions, " ")
+	sequence.Meta.Date = strings.Join(dates, "\n")
+	if len(dates) > 0 {
+		sequence.Meta.Locus.ModificationDate = dateRegex.FindString(dates[len(dates)-1])
+	}
+	sequence.Meta.Definition = strings.Join(definition, " ")
+	sequence.Meta.Keywords = strings.Join(keywords, " ")
+	sequence.Meta.Organism = strings.TrimSpace(strings.Split(sequence.Meta.Source, " (")[0])
+	for _, taxon := range strings.Split(strings.Join(taxonomy, " "), ";") {
+		if taxon = strings.TrimSuffix(strings.TrimSpace(taxon), "."); taxon != "" {
+			sequence.Meta.Taxonomy = append(sequence.Meta.Taxonomy, taxon)
+		}
+	}
+	sequence.Sequence = sequenceBuilder.String()
+	sequence.Meta.BaseCount = baseCounts(sequence.Sequence)
+	if sequence.Meta.Locus.SequenceLength == "" {
+		sequence.Meta.Locus.SequenceLength = strconv.Itoa(len(sequence.Sequence))
+	}
+
+	features, err := parseFeatures(featureLines)
+	if err != nil {
+		return sequence, fmt.Errorf("record starting on line %d: %w", start, err)
+	}
+	for i := range features {
+		_ = sequence.AddFeature(&features[i])
+	}
+	return sequence, nil
+}
+
+// parseID parses an ID line. Current ID lines look like
+//
+//	ID   X56734; SV 1; linear; mRNA; STD; PLN; 1859 BP.
+//
+// and lines from before 2006 look like
+//
+//	ID   X56734     standard; RNA; PLN; 1859 BP.
+func parseID(sequence *genbank.Genbank, content string) error {
+	fields := strings.Split(strings.TrimSuffix(content, "."), ";")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	locus := &sequence.Meta.Locus
+	var division, length string
+	switch len(fields) {
+	case 7:
+		locus.Name = fields[0]
+		if version := strings.TrimPrefix(fields[1], "SV "); version != fields[1] && version != "" {
+			sequence.Meta.Version = fields[0] + "." + version
+		}
+		locus.Circular = fields[2] == "circular"
+		locus.MoleculeType = fields[3]
+		division, length = fields[5], fields[6]
+	case 4:
+		locus.Name = strings.Fields(fields[0])[0]
+		locus.MoleculeType = fields[1]
+		division, length = fields[2], fields[3]
+	default:
+		return fmt.Errorf("ID line must have 7 (or 4 in old files) semicolon separated fields, got %d", len(fields))
+	}
+	if genbankDivision, ok := emblToGenbankDivisions[division]; ok {
+		division = genbankDivision
+	}
+	locus.GenbankDivision = division
+	length, coding, _ := strings.Cut(length, " ")
+	if _, err := strconv.Atoi(length); err != nil {
+		return fmt.Errorf("invalid sequence length %q", length)
+	}
+	locus.SequenceLength = length
+	locus.SequenceCoding = strings.ToLower(coding)
+	return nil
+}
+
+// joinLines appends a line to a multi-line value.
+func joinLines(value string, line string, separator string) string {
+	if value == "" {
+		return line
+	}
+	return value + separator + line
+}
+
+// addReferenceLine adds a line of a reference to its field. Continued
+// lines are joined with spaces.
+func addReferenceLine(reference *genbank.Reference, code string, content string, continued bool) {
+	add := func(field *string) {
+		if continued {
+			*field = joinLines(*field, content, " ")
+		} else {
+			*field = content
+		}
+	}
+	switch code {
+	case "RC":
+		add(&reference.Remark)
+	case "RP":
+		// RP 1-1859, 1900-2000 is GenBank's (bases 1 to 1859; 1900 to 2000)
+		ranges := strings.Split(content, ",")
+		for i := range ranges {
+			ranges[i] = strings.Replace(strings.TrimSpace(ranges[i]), "-", " to ", 1)
+		}
+		reference.Range = "(bases " + strings.Join(ranges, "; ") + ")"
+	case "RX":
+		database, identifier, _ := strings.Cut(content, ";")
+		if database == "PUBMED" {
+			reference.PubMed = strings.TrimSuffix(strings.TrimSpace(identifier), ".")
+		}
+	case "RG":
+		add(&reference.Consortium)
+	case "RA":
+		add(&reference.Authors)
+		reference.Authors = strings.TrimSuffix(reference.Authors, ";")
+	case "RT":
+		add(&reference.Title)
+		reference.Title = strings.Trim(strings.TrimSuffix(reference.Title, ";"), "\"")
+	case "RL":
+		add(&reference.Journal)
+	}
+}
+
+// baseCounts counts the bases of a sequence, like the SQ line.
+func baseCounts(sequence string) []genbank.BaseCount {
+	counts := make(map[string]int)
+	for _, base := range strings.ToLower(sequence) {
+		switch base {
+		case 'a', 'c', 'g', 't':
+			counts[string(base)]++
+		default:
+			counts["other"]++
+		}
+	}
+	var baseCounts []genbank.BaseCount
+	for _, base := range []string{"a", "c", "g", "t", "other"} {
+		baseCounts = append(baseCounts, genbank.BaseCount{Base: base, Count: counts[base]})
+	}
+	return baseCounts
+}
+
+/******************************************************************************
+
+Feature table
+
+The feature table of EMBL files is the same as in GenBank files, with FT in
+the first two columns. Feature keys start at column 6, and locations and
+qualifiers at column 22.
+
+******************************************************************************/
+
+// parseFeatures parses the content of FT lines into features.
+func parseFeatures(lines []string) ([]genbank.Feature, error) {
+	var features []genbank.Feature
+	var feature *genbank.Feature
+	var location, qualifier, value string
+	var openQuote bool
+
+	saveQualifier := func() {
+		if qualifier == "" {
+			return
+		}
+		if strings.HasPrefix(value, "\"") {
+			value = strings.TrimSuffix(strings.TrimPrefix(value, "\""), "\"")
+			value = strings.ReplaceAll(value, "\"\"", "\"")
+		}
+		genbank.Put(feature.Attributes, qualifier, value)
+		qualifier, value = "", ""
+	}
+	saveFeature := func() error {
+		if feature == nil {
+			return nil
+		}
+		saveQualifier()
+		parsedLocation, err := genbank.ParseLocation(location)
+		if err != nil {
+			return fmt.Errorf("feature %s has invalid location %s: %w", feature.Type, location, err)
+		}
+		feature.Location = parsedLocation
+		features = append(features, *feature)
+		return nil
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case openQuote:
+			// Long values without spaces, like translations, are broken
+			// anywhere. Everything else is broken at spaces.
+			if qualifier == "translation" {
+				value += trimmed
+			} else {
+				value += " " + trimmed
+			}
+			openQuote = !quoteClosed(value)
+		case !strings.HasPrefix(line, " "):
+			if err := saveFeature(); err != nil {
+				return nil, err
+			}
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("invalid feature line %q", line)
+			}
+			feature = &genbank.Feature{Type: fields[0], Attributes: genbank.NewMultiMap[string, string]()}
+			location = fields[1]
+		case feature == nil:
+			return nil, fmt.Errorf("feature table line %q before the first feature", line)
+		case strings.HasPrefix(trimmed, "/"):
+			saveQualifier()
+			var hasValue bool
+			qualifier, value, hasValue = strings.Cut(trimmed[1:], "=")
+			openQuote = hasValue && strings.HasPrefix(value, "\"") && !quoteClosed(value)
+		case qualifier == "":
+			// locations can span several lines.
+			location += trimmed
+		default:
+			return nil, fmt.Errorf("unexpected feature table line %q in qualifier %s", line, qualifier)
+		}
+	}
+	if openQuote {
+		return nil, fmt.Errorf("qualifier %s of feature %s has no closing quote", qualifier, feature.Type)
+	}
+	if err := saveFeature(); err != nil {
+		return nil, err
+	}
+	return features, nil
+}
+
+// quoteClosed returns whether a quoted value is complete. Quotes in values
+// are escaped by doubling them, so a value is complete if it has an even
+// number of quotes.
+func quoteClosed(value string) bool {
+	return len(value) > 1 && strings.HasSuffix(value, "\"") && strings.Count(value, "\"")%2 == 0
+}
+
+/******************************************************************************
+
+Writer
+
+******************************************************************************/
+
+// lineWidth is the maximum width of EMBL lines.
+const lineWidth = 80
+
+// unquotedQualifiers are qualifiers whose values are not quoted in the
+// INSDC feature table definition.
+var unquotedQualifiers = map[string]bool{
+	"anticodon":        true,
+	"citation":         true,
+	"codon_start":      true,
+	"compare":          true,
+	"direction":        true,
+	"estimated_length": true,
+	"mod_base":         true,
+	"number":           true,
+	"rpt_type":         true,
+	"rpt_unit_range":   true,
+	"tag_peptide":      true,
+	"transl_except":    true,
+	"transl_table":     true,
+}
+
+// noValueQualifiers are qualifiers that have no value.
+var noValueQualifiers = map[string]bool{
+	"environmental_sample": true,
+	"focus":                true,
+	"germline":             true,
+	"macronuclear":         true,
+	"metagenomic":          true,
+	"partial":              true,
+	"proviral":             true,
+	"pseudo":               true,
+	"rearranged":           true,
+	"ribosomal_slippage":   true,
+	"transgenic":           true,
+	"trans_splicing":       true,
+}
+
+// Write writes a genbank record as an EMBL record.
+func Write(w io.Writer, sequence genbank.Genbank) (int64, error) {
+	var sb strings.Builder
+	meta := sequence.Meta
+	separator := func() { sb.WriteString("XX\n") }
+
+	// ID
+	topology := "linear"
+	if meta.Locus.Circular {
+		topology = "circular"
+	}
+	moleculeType := meta.Locus.MoleculeType
+	if moleculeType == "" {
+		moleculeType = "DNA"
+	}
+	division, ok := genbankToEmblDivisions[meta.Locus.GenbankDivision]
+	if !ok {
+		division = meta.Locus.GenbankDivision
+	}
+	name := meta.Locus.Name
+	if name == "" {
+		name = "XXX"
+	}
+	version := "SV"
+	if _, number, found := strings.Cut(meta.Version, "."); found {
+		version = "SV " + number
+	}
+	fmt.Fprintf(&sb, "ID   %s; %s; %s; %s; STD; %s; %d BP.\n", name, version, topology, moleculeType, division, len(sequence.Sequence))
+	separator()
+
+	if accessions := strings.Fields(meta.Accession); len(accessions) > 0 {
+		writeWrapped(&sb, "AC", strings.Join(accessions, "; ")+";", " ")
+		separator()
+	}
+	if meta.Date != "" {
+		for _, date := range strings.Split(meta.Date, "\n") {
+			sb.WriteString("DT   " + date + "\n")
+		}
+		separator()
+	} else if meta.Locus.ModificationDate != "" {
+		sb.WriteString("DT   " + meta.Locus.ModificationDate + "\n")
+		separator()
+	}
+	if meta.Definition != "" {
+		writeWrapped(&sb, "DE", meta.Definition, " ")
+		separator()
+	}
+	if meta.Keywords != "" {
+		writeWrapped(&sb, "KW", meta.Keywords, " ")
+		separator()
+	}
+	if source := meta.Source; source != "" || meta.Organism != "" {
+		if source == "" {
+			source = meta.Organism
+		}
+		writeWrapped(&sb, "OS", source, " ")
+		if len(meta.Taxonomy) > 0 {
+			writeWrapped(&sb, "OC", strings.Join(meta.Taxonomy, "; ")+".", " ")
+		}
+		if organelle, ok := meta.Other["OG"]; ok {
+			writeLines(&sb, "OG", organelle)
+		}
+		separator()
+	}
+	for i, reference := range meta.References {
+		fmt.Fprintf(&sb, "RN   [%d]\n", i+1)
+		if reference.Remark != "" {
+			writeWrapped(&sb, "RC", reference.Remark, " ")
+		}
+		if ranges := rangeRegex.FindAllStringSubmatch(reference.Range, -1); len(ranges) > 0 {
+			var positions []string
+			for _, match := range ranges {
+				positions = append(positions, match[1]+"-"+match[2])
+			}
+			sb.WriteString("RP   " + strings.Join(positions, ", ") + "\n")
+		}
+		if reference.PubMed != "" {
+			sb.WriteString("RX   PUBMED; " + reference.PubMed + ".\n")
+		}
+		if reference.Consortium != "" {
+			writeWrapped(&sb, "RG", reference.Consortium, " ")
+		}
+		if reference.Authors != "" {
+			writeWrapped(&sb, "RA", reference.Authors+";", " ")
+		}
+		if reference.Title != "" {
+			writeWrapped(&sb, "RT", "\""+reference.Title+"\";", " ")
+		} else {
+			sb.WriteString("RT   ;\n")
+		}
+		writeWrapped(&sb, "RL", reference.Journal, " ")
+		separator()
+	}
+
+	// other lines are written in alphabetical order of their codes. Keys
+	// from GenBank files, like DBLINK, have no EMBL equivalent.
+	var codes []string
+	for code := range meta.Other {
+		if len(code) == 2 && code == strings.ToUpper(code) && code != "OG" {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		writeLines(&sb, code, meta.Other[code])
+		separator()
+	}
+	if comment, ok := meta.Other["COMMENT"]; ok {
+		for _, line := range strings.Split(comment, "\n") {
+			writeWrapped(&sb, "CC", line, " ")
+		}
+		separator()
+	}
+
+	if len(sequence.Features) > 0 {
+		sb.WriteString("FH   Key             Location/Qualifiers\nFH\n")
+		for _, feature := range sequence.Features {
+			writeFeature(&sb, feature)
+		}
+		separator()
+	}
+
+	writeSequence(&sb, sequence.Sequence)
+	n, err := w.Write([]byte(sb.String()))
+	return int64(n), err
+}
+
+// writeLines writes a multi-line value with one line per line.
+func writeLines(sb *strings.Builder, code string, value string) {
+	for _, line := range strings.Split(value, "\n") {
+		sb.WriteString(code + "   " + line + "\n")
+	}
+}
+
+// writeWrapped writes a value on lines with the given code, wrapping it to
+// the line width. Words are separated by separator, which is a space for
+// text and the empty string for values that can be broken anywhere.
+func writeWrapped(sb *strings.Builder, code string, value string, separator string) {
+	writePrefixed(sb, code+"   ", value, separator)
+}
+
+// writePrefixed writes a value on lines starting with prefix, wrapping it to
+// the line width.
+func writePrefixed(sb *strings.Builder, prefix string, value string, separator string) {
+	width := lineWidth - len(prefix)
+	for _, line := range wrap(value, width, separator) {
+		sb.WriteString(prefix + line + "\n")
+	}
+}
+
+// wrap splits a value into lines of at most width characters. With a space
+// separator lines are broken between words, and words longer than a line are
+// broken anywhere.
+func wrap(value string, width int, separator string) []string {
+	var lines []string
+	var line string
+	var words []string
+	if separator == "" {
+		words = []string{value}
+	} else {
+		words = strings.Split(value, separator)
+	}
+	for _, word := range words {
+		if line != "" && len(line)+len(separator)+len(word) <= width {
+			line += separator + word
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		for len(word) > width {
+			lines = append(lines, word[:width])
+			word = word[width:]
+		}
+		line = word
+	}
+	return append(lines, line)
+}
+
+// writeFeature writes a feature to the feature table. Qualifiers are written
+// in alphabetical order.
+func writeFeature(sb *strings.Builder, feature genbank.Feature) {
+	location := feature.Location.GbkLocationString
+	if location == "" {
+		location = genbank.BuildLocationString(feature.Location)
+	}
+	const qualifierPrefix = "FT                   "
+	fmt.Fprintf(sb, "FT   %-16s", feature.Type)
+	// locations are broken after commas.
+	for i, line := range wrap(strings.ReplaceAll(location, ",", ", "), lineWidth-len(qualifierPrefix), " ") {
+		if i > 0 {
+			sb.WriteString(qualifierPrefix)
+		}
+		sb.WriteString(strings.ReplaceAll(line, ", ", ",") + "\n")
+	}
+
+	keys := make([]string, 0, len(feature.Attributes))
+	for key := range feature.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range feature.Attributes[key] {
+			switch {
+			case value == "" && noValueQualifiers[key]:
+				sb.WriteString(qualifierPrefix + "/" + key + "\n")
+			case unquotedQualifiers[key]:
+				writePrefixed(sb, qualifierPrefix, "/"+key+"="+value, "")
+			case key == "translation" || !strings.Contains(value, " "):
+				writePrefixed(sb, qualifierPrefix, "/"+key+"=\""+strings.ReplaceAll(value, "\"", "\"\"")+"\"", "")
+			default:
+				writePrefixed(sb, qualifierPrefix, "/"+key+"=\""+strings.ReplaceAll(value, "\"", "\"\"")+"\"", " ")
+			}
+		}
+	}
+}
+
+// writeSequence writes the SQ line, the sequence in lines of 60 bases
+// numbered at the end, and the // terminator.
+func writeSequence(sb *strings.Builder, sequence string) {
+	counts := baseCounts(sequence)
+	fmt.Fprintf(sb, "SQ   Sequence %d BP; %d A; %d C; %d G; %d T; %d other;\n", len(sequence), counts[0].Count, counts[1].Count, counts[2].Count, counts[3].Count, counts[4].Count)
+	lowercase := strings.ToLower(sequence)
+	for start := 0; start < len(lowercase); start += 60 {
+		end := min(start+60, len(lowercase))
+		var line strings.Builder
+		line.WriteString("    ")
+		for block := start; block < end; block += 10 {
+			line.WriteString(" " + lowercase[block:min(block+10, end)])
+		}
+		fmt.Fprintf(sb, "%-70s%10d\n", line.String(), end)
+	}
+	sb.WriteString("//\n")
+}
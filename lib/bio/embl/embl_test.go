@@ -0,0 +1,243 @@
+package embl
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+const maxLineSize = 2 * 32 * 1024
+
+func readFile(t *testing.T, path string) []genbank.Genbank {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	return readAll(t, file)
+}
+
+func readAll(t *testing.T, r io.Reader) []genbank.Genbank {
+	t.Helper()
+	parser := NewParser(r, maxLineSize)
+	var records []genbank.Genbank
+	for {
+		record, err := parser.Next()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestParse(t *testing.T) {
+	records := readFile(t, "data/X56734.embl")
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	record := records[0]
+	meta := record.Meta
+
+	expectedLocus := genbank.Locus{
+		Name:             "X56734",
+		SequenceLength:   "120",
+		MoleculeType:     "mRNA",
+		GenbankDivision:  "PLN",
+		ModificationDate: "25-NOV-2005",
+		SequenceCoding:   "bp",
+	}
+	if diff := cmp.Diff(expectedLocus, meta.Locus); diff != "" {
+		t.Errorf("unexpected locus (-want +got):\n%s", diff)
+	}
+	checks := map[string][2]string{
+		"version":    {"X56734.1", meta.Version},
+		"accession":  {"X56734 S46826", meta.Accession},
+		"definition": {"Trifolium repens mRNA for non-cyanogenic beta-glucosidase", meta.Definition},
+		"keywords":   {"beta-glucosidase.", meta.Keywords},
+		"source":     {"Trifolium repens (white clover)", meta.Source},
+		"organism":   {"Trifolium repens", meta.Organism},
+		"comment":    {"This is a shortened version of the X56734 example\nof the EMBL user manual.", meta.Other["COMMENT"]},
+		"DR":         {"MD5; 1e51ca3a5450c43524b9185c236cc5cc.\nEuropePMC; PMC99098; 11752244.", meta.Other["DR"]},
+		"date":       {"12-SEP-1991 (Rel. 29, Created)\n25-NOV-2005 (Rel. 85, Last updated, Version 11)", meta.Date},
+	}
+	for name, check := range checks {
+		if check[0] != check[1] {
+			t.Errorf("expected %s %q, got %q", name, check[0], check[1])
+		}
+	}
+	if len(meta.Taxonomy) != 16 || meta.Taxonomy[0] != "Eukaryota" || meta.Taxonomy[15] != "Trifolium" {
+		t.Errorf("unexpected taxonomy %v", meta.Taxonomy)
+	}
+
+	expectedReferences := []genbank.Reference{
+		{
+			Authors: "Oxtoby E., Dunn M.A., Pancoro A., Hughes M.A.",
+			Journal: "Submitted (19-NOV-1990) to the INSDC. Hughes M.A., University of Newcastle Upon Tyne, Medical School, Newcastle Upon Tyne, NE2 4HH, UK",
+			Range:   "(bases 1 to 120)",
+		},
+		{
+			Authors: "Oxtoby E., Dunn M.A., Pancoro A., Hughes M.A.",
+			Title:   "Nucleotide and derived amino acid sequence of the cyanogenic beta-glucosidase (linamarase) from white clover (Trifolium repens L.)",
+			Journal: "Plant Mol. Biol. 17(2):209-219(1991).",
+			PubMed:  "1907511",
+			Range:   "(bases 1 to 120)",
+		},
+	}
+	if diff := cmp.Diff(expectedReferences, meta.References); diff != "" {
+		t.Errorf("unexpected references (-want +got):\n%s", diff)
+	}
+
+	if len(record.Sequence) != 120 || !strings.HasPrefix(record.Sequence, "aaacaaacca") {
+		t.Errorf("unexpected sequence %q", record.Sequence)
+	}
+	if len(record.Features) != 4 {
+		t.Fatalf("expected 4 features, got %d", len(record.Features))
+	}
+	mrna := record.Features[1]
+	if !mrna.Location.FivePrimePartial || !mrna.Location.ThreePrimePartial {
+		t.Errorf("expected partial mRNA location, got %+v", mrna.Location)
+	}
+	if note := mrna.Attributes["note"][0]; note != `an "example" of escaped quotes` {
+		t.Errorf("unexpected escaped note %q", note)
+	}
+	cds := record.Features[2]
+	if cds.Location.GbkLocationString != "join(14..40,61..>120)" || len(cds.Location.SubLocations) != 2 {
+		t.Errorf("unexpected CDS location %+v", cds.Location)
+	}
+	cdsChecks := map[string]string{
+		"codon_start": "1",
+		"product":     "non-cyanogenic beta-glucosidase with a product name that is wrapped",
+		"translation": "MDFLLAMMHLWVASLALWLLKAAPTLRSTFPSGGAFGDLMDFLLAMMHLWVA",
+		"pseudo":      "",
+	}
+	for key, expected := range cdsChecks {
+		if values := cds.Attributes[key]; len(values) != 1 || values[0] != expected {
+			t.Errorf("expected CDS qualifier %s %q, got %q", key, expected, values)
+		}
+	}
+	reverse, err := record.Features[3].GetSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverse != "gcttagttaag" {
+		t.Errorf("unexpected reverse feature sequence %q", reverse)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	records := readFile(t, "data/X56734.embl")
+	var buffer bytes.Buffer
+	if _, err := Write(&buffer, records[0]); err != nil {
+		t.Fatal(err)
+	}
+	written := readAll(t, &buffer)
+	if diff := cmp.Diff(records, written, cmpopts.IgnoreFields(genbank.Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("records changed after writing (-want +got):\n%s", diff)
+	}
+
+	// wrapped lines, like the CDS location, are joined when written.
+	var output bytes.Buffer
+	_, _ = Write(&output, records[0])
+	for _, line := range []string{
+		"ID   X56734; SV 1; linear; mRNA; STD; PLN; 120 BP.\n",
+		"FT   CDS             join(14..40,61..>120)\n",
+		"     aaacaaacca aatatggatt ttattgtagc catatttgct ctgtttgtta ttagctcatt        60\n",
+		"SQ   Sequence 120 BP; 39 A; 24 C; 16 G; 41 T; 0 other;\n",
+		"RX   PUBMED; 1907511.\n",
+	} {
+		if !strings.Contains(output.String(), line) {
+			t.Errorf("expected line %q in written file", line)
+		}
+	}
+}
+
+func TestGenbankToEmbl(t *testing.T) {
+	file, err := os.Open("../genbank/data/phix174.gb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	sequence, err := genbank.NewParser(file, maxLineSize).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if _, err := Write(&buffer, sequence); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buffer.String(), "\n") {
+		if len(line) > lineWidth {
+			t.Errorf("line longer than %d characters: %q", lineWidth, line)
+		}
+	}
+	records := readAll(t, &buffer)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	record := records[0]
+	if record.Sequence != sequence.Sequence {
+		t.Errorf("sequence changed after conversion")
+	}
+	if record.Meta.Locus.GenbankDivision != sequence.Meta.Locus.GenbankDivision || record.Meta.Locus.Circular != sequence.Meta.Locus.Circular {
+		t.Errorf("locus changed after conversion: %+v, %+v", sequence.Meta.Locus, record.Meta.Locus)
+	}
+	if record.Meta.Definition != sequence.Meta.Definition || record.Meta.Version != sequence.Meta.Version {
+		t.Errorf("meta changed after conversion: %q %q", record.Meta.Definition, record.Meta.Version)
+	}
+	if diff := cmp.Diff(sequence.Meta.Taxonomy, record.Meta.Taxonomy); diff != "" {
+		t.Errorf("taxonomy changed after conversion (-want +got):\n%s", diff)
+	}
+	if len(record.Features) != len(sequence.Features) {
+		t.Fatalf("expected %d features, got %d", len(sequence.Features), len(record.Features))
+	}
+	for i, feature := range sequence.Features {
+		if diff := cmp.Diff(feature.Attributes, record.Features[i].Attributes); diff != "" {
+			t.Errorf("feature %d qualifiers changed (-want +got):\n%s", i, diff)
+		}
+		expected, _ := feature.GetSequence()
+		got, _ := record.Features[i].GetSequence()
+		if expected != got {
+			t.Errorf("feature %d sequence changed", i)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no terminator", "ID   X1; SV 1; linear; DNA; STD; UNC; 4 BP.\nSQ   Sequence 4 BP;\n     acgt 4\n"},
+		{"bad ID", "ID   X1; linear\n//\n"},
+		{"bad length", "ID   X1; SV 1; linear; DNA; STD; UNC; four BP.\n//\n"},
+		{"bad location", "ID   X1; SV 1; linear; DNA; STD; UNC; 4 BP.\nFT   CDS             1..\n//\n"},
+		{"open quote", "ID   X1; SV 1; linear; DNA; STD; UNC; 4 BP.\nFT   CDS             1..4\nFT                   /note=\"open\n//\n"},
+		{"reference line without RN", "ID   X1; SV 1; linear; DNA; STD; UNC; 4 BP.\nRA   Someone;\n//\n"},
+	}
+	for _, test := range tests {
+		parser := NewParser(strings.NewReader(test.input), maxLineSize)
+		if _, err := parser.Next(); err == nil || err == io.EOF {
+			t.Errorf("%s: expected an error, got %v", test.name, err)
+		}
+	}
+}
+
+func TestParseOldID(t *testing.T) {
+	input := "ID   X56734     standard; RNA; PLN; 4 BP.\nSQ   Sequence 4 BP;\n     acgt                                                                      4\n//\n"
+	records := readAll(t, strings.NewReader(input))
+	locus := records[0].Meta.Locus
+	if locus.Name != "X56734" || locus.MoleculeType != "RNA" || locus.SequenceLength != "4" || records[0].Sequence != "acgt" {
+		t.Errorf("unexpected record from old ID line: %+v %q", locus, records[0].Sequence)
+	}
+}
@@ -0,0 +1,73 @@
+package embl_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/koeng101/dnadesign/lib/bio/embl"
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+func Example_read() {
+	file, _ := os.Open("data/X56734.embl")
+	defer file.Close()
+	parser := embl.NewParser(file, 2*32*1024)
+	record, _ := parser.Next()
+
+	fmt.Println(record.Meta.Locus.Name, record.Meta.Organism)
+	for _, feature := range record.Features {
+		fmt.Println(feature.Type, genbank.BuildLocationString(feature.Location))
+	}
+	// Output:
+	// X56734 Trifolium repens
+	// source 1..120
+	// mRNA <1..>120
+	// CDS join(14..40,61..>120)
+	// misc_feature complement(100..110)
+}
+
+func ExampleWrite() {
+	file, _ := os.Open("data/X56734.embl")
+	defer file.Close()
+	record, _ := embl.NewParser(file, 2*32*1024).Next()
+
+	record.Features = record.Features[:1]
+	record.Sequence = record.Sequence[:20]
+	record.Meta.References = nil
+	_, _ = embl.Write(os.Stdout, record)
+	// Output:
+	// ID   X56734; SV 1; linear; mRNA; STD; PLN; 20 BP.
+	// XX
+	// AC   X56734; S46826;
+	// XX
+	// DT   12-SEP-1991 (Rel. 29, Created)
+	// DT   25-NOV-2005 (Rel. 85, Last updated, Version 11)
+	// XX
+	// DE   Trifolium repens mRNA for non-cyanogenic beta-glucosidase
+	// XX
+	// KW   beta-glucosidase.
+	// XX
+	// OS   Trifolium repens (white clover)
+	// OC   Eukaryota; Viridiplantae; Streptophyta; Embryophyta; Tracheophyta;
+	// OC   Spermatophyta; Magnoliophyta; eudicotyledons; core eudicotyledons; rosids;
+	// OC   fabids; Fabales; Fabaceae; Papilionoideae; Trifolieae; Trifolium.
+	// XX
+	// DR   MD5; 1e51ca3a5450c43524b9185c236cc5cc.
+	// DR   EuropePMC; PMC99098; 11752244.
+	// XX
+	// CC   This is a shortened version of the X56734 example
+	// CC   of the EMBL user manual.
+	// XX
+	// FH   Key             Location/Qualifiers
+	// FH
+	// FT   source          1..120
+	// FT                   /clone_lib="lambda gt10"
+	// FT                   /db_xref="taxon:3899"
+	// FT                   /mol_type="mRNA"
+	// FT                   /organism="Trifolium repens"
+	// FT                   /tissue_type="leaves"
+	// XX
+	// SQ   Sequence 20 BP; 11 A; 3 C; 2 G; 4 T; 0 other;
+	//      aaacaaacca aatatggatt                                                    20
+	// //
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/koeng101/dnadesign/lib/bio/fasta"
 	"github.com/koeng101/dnadesign/lib/bio/fastq"
 	"github.com/koeng101/dnadesign/lib/bio/sam"
+	"github.com/koeng101/dnadesign/lib/bio/slow5"
 )
 
 // Example_read shows an example of reading a file from disk.
@@ -38,6 +39,17 @@ func Example_readGz() {
 	// Output: ADQLTEEQIAEFKEAFSLFDKDGDGTITTKELGTVMRSLGQNPTEAELQDMINEVDADGNGTIDFPEFLTMMARKMKDTDSEEEIREAFRVFDKDGNGYISAAELRHVMTNLGEKLTDEEVDEMIREADIDGDGQVNYEEFVQMMTAK*
 }
 
+// Example_readCompressed shows that parsers transparently decompress gzip
+// and BGZF compressed input.
+func Example_readCompressed() {
+	file, _ := os.Open("fasta/data/base.fasta.gz")
+	parser := bio.NewFastaParser(file)
+	records, _ := parser.Parse()
+
+	fmt.Println(records[1].Sequence)
+	// Output: ADQLTEEQIAEFKEAFSLFDKDGDGTITTKELGTVMRSLGQNPTEAELQDMINEVDADGNGTIDFPEFLTMMARKMKDTDSEEEIREAFRVFDKDGNGYISAAELRHVMTNLGEKLTDEEVDEMIREADIDGDGQVNYEEFVQMMTAK*
+}
+
 func Example_newParserGz() {
 	// First, lets make a file that is gzip'd, represented by this
 	// buffer.
@@ -276,6 +288,21 @@ func ExampleNewSlow5Parser() {
 	// Output: [430 472 463]
 }
 
+func ExampleNewBlow5Parser() {
+	// Convert a slow5 file to blow5. Usually, blow5 files are read directly
+	// with `file, err := os.Open(path)`.
+	file, _ := os.Open("slow5/data/example.slow5")
+	defer file.Close()
+	var blow5 bytes.Buffer
+	_ = slow5.Slow5ToBlow5(file, &blow5, bio.DefaultMaxLengths[bio.Slow5], slow5.CompressionZlib, slow5.CompressionSvbZd)
+
+	parser, _ := bio.NewBlow5Parser(&blow5)
+	reads, _ := parser.Parse()
+
+	fmt.Println(reads[0].RawSignal[0:3])
+	// Output: [430 472 463]
+}
+
 func ExampleNewPileupParser() {
 	file := strings.NewReader(`seq1 	272 	T 	24 	,.$.....,,.,.,...,,,.,..^+. 	<<<+;<<<<<<<<<<<=<;<;7<&
 seq1 	273 	T 	23 	,.....,,.,.,...,,,.,..A 	<<<;<<<<<<<<<3<=<<<;<<+
@@ -442,6 +469,88 @@ ae9a66f5-bf71-4572-8106-f6f8dbd3b799	16	pOpen_V3_amplified	1	60	8S54M1D3M1D108M1
 	// Output: 8S54M1D3M1D108M1D1M1D62M226S
 }
 
+func ExampleNewBamParser() {
+	// The following can be replaced with a any io.Reader. For example,
+	// `file, err := os.Open(path)` for file would also work.
+	file, _ := os.Open("bam/data/aln.bam")
+	defer file.Close()
+	parser, _ := bio.NewBamParser(file)
+
+	channel := make(chan sam.Alignment)
+	ctx := context.Background()
+	go func() { _ = parser.ParseToChannel(ctx, channel, false) }()
+
+	var primary int
+	for alignment := range channel {
+		if sam.Primary(alignment) {
+			primary++
+		}
+	}
+	fmt.Println(primary)
+	// Output: 18
+}
+
+func ExampleNewVcfParser() {
+	// The following can be replaced with a any io.Reader. For example,
+	// `file, err := os.Open(path)` for file would also work.
+	file, _ := os.Open("vcf/data/example.vcf")
+	defer file.Close()
+	parser, _ := bio.NewVcfParser(file)
+
+	records, _ := parser.Parse()
+	header, _ := parser.Header()
+	fmt.Println(len(records), header.Samples)
+	// Output: 5 [NA00001 NA00002 NA00003]
+}
+
+func ExampleNewGffParser() {
+	// The following can be replaced with a any io.Reader. For example,
+	// `file, err := os.Open(path)` for file would also work.
+	file, _ := os.Open("../data/ecoli-mg1655-short.gff")
+	defer file.Close()
+	parser := bio.NewGffParser(file)
+
+	annotations, _ := parser.Next()
+	fmt.Println(len(annotations.Features))
+	// Output: 10
+}
+
+func ExampleNewBedParser() {
+	// The following can be replaced with a any io.Reader. For example,
+	// `file, err := os.Open(path)` for file would also work.
+	file, _ := os.Open("bed/data/amplicons.bed")
+	defer file.Close()
+	parser, _ := bio.NewBedParser(file)
+
+	records, _ := parser.Parse()
+	fmt.Println(records[0].Name, records[0].ChromStart, records[0].ChromEnd)
+	// Output: amp1 0 10
+}
+
+func ExampleNewEmblParser() {
+	// The following can be replaced with a any io.Reader. For example,
+	// `file, err := os.Open(path)` for file would also work.
+	file, _ := os.Open("embl/data/X56734.embl")
+	defer file.Close()
+	parser := bio.NewEmblParser(file)
+
+	records, _ := parser.Parse()
+	fmt.Println(records[0].Meta.Locus.Name, len(records[0].Features))
+	// Output: X56734 4
+}
+
+func ExampleNewSnapgeneParser() {
+	// The following can be replaced with a any io.Reader. For example,
+	// `file, err := os.Open(path)` for file would also work.
+	file, _ := os.Open("snapgene/data/puc19_lacz.dna")
+	defer file.Close()
+	parser := bio.NewSnapgeneParser(file)
+
+	sequences, _ := parser.Parse()
+	fmt.Println(sequences[0].Meta.Locus.Name, len(sequences[0].Features))
+	// Output: pUC19_lacZ 4
+}
+
 func ExampleFilterData() {
 	// Create channels for input and output
 	inputChan := make(chan sam.Alignment, 2) // Buffered channel to prevent blocking
@@ -60,3 +60,17 @@ func ExampleRecord_WriteTo() {
 	fmt.Println(newRecord.Identifier)
 	// Output: testing
 }
+
+// This example shows how to index a fasta file and pull a region out of it
+// without parsing the whole file.
+func ExampleIndexedReader_FetchRegion() {
+	file, _ := os.Open("data/base.fasta")
+	defer file.Close()
+	index, _ := fasta.BuildIndex(file)
+
+	// Any io.ReaderAt works, including an *os.File.
+	reader := fasta.NewIndexedReader(file, index)
+	region, _ := reader.FetchRegion("MCHU", 0, 10)
+	fmt.Println(region)
+	// Output: ADQLTEEQIA
+}
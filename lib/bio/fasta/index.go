@@ -0,0 +1,235 @@
+package fasta
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+Fasta index begins here
+
+Large reference genomes are often many gigabytes, and we usually only need a
+single gene or region out of them. Streaming the whole file with the Parser
+to get at one region is slow, so instead we use a samtools-compatible .fai
+index, which records where each sequence starts in the file and how its
+lines are wrapped. With that information, the byte offset of any base can be
+computed directly, and only the bytes of the region need to be read.
+
+The .fai format is a tab-delimited file with one line per sequence:
+
+	NAME	LENGTH	OFFSET	LINEBASES	LINEWIDTH
+
+Indexing requires that every line of a sequence, except the last, has the
+same length. This is true of almost every fasta file written by a program.
+
+Spec: http://www.htslib.org/doc/faidx.html
+
+******************************************************************************/
+
+// IndexRecord is a single line of a .fai index, describing where a single
+// sequence is found in a fasta file.
+type IndexRecord struct {
+	Name      string // Name of the sequence, which is the identifier up to the first whitespace.
+	Length    int64  // Total length of the sequence, in bases.
+	Offset    int64  // Byte offset of the first base of the sequence in the file.
+	LineBases int64  // Number of bases on each line.
+	LineWidth int64  // Number of bytes in each line, including the line terminator.
+}
+
+// Index is a samtools-compatible fasta index (.fai).
+type Index struct {
+	Records []IndexRecord
+}
+
+// BuildIndex reads a fasta file and builds its index.
+func BuildIndex(r io.Reader) (Index, error) {
+	var index Index
+	reader := bufio.NewReader(r)
+	names := make(map[string]bool)
+	var record *IndexRecord
+	var offset int64
+	var lineNumber int
+	var shortLine, blankLine bool // Whether the last line of the current sequence has been seen.
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Index{}, err
+		}
+		if len(line) == 0 {
+			break
+		}
+		lineNumber++
+		lineWidth := int64(len(line))
+		offset += lineWidth
+		bases := int64(len(bytes.TrimRight(line, "\r\n")))
+
+		switch {
+		case line[0] == '>':
+			fields := strings.Fields(string(line[1:]))
+			if len(fields) == 0 {
+				return Index{}, fmt.Errorf("line %d: sequence has no name", lineNumber)
+			}
+			if names[fields[0]] {
+				return Index{}, fmt.Errorf("line %d: duplicate sequence name %q", lineNumber, fields[0])
+			}
+			names[fields[0]] = true
+			index.Records = append(index.Records, IndexRecord{Name: fields[0], Offset: offset})
+			record = &index.Records[len(index.Records)-1]
+			shortLine, blankLine = false, false
+		case record == nil:
+			return Index{}, fmt.Errorf("line %d: missing sequence identifier", lineNumber)
+		case bases == 0:
+			blankLine = true
+		default:
+			if blankLine {
+				return Index{}, fmt.Errorf("line %d: blank line inside sequence %q", lineNumber, record.Name)
+			}
+			if record.LineBases == 0 {
+				record.LineBases = bases
+				record.LineWidth = lineWidth
+			} else if shortLine || bases > record.LineBases || (bases == record.LineBases && lineWidth != record.LineWidth && err == nil) {
+				return Index{}, fmt.Errorf("line %d: different line length in sequence %q", lineNumber, record.Name)
+			}
+			shortLine = bases < record.LineBases
+			record.Length += bases
+		}
+		if err != nil {
+			break
+		}
+	}
+	return index, nil
+}
+
+// ReadIndex reads a .fai index.
+func ReadIndex(r io.Reader) (Index, error) {
+	var index Index
+	scanner := bufio.NewScanner(r)
+	var lineNumber int
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if len(line) == 0 {
+			continue
+		}
+		values := strings.Split(line, "\t")
+		if len(values) < 5 {
+			return Index{}, fmt.Errorf("line %d: expected 5 tab-delimited values, got %d", lineNumber, len(values))
+		}
+		record := IndexRecord{Name: values[0]}
+		for i, field := range []*int64{&record.Length, &record.Offset, &record.LineBases, &record.LineWidth} {
+			value, err := strconv.ParseInt(values[i+1], 10, 64)
+			if err != nil {
+				return Index{}, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			*field = value
+		}
+		if err := record.validate(); err != nil {
+			return Index{}, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		index.Records = append(index.Records, record)
+	}
+	return index, scanner.Err()
+}
+
+// validate checks that the bases of a record can be found with its line
+// lengths.
+func (record IndexRecord) validate() error {
+	if record.Length < 0 || record.Offset < 0 || record.LineBases < 0 || record.LineWidth < 0 {
+		return fmt.Errorf("negative value in the index of %q", record.Name)
+	}
+	if record.Length > 0 && (record.LineBases == 0 || record.LineWidth < record.LineBases) {
+		return fmt.Errorf("sequence %q has %d bases per line in lines of %d bytes", record.Name, record.LineBases, record.LineWidth)
+	}
+	return nil
+}
+
+// WriteTo writes the index in .fai format.
+func (index *Index) WriteTo(w io.Writer) (int64, error) {
+	var writtenBytes int64
+	for _, record := range index.Records {
+		newWrittenBytes, err := fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", record.Name, record.Length, record.Offset, record.LineBases, record.LineWidth)
+		writtenBytes += int64(newWrittenBytes)
+		if err != nil {
+			return writtenBytes, err
+		}
+	}
+	return writtenBytes, nil
+}
+
+// IndexedReader provides random access to the sequences of an indexed fasta
+// file. It should be initialized with NewIndexedReader.
+type IndexedReader struct {
+	reader  io.ReaderAt
+	records map[string]IndexRecord
+}
+
+// NewIndexedReader returns an IndexedReader that reads sequences from r
+// using index.
+func NewIndexedReader(r io.ReaderAt, index Index) *IndexedReader {
+	records := make(map[string]IndexRecord, len(index.Records))
+	for _, record := range index.Records {
+		records[record.Name] = record
+	}
+	return &IndexedReader{reader: r, records: records}
+}
+
+// Fetch returns the entire sequence with the given name.
+func (ir *IndexedReader) Fetch(name string) (Record, error) {
+	record, ok := ir.records[name]
+	if !ok {
+		return Record{}, fmt.Errorf("sequence %q not found in index", name)
+	}
+	sequence, err := ir.FetchRegion(name, 0, int(record.Length))
+	return Record{Identifier: name, Sequence: sequence}, err
+}
+
+// FetchRegion returns the bases of the sequence with the given name from
+// start to end. Like Go slices, start and end are 0-based and end is
+// exclusive, so FetchRegion(name, 0, 10) returns the first 10 bases.
+func (ir *IndexedReader) FetchRegion(name string, start, end int) (string, error) {
+	record, ok := ir.records[name]
+	if !ok {
+		return "", fmt.Errorf("sequence %q not found in index", name)
+	}
+	if start < 0 || end > int(record.Length) || start > end {
+		return "", fmt.Errorf("region [%d, %d) out of range for sequence %q of length %d", start, end, name, record.Length)
+	}
+	if start == end {
+		return "", nil
+	}
+	startByte := basePosition(record.Offset, record.LineBases, record.LineWidth, int64(start))
+	endByte := basePosition(record.Offset, record.LineBases, record.LineWidth, int64(end-1)) + 1
+	data := make([]byte, endByte-startByte)
+	n, err := ir.reader.ReadAt(data, startByte)
+	if n < len(data) {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", fmt.Errorf("failed to read region of %q: %w", name, err)
+	}
+	return stripLineTerminators(data), nil
+}
+
+// basePosition returns the byte offset in the file of the base at position
+// in a sequence.
+func basePosition(offset, lineBases, lineWidth, position int64) int64 {
+	return offset + (position/lineBases)*lineWidth + position%lineBases
+}
+
+// stripLineTerminators removes all newlines and carriage returns from data.
+func stripLineTerminators(data []byte) string {
+	var sequence strings.Builder
+	sequence.Grow(len(data))
+	for _, character := range data {
+		if character != '\n' && character != '\r' {
+			sequence.WriteByte(character)
+		}
+	}
+	return sequence.String()
+}
@@ -0,0 +1,158 @@
+package fasta
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBuildIndex(t *testing.T) {
+	file, err := os.Open("data/base.fasta")
+	if err != nil {
+		t.Fatalf("Failed to open base.fasta: %s", err)
+	}
+	defer file.Close()
+	index, err := BuildIndex(file)
+	if err != nil {
+		t.Fatalf("Failed to build index: %s", err)
+	}
+	expected := []IndexRecord{
+		{Name: "gi|5524211|gb|AAD44166.1|", Length: 284, Offset: 66, LineBases: 70, LineWidth: 71},
+		{Name: "MCHU", Length: 149, Offset: 417, LineBases: 64, LineWidth: 65},
+	}
+	if len(index.Records) != len(expected) {
+		t.Fatalf("Expected %d records, got %d", len(expected), len(index.Records))
+	}
+	for i := range expected {
+		if index.Records[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], index.Records[i])
+		}
+	}
+}
+
+// TestIndexSamtoolsCompatible checks that we build the same index as
+// `samtools faidx`, and that we can read it back and write it unchanged.
+func TestIndexSamtoolsCompatible(t *testing.T) {
+	samtoolsIndex, err := os.ReadFile("data/template.fasta.fai")
+	if err != nil {
+		t.Fatalf("Failed to read template.fasta.fai: %s", err)
+	}
+	fastaFile, err := os.Open("data/template.fasta")
+	if err != nil {
+		t.Fatalf("Failed to open template.fasta: %s", err)
+	}
+	defer fastaFile.Close()
+	index, err := BuildIndex(fastaFile)
+	if err != nil {
+		t.Fatalf("Failed to build index: %s", err)
+	}
+	var buffer bytes.Buffer
+	_, _ = index.WriteTo(&buffer)
+	if buffer.String() != string(samtoolsIndex) {
+		t.Errorf("Built index differs from samtools. Expected:\n%s\nGot:\n%s", samtoolsIndex, buffer.String())
+	}
+
+	readIndex, err := ReadIndex(bytes.NewReader(samtoolsIndex))
+	if err != nil {
+		t.Fatalf("Failed to read index: %s", err)
+	}
+	buffer.Reset()
+	_, _ = readIndex.WriteTo(&buffer)
+	if buffer.String() != string(samtoolsIndex) {
+		t.Errorf("Round tripped index differs. Expected:\n%s\nGot:\n%s", samtoolsIndex, buffer.String())
+	}
+}
+
+func TestFetchRegion(t *testing.T) {
+	data, err := os.ReadFile("data/base.fasta")
+	if err != nil {
+		t.Fatalf("Failed to read base.fasta: %s", err)
+	}
+	record, err := NewParser(bytes.NewReader(data), 1024).Next()
+	if err != nil {
+		t.Fatalf("Failed to parse base.fasta: %s", err)
+	}
+	index, _ := BuildIndex(bytes.NewReader(data))
+	reader := NewIndexedReader(bytes.NewReader(data), index)
+
+	full, err := reader.Fetch("gi|5524211|gb|AAD44166.1|")
+	if err != nil {
+		t.Fatalf("Failed to fetch: %s", err)
+	}
+	if full.Sequence != record.Sequence {
+		t.Errorf("Fetched sequence does not match parsed sequence")
+	}
+	// Check every region that crosses a line boundary, or touches an end.
+	for _, region := range [][2]int{{0, 1}, {0, 70}, {69, 71}, {70, 140}, {65, 215}, {280, 284}, {0, 284}, {10, 10}} {
+		got, err := reader.FetchRegion("gi|5524211|gb|AAD44166.1|", region[0], region[1])
+		if err != nil {
+			t.Errorf("Region %v: got error: %s", region, err)
+		}
+		if expected := record.Sequence[region[0]:region[1]]; got != expected {
+			t.Errorf("Region %v: expected %s, got %s", region, expected, got)
+		}
+	}
+	mchu, _ := reader.FetchRegion("MCHU", 140, 149)
+	if mchu != "FVQMMTAK*" {
+		t.Errorf("Expected end of MCHU, got %s", mchu)
+	}
+
+	for _, region := range [][2]int{{-1, 10}, {10, 5}, {0, 285}} {
+		if _, err := reader.FetchRegion("MCHU", region[0], region[1]); err == nil {
+			t.Errorf("Region %v: expected out of range error", region)
+		}
+	}
+	if _, err := reader.Fetch("missing"); err == nil {
+		t.Errorf("Expected error fetching missing sequence")
+	}
+}
+
+func TestBuildIndexErrors(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+	}{
+		{"no identifier", "ATGC\n>seq\nATGC\n"},
+		{"duplicate name", ">seq\nATGC\n>seq desc\nATGC\n"},
+		{"long line", ">seq\nATGC\nATGCA\n"},
+		{"short line in middle", ">seq\nATGC\nAT\nATGC\n"},
+		{"blank line in middle", ">seq\nATGC\n\nATGC\n"},
+		{"no name", ">\nATGC\n"},
+	} {
+		if _, err := BuildIndex(strings.NewReader(test.content)); err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+	// CRLF files and files without a final newline are fine.
+	index, err := BuildIndex(strings.NewReader(">seq\r\nATGC\r\nAT"))
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+	if index.Records[0] != (IndexRecord{Name: "seq", Length: 6, Offset: 6, LineBases: 4, LineWidth: 6}) {
+		t.Errorf("Got unexpected record: %+v", index.Records[0])
+	}
+}
+
+func TestReadIndexErrors(t *testing.T) {
+	for _, line := range []string{
+		"seq\t6\t6\t0\t6",
+		"seq\t6\t6\t4\t0",
+		"seq\t6\t6\t4\t3",
+		"seq\t-6\t6\t4\t5",
+		"seq\t6",
+	} {
+		if _, err := ReadIndex(strings.NewReader(line + "\n")); err == nil {
+			t.Errorf("Expected error reading index line %q", line)
+		}
+	}
+	// empty sequences have no lines.
+	index, err := ReadIndex(strings.NewReader("empty\t0\t7\t0\t0\n"))
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+	sequence, err := NewIndexedReader(strings.NewReader(">empty\n"), index).Fetch("empty")
+	if err != nil || sequence.Sequence != "" {
+		t.Errorf("Expected empty sequence, got %q (%v)", sequence.Sequence, err)
+	}
+}
@@ -0,0 +1,229 @@
+package fastq
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+Fastq index begins here
+
+This is the fastq version of the fasta .fai index, compatible with
+`samtools fqidx`. It has one extra column, QUALOFFSET, which is the byte
+offset of the first quality score of the read:
+
+	NAME	LENGTH	OFFSET	LINEBASES	LINEWIDTH	QUALOFFSET
+
+Like the Parser, indexing only supports fastq files where each read has its
+sequence and quality on a single line, which is what sequencers output.
+
+Spec: http://www.htslib.org/doc/samtools-fqidx.html
+
+******************************************************************************/
+
+// IndexRecord is a single line of a fastq index, describing where a single
+// read is found in a fastq file.
+type IndexRecord struct {
+	Name          string // Identifier of the read.
+	Length        int64  // Length of the read, in bases.
+	Offset        int64  // Byte offset of the first base of the read.
+	LineBases     int64  // Number of bases on each line.
+	LineWidth     int64  // Number of bytes in each line, including the line terminator.
+	QualityOffset int64  // Byte offset of the first quality score of the read.
+}
+
+// Index is a samtools-compatible fastq index.
+type Index struct {
+	Records []IndexRecord
+}
+
+// BuildIndex reads a fastq file and builds its index.
+func BuildIndex(r io.Reader) (Index, error) {
+	var index Index
+	reader := bufio.NewReader(r)
+	names := make(map[string]bool)
+	var offset int64
+	var lineNumber int
+	readLine := func() ([]byte, error) {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+			return nil, err
+		}
+		lineNumber++
+		offset += int64(len(line))
+		return line, nil
+	}
+	for {
+		identifier, err := readLine()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Index{}, err
+		}
+		if len(bytes.TrimSpace(identifier)) == 0 {
+			continue
+		}
+		if identifier[0] != '@' {
+			return Index{}, fmt.Errorf("line %d: did not find fastq start '@'", lineNumber)
+		}
+		fields := strings.Fields(string(identifier[1:]))
+		if len(fields) == 0 {
+			return Index{}, fmt.Errorf("line %d: read has no name", lineNumber)
+		}
+		record := IndexRecord{Name: fields[0], Offset: offset}
+		if names[record.Name] {
+			return Index{}, fmt.Errorf("line %d: duplicate read name %q", lineNumber, record.Name)
+		}
+		names[record.Name] = true
+
+		sequence, err := readLine()
+		if err != nil {
+			return Index{}, fmt.Errorf("line %d: missing sequence for %q: %w", lineNumber, record.Name, err)
+		}
+		record.Length = int64(len(bytes.TrimRight(sequence, "\r\n")))
+		record.LineBases = record.Length
+		record.LineWidth = int64(len(sequence))
+
+		plus, err := readLine()
+		if err != nil || plus[0] != '+' {
+			return Index{}, fmt.Errorf("line %d: missing '+' line for %q", lineNumber, record.Name)
+		}
+
+		record.QualityOffset = offset
+		quality, err := readLine()
+		if err != nil {
+			return Index{}, fmt.Errorf("line %d: missing quality for %q: %w", lineNumber, record.Name, err)
+		}
+		if int64(len(bytes.TrimRight(quality, "\r\n"))) != record.Length {
+			return Index{}, fmt.Errorf("line %d: got different lengths for sequence and quality of %q", lineNumber, record.Name)
+		}
+		index.Records = append(index.Records, record)
+	}
+	return index, nil
+}
+
+// ReadIndex reads a fastq index.
+func ReadIndex(r io.Reader) (Index, error) {
+	var index Index
+	scanner := bufio.NewScanner(r)
+	var lineNumber int
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if len(line) == 0 {
+			continue
+		}
+		values := strings.Split(line, "\t")
+		if len(values) < 6 {
+			return Index{}, fmt.Errorf("line %d: expected 6 tab-delimited values, got %d", lineNumber, len(values))
+		}
+		record := IndexRecord{Name: values[0]}
+		for i, field := range []*int64{&record.Length, &record.Offset, &record.LineBases, &record.LineWidth, &record.QualityOffset} {
+			value, err := strconv.ParseInt(values[i+1], 10, 64)
+			if err != nil {
+				return Index{}, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			*field = value
+		}
+		if err := record.validate(); err != nil {
+			return Index{}, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		index.Records = append(index.Records, record)
+	}
+	return index, scanner.Err()
+}
+
+// validate checks that the bases of a record can be found with its line
+// lengths.
+func (record IndexRecord) validate() error {
+	if record.Length < 0 || record.Offset < 0 || record.LineBases < 0 || record.LineWidth < 0 || record.QualityOffset < 0 {
+		return fmt.Errorf("negative value in the index of %q", record.Name)
+	}
+	if record.Length > 0 && (record.LineBases == 0 || record.LineWidth < record.LineBases) {
+		return fmt.Errorf("read %q has %d bases per line in lines of %d bytes", record.Name, record.LineBases, record.LineWidth)
+	}
+	return nil
+}
+
+// WriteTo writes the index in samtools fqidx format.
+func (index *Index) WriteTo(w io.Writer) (int64, error) {
+	var writtenBytes int64
+	for _, record := range index.Records {
+		newWrittenBytes, err := fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n", record.Name, record.Length, record.Offset, record.LineBases, record.LineWidth, record.QualityOffset)
+		writtenBytes += int64(newWrittenBytes)
+		if err != nil {
+			return writtenBytes, err
+		}
+	}
+	return writtenBytes, nil
+}
+
+// IndexedReader provides random access to the reads of an indexed fastq
+// file. It should be initialized with NewIndexedReader.
+type IndexedReader struct {
+	reader  io.ReaderAt
+	records map[string]IndexRecord
+}
+
+// NewIndexedReader returns an IndexedReader that reads from r using index.
+func NewIndexedReader(r io.ReaderAt, index Index) *IndexedReader {
+	records := make(map[string]IndexRecord, len(index.Records))
+	for _, record := range index.Records {
+		records[record.Name] = record
+	}
+	return &IndexedReader{reader: r, records: records}
+}
+
+// Fetch returns the read with the given name. The index does not store the
+// optional values of the identifier line, so the returned read has no
+// Optionals.
+func (ir *IndexedReader) Fetch(name string) (Read, error) {
+	record, ok := ir.records[name]
+	if !ok {
+		return Read{}, fmt.Errorf("read %q not found in index", name)
+	}
+	sequence, quality, err := ir.FetchRegion(name, 0, int(record.Length))
+	return Read{Identifier: name, Optionals: make(map[string]string), Sequence: sequence, Quality: quality}, err
+}
+
+// FetchRegion returns the bases and quality scores of the read with the given
+// name from start to end. Like Go slices, start and end are 0-based and end is
+// exclusive.
+func (ir *IndexedReader) FetchRegion(name string, start, end int) (sequence string, quality string, err error) {
+	record, ok := ir.records[name]
+	if !ok {
+		return "", "", fmt.Errorf("read %q not found in index", name)
+	}
+	if start < 0 || end > int(record.Length) || start > end {
+		return "", "", fmt.Errorf("region [%d, %d) out of range for read %q of length %d", start, end, name, record.Length)
+	}
+	sequence, err = ir.readAt(record.Offset+int64(start), end-start)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read sequence of %q: %w", name, err)
+	}
+	quality, err = ir.readAt(record.QualityOffset+int64(start), end-start)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read quality of %q: %w", name, err)
+	}
+	return sequence, quality, nil
+}
+
+// readAt reads length bytes at offset.
+func (ir *IndexedReader) readAt(offset int64, length int) (string, error) {
+	data := make([]byte, length)
+	n, err := ir.reader.ReadAt(data, offset)
+	if n < length {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return string(data), nil
+}
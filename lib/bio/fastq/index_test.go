@@ -0,0 +1,114 @@
+package fastq
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	data, err := os.ReadFile("data/nanosavseq.fastq")
+	if err != nil {
+		t.Fatalf("Failed to read nanosavseq.fastq: %s", err)
+	}
+	index, err := BuildIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to build index: %s", err)
+	}
+
+	// Writing then reading the index gives back the same index.
+	var buffer bytes.Buffer
+	_, _ = index.WriteTo(&buffer)
+	readIndex, err := ReadIndex(&buffer)
+	if err != nil {
+		t.Fatalf("Failed to read index: %s", err)
+	}
+	if len(readIndex.Records) != len(index.Records) {
+		t.Fatalf("Expected %d records, got %d", len(index.Records), len(readIndex.Records))
+	}
+	for i := range index.Records {
+		if readIndex.Records[i] != index.Records[i] {
+			t.Errorf("Expected %+v, got %+v", index.Records[i], readIndex.Records[i])
+		}
+	}
+
+	// Every read in the file can be fetched.
+	reader := NewIndexedReader(bytes.NewReader(data), readIndex)
+	parser := NewParser(bytes.NewReader(data), 2*32*1024)
+	var count int
+	for {
+		read, err := parser.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("Failed to parse: %s", err)
+			}
+			break
+		}
+		count++
+		fetched, err := reader.Fetch(read.Identifier)
+		if err != nil {
+			t.Fatalf("Failed to fetch %s: %s", read.Identifier, err)
+		}
+		if fetched.Sequence != read.Sequence || fetched.Quality != read.Quality {
+			t.Errorf("Fetched read %s does not match parsed read", read.Identifier)
+		}
+		sequence, quality, err := reader.FetchRegion(read.Identifier, 10, 20)
+		if err != nil || sequence != read.Sequence[10:20] || quality != read.Quality[10:20] {
+			t.Errorf("Fetched region of %s does not match parsed read. Got error: %v", read.Identifier, err)
+		}
+	}
+	if count != len(index.Records) {
+		t.Errorf("Parsed %d reads, but indexed %d", count, len(index.Records))
+	}
+	if _, _, err := reader.FetchRegion(index.Records[0].Name, 0, int(index.Records[0].Length)+1); err == nil {
+		t.Errorf("Expected out of range error")
+	}
+	if _, err := reader.Fetch("missing"); err == nil {
+		t.Errorf("Expected error fetching missing read")
+	}
+}
+
+func TestBuildIndexSmall(t *testing.T) {
+	index, err := BuildIndex(strings.NewReader("@read1 ch=1\nATGC\n+\n!!!!\n@read2\nGATTACA\n+\nIIIIIII"))
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+	expected := []IndexRecord{
+		{Name: "read1", Length: 4, Offset: 12, LineBases: 4, LineWidth: 5, QualityOffset: 19},
+		{Name: "read2", Length: 7, Offset: 31, LineBases: 7, LineWidth: 8, QualityOffset: 41},
+	}
+	for i := range expected {
+		if index.Records[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], index.Records[i])
+		}
+	}
+
+	for _, content := range []string{
+		"read1\nATGC\n+\n!!!!\n",
+		"@read1\nATGC\n-\n!!!!\n",
+		"@read1\nATGC\n+\n!!!\n",
+		"@read1\nATGC\n+\n",
+		"@read1\nATGC\n+\n!!!!\n@read1\nATGC\n+\n!!!!\n",
+	} {
+		if _, err := BuildIndex(strings.NewReader(content)); err == nil {
+			t.Errorf("Expected error indexing %q", content)
+		}
+	}
+}
+
+func TestReadIndexErrors(t *testing.T) {
+	for _, line := range []string{
+		"read1\t4\t12\t0\t5\t19",
+		"read1\t4\t12\t4\t0\t19",
+		"read1\t4\t12\t4\t3\t19",
+		"read1\t4\t12\t4\t5\t-19",
+		"read1\t4\t12\t4\t5",
+	} {
+		if _, err := ReadIndex(strings.NewReader(line + "\n")); err == nil {
+			t.Errorf("Expected error reading index line %q", line)
+		}
+	}
+}
@@ -40,6 +40,10 @@ type Genbank struct {
 	Meta     Meta
 	Features []Feature
 	Sequence string // will be changed and include reader, writer, and byte slice.
+
+	// Original is the original text of a record parsed by a round trip
+	// parser. It is nil for records from other parsers.
+	Original *Original `json:"-"`
 }
 
 // Meta holds the meta data for Genbank and other annotated sequence files.
@@ -102,6 +106,8 @@ type Location struct {
 	End               int        `json:"end"`
 	Complement        bool       `json:"complement"`
 	Join              bool       `json:"join"`
+	Order             bool       `json:"order"` // order(...), sublocations in no particular order, which aren't joined
+	Bond              bool       `json:"bond"`  // bond(...), residues bonded together in protein records
 	FivePrimePartial  bool       `json:"five_prime_partial"`
 	ThreePrimePartial bool       `json:"three_prime_partial"`
 	GbkLocationString string     `json:"gbk_location_string"`
@@ -184,7 +190,11 @@ func CopyLocation(location Location) Location {
 }
 
 // getFeatureSequence takes a feature and location object and returns a sequence string.
+// order and bond locations are not contiguous sequences, so they return an error.
 func getFeatureSequence(feature Feature, location Location) (string, error) {
+	if location.Order || location.Bond {
+		return "", fmt.Errorf("%s location has no sequence, since its parts are not joined", BuildLocationString(location))
+	}
 	var sequenceBuffer bytes.Buffer
 	parentSequence := feature.ParentSequence.Sequence
 
@@ -216,20 +226,12 @@ func (sequence *Genbank) WriteTo(w io.Writer) (int64, error) {
 	var newWrittenBytes int
 	var err error
 
-	locus := sequence.Meta.Locus
-	var shape string
-
-	if locus.Circular {
-		shape = "circular"
-	} else {
-		shape = "linear"
+	if sequence.Original != nil {
+		return sequence.writeOriginal(w)
 	}
 
-	fivespace := generateWhiteSpace(subMetaIndex)
-
 	// building locus
-	locusData := locus.Name + fivespace + locus.SequenceLength + " bp" + fivespace + locus.MoleculeType + fivespace + shape + fivespace + locus.GenbankDivision + fivespace + locus.ModificationDate
-	locusString := "LOCUS       " + locusData + "\n"
+	locusString := buildLocusString(sequence.Meta.Locus)
 	newWrittenBytes, err = w.Write([]byte(locusString))
 	writtenBytes += int64(newWrittenBytes)
 	if err != nil {
@@ -265,90 +267,21 @@ func (sequence *Genbank) WriteTo(w io.Writer) (int64, error) {
 		return writtenBytes, err
 	}
 
-	sourceString := buildMetaString("SOURCE", sequence.Meta.Source)
+	sourceString := buildSourceString(sequence.Meta)
 	newWrittenBytes, err = w.Write([]byte(sourceString))
 	writtenBytes += int64(newWrittenBytes)
 	if err != nil {
 		return writtenBytes, err
 	}
 
-	organismString := buildMetaString("  ORGANISM", sequence.Meta.Organism)
-	newWrittenBytes, err = w.Write([]byte(organismString))
-	writtenBytes += int64(newWrittenBytes)
-	if err != nil {
-		return writtenBytes, err
-	}
-
-	if len(sequence.Meta.Taxonomy) > 0 {
-		var taxonomyString strings.Builder
-		for i, taxonomyData := range sequence.Meta.Taxonomy {
-			taxonomyString.WriteString(taxonomyData)
-			if len(sequence.Meta.Taxonomy) == i+1 {
-				taxonomyString.WriteString(".")
-			} else {
-				taxonomyString.WriteString("; ")
-			}
-		}
-		newWrittenBytes, err = w.Write([]byte(buildMetaString("", taxonomyString.String())))
-		writtenBytes += int64(newWrittenBytes)
-		if err != nil {
-			return writtenBytes, err
-		}
-	}
-
 	// building references
-	// TODO: could use reflection to get keys and make more general.
 	for referenceIndex, reference := range sequence.Meta.References {
-		referenceString := buildMetaString("REFERENCE", fmt.Sprintf("%d  %s", referenceIndex+1, reference.Range))
+		referenceString := buildReferenceString(referenceIndex, reference)
 		newWrittenBytes, err = w.Write([]byte(referenceString))
 		writtenBytes += int64(newWrittenBytes)
 		if err != nil {
 			return writtenBytes, err
 		}
-
-		if reference.Authors != "" {
-			authorsString := buildMetaString("  AUTHORS", reference.Authors)
-			newWrittenBytes, err = w.Write([]byte(authorsString))
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-		}
-
-		if reference.Title != "" {
-			titleString := buildMetaString("  TITLE", reference.Title)
-			newWrittenBytes, err = w.Write([]byte(titleString))
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-		}
-
-		if reference.Journal != "" {
-			journalString := buildMetaString("  JOURNAL", reference.Journal)
-			newWrittenBytes, err = w.Write([]byte(journalString))
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-		}
-
-		if reference.PubMed != "" {
-			pubMedString := buildMetaString("  PUBMED", reference.PubMed)
-			newWrittenBytes, err = w.Write([]byte(pubMedString))
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-		}
-		if reference.Consortium != "" {
-			consrtmString := buildMetaString("  CONSRTM", reference.Consortium)
-			newWrittenBytes, err = w.Write([]byte(consrtmString))
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-		}
 	}
 
 	// building other meta fields that are catch all
@@ -380,87 +313,8 @@ func (sequence *Genbank) WriteTo(w io.Writer) (int64, error) {
 		}
 	}
 
-	// start writing base count
-	if len(sequence.Meta.BaseCount) > 0 {
-		newWrittenBytes, err = w.Write([]byte("BASE COUNT    "))
-		writtenBytes += int64(newWrittenBytes)
-		if err != nil {
-			return writtenBytes, err
-		}
-		for _, baseCount := range sequence.Meta.BaseCount {
-			newWrittenBytes, err = w.Write([]byte(strconv.Itoa(baseCount.Count) + " " + baseCount.Base + "   "))
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-		}
-		newWrittenBytes, err = w.Write([]byte("\n"))
-		writtenBytes += int64(newWrittenBytes)
-		if err != nil {
-			return writtenBytes, err
-		}
-	}
-
-	// start writing sequence section.
-	newWrittenBytes, err = w.Write([]byte("ORIGIN\n"))
-	writtenBytes += int64(newWrittenBytes)
-	if err != nil {
-		return writtenBytes, err
-	}
-
-	// iterate over every character in sequence range.
-	for index, base := range sequence.Sequence {
-		// if 60th character add newline then whitespace and index number and space before adding next base.
-		if index%60 == 0 {
-			if index != 0 {
-				newWrittenBytes, err = w.Write([]byte("\n"))
-				writtenBytes += int64(newWrittenBytes)
-				if err != nil {
-					return writtenBytes, err
-				}
-			}
-			lineNumberString := strconv.Itoa(index + 1)          // genbank indexes at 1 for some reason
-			leadingWhiteSpaceLength := 9 - len(lineNumberString) // <- I wish I was kidding
-			for i := 0; i < leadingWhiteSpaceLength; i++ {
-				newWrittenBytes, err = w.Write([]byte(" "))
-				writtenBytes += int64(newWrittenBytes)
-				if err != nil {
-					return writtenBytes, err
-				}
-			}
-			newWrittenBytes, err = w.Write([]byte(lineNumberString + " "))
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-			newWrittenBytes, err = w.Write([]byte{byte(base)})
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-			// if base index is divisible by ten add a space (genbank convention)
-		} else if index%10 == 0 {
-			newWrittenBytes, err = w.Write([]byte(" "))
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-			newWrittenBytes, err = w.Write([]byte{byte(base)})
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-			// else just add the base.
-		} else {
-			newWrittenBytes, err = w.Write([]byte{byte(base)})
-			writtenBytes += int64(newWrittenBytes)
-			if err != nil {
-				return writtenBytes, err
-			}
-		}
-	}
-	// finish genbank file with "//" on newline (again a genbank convention)
-	newWrittenBytes, err = w.Write([]byte("\n//\n"))
+	// start writing base count and sequence section.
+	newWrittenBytes, err = w.Write([]byte(buildOriginString(sequence.Meta.BaseCount, sequence.Sequence)))
 	writtenBytes += int64(newWrittenBytes)
 	if err != nil {
 		return writtenBytes, err
@@ -523,6 +377,14 @@ type parseLoopParameters struct {
 	currentLine      string
 	prevline         string
 	multiLineFeature bool
+
+	// state for round trip parsing, only used when original is not nil.
+	original     *Original
+	block        originalBlock // header block being read
+	featureLines []string      // lines of the feature being read
+	featureTexts []string      // text of each feature in features
+	inTrailer    bool
+	trailer      strings.Builder
 }
 
 // method to init loop parameters
@@ -539,11 +401,24 @@ func (params *parseLoopParameters) init() {
 func (params *parseLoopParameters) saveLastAttribute() {
 	newValue := params.attributeValue != ""
 	emptyType := params.feature.Type != ""
+	var featureText string
+	if params.original != nil {
+		// Unless we reached the end of the features, the last line read is
+		// the start of the next feature.
+		lines := params.featureLines
+		if !params.inTrailer && len(lines) > 0 {
+			lines, params.featureLines = lines[:len(lines)-1], lines[len(lines)-1:]
+		} else {
+			params.featureLines = nil
+		}
+		featureText = strings.Join(lines, "")
+	}
 	if newValue || emptyType {
 		if newValue {
 			Put(params.feature.Attributes, params.attribute, params.attributeValue)
 		}
 		params.features = append(params.features, params.feature)
+		params.featureTexts = append(params.featureTexts, featureText)
 
 		// reset attribute state
 		params.attributeValue = ""
@@ -565,6 +440,16 @@ func (header *Header) WriteTo(w io.Writer) (int64, error) {
 type Parser struct {
 	scanner    bufio.Scanner
 	parameters parseLoopParameters
+	line       string
+	roundTrip  bool
+	lineEnding string          // line ending of the last scanned line
+	preamble   strings.Builder // lines before the first LOCUS in round trip mode
+
+	// The LOCUS line of the next record, read while looking for the end of
+	// the current record in round trip mode.
+	pendingLine       string
+	pendingLineEnding string
+	hasPendingLine    bool
 }
 
 // Header returns nil,nil.
@@ -583,18 +468,41 @@ func NewParser(r io.Reader, maxLineSize int) *Parser {
 	}
 }
 
+// NewRoundTripParser returns a Parser like NewParser, which also keeps the
+// original text of each record in Genbank.Original. Writing a record parsed
+// this way reproduces the input byte for byte, and after edits only the
+// edited sections of the record are rewritten, keeping the qualifier order,
+// quoting, and wrapping of everything else.
+func NewRoundTripParser(r io.Reader, maxLineSize int) *Parser {
+	parser := NewParser(r, maxLineSize)
+	parser.roundTrip = true
+	parser.scanner.Split(parser.scanLines)
+	return parser
+}
+
+// scanLines is bufio.ScanLines, also recording the line ending of each line
+// so that it can be written back.
+func (parser *Parser) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if token != nil {
+		parser.lineEnding = string(data[len(token):advance])
+	}
+	return advance, token, err
+}
+
 // Next takes in a reader representing a multi gbk/gb/genbank file and outputs the next record
 func (parser *Parser) Next() (Genbank, error) {
 	parser.parameters.init()
 	// Loop through each line of the file
-	for lineNum := 0; parser.scanner.Scan(); lineNum++ {
+	for lineNum := 0; parser.scan(); lineNum++ {
 		// get line from scanner and split it
-		line := parser.scanner.Text()
+		line := parser.line
 		splitLine := strings.Split(strings.TrimSpace(line), " ")
 
 		prevline := parser.parameters.currentLine
 		parser.parameters.currentLine = line
 		parser.parameters.prevline = prevline
+		rawLine := line + parser.lineEnding
 
 		// keep scanning until we find the start of the first record
 		if !parser.parameters.genbankStarted {
@@ -606,6 +514,13 @@ func (parser *Parser) Next() (Genbank, error) {
 				parser.parameters.init()
 				parser.parameters.genbank.Meta.Locus = parseLocus(line)
 				parser.parameters.genbankStarted = true
+				if parser.roundTrip {
+					parser.parameters.original = &Original{preamble: parser.preamble.String(), newline: parser.lineEnding}
+					parser.parameters.block = originalBlock{key: "LOCUS", text: rawLine}
+					parser.preamble.Reset()
+				}
+			} else if parser.roundTrip {
+				parser.preamble.WriteString(rawLine)
 			}
 			continue
 		}
@@ -622,6 +537,14 @@ func (parser *Parser) Next() (Genbank, error) {
 			if string(line[0]) != " " || parser.parameters.metadataTag == "FEATURES" {
 				// If this is true, it means we are beginning a new meta tag. In that case, let's save
 				// the older data, and then continue along.
+				if parser.parameters.original != nil {
+					parser.parameters.original.addBlock(parser.parameters.block)
+					if parser.parameters.metadataTag == "FEATURES" {
+						parser.parameters.featureLines = []string{rawLine}
+					} else {
+						parser.parameters.block = originalBlock{key: strings.TrimSpace(splitLine[0]), text: rawLine}
+					}
+				}
 				switch parser.parameters.metadataTag {
 				case "DEFINITION":
 					parser.parameters.genbank.Meta.Definition = parseMetadata(parser.parameters.metadataData)
@@ -661,9 +584,20 @@ func (parser *Parser) Next() (Genbank, error) {
 				parser.parameters.metadataData = []string{strings.TrimSpace(line[len(parser.parameters.metadataTag):])}
 			} else {
 				parser.parameters.metadataData = append(parser.parameters.metadataData, line)
+				parser.parameters.block.text += rawLine
 			}
 		case "features":
 			baseCountFlag := strings.Contains(line, "BASE COUNT") // example string for BASE COUNT: "BASE COUNT    67070277 a   48055043 c   48111528 g   67244164 t   18475410 n"
+			if parser.parameters.original != nil {
+				if baseCountFlag || strings.Contains(line, "ORIGIN") || strings.Contains(line, "CONTIG") {
+					parser.parameters.inTrailer = true
+				}
+				if parser.parameters.inTrailer {
+					parser.parameters.trailer.WriteString(rawLine)
+				} else {
+					parser.parameters.featureLines = append(parser.parameters.featureLines, rawLine)
+				}
+			}
 			if baseCountFlag {
 				fields := strings.Fields(line)
 				for countIndex := 2; countIndex < len(fields)-1; countIndex += 2 { // starts at two because we don't want to include "BASE COUNT" in our fields
@@ -689,13 +623,16 @@ func (parser *Parser) Next() (Genbank, error) {
 				parser.parameters.saveLastAttribute()
 
 				// add our features to the genbank
-				for _, feature := range parser.parameters.features {
+				for featureIndex, feature := range parser.parameters.features {
 					// TODO: parse location when line is read, or track line number so error is localized
 					location, err := parseLocation(feature.Location.GbkLocationString)
 					if err != nil {
 						return Genbank{}, &ParseError{before: true, line: line, lineNo: lineNum, wraps: err, info: "invalid feature location"}
 					}
 					feature.Location = location
+					if parser.parameters.original != nil {
+						parser.parameters.original.features = append(parser.parameters.original.features, originalFeature{text: parser.parameters.featureTexts[featureIndex], feature: feature.Copy()})
+					}
 					err = parser.parameters.genbank.AddFeature(&feature)
 					if err != nil {
 						return Genbank{}, &ParseError{before: true, line: line, lineNo: lineNum, wraps: err, info: "problem adding feature"}
@@ -773,24 +710,66 @@ func (parser *Parser) Next() (Genbank, error) {
 			}
 
 		case "sequence":
+			if parser.parameters.original != nil {
+				parser.parameters.trailer.WriteString(rawLine)
+			}
 			if len(line) < 2 { // throw error if line is malformed
 				return Genbank{}, &ParseError{line: line, lineNo: lineNum, info: "too short line found while parsing genbank sequence"}
 			} else if line[0:2] == "//" { // end of sequence
-				parser.parameters.genbank.Sequence = parser.parameters.sequenceBuilder.String()
-
-				parser.parameters.genbankStarted = false
-				parser.parameters.sequenceBuilder.Reset()
-				return parser.parameters.genbank, nil
+				if parser.parameters.original != nil {
+					// Keep the lines up to the next record, so that they are
+					// written back with this one.
+					parser.parameters.parseStep = "end"
+					continue
+				}
+				return parser.finishRecord(), nil
 			} else { // add line to total sequence
 				parser.parameters.sequenceBuilder.WriteString(sequenceRegex.ReplaceAllString(line, ""))
 			}
+		case "end":
+			if strings.Contains(line, "LOCUS") {
+				parser.pendingLine, parser.pendingLineEnding, parser.hasPendingLine = line, parser.lineEnding, true
+				return parser.finishRecord(), nil
+			}
+			parser.parameters.trailer.WriteString(rawLine)
 		default:
 			return Genbank{}, fmt.Errorf("Unknown parse step: %s", parser.parameters.parseStep)
 		}
 	}
+	if parser.parameters.genbankStarted && parser.parameters.parseStep == "end" {
+		return parser.finishRecord(), nil
+	}
 	return Genbank{}, io.EOF
 }
 
+// scan reads the next line into parser.line, returning false at the end of
+// the input.
+func (parser *Parser) scan() bool {
+	if parser.hasPendingLine {
+		parser.line, parser.lineEnding, parser.hasPendingLine = parser.pendingLine, parser.pendingLineEnding, false
+		return true
+	}
+	if !parser.scanner.Scan() {
+		return false
+	}
+	parser.line = parser.scanner.Text()
+	return true
+}
+
+// finishRecord returns the record being parsed once its sequence is read.
+func (parser *Parser) finishRecord() Genbank {
+	parser.parameters.genbank.Sequence = parser.parameters.sequenceBuilder.String()
+	if parser.parameters.original != nil {
+		parser.parameters.original.trailer = parser.parameters.trailer.String()
+		parser.parameters.original.finish(parser.parameters.genbank)
+		parser.parameters.genbank.Original = parser.parameters.original
+	}
+
+	parser.parameters.genbankStarted = false
+	parser.parameters.sequenceBuilder.Reset()
+	return parser.parameters.genbank
+}
+
 func countLeadingSpaces(line string) int {
 	return len(line) - len(strings.TrimLeft(line, " "))
 }
@@ -1017,8 +996,12 @@ func parseLocation(locationString string) (Location, error) {
 		firstOuterParentheses := strings.Index(locationString, "(")
 		expression := locationString[firstOuterParentheses+1 : strings.LastIndex(locationString, ")")]
 		switch command := locationString[0:firstOuterParentheses]; command {
-		case "join":
-			location.Join = true
+		// order and bond, like bond(65,67) in protein records, have
+		// sublocations like joins, but aren't joined together.
+		case "join", "order", "bond":
+			location.Join = command == "join"
+			location.Order = command == "order"
+			location.Bond = command == "bond"
 			// This case checks for join(complement(x..x),complement(x..x)), or any more complicated derivatives
 			if strings.ContainsAny(expression, "(") {
 				firstInnerParentheses := strings.Index(expression, "(")
@@ -1082,13 +1065,105 @@ func parseLocation(locationString string) (Location, error) {
 	}
 
 	// if excess root node then trim node. Maybe should just be handled with second arg?
-	if location.Start == 0 && location.End == 0 && !location.Join && !location.Complement {
+	if location.Start == 0 && location.End == 0 && !location.Join && !location.Order && !location.Bond && !location.Complement {
 		location = location.SubLocations[0]
 	}
 
 	return location, nil
 }
 
+// buildOriginString builds the end of genbank files: the base count, the
+// sequence in lines of 60 bases, and the // terminator.
+func buildOriginString(baseCounts []BaseCount, sequence string) string {
+	var originString strings.Builder
+	if len(baseCounts) > 0 {
+		originString.WriteString("BASE COUNT    ")
+		for _, baseCount := range baseCounts {
+			originString.WriteString(strconv.Itoa(baseCount.Count) + " " + baseCount.Base + "   ")
+		}
+		originString.WriteString("\n")
+	}
+	originString.WriteString("ORIGIN\n")
+
+	// iterate over every character in sequence range.
+	for index := 0; index < len(sequence); index++ {
+		// if 60th character add newline then whitespace and index number and space before adding next base.
+		if index%60 == 0 {
+			if index != 0 {
+				originString.WriteString("\n")
+			}
+			lineNumberString := strconv.Itoa(index + 1)          // genbank indexes at 1 for some reason
+			leadingWhiteSpaceLength := 9 - len(lineNumberString) // <- I wish I was kidding
+			originString.WriteString(generateWhiteSpace(leadingWhiteSpaceLength) + lineNumberString + " ")
+			// if base index is divisible by ten add a space (genbank convention)
+		} else if index%10 == 0 {
+			originString.WriteString(" ")
+		}
+		originString.WriteByte(sequence[index])
+	}
+	// finish genbank file with "//" on newline (again a genbank convention)
+	originString.WriteString("\n//\n")
+	return originString.String()
+}
+
+// buildLocusString builds the LOCUS line of genbank files.
+func buildLocusString(locus Locus) string {
+	var shape string
+
+	if locus.Circular {
+		shape = "circular"
+	} else {
+		shape = "linear"
+	}
+
+	// protein records, like GenPept records, are in amino acids.
+	sequenceCoding := locus.SequenceCoding
+	if sequenceCoding == "" {
+		sequenceCoding = "bp"
+	}
+
+	fivespace := generateWhiteSpace(subMetaIndex)
+	locusData := locus.Name + fivespace + locus.SequenceLength + " " + sequenceCoding + fivespace + locus.MoleculeType + fivespace + shape + fivespace + locus.GenbankDivision + fivespace + locus.ModificationDate
+	return "LOCUS       " + locusData + "\n"
+}
+
+// buildSourceString builds the SOURCE section of genbank files, including
+// the organism and its taxonomy.
+func buildSourceString(meta Meta) string {
+	sourceString := buildMetaString("SOURCE", meta.Source) + buildMetaString("  ORGANISM", meta.Organism)
+	if len(meta.Taxonomy) > 0 {
+		var taxonomyString strings.Builder
+		for i, taxonomyData := range meta.Taxonomy {
+			taxonomyString.WriteString(taxonomyData)
+			if len(meta.Taxonomy) == i+1 {
+				taxonomyString.WriteString(".")
+			} else {
+				taxonomyString.WriteString("; ")
+			}
+		}
+		sourceString += buildMetaString("", taxonomyString.String())
+	}
+	return sourceString
+}
+
+// buildReferenceString builds a REFERENCE section of genbank files.
+// TODO: could use reflection to get keys and make more general.
+func buildReferenceString(referenceIndex int, reference Reference) string {
+	referenceString := buildMetaString("REFERENCE", fmt.Sprintf("%d  %s", referenceIndex+1, reference.Range))
+	for _, field := range []struct{ key, value string }{
+		{"  AUTHORS", reference.Authors},
+		{"  TITLE", reference.Title},
+		{"  JOURNAL", reference.Journal},
+		{"  PUBMED", reference.PubMed},
+		{"  CONSRTM", reference.Consortium},
+	} {
+		if field.value != "" {
+			referenceString += buildMetaString(field.key, field.value)
+		}
+	}
+	return referenceString
+}
+
 // buildMetaString is a helper function to build the meta section of genbank files.
 func buildMetaString(name string, data string) string {
 	keyWhitespaceTrailLength := 12 - len(name) // I wish I was kidding.
@@ -1112,6 +1187,14 @@ func buildMetaString(name string, data string) string {
 	return returnData
 }
 
+// ParseLocation parses a gbk location string, like
+// complement(join(1..10,20..30)), into a Location. It is the inverse of
+// BuildLocationString, and is useful for formats that share the location
+// syntax of genbank, like EMBL.
+func ParseLocation(locationString string) (Location, error) {
+	return parseLocation(locationString)
+}
+
 // BuildLocationString is a recursive function that takes a location object and creates a gbk location string for Build()
 func BuildLocationString(location Location) string {
 	var locationString string
@@ -1119,21 +1202,28 @@ func BuildLocationString(location Location) string {
 	if location.Complement {
 		location.Complement = false
 		locationString = "complement(" + BuildLocationString(location) + ")"
-	} else if location.Join {
-		locationString = "join("
+	} else if location.Join || location.Order || location.Bond {
+		switch {
+		case location.Order:
+			locationString = "order("
+		case location.Bond:
+			locationString = "bond("
+		default:
+			locationString = "join("
+		}
 		for _, sublocation := range location.SubLocations {
 			locationString += BuildLocationString(sublocation) + ","
 		}
 		locationString = strings.TrimSuffix(locationString, ",") + ")"
 	} else {
-		locationString = strconv.Itoa(location.Start+1) + ".." + strconv.Itoa(location.End)
+		start, end := strconv.Itoa(location.Start+1), strconv.Itoa(location.End)
 		if location.FivePrimePartial {
-			locationString = "<" + locationString
+			start = "<" + start
 		}
-
 		if location.ThreePrimePartial {
-			locationString += ">"
+			end = ">" + end
 		}
+		locationString = start + ".." + end
 	}
 	return locationString
 }
@@ -161,6 +161,24 @@ func TestPartialLocationParseRegression(t *testing.T) {
 	}
 }
 
+func TestThreePrimePartialLocationBuildRegression(t *testing.T) {
+	// 687..3158> is how older writers, including this one, wrote 3' partial
+	// locations. The INSDC feature table puts the > before the end.
+	for _, locationString := range []string{"687..3158>", "687..>3158"} {
+		location, err := ParseLocation(locationString)
+		if err != nil {
+			t.Fatalf("Failed to parse %s. Got err: %s", locationString, err)
+		}
+		if location.Start != 686 || location.End != 3158 || location.FivePrimePartial || !location.ThreePrimePartial {
+			t.Errorf("Failed to parse %s as a 3' partial location. Got %+v", locationString, location)
+		}
+		location.GbkLocationString = ""
+		if built := BuildLocationString(location); built != "687..>3158" {
+			t.Errorf("Expected %s to be built as 687..>3158, got %s", locationString, built)
+		}
+	}
+}
+
 func TestSubLocationStringParseRegression(t *testing.T) {
 	location := "join(complement(5306942..5307394),complement(5304401..5305029),complement(5303328..5303393),complement(5301928..5302004))"
 	parsedLocation, err := parseLocation(location)
@@ -585,7 +603,16 @@ func Test_parseLocation(t *testing.T) {
 		want    Location
 		wantErr bool
 	}{
-		// TODO: Add test cases.
+		{
+			name: "order",
+			args: args{"order(1..3,5..6)"},
+			want: Location{Order: true, GbkLocationString: "order(1..3,5..6)", SubLocations: []Location{{Start: 0, End: 3}, {Start: 4, End: 6}}},
+		},
+		{
+			name: "bond",
+			args: args{"bond(1..3,5..6)"},
+			want: Location{Bond: true, GbkLocationString: "bond(1..3,5..6)", SubLocations: []Location{{Start: 0, End: 3}, {Start: 4, End: 6}}},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -631,7 +658,31 @@ func TestBuildLocationString(t *testing.T) {
 		args args
 		want string
 	}{
-		// TODO: Add test cases.
+		{
+			name: "5' partial",
+			args: args{Location{Start: 0, End: 120, FivePrimePartial: true}},
+			want: "<1..120",
+		},
+		{
+			name: "3' partial",
+			args: args{Location{Start: 0, End: 120, ThreePrimePartial: true}},
+			want: "1..>120",
+		},
+		{
+			name: "partial",
+			args: args{Location{Start: 0, End: 120, FivePrimePartial: true, ThreePrimePartial: true}},
+			want: "<1..>120",
+		},
+		{
+			name: "order",
+			args: args{Location{Order: true, SubLocations: []Location{{Start: 0, End: 3}, {Start: 4, End: 6}}}},
+			want: "order(1..3,5..6)",
+		},
+		{
+			name: "complement bond",
+			args: args{Location{Complement: true, Bond: true, SubLocations: []Location{{Start: 0, End: 3}, {Start: 4, End: 6}}}},
+			want: "complement(bond(1..3,5..6))",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -749,3 +800,35 @@ func TestConsortiumRegression(t *testing.T) {
 		t.Errorf("Failed to read consrtm. Got err: %s", err)
 	}
 }
+
+func TestOrderLocationSequence(t *testing.T) {
+	sequence := Genbank{Sequence: "AAATTTGGG"}
+	for _, locationString := range []string{"order(1..3,7..9)", "bond(1..3,7..9)"} {
+		location, _ := parseLocation(locationString)
+		feature := Feature{Type: "misc_feature", Location: location}
+		_ = sequence.AddFeature(&feature)
+		if _, err := feature.GetSequence(); err == nil {
+			t.Errorf("Expected an error getting the sequence of %s", locationString)
+		}
+	}
+	location, _ := parseLocation("join(1..3,7..9)")
+	feature := Feature{Type: "misc_feature", Location: location}
+	_ = sequence.AddFeature(&feature)
+	if joined, err := feature.GetSequence(); err != nil || joined != "AAAGGG" {
+		t.Errorf("Expected AAAGGG, got %s (%v)", joined, err)
+	}
+}
+
+func TestBuildLocusStringSequenceCoding(t *testing.T) {
+	locus := parseLocus("LOCUS       INS_HUMAN                110 aa            linear   PRI 01-JAN-2024")
+	if locus.SequenceCoding != "aa" {
+		t.Fatalf("Expected sequence coding aa, got %q", locus.SequenceCoding)
+	}
+	if locusString := buildLocusString(locus); !strings.Contains(locusString, " 110 aa ") {
+		t.Errorf("Expected protein LOCUS line in aa, got %q", locusString)
+	}
+	// records without a sequence coding, like ones built from scratch, are in bp.
+	if locusString := buildLocusString(Locus{Name: "pUC19", SequenceLength: "2686", Circular: true}); !strings.Contains(locusString, " 2686 bp ") {
+		t.Errorf("Expected LOCUS line in bp, got %q", locusString)
+	}
+}
@@ -0,0 +1,352 @@
+package genbank
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+)
+
+/******************************************************************************
+
+Round trip writing
+
+Genbank structs don't keep everything in a GenBank file: qualifiers are stored
+in a map, wrapped lines are joined, and spacing is normalized. Records parsed
+with NewRoundTripParser keep the original text of each section of the file
+along with the values parsed from it. When writing, every section whose values
+haven't changed is written as it was read, so parse->write reproduces the
+input, and an edit only changes the lines of the edited section.
+
+******************************************************************************/
+
+// Original holds the original text of a genbank record, split into the
+// sections that can be written back independently.
+type Original struct {
+	preamble string // lines before LOCUS, like the header of flat files
+	newline  string
+	header   []originalBlock
+	features []originalFeature
+	trailer  string // BASE COUNT, ORIGIN or CONTIG, the sequence, and //
+	value    string // the parsed values of the trailer
+}
+
+// originalBlock is a top level keyword of the header, like DEFINITION or
+// REFERENCE, with its indented lines.
+type originalBlock struct {
+	key   string
+	index int // index of REFERENCE blocks
+	text  string
+	value string // the parsed values of the block
+}
+
+// originalFeature is the text of a feature and the feature parsed from it.
+type originalFeature struct {
+	text    string
+	feature Feature
+}
+
+// standardKeys are header keywords stored in their own Meta fields.
+var standardKeys = []string{"DEFINITION", "ACCESSION", "VERSION", "KEYWORDS", "SOURCE"}
+
+// addBlock adds a header block to the original text.
+func (original *Original) addBlock(block originalBlock) {
+	if block.key == "REFERENCE" {
+		for _, previous := range original.header {
+			if previous.key == "REFERENCE" {
+				block.index++
+			}
+		}
+	}
+	original.header = append(original.header, block)
+}
+
+// finish stores the values of each section once the record is parsed.
+func (original *Original) finish(sequence Genbank) {
+	for i, block := range original.header {
+		original.header[i].value, _ = blockValue(sequence.Meta, block.key, block.index)
+	}
+	original.value = trailerValue(sequence)
+}
+
+// blockValue returns the values of the header block with the given keyword,
+// or false if the block isn't in meta.
+func blockValue(meta Meta, key string, index int) (string, bool) {
+	switch key {
+	case "LOCUS":
+		return fmt.Sprintf("%+v", meta.Locus), true
+	case "DEFINITION":
+		return meta.Definition, true
+	case "ACCESSION":
+		return meta.Accession, true
+	case "VERSION":
+		return meta.Version, true
+	case "KEYWORDS":
+		return meta.Keywords, true
+	case "SOURCE":
+		return meta.Source + "\x00" + meta.Organism + "\x00" + strings.Join(meta.Taxonomy, "\x00"), true
+	case "REFERENCE":
+		if index >= len(meta.References) {
+			return "", false
+		}
+		return fmt.Sprintf("%+v", meta.References[index]), true
+	case "FEATURES":
+		return "", true
+	}
+	value, ok := meta.Other[key]
+	return value, ok
+}
+
+// buildBlock builds the header block with the given keyword from meta.
+func buildBlock(meta Meta, key string, index int) string {
+	switch key {
+	case "LOCUS":
+		return buildLocusString(meta.Locus)
+	case "DEFINITION":
+		return buildMetaString(key, meta.Definition)
+	case "ACCESSION":
+		return buildMetaString(key, meta.Accession)
+	case "VERSION":
+		return buildMetaString(key, meta.Version)
+	case "KEYWORDS":
+		return buildMetaString(key, meta.Keywords)
+	case "SOURCE":
+		return buildSourceString(meta)
+	case "REFERENCE":
+		return buildEditedReferenceString(index, meta.References[index])
+	}
+	return buildMetaString(key, meta.Other[key])
+}
+
+// trailerValue returns the values written in the trailer of a record.
+func trailerValue(sequence Genbank) string {
+	return fmt.Sprintf("%v\x00%s\x00%s", sequence.Meta.BaseCount, sequence.Meta.Other["CONTIG"], sequence.Sequence)
+}
+
+// writeOriginal writes a record parsed by a round trip parser, reusing the
+// original text of every unchanged section.
+func (sequence *Genbank) writeOriginal(w io.Writer) (int64, error) {
+	original := sequence.Original
+	var output strings.Builder
+	// generated text is written with the line endings of the original file.
+	writeGenerated := func(text string) {
+		if original.newline != "\n" && original.newline != "" {
+			text = strings.ReplaceAll(text, "\n", original.newline)
+		}
+		output.WriteString(text)
+	}
+
+	// CONTIG is written in the trailer of the original record, and as a
+	// header block if the trailer is rewritten.
+	trailerUnchanged := trailerValue(*sequence) == original.value
+	output.WriteString(original.preamble)
+	present := make(map[string]bool)
+	regenerated := make(map[string]bool)
+	var references int
+	for _, block := range original.header {
+		present[block.key] = true
+		if block.key == "REFERENCE" {
+			references++
+		}
+		if block.key == "FEATURES" {
+			writeGenerated(sequence.newHeaderBlocks(present, references, trailerUnchanged))
+			output.WriteString(block.text)
+			continue
+		}
+		value, ok := blockValue(sequence.Meta, block.key, block.index)
+		id := fmt.Sprintf("%s %d", block.key, block.index)
+		switch {
+		case !ok:
+			// the block was removed.
+		case value == block.value:
+			output.WriteString(block.text)
+		case !regenerated[id]:
+			// Repeated keywords are stored once in Meta.Other, so they are
+			// only written once if they are edited.
+			writeGenerated(buildBlock(sequence.Meta, block.key, block.index))
+			regenerated[id] = true
+		}
+	}
+
+	used := make([]bool, len(original.features))
+	var next int
+	for _, feature := range sequence.Features {
+		index := original.findFeature(feature, next, used)
+		if index != -1 {
+			used[index] = true
+			next = index + 1
+			output.WriteString(original.features[index].text)
+			continue
+		}
+		// An edited feature takes the place of the original feature at the
+		// same position, keeping its qualifier order and quoting.
+		var text string
+		if next < len(original.features) && !used[next] && original.features[next].feature.Type == feature.Type {
+			text = original.features[next].text
+			used[next] = true
+			next++
+		}
+		writeGenerated(buildFeatureString(feature, text))
+	}
+
+	if trailerUnchanged {
+		output.WriteString(original.trailer)
+	} else {
+		writeGenerated(buildOriginString(sequence.Meta.BaseCount, sequence.Sequence))
+	}
+
+	written, err := w.Write([]byte(output.String()))
+	return int64(written), err
+}
+
+// newHeaderBlocks builds the header blocks that were added to meta after
+// parsing, which are written just before FEATURES.
+func (sequence *Genbank) newHeaderBlocks(present map[string]bool, references int, trailerUnchanged bool) string {
+	var blocks strings.Builder
+	for _, key := range standardKeys {
+		if value, _ := blockValue(sequence.Meta, key, 0); !present[key] && strings.Trim(value, "\x00") != "" {
+			blocks.WriteString(buildBlock(sequence.Meta, key, 0))
+		}
+	}
+	for index := references; index < len(sequence.Meta.References); index++ {
+		blocks.WriteString(buildEditedReferenceString(index, sequence.Meta.References[index]))
+	}
+	var otherKeys []string
+	for key := range sequence.Meta.Other {
+		if !present[key] && (key != "CONTIG" || !trailerUnchanged) {
+			otherKeys = append(otherKeys, key)
+		}
+	}
+	sort.Strings(otherKeys)
+	for _, key := range otherKeys {
+		blocks.WriteString(buildMetaString(key, sequence.Meta.Other[key]))
+	}
+	return blocks.String()
+}
+
+// findFeature returns the index of an unused original feature equal to
+// feature, starting the search at next, or -1 if there is none.
+func (original *Original) findFeature(feature Feature, next int, used []bool) int {
+	for offset := range original.features {
+		index := (next + offset) % len(original.features)
+		if !used[index] && featuresEqual(original.features[index].feature, feature) {
+			return index
+		}
+	}
+	return -1
+}
+
+// featuresEqual returns whether two features would be written the same way.
+func featuresEqual(a Feature, b Feature) bool {
+	if a.Type != b.Type || a.Description != b.Description || !locationsEqual(a.Location, b.Location) || len(a.Attributes) != len(b.Attributes) {
+		return false
+	}
+	for key, values := range a.Attributes {
+		if otherValues, ok := b.Attributes[key]; !ok || !slices.Equal(values, otherValues) {
+			return false
+		}
+	}
+	return true
+}
+
+// locationsEqual returns whether two locations are equal, treating nil and
+// empty sub locations the same.
+func locationsEqual(a Location, b Location) bool {
+	if a.Start != b.Start || a.End != b.End || a.Complement != b.Complement || a.Join != b.Join || a.Order != b.Order || a.Bond != b.Bond || a.FivePrimePartial != b.FivePrimePartial || a.ThreePrimePartial != b.ThreePrimePartial || a.GbkLocationString != b.GbkLocationString || len(a.SubLocations) != len(b.SubLocations) {
+		return false
+	}
+	for i := range a.SubLocations {
+		if !locationsEqual(a.SubLocations[i], b.SubLocations[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// qualifierStyle is how a qualifier value is written.
+type qualifierStyle int
+
+const (
+	quoted   qualifierStyle = iota // /note="value"
+	unquoted                       // /codon_start=1
+	bare                           // /pseudo
+)
+
+// qualifierStyles reads the order and style of the qualifiers in the text of
+// a feature.
+func qualifierStyles(text string) ([]string, map[string]qualifierStyle) {
+	var order []string
+	styles := make(map[string]qualifierStyle)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "/") {
+			continue
+		}
+		key, value, hasValue := strings.Cut(line[1:], "=")
+		if _, ok := styles[key]; ok {
+			continue
+		}
+		order = append(order, key)
+		switch {
+		case !hasValue:
+			styles[key] = bare
+		case strings.HasPrefix(value, "\""):
+			styles[key] = quoted
+		default:
+			styles[key] = unquoted
+		}
+	}
+	return order, styles
+}
+
+// buildEditedReferenceString builds a REFERENCE section like WriteTo, also
+// writing its REMARK, so that remarks of edited references aren't lost.
+func buildEditedReferenceString(referenceIndex int, reference Reference) string {
+	referenceString := buildReferenceString(referenceIndex, reference)
+	if reference.Remark != "" {
+		referenceString += buildMetaString("  REMARK", reference.Remark)
+	}
+	return referenceString
+}
+
+// buildFeatureString builds a feature, writing its qualifiers in the order
+// and style they have in the original text of the feature. Qualifiers that
+// aren't in the original text are quoted, and written after the others in
+// alphabetical order.
+func buildFeatureString(feature Feature, originalText string) string {
+	whiteSpaceTrailLength := 16 - len(feature.Type) // I wish I was kidding.
+	whiteSpaceTrail := generateWhiteSpace(whiteSpaceTrailLength)
+	var location string
+
+	if feature.Location.GbkLocationString != "" {
+		location = feature.Location.GbkLocationString
+	} else {
+		location = BuildLocationString(feature.Location)
+	}
+	var returnString strings.Builder
+	returnString.WriteString(generateWhiteSpace(subMetaIndex) + feature.Type + whiteSpaceTrail + location + "\n")
+
+	order, styles := qualifierStyles(originalText)
+	var newKeys []string
+	for key := range feature.Attributes {
+		if _, ok := styles[key]; !ok {
+			newKeys = append(newKeys, key)
+		}
+	}
+	sort.Strings(newKeys)
+	for _, key := range append(order, newKeys...) {
+		for _, value := range feature.Attributes[key] {
+			returnString.WriteString(generateWhiteSpace(qualifierIndex) + "/" + key)
+			switch {
+			case styles[key] == bare && value == "":
+			case styles[key] == unquoted:
+				returnString.WriteString("=" + value)
+			default:
+				returnString.WriteString("=\"" + value + "\"")
+			}
+			returnString.WriteString("\n")
+		}
+	}
+	return returnString.String()
+}
@@ -0,0 +1,200 @@
+package genbank
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// roundTripCorpus are the files that parse->write must reproduce byte for
+// byte. They cover CRLF line endings, flat file headers, multiple records,
+// wrapped qualifiers, and files from different vendors.
+var roundTripCorpus = []string{
+	"data/NC_001141.2_redux.gb",
+	"data/benchling.gb",
+	"data/flatGbk_test.seq",
+	"data/multiGbk_test.seq",
+	"data/phix174.gb",
+	"data/pichia_chr1_head.gb",
+	"data/puc19.gbk",
+	"data/puc19_303_regression.gbk",
+	"data/puc19_consrtm.gbk",
+	"data/puc19_snapgene.gb",
+	"data/sample.gbk",
+	"data/t4_intron.gb",
+}
+
+func readRoundTrip(t *testing.T, data []byte) []Genbank {
+	t.Helper()
+	parser := NewRoundTripParser(bytes.NewReader(data), 1024*1024)
+	var records []Genbank
+	for {
+		record, err := parser.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("Failed to parse: %s", err)
+			}
+			break
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func writeRoundTrip(records []Genbank) string {
+	var buffer bytes.Buffer
+	for _, record := range records {
+		_, _ = record.WriteTo(&buffer)
+	}
+	return buffer.String()
+}
+
+func TestRoundTripCorpus(t *testing.T) {
+	for _, path := range roundTripCorpus {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %s", path, err)
+			}
+			records := readRoundTrip(t, data)
+			if output := writeRoundTrip(records); output != string(data) {
+				t.Errorf("Round trip of %s does not match:\n%s", path, firstDifference(string(data), output))
+			}
+		})
+	}
+}
+
+// firstDifference returns the first line that differs between two texts.
+func firstDifference(expected, got string) string {
+	expectedLines := strings.SplitAfter(expected, "\n")
+	gotLines := strings.SplitAfter(got, "\n")
+	for i := range expectedLines {
+		if i >= len(gotLines) || expectedLines[i] != gotLines[i] {
+			var gotLine string
+			if i < len(gotLines) {
+				gotLine = gotLines[i]
+			}
+			return fmt.Sprintf("line %d: expected %q, got %q", i+1, expectedLines[i], gotLine)
+		}
+	}
+	return "output has extra lines"
+}
+
+func TestRoundTripMatchesParser(t *testing.T) {
+	data, _ := os.ReadFile("data/puc19.gbk")
+	expected, _ := parse(bytes.NewReader(data))
+	got := readRoundTrip(t, data)[0]
+	if got.Original == nil {
+		t.Fatalf("Round trip parser did not keep the original text")
+	}
+	got.Original = nil
+	if diff := cmp.Diff(expected, got, cmpopts.IgnoreFields(Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("Round trip parser parsed differently than the parser:\n%s", diff)
+	}
+}
+
+// changedLines returns the lines removed from and added to a text.
+func changedLines(before, after string) ([]string, []string) {
+	count := make(map[string]int)
+	for _, line := range strings.Split(before, "\n") {
+		count[line]++
+	}
+	var added []string
+	for _, line := range strings.Split(after, "\n") {
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		added = append(added, line)
+	}
+	var removed []string
+	for line, n := range count {
+		for ; n > 0; n-- {
+			removed = append(removed, line)
+		}
+	}
+	return removed, added
+}
+
+func TestRoundTripEdits(t *testing.T) {
+	data, _ := os.ReadFile("data/phix174.gb")
+	original := string(data)
+
+	tests := []struct {
+		name    string
+		edit    func(sequence *Genbank)
+		removed int
+		added   []string
+	}{
+		{
+			name: "edit qualifier",
+			edit: func(sequence *Genbank) { sequence.Features[2].Attributes["product"] = []string{"protein A edited"} },
+			// The product, and the translation which is no longer wrapped.
+			removed: 11,
+			added: []string{
+				`                     /note="DNA replication initiation protein"
+                     /codon_start=1
+                     /transl_table=11
+                     /product="protein A edited"
+                     /protein_id="AJR02264.1"`,
+			},
+		},
+		{
+			name:    "edit definition",
+			edit:    func(sequence *Genbank) { sequence.Meta.Definition = "Edited." },
+			removed: 1,
+			added:   []string{"DEFINITION  Edited."},
+		},
+		{
+			name:    "add header",
+			edit:    func(sequence *Genbank) { sequence.Meta.Other["PROJECT"] = "Edited." },
+			removed: 0,
+			added:   []string{"PROJECT     Edited.\nFEATURES             Location/Qualifiers"},
+		},
+		{
+			name:    "remove feature",
+			edit:    func(sequence *Genbank) { sequence.Features = sequence.Features[1:] },
+			removed: 8,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sequence := readRoundTrip(t, data)[0]
+			test.edit(&sequence)
+			output := writeRoundTrip([]Genbank{sequence})
+			removed, _ := changedLines(original, output)
+			if len(removed) != test.removed {
+				t.Errorf("Expected %d removed lines, got %d: %q", test.removed, len(removed), removed)
+			}
+			for _, line := range test.added {
+				if !strings.Contains(output, line+"\n") {
+					t.Errorf("Expected line %q in output", line)
+				}
+			}
+
+			// The output parses to the edited record.
+			reparsed := readRoundTrip(t, []byte(output))[0]
+			if diff := cmp.Diff(sequence, reparsed, cmpopts.IgnoreFields(Feature{}, "ParentSequence"), cmpopts.IgnoreFields(Genbank{}, "Original")); diff != "" {
+				t.Errorf("Output does not parse to the edited record:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRoundTripEditedReferenceKeepsRemark(t *testing.T) {
+	data, _ := os.ReadFile("data/NC_001141.2_redux.gb")
+	sequence := readRoundTrip(t, data)[0]
+	sequence.Meta.References[4].Title = "Edited"
+	output := writeRoundTrip([]Genbank{sequence})
+	if !strings.Contains(output, "  TITLE     Edited\n") || !strings.Contains(output, "  REMARK    Protein update by submitter\n") {
+		t.Errorf("Expected edited reference with its remark in output")
+	}
+}
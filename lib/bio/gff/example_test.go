@@ -0,0 +1,35 @@
+package gff_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+	"github.com/koeng101/dnadesign/lib/bio/gff"
+)
+
+func ExampleNewParser() {
+	file, _ := os.Open("../../data/ecoli-mg1655-short.gff")
+	defer file.Close()
+	parser := gff.NewParser(file, 1024*1024)
+	annotations, _ := parser.Next()
+
+	for _, feature := range annotations.Features[:2] {
+		fmt.Println(feature.Type, feature.Segments[0].Start, feature.Segments[0].End, feature.Attribute("gene"))
+	}
+	// Output:
+	// gene 190 255 [thrL]
+	// CDS 190 255 [thrL]
+}
+
+func ExampleToGenbank() {
+	file, _ := os.Open("../../data/ecoli-mg1655-short.gff")
+	defer file.Close()
+	annotations, _ := gff.NewParser(file, 1024*1024).Next()
+
+	sequences, _ := gff.ToGenbank(annotations)
+	cds := sequences[0].Features[1]
+	sequence, _ := cds.GetSequence()
+	fmt.Println(genbank.BuildLocationString(cds.Location), sequence[:12])
+	// Output: 190..255 ATGAAACGCATT
+}
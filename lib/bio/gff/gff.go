@@ -0,0 +1,797 @@
+/*
+Package gff provides GFF3 parsers and writers.
+
+GFF3 (General Feature Format, version 3) is a tab-delimited text format for
+annotating sequences. Each line describes a single feature, with nine
+columns:
+
+	seqid	source	type	start	end	score	strand	phase	attributes
+	U00096.3	feature	CDS	190	255	.	+	0	gene=thrL;product=thr operon leader peptide
+
+Features are related to each other using the ID and Parent attributes, so
+that a gene can have mRNAs, which can have exons and CDSs. Features that are
+discontinuous, such as a CDS spanning multiple exons, are written as
+multiple lines sharing the same ID. The sequences being annotated may be
+included at the end of the file, after a ##FASTA directive.
+
+This package provides a parser and writer for GFF3 files, as well as
+functions to convert between GFF3 and the Genbank struct used by the genbank
+package.
+
+Spec: https://github.com/The-Sequence-Ontology/Specifications/blob/master/gff3.md
+*/
+package gff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/koeng101/dnadesign/lib/bio/fasta"
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+/******************************************************************************
+
+GFF3 structs begin here
+
+******************************************************************************/
+
+// Gff is a single GFF3 file.
+type Gff struct {
+	Version         string           // Version of ##gff-version, such as 3 or 3.1.26.
+	SequenceRegions []SequenceRegion // ##sequence-region directives.
+	Directives      []string         // All other directives, without the leading ##, such as "species https://...".
+	Features        []Feature
+	Sequences       []fasta.Record // Sequences of the ##FASTA section.
+}
+
+// SequenceRegion is a ##sequence-region directive, giving the bounds of a
+// sequence. Like the rest of GFF3, Start and End are 1-based and inclusive.
+type SequenceRegion struct {
+	SeqID string
+	Start int
+	End   int
+}
+
+// Feature is a single GFF3 feature. A feature written across multiple lines
+// sharing the same ID is a single Feature, with one Segment for each line.
+type Feature struct {
+	SeqID      string // ID of the sequence the feature is on.
+	Source     string // Program or database that generated the feature.
+	Type       string // Type of the feature, such as gene, mRNA, or CDS.
+	Strand     byte   // +, -, . for features without a strand, or ? for unknown.
+	Segments   []Segment
+	Attributes []Attribute // Attributes of the feature, in order.
+}
+
+// Segment is a single line of a feature. Like the rest of GFF3, Start and
+// End are 1-based and inclusive.
+type Segment struct {
+	Start int
+	End   int
+	Score string // Score of the segment, or "." if there is no score.
+	Phase int    // Number of bases to remove to reach the next codon. Only used by CDS features, and written as . for all other types.
+}
+
+// Attribute is a single tag of the attributes column, with its values
+// decoded.
+type Attribute struct {
+	Tag    string
+	Values []string
+}
+
+// Header is a blank struct, needed for compatibility with bio parsers. It contains nothing.
+type Header struct{}
+
+// WriteTo is a blank function, needed for compatibility with bio parsers. It doesn't do anything.
+func (header *Header) WriteTo(w io.Writer) (int64, error) {
+	return 0, nil
+}
+
+// Attribute returns the values of a tag of the feature, or nil if the
+// feature does not have the tag.
+func (feature *Feature) Attribute(tag string) []string {
+	for _, attribute := range feature.Attributes {
+		if attribute.Tag == tag {
+			return attribute.Values
+		}
+	}
+	return nil
+}
+
+// ID returns the ID of the feature, or an empty string if it has none.
+func (feature *Feature) ID() string {
+	if values := feature.Attribute("ID"); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Parents returns the IDs of the parents of the feature.
+func (feature *Feature) Parents() []string {
+	return feature.Attribute("Parent")
+}
+
+// Feature returns the feature with the given ID.
+func (gff *Gff) Feature(id string) (Feature, bool) {
+	for _, feature := range gff.Features {
+		if feature.ID() == id {
+			return feature, true
+		}
+	}
+	return Feature{}, false
+}
+
+// Children returns all features with id as a Parent, in order.
+func (gff *Gff) Children(id string) []Feature {
+	var children []Feature
+	for _, feature := range gff.Features {
+		for _, parent := range feature.Parents() {
+			if parent == id {
+				children = append(children, feature)
+				break
+			}
+		}
+	}
+	return children
+}
+
+/******************************************************************************
+
+Escaping
+
+GFF3 uses URL-style percent encoding for characters with special meaning.
+Tabs, newlines, and percent signs must be escaped everywhere, while the
+attribute separators ;=&, must also be escaped within attributes.
+
+******************************************************************************/
+
+// unescape decodes percent encoded characters. Invalid escapes, like a bare
+// percent sign, are common in real files, so they are kept as they are.
+func unescape(text string) string {
+	if !strings.Contains(text, "%") {
+		return text
+	}
+	var builder strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '%' && i+2 < len(text) {
+			value, err := strconv.ParseUint(text[i+1:i+3], 16, 8)
+			if err == nil {
+				builder.WriteByte(byte(value))
+				i += 2
+				continue
+			}
+		}
+		builder.WriteByte(text[i])
+	}
+	return builder.String()
+}
+
+// escape percent encodes control characters, percent signs, and any
+// characters in special.
+func escape(text string, special string) string {
+	var builder strings.Builder
+	for i := 0; i < len(text); i++ {
+		character := text[i]
+		if character < 0x20 || character == 0x7f || character == '%' || strings.IndexByte(special, character) != -1 {
+			fmt.Fprintf(&builder, "%%%02X", character)
+			continue
+		}
+		builder.WriteByte(character)
+	}
+	return builder.String()
+}
+
+// escapeColumn escapes a value of one of the first eight columns.
+func escapeColumn(text string) string {
+	return escape(text, "")
+}
+
+// escapeAttribute escapes a tag or value of the attributes column.
+func escapeAttribute(text string) string {
+	return escape(text, ";=&,")
+}
+
+/******************************************************************************
+
+Parser
+
+******************************************************************************/
+
+// Parser is a GFF3 parser created on an io.Reader.
+type Parser struct {
+	scanner bufio.Scanner
+	line    int
+	done    bool
+}
+
+// Header returns nil,nil.
+func (parser *Parser) Header() (Header, error) {
+	return Header{}, nil
+}
+
+// NewParser returns a Parser that uses r as the source from which to parse
+// GFF3 files.
+func NewParser(r io.Reader, maxLineSize int) *Parser {
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, maxLineSize)
+	scanner.Buffer(buf, maxLineSize)
+	return &Parser{
+		scanner: *scanner,
+	}
+}
+
+// Next parses the GFF3 file. Each GFF3 file is a single Gff, so the first
+// call returns the whole file and following calls return io.EOF.
+func (parser *Parser) Next() (Gff, error) {
+	if parser.done {
+		return Gff{}, io.EOF
+	}
+	parser.done = true
+
+	var gff Gff
+	featureIndex := make(map[string]int) // index in gff.Features of each ID, for features spanning multiple lines.
+	var inFasta bool
+	for parser.scanner.Scan() {
+		parser.line++
+		line := parser.scanner.Text()
+		if parser.line == 1 {
+			version, found := strings.CutPrefix(line, "##gff-version")
+			if !found {
+				return Gff{}, fmt.Errorf("line 1: GFF3 files must begin with ##gff-version, got: %s", line)
+			}
+			gff.Version = strings.TrimSpace(version)
+			continue
+		}
+		switch {
+		case inFasta:
+			line = strings.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			if line[0] == '>' {
+				gff.Sequences = append(gff.Sequences, fasta.Record{Identifier: line[1:]})
+				continue
+			}
+			if len(gff.Sequences) == 0 {
+				return Gff{}, fmt.Errorf("line %d: missing sequence identifier in ##FASTA section", parser.line)
+			}
+			gff.Sequences[len(gff.Sequences)-1].Sequence += line
+		case strings.TrimSpace(line) == "":
+			continue
+		case line == "##FASTA":
+			inFasta = true
+		case line[0] == '>':
+			// The ##FASTA directive may be left out before the sequences.
+			inFasta = true
+			gff.Sequences = append(gff.Sequences, fasta.Record{Identifier: strings.TrimSpace(line[1:])})
+		case strings.HasPrefix(line, "###"):
+			// ### marks that all forward references have been resolved,
+			// which we do not need since the whole file is read at once.
+			continue
+		case strings.HasPrefix(line, "##sequence-region"):
+			fields := strings.Fields(line)
+			if len(fields) != 4 {
+				return Gff{}, fmt.Errorf("line %d: ##sequence-region should have a seqid, start, and end, got: %s", parser.line, line)
+			}
+			start, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return Gff{}, fmt.Errorf("line %d: invalid ##sequence-region start: %w", parser.line, err)
+			}
+			end, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return Gff{}, fmt.Errorf("line %d: invalid ##sequence-region end: %w", parser.line, err)
+			}
+			gff.SequenceRegions = append(gff.SequenceRegions, SequenceRegion{SeqID: unescape(fields[1]), Start: start, End: end})
+		case strings.HasPrefix(line, "##"):
+			gff.Directives = append(gff.Directives, line[2:])
+		case line[0] == '#':
+			// Comments are ignored.
+			continue
+		default:
+			feature, err := parseLine(line)
+			if err != nil {
+				return Gff{}, fmt.Errorf("line %d: %w", parser.line, err)
+			}
+			id := feature.ID()
+			index, ok := featureIndex[id]
+			if id == "" || !ok {
+				if id != "" {
+					featureIndex[id] = len(gff.Features)
+				}
+				gff.Features = append(gff.Features, feature)
+				continue
+			}
+			// Lines sharing an ID are segments of a single feature.
+			existing := &gff.Features[index]
+			if existing.SeqID != feature.SeqID || existing.Type != feature.Type || existing.Strand != feature.Strand {
+				return Gff{}, fmt.Errorf("line %d: feature %q has lines with different seqids, types, or strands", parser.line, id)
+			}
+			existing.Segments = append(existing.Segments, feature.Segments...)
+		}
+	}
+	if err := parser.scanner.Err(); err != nil {
+		return Gff{}, err
+	}
+	if parser.line == 0 {
+		return Gff{}, io.EOF
+	}
+	for _, record := range gff.Sequences {
+		if record.Sequence == "" {
+			return Gff{}, fmt.Errorf("sequence %q in ##FASTA section has no sequence", record.Identifier)
+		}
+	}
+	return gff, nil
+}
+
+// parseLine parses a single feature line into a Feature with one Segment.
+func parseLine(line string) (Feature, error) {
+	columns := strings.Split(line, "\t")
+	if len(columns) != 9 {
+		return Feature{}, fmt.Errorf("expected 9 tab-delimited columns, got %d", len(columns))
+	}
+	feature := Feature{SeqID: unescape(columns[0]), Source: unescape(columns[1]), Type: unescape(columns[2])}
+	var segment Segment
+	var err error
+	segment.Start, err = strconv.Atoi(columns[3])
+	if err != nil {
+		return Feature{}, fmt.Errorf("invalid start: %w", err)
+	}
+	segment.End, err = strconv.Atoi(columns[4])
+	if err != nil {
+		return Feature{}, fmt.Errorf("invalid end: %w", err)
+	}
+	if segment.Start > segment.End {
+		return Feature{}, fmt.Errorf("start %d is greater than end %d", segment.Start, segment.End)
+	}
+	segment.Score = columns[5]
+	switch columns[6] {
+	case "+", "-", ".", "?":
+		feature.Strand = columns[6][0]
+	default:
+		return Feature{}, fmt.Errorf("invalid strand %q, should be +, -, ., or ?", columns[6])
+	}
+	if columns[7] != "." {
+		segment.Phase, err = strconv.Atoi(columns[7])
+		if err != nil || segment.Phase < 0 || segment.Phase > 2 {
+			return Feature{}, fmt.Errorf("invalid phase %q, should be 0, 1, 2, or .", columns[7])
+		}
+	}
+	feature.Segments = []Segment{segment}
+
+	if columns[8] != "." {
+		for _, pair := range strings.Split(columns[8], ";") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			tag, value, found := strings.Cut(pair, "=")
+			if !found {
+				return Feature{}, fmt.Errorf("attribute %q should be tag=value", pair)
+			}
+			attribute := Attribute{Tag: unescape(tag)}
+			for _, value := range strings.Split(value, ",") {
+				attribute.Values = append(attribute.Values, unescape(value))
+			}
+			feature.Attributes = append(feature.Attributes, attribute)
+		}
+	}
+	return feature, nil
+}
+
+/******************************************************************************
+
+Writer
+
+******************************************************************************/
+
+// WriteTo writes the feature as GFF3 lines, one for each segment.
+func (feature *Feature) WriteTo(w io.Writer) (int64, error) {
+	strand := "."
+	if feature.Strand != 0 {
+		strand = string(feature.Strand)
+	}
+	attributes := make([]string, len(feature.Attributes))
+	for i, attribute := range feature.Attributes {
+		values := make([]string, len(attribute.Values))
+		for j, value := range attribute.Values {
+			values[j] = escapeAttribute(value)
+		}
+		attributes[i] = escapeAttribute(attribute.Tag) + "=" + strings.Join(values, ",")
+	}
+	attributeColumn := strings.Join(attributes, ";")
+	if attributeColumn == "" {
+		attributeColumn = "."
+	}
+
+	var builder strings.Builder
+	for _, segment := range feature.Segments {
+		score := segment.Score
+		if score == "" {
+			score = "."
+		}
+		phase := "."
+		if feature.Type == "CDS" {
+			phase = strconv.Itoa(segment.Phase)
+		}
+		fmt.Fprintf(&builder, "%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\n", escapeColumn(feature.SeqID), escapeColumn(defaultMissing(feature.Source)), escapeColumn(feature.Type), segment.Start, segment.End, score, strand, phase, attributeColumn)
+	}
+	newWrittenBytes, err := w.Write([]byte(builder.String()))
+	return int64(newWrittenBytes), err
+}
+
+// defaultMissing returns "." for empty values.
+func defaultMissing(value string) string {
+	if value == "" {
+		return "."
+	}
+	return value
+}
+
+// WriteTo writes the GFF3 file, including its ##FASTA section.
+func (gff *Gff) WriteTo(w io.Writer) (int64, error) {
+	var writtenBytes int64
+	var builder strings.Builder
+	version := gff.Version
+	if version == "" {
+		version = "3"
+	}
+	fmt.Fprintf(&builder, "##gff-version %s\n", version)
+	for _, region := range gff.SequenceRegions {
+		fmt.Fprintf(&builder, "##sequence-region %s %d %d\n", escapeColumn(region.SeqID), region.Start, region.End)
+	}
+	for _, directive := range gff.Directives {
+		fmt.Fprintf(&builder, "##%s\n", directive)
+	}
+	newWrittenBytes, err := w.Write([]byte(builder.String()))
+	writtenBytes += int64(newWrittenBytes)
+	if err != nil {
+		return writtenBytes, err
+	}
+
+	for i := range gff.Features {
+		newWrittenBytes, err := gff.Features[i].WriteTo(w)
+		writtenBytes += newWrittenBytes
+		if err != nil {
+			return writtenBytes, err
+		}
+	}
+
+	if len(gff.Sequences) == 0 {
+		return writtenBytes, nil
+	}
+	newWrittenBytes, err = w.Write([]byte("##FASTA\n"))
+	writtenBytes += int64(newWrittenBytes)
+	if err != nil {
+		return writtenBytes, err
+	}
+	for i := range gff.Sequences {
+		newWrittenBytes, err := gff.Sequences[i].WriteTo(w)
+		writtenBytes += newWrittenBytes
+		if err != nil {
+			return writtenBytes, err
+		}
+	}
+	return writtenBytes, nil
+}
+
+/******************************************************************************
+
+Genbank conversion begins here
+
+GFF3 and Genbank describe mostly the same things, but in different ways:
+
+  - GFF3 coordinates are 1-based and inclusive, while genbank.Location
+    coordinates are 0-based and exclusive of the end, like Go slices.
+  - Discontinuous GFF3 features have a segment per line, which become
+    join(...) locations, and minus strand features become complement(...)
+    locations.
+  - Partial features are marked in GFF3 with the start_range and end_range
+    attributes, as done by NCBI, which become the < and > partial markers.
+  - The phase of the first segment of a CDS becomes the codon_start
+    qualifier.
+  - Every other attribute, including ID and Parent, becomes a qualifier.
+
+The source and score columns have no place in a Genbank, so they are lost
+when converting to a Genbank.
+
+******************************************************************************/
+
+// ToGenbank converts a GFF3 file into Genbanks, one for each sequence in the
+// file. Sequences are found from ##sequence-region directives, the ##FASTA
+// section, and the features, in that order.
+func ToGenbank(gff Gff) ([]genbank.Genbank, error) {
+	var seqIDs []string
+	indexes := make(map[string]int)
+	addSeqID := func(seqID string) {
+		if _, ok := indexes[seqID]; !ok {
+			indexes[seqID] = len(seqIDs)
+			seqIDs = append(seqIDs, seqID)
+		}
+	}
+	for _, region := range gff.SequenceRegions {
+		addSeqID(region.SeqID)
+	}
+	for _, record := range gff.Sequences {
+		addSeqID(sequenceName(record))
+	}
+	for _, feature := range gff.Features {
+		addSeqID(feature.SeqID)
+	}
+
+	genbanks := make([]genbank.Genbank, len(seqIDs))
+	for i, seqID := range seqIDs {
+		genbanks[i].Meta.Locus.Name = seqID
+		genbanks[i].Meta.Other = make(map[string]string)
+	}
+	for _, region := range gff.SequenceRegions {
+		genbanks[indexes[region.SeqID]].Meta.Locus.SequenceLength = strconv.Itoa(region.End)
+	}
+	for _, record := range gff.Sequences {
+		sequence := &genbanks[indexes[sequenceName(record)]]
+		sequence.Sequence = record.Sequence
+		sequence.Meta.Locus.SequenceLength = strconv.Itoa(len(record.Sequence))
+	}
+	for _, feature := range gff.Features {
+		sequence := &genbanks[indexes[feature.SeqID]]
+		genbankFeature, err := toGenbankFeature(feature)
+		if err != nil {
+			return nil, err
+		}
+		if values := feature.Attribute("Is_circular"); len(values) > 0 && values[0] == "true" {
+			sequence.Meta.Locus.Circular = true
+		}
+		sequence.Features = append(sequence.Features, genbankFeature)
+	}
+	// ParentSequence is set once all Genbanks are in their final place.
+	for i := range genbanks {
+		for j := range genbanks[i].Features {
+			genbanks[i].Features[j].ParentSequence = &genbanks[i]
+		}
+	}
+	return genbanks, nil
+}
+
+// sequenceName returns the name of a fasta record, which is its identifier
+// up to the first whitespace.
+func sequenceName(record fasta.Record) string {
+	fields := strings.Fields(record.Identifier)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// toGenbankFeature converts a single GFF3 feature into a genbank.Feature.
+func toGenbankFeature(feature Feature) (genbank.Feature, error) {
+	if len(feature.Segments) == 0 {
+		return genbank.Feature{}, fmt.Errorf("feature %q of type %s has no segments", feature.ID(), feature.Type)
+	}
+	// Segments keep their order, since features spanning the origin of a
+	// circular sequence, like join(5000..5386,1..136), are not sorted.
+	segments := feature.Segments
+	subLocations := make([]genbank.Location, len(segments))
+	lowest, highest := 0, 0
+	for i, segment := range segments {
+		subLocations[i] = genbank.Location{Start: segment.Start - 1, End: segment.End}
+		if segment.Start < segments[lowest].Start {
+			lowest = i
+		}
+		if segment.End > segments[highest].End {
+			highest = i
+		}
+	}
+	fivePrimePartial := feature.Attribute("start_range") != nil
+	threePrimePartial := feature.Attribute("end_range") != nil
+	subLocations[lowest].FivePrimePartial = fivePrimePartial
+	subLocations[highest].ThreePrimePartial = threePrimePartial
+
+	location := subLocations[0]
+	if len(subLocations) > 1 {
+		location = genbank.Location{Join: true, SubLocations: subLocations, FivePrimePartial: fivePrimePartial, ThreePrimePartial: threePrimePartial}
+	}
+	location.Complement = feature.Strand == '-'
+
+	attributes := genbank.NewMultiMap[string, string]()
+	for _, attribute := range feature.Attributes {
+		switch attribute.Tag {
+		case "start_range", "end_range":
+			continue
+		case "partial":
+			if fivePrimePartial || threePrimePartial {
+				continue
+			}
+		}
+		for _, value := range attribute.Values {
+			genbank.Put(attributes, attribute.Tag, value)
+		}
+	}
+	if feature.Type == "CDS" && feature.Attribute("codon_start") == nil {
+		// The phase of the 5' segment is where translation begins.
+		firstSegment := segments[0]
+		if feature.Strand == '-' {
+			firstSegment = segments[len(segments)-1]
+		}
+		if firstSegment.Phase != 0 {
+			genbank.Put(attributes, "codon_start", strconv.Itoa(firstSegment.Phase+1))
+		}
+	}
+	return genbank.Feature{Type: feature.Type, Location: location, Attributes: attributes}, nil
+}
+
+// FromGenbank converts Genbanks into a single GFF3 file. The locus name of
+// each Genbank is used as its seqid.
+func FromGenbank(sequences ...genbank.Genbank) (Gff, error) {
+	gff := Gff{Version: "3"}
+	for _, sequence := range sequences {
+		seqID := sequence.Meta.Locus.Name
+		if seqID == "" {
+			return Gff{}, fmt.Errorf("genbank has no locus name to use as a seqid")
+		}
+		length := len(sequence.Sequence)
+		if length == 0 {
+			length, _ = strconv.Atoi(sequence.Meta.Locus.SequenceLength)
+		}
+		if length > 0 {
+			gff.SequenceRegions = append(gff.SequenceRegions, SequenceRegion{SeqID: seqID, Start: 1, End: length})
+		}
+		if sequence.Sequence != "" {
+			gff.Sequences = append(gff.Sequences, fasta.Record{Identifier: seqID, Sequence: sequence.Sequence})
+		}
+
+		var circularMarked bool
+		for i, genbankFeature := range sequence.Features {
+			feature, err := fromGenbankFeature(seqID, i, genbankFeature)
+			if err != nil {
+				return Gff{}, err
+			}
+			circularMarked = circularMarked || feature.Attribute("Is_circular") != nil
+			gff.Features = append(gff.Features, feature)
+		}
+		if sequence.Meta.Locus.Circular && !circularMarked {
+			// GFF3 marks circular sequences on a feature spanning the
+			// sequence, which is the source feature in a Genbank.
+			for i := range gff.Features {
+				if gff.Features[i].SeqID == seqID && gff.Features[i].Type == "source" {
+					gff.Features[i].Attributes = append(gff.Features[i].Attributes, Attribute{Tag: "Is_circular", Values: []string{"true"}})
+					break
+				}
+			}
+		}
+	}
+	return gff, nil
+}
+
+// fromGenbankFeature converts a single genbank.Feature into a GFF3 feature.
+func fromGenbankFeature(seqID string, index int, genbankFeature genbank.Feature) (Feature, error) {
+	leaves := flattenLocation(genbankFeature.Location, false)
+	var complemented int
+	for _, leaf := range leaves {
+		if leaf.Complement {
+			complemented++
+		}
+	}
+	feature := Feature{SeqID: seqID, Type: genbankFeature.Type, Strand: '+'}
+	switch complemented {
+	case 0:
+	case len(leaves):
+		feature.Strand = '-'
+	default:
+		return Feature{}, fmt.Errorf("feature %d (%s) of %s has locations on both strands, which GFF3 cannot represent", index, genbankFeature.Type, seqID)
+	}
+
+	var fivePrimePartial, threePrimePartial bool
+	minStart, maxEnd := -1, -1
+	for _, leaf := range leaves {
+		start := leaf.Start + 1
+		if leaf.Start == leaf.End {
+			// Single base locations, like "5", are parsed with Start equal to End.
+			start = leaf.End
+		}
+		feature.Segments = append(feature.Segments, Segment{Start: start, End: leaf.End})
+		fivePrimePartial = fivePrimePartial || leaf.FivePrimePartial
+		threePrimePartial = threePrimePartial || leaf.ThreePrimePartial
+		if minStart == -1 || start < minStart {
+			minStart = start
+		}
+		if leaf.End > maxEnd {
+			maxEnd = leaf.End
+		}
+	}
+	if len(feature.Segments) == 0 {
+		return Feature{}, fmt.Errorf("feature %d (%s) of %s has no location", index, genbankFeature.Type, seqID)
+	}
+
+	if feature.Type == "CDS" {
+		setPhases(&feature, genbankFeature.Attributes, fivePrimeOrder(genbankFeature.Location, 0))
+	}
+
+	// Features spanning multiple lines must share an ID.
+	if len(feature.Segments) > 1 && len(genbankFeature.Attributes["ID"]) == 0 {
+		feature.Attributes = append(feature.Attributes, Attribute{Tag: "ID", Values: []string{fmt.Sprintf("%s_%d", feature.Type, index+1)}})
+	}
+	tags := make([]string, 0, len(genbankFeature.Attributes))
+	for tag := range genbankFeature.Attributes {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		values := make([]string, len(genbankFeature.Attributes[tag]))
+		copy(values, genbankFeature.Attributes[tag])
+		feature.Attributes = append(feature.Attributes, Attribute{Tag: tag, Values: values})
+	}
+	if fivePrimePartial || threePrimePartial {
+		feature.Attributes = append(feature.Attributes, Attribute{Tag: "partial", Values: []string{"true"}})
+	}
+	if fivePrimePartial {
+		feature.Attributes = append(feature.Attributes, Attribute{Tag: "start_range", Values: []string{".", strconv.Itoa(minStart)}})
+	}
+	if threePrimePartial {
+		feature.Attributes = append(feature.Attributes, Attribute{Tag: "end_range", Values: []string{strconv.Itoa(maxEnd), "."}})
+	}
+	return feature, nil
+}
+
+// flattenLocation returns the simple ranges of a location, in order, with
+// Complement set on every range that is complemented by itself or by any
+// location containing it.
+func flattenLocation(location genbank.Location, complement bool) []genbank.Location {
+	complement = complement != location.Complement
+	if len(location.SubLocations) == 0 {
+		location.Complement = complement
+		return []genbank.Location{location}
+	}
+	var leaves []genbank.Location
+	for _, subLocation := range location.SubLocations {
+		leaves = append(leaves, flattenLocation(subLocation, complement)...)
+	}
+	return leaves
+}
+
+// fivePrimeOrder returns the indices of the ranges of a location, in the
+// order of flattenLocation, from 5' to 3'. Complemented locations are read
+// from their last sublocation to their first, so both
+// complement(join(1..10,20..30)) and join(complement(20..30),complement(1..10))
+// are read from 20..30 to 1..10. first is the index of the first range.
+func fivePrimeOrder(location genbank.Location, first int) []int {
+	if len(location.SubLocations) == 0 {
+		return []int{first}
+	}
+	subOrders := make([][]int, len(location.SubLocations))
+	for i, subLocation := range location.SubLocations {
+		subOrders[i] = fivePrimeOrder(subLocation, first)
+		first += len(subOrders[i])
+	}
+	if location.Complement {
+		slices.Reverse(subOrders)
+	}
+	var order []int
+	for _, subOrder := range subOrders {
+		order = append(order, subOrder...)
+	}
+	return order
+}
+
+// setPhases sets the phase of each segment of a CDS from its codon_start
+// qualifier, reading the segments in order, from 5' to 3'. The phase of each
+// following segment depends on how many bases of the last codon were left
+// over from the segment before it.
+func setPhases(feature *Feature, attributes map[string][]string, order []int) {
+	phase := 0
+	if values := attributes["codon_start"]; len(values) > 0 {
+		if codonStart, err := strconv.Atoi(values[0]); err == nil && codonStart >= 1 && codonStart <= 3 {
+			phase = codonStart - 1
+		}
+	}
+	for _, i := range order {
+		segment := &feature.Segments[i]
+		segment.Phase = phase
+		length := segment.End - segment.Start + 1
+		phase = (3 - (length-phase)%3) % 3
+	}
+}
@@ -0,0 +1,275 @@
+package gff
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+func parse(t *testing.T, text string) Gff {
+	t.Helper()
+	gff, err := NewParser(strings.NewReader(text), 1024*1024).Next()
+	if err != nil {
+		t.Fatalf("Failed to parse: %s", err)
+	}
+	return gff
+}
+
+// multiExon is a minus strand gene with a CDS spanning two exons, a 5'
+// partial mRNA, and escaped attributes.
+const multiExon = `##gff-version 3
+##sequence-region chr1 1 40
+##species https://www.ncbi.nlm.nih.gov/Taxonomy/Browser/wwwtax.cgi?id=9606
+# a comment
+chr1	test	gene	5	34	.	-	.	ID=gene1;Name=abc%3Bdef
+chr1	test	mRNA	5	34	.	-	.	ID=mrna1;Parent=gene1;partial=true;start_range=.,5
+chr1	test	exon	5	14	.	-	.	Parent=mrna1
+chr1	test	exon	20	34	.	-	.	Parent=mrna1
+chr1	test	CDS	5	14	0.5	-	1	ID=cds1;Parent=mrna1;Note=first%2Csecond,third
+chr1	test	CDS	20	34	0.5	-	1	ID=cds1;Parent=mrna1;Note=first%2Csecond,third
+##FASTA
+>chr1 test chromosome
+AAAAGGGGCCCCTTTTAAAAGGGGCCCCTTTTAAAAGGGG
+`
+
+func TestParse(t *testing.T) {
+	gff := parse(t, multiExon)
+	if gff.Version != "3" || len(gff.SequenceRegions) != 1 || gff.SequenceRegions[0].End != 40 {
+		t.Errorf("Failed to parse directives: %+v", gff)
+	}
+	if len(gff.Directives) != 1 || !strings.HasPrefix(gff.Directives[0], "species ") {
+		t.Errorf("Failed to parse other directives: %v", gff.Directives)
+	}
+	if len(gff.Features) != 5 {
+		t.Fatalf("Expected 5 features with the two CDS lines combined, got %d", len(gff.Features))
+	}
+	cds, ok := gff.Feature("cds1")
+	if !ok || len(cds.Segments) != 2 || cds.Segments[0].Phase != 1 || cds.Segments[1].Score != "0.5" || cds.Strand != '-' {
+		t.Errorf("Failed to combine CDS segments: %+v", cds)
+	}
+	if notes := cds.Attribute("Note"); len(notes) != 2 || notes[0] != "first,second" {
+		t.Errorf("Failed to split and unescape values: %q", notes)
+	}
+	gene, _ := gff.Feature("gene1")
+	if name := gene.Attribute("Name"); name[0] != "abc;def" {
+		t.Errorf("Failed to unescape Name: %q", name)
+	}
+	if children := gff.Children("mrna1"); len(children) != 3 {
+		t.Errorf("Expected 3 children of mrna1, got %d", len(children))
+	}
+	if len(gff.Sequences) != 1 || len(gff.Sequences[0].Sequence) != 40 {
+		t.Errorf("Failed to parse ##FASTA section: %+v", gff.Sequences)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, path := range []string{"../../data/ecoli-mg1655-short.gff", ""} {
+		text := multiExon
+		if path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %s", path, err)
+			}
+			text = string(data)
+		}
+		gff := parse(t, text)
+		var buffer bytes.Buffer
+		if _, err := gff.WriteTo(&buffer); err != nil {
+			t.Fatalf("Failed to write: %s", err)
+		}
+		if diff := cmp.Diff(gff, parse(t, buffer.String())); diff != "" {
+			t.Errorf("Round trip of %q does not match. Diff:\n%s", path, diff)
+		}
+	}
+
+	// Writing preserves attribute order and escaping.
+	gff := parse(t, multiExon)
+	var buffer bytes.Buffer
+	_, _ = gff.Features[4].WriteTo(&buffer)
+	expected := "chr1\ttest\tCDS\t5\t14\t0.5\t-\t1\tID=cds1;Parent=mrna1;Note=first%2Csecond,third\nchr1\ttest\tCDS\t20\t34\t0.5\t-\t1\tID=cds1;Parent=mrna1;Note=first%2Csecond,third\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, buffer.String())
+	}
+}
+
+func TestToGenbank(t *testing.T) {
+	data, err := os.ReadFile("../../data/ecoli-mg1655-short.gff")
+	if err != nil {
+		t.Fatalf("Failed to read gff: %s", err)
+	}
+	genbanks, err := ToGenbank(parse(t, string(data)))
+	if err != nil {
+		t.Fatalf("Failed to convert: %s", err)
+	}
+	if len(genbanks) != 1 || genbanks[0].Meta.Locus.Name != "U00096.3" || genbanks[0].Meta.Locus.SequenceLength != "6370" {
+		t.Fatalf("Failed to convert sequence: %+v", genbanks[0].Meta.Locus)
+	}
+	cds := genbanks[0].Features[1]
+	sequence, err := cds.GetSequence()
+	if err != nil {
+		t.Fatalf("Failed to get CDS sequence: %s", err)
+	}
+	if cds.Type != "CDS" || len(sequence) != 66 || !strings.HasPrefix(sequence, "ATG") {
+		t.Errorf("Wrong CDS sequence: %s", sequence)
+	}
+	if cds.Attributes["product"][0] != "thr operon leader peptide" {
+		t.Errorf("Failed to convert attributes: %v", cds.Attributes)
+	}
+
+	genbanks, err = ToGenbank(parse(t, multiExon))
+	if err != nil {
+		t.Fatalf("Failed to convert: %s", err)
+	}
+	features := genbanks[0].Features
+	if location := genbank.BuildLocationString(features[1].Location); location != "complement(<5..34)" {
+		t.Errorf("Expected complement(<5..34), got %s", location)
+	}
+	if _, ok := features[1].Attributes["start_range"]; ok {
+		t.Errorf("start_range should be converted into a partial location")
+	}
+	if location := genbank.BuildLocationString(features[4].Location); location != "complement(join(5..14,20..34))" {
+		t.Errorf("Expected complement(join(5..14,20..34)), got %s", location)
+	}
+	// The 5' segment of a minus strand CDS is the highest one.
+	if codonStart := features[4].Attributes["codon_start"]; len(codonStart) != 1 || codonStart[0] != "2" {
+		t.Errorf("Expected codon_start 2 from phase 1, got %v", codonStart)
+	}
+	sequence, _ = features[4].GetSequence()
+	if sequence != "TTAAAAGGGGCCCCTAAGGGGCCCC" {
+		t.Errorf("Wrong spliced sequence: %s", sequence)
+	}
+}
+
+func TestGenbankRoundTrip(t *testing.T) {
+	original := parse(t, multiExon)
+	genbanks, err := ToGenbank(original)
+	if err != nil {
+		t.Fatalf("Failed to convert to genbank: %s", err)
+	}
+	gff, err := FromGenbank(genbanks...)
+	if err != nil {
+		t.Fatalf("Failed to convert from genbank: %s", err)
+	}
+	if len(gff.Features) != len(original.Features) {
+		t.Fatalf("Expected %d features, got %d", len(original.Features), len(gff.Features))
+	}
+	for i, feature := range gff.Features {
+		if diff := cmp.Diff(original.Features[i].Segments, feature.Segments, cmp.FilterPath(func(p cmp.Path) bool { return p.Last().String() == ".Score" }, cmp.Ignore())); diff != "" {
+			t.Errorf("Segments of feature %d do not match. Diff:\n%s", i, diff)
+		}
+		if feature.Strand != '-' || feature.ID() != original.Features[i].ID() {
+			t.Errorf("Feature %d lost its strand or ID: %+v", i, feature)
+		}
+	}
+	mrna := gff.Features[1]
+	if start := mrna.Attribute("start_range"); len(start) != 2 || start[1] != "5" {
+		t.Errorf("Failed to convert partial location to start_range: %+v", mrna)
+	}
+	if gff.Sequences[0].Identifier != "chr1" || gff.SequenceRegions[0].End != 40 {
+		t.Errorf("Failed to convert sequence: %+v", gff.Sequences)
+	}
+}
+
+func TestFromGenbank(t *testing.T) {
+	file, err := os.Open("../../data/phix174.gb")
+	if err != nil {
+		t.Fatalf("Failed to open phix174.gb: %s", err)
+	}
+	defer file.Close()
+	sequence, err := genbank.NewParser(file, 1024*1024).Next()
+	if err != nil {
+		t.Fatalf("Failed to parse phix174.gb: %s", err)
+	}
+	gff, err := FromGenbank(sequence)
+	if err != nil {
+		t.Fatalf("Failed to convert: %s", err)
+	}
+	if len(gff.Features) != len(sequence.Features) || gff.SequenceRegions[0].End != 5386 {
+		t.Fatalf("Expected %d features on 5386 bp, got %d", len(sequence.Features), len(gff.Features))
+	}
+	if source := gff.Features[0]; source.Type != "source" || source.Attribute("Is_circular") == nil {
+		t.Errorf("Expected circular sequence to be marked on source: %+v", source)
+	}
+
+	// Features spanning the origin are split into segments sharing an ID,
+	// and keep their order when converted back.
+	cds := gff.Features[2]
+	if len(cds.Segments) != 2 || cds.Segments[0].Start != 3981 || cds.Segments[1].End != 136 || cds.ID() == "" {
+		t.Errorf("Failed to convert join across the origin: %+v", cds)
+	}
+	genbanks, err := ToGenbank(gff)
+	if err != nil {
+		t.Fatalf("Failed to convert back: %s", err)
+	}
+	if !genbanks[0].Meta.Locus.Circular {
+		t.Errorf("Expected circular sequence")
+	}
+	for i, feature := range genbanks[0].Features {
+		expected, _ := sequence.Features[i].GetSequence()
+		got, _ := feature.GetSequence()
+		if expected != got {
+			t.Errorf("Sequence of feature %d (%s) changed in round trip", i, feature.Type)
+		}
+		if genbank.BuildLocationString(feature.Location) != genbank.BuildLocationString(sequence.Features[i].Location) {
+			t.Errorf("Location of feature %d changed from %s to %s", i, genbank.BuildLocationString(sequence.Features[i].Location), genbank.BuildLocationString(feature.Location))
+		}
+	}
+}
+
+func TestFromGenbankMinusStrandPhases(t *testing.T) {
+	// The 5' segment of these CDSs is 20..30, or 1..10 across the origin,
+	// however their locations are nested.
+	for _, test := range []struct {
+		location string
+		phases   map[int]int // phase of the segment starting at each base
+	}{
+		{"complement(join(1..10,20..30))", map[int]int{20: 0, 1: 1}},
+		{"join(complement(20..30),complement(1..10))", map[int]int{20: 0, 1: 1}},
+		{"complement(join(50..60,1..10))", map[int]int{1: 0, 50: 2}},
+	} {
+		location, err := genbank.ParseLocation(test.location)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %s", test.location, err)
+		}
+		attributes := genbank.NewMultiMap[string, string]()
+		genbank.Put(attributes, "codon_start", "1")
+		sequence := genbank.Genbank{
+			Meta:     genbank.Meta{Locus: genbank.Locus{Name: "test", Circular: true}},
+			Sequence: strings.Repeat("ATGC", 15),
+			Features: []genbank.Feature{{Type: "CDS", Location: location, Attributes: attributes}},
+		}
+		gff, err := FromGenbank(sequence)
+		if err != nil {
+			t.Fatalf("Failed to convert %s: %s", test.location, err)
+		}
+		for _, segment := range gff.Features[0].Segments {
+			if phase, ok := test.phases[segment.Start]; !ok || segment.Phase != phase {
+				t.Errorf("%s: expected phase %d for segment %d..%d, got %d", test.location, phase, segment.Start, segment.End, segment.Phase)
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		"chr1\ttest\tgene\t1\t10\t.\t+\t.\tID=a\n",
+		"##gff-version 3\nchr1\ttest\tgene\t1\t10\t.\t+\t.\n",
+		"##gff-version 3\nchr1\ttest\tgene\tone\t10\t.\t+\t.\tID=a\n",
+		"##gff-version 3\nchr1\ttest\tgene\t1\tten\t.\t+\t.\tID=a\n",
+		"##gff-version 3\nchr1\ttest\tgene\t10\t1\t.\t+\t.\tID=a\n",
+		"##gff-version 3\nchr1\ttest\tgene\t1\t10\t.\tx\t.\tID=a\n",
+		"##gff-version 3\nchr1\ttest\tCDS\t1\t10\t.\t+\t3\tID=a\n",
+		"##gff-version 3\nchr1\ttest\tgene\t1\t10\t.\t+\t.\tID\n",
+		"##gff-version 3\nchr1\ttest\tgene\t1\t10\t.\t+\t.\tID=a\nchr1\ttest\tmRNA\t20\t30\t.\t+\t.\tID=a\n",
+		"##gff-version 3\n##sequence-region chr1 1\n",
+		"##gff-version 3\n##FASTA\nATGC\n",
+	} {
+		if _, err := NewParser(strings.NewReader(text), 1024).Next(); err == nil {
+			t.Errorf("Expected error parsing %q", text)
+		}
+	}
+}
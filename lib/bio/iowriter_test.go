@@ -4,13 +4,16 @@ import (
 	"io"
 	"testing"
 
+	"github.com/koeng101/dnadesign/lib/bio/bed"
 	"github.com/koeng101/dnadesign/lib/bio/fasta"
 	"github.com/koeng101/dnadesign/lib/bio/fastq"
 	"github.com/koeng101/dnadesign/lib/bio/genbank"
+	"github.com/koeng101/dnadesign/lib/bio/gff"
 	"github.com/koeng101/dnadesign/lib/bio/pileup"
 	"github.com/koeng101/dnadesign/lib/bio/sam"
 	"github.com/koeng101/dnadesign/lib/bio/slow5"
 	"github.com/koeng101/dnadesign/lib/bio/uniprot"
+	"github.com/koeng101/dnadesign/lib/bio/vcf"
 )
 
 func TestAllTypesImplementWriterTo(t *testing.T) {
@@ -21,4 +24,8 @@ func TestAllTypesImplementWriterTo(t *testing.T) {
 	var _ io.WriterTo = &sam.Alignment{}
 	var _ io.WriterTo = &pileup.Line{}
 	var _ io.WriterTo = &uniprot.Entry{}
+	var _ io.WriterTo = &vcf.Record{}
+	var _ io.WriterTo = &gff.Gff{}
+	var _ io.WriterTo = &bed.Record{}
+	var _ io.WriterTo = &vcf.Header{}
 }
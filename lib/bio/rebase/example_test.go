@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/koeng101/dnadesign/lib/bio/rebase"
+	"github.com/koeng101/dnadesign/lib/clone"
 )
 
 // This example reads rebase into an enzymeMap and returns the AarI recognition
@@ -26,3 +27,25 @@ func ExampleExport() {
 	fmt.Println(string(enzymeJSON)[:100])
 	// Output: {"AaaI":{"name":"AaaI","isoschizomers":["XmaIII","BseX3I","BsoDI","BstZI","EagI","EclXI","Eco52I","S
 }
+
+// This example converts the commercially available enzymes of rebase to
+// clone.Enzymes, so that clone.CutWithEnzymeByNameFrom can cut with them.
+func ExampleCloneEnzymes() {
+	enzymeMap, _ := rebase.Read("data/rebase_test.txt")
+	enzymes := rebase.CloneEnzymes(enzymeMap)
+
+	fragments, _ := clone.CutWithEnzymeByNameFrom(enzymes, clone.Part{Sequence: "AAAAAAAAAAGACGTCTTTTTTTTTT"}, false, "AatII", false)
+	fmt.Println(fragments[0].ForwardOverhang, fragments[0].Sequence)
+	// Output: ACGT CTTTTTTTTTT
+}
+
+// This example finds the commercially available enzymes of rebase that cut a
+// plasmid once, to plan a diagnostic digest.
+func ExampleCloneEnzymes_restrictionMap() {
+	enzymeMap, _ := rebase.Read("data/rebase_test.txt")
+	plasmid := clone.Part{Sequence: "GACGTCATATATATATATATTTATAAGCGCGCGCGCGCGCGCGCGCGCGTTATAAGCGCGCGCGC", Circular: true}
+
+	restrictionMap := clone.NewRestrictionMap(plasmid, rebase.CloneEnzymes(enzymeMap))
+	fmt.Println(restrictionMap.UniqueCutters(), restrictionMap.Cutters(2))
+	// Output: [AatII] [AanI]
+}
@@ -152,6 +152,8 @@ import (
 	"io"
 	"os"
 	"strings"
+
+	"github.com/koeng101/dnadesign/lib/clone"
 )
 
 //go:embed data/rebase.txt
@@ -299,3 +301,32 @@ func Rebase() map[string]Enzyme {
 	}
 	return enzymes
 }
+
+// CloneEnzyme converts an enzyme to a clone.Enzyme, to simulate digests with
+// the clone package. It returns clone.ErrUnknownCut for enzymes without a
+// known cut site.
+func (enzyme Enzyme) CloneEnzyme() (clone.Enzyme, error) {
+	return clone.NewEnzyme(enzyme.Name, enzyme.RecognitionSequence)
+}
+
+// CloneEnzymes converts the commercially available enzymes of an enzymeMap to
+// clone.Enzymes, skipping enzymes that clone can't simulate, like enzymes
+// without a known cut site. clone.CutWithEnzymeByNameFrom cuts with any of
+// them by name:
+//
+//	enzymes := rebase.CloneEnzymes(enzymeMap)
+//	fragments, err := clone.CutWithEnzymeByNameFrom(enzymes, part, false, "EcoRI", false)
+func CloneEnzymes(enzymeMap map[string]Enzyme) map[string]clone.Enzyme {
+	cloneEnzymes := make(map[string]clone.Enzyme)
+	for name, enzyme := range enzymeMap {
+		if len(enzyme.CommercialAvailability) == 0 {
+			continue
+		}
+		cloneEnzyme, err := enzyme.CloneEnzyme()
+		if err != nil {
+			continue
+		}
+		cloneEnzymes[name] = cloneEnzyme
+	}
+	return cloneEnzymes
+}
@@ -5,6 +5,8 @@ import (
 	"io"
 	"strings"
 	"testing"
+
+	"github.com/koeng101/dnadesign/lib/clone"
 )
 
 func TestRead(t *testing.T) {
@@ -73,3 +75,32 @@ func TestExport_error(t *testing.T) {
 func TestRebase(t *testing.T) {
 	_ = Rebase()
 }
+
+func TestCloneEnzyme(t *testing.T) {
+	enzymeMap, err := Read("data/rebase_test.txt")
+	if err != nil {
+		t.Fatal("Failed to read test file")
+	}
+	aarI, err := enzymeMap["AarI"].CloneEnzyme()
+	if err != nil {
+		t.Fatalf("Failed to convert AarI: %s", err)
+	}
+	if aarI.RecognitionSite != "CACCTGC" || aarI.Skip != 4 || aarI.OverheadLength != 4 {
+		t.Errorf("Unexpected AarI %+v", aarI)
+	}
+	_, err = enzymeMap["AamI"].CloneEnzyme()
+	if !errors.Is(err, clone.ErrUnknownCut) {
+		t.Errorf("AamI has an unknown cut site, got %v", err)
+	}
+
+	cloneEnzymes := CloneEnzymes(enzymeMap)
+	if len(cloneEnzymes) != 20 {
+		t.Errorf("Expected 20 commercially available enzymes, got %d", len(cloneEnzymes))
+	}
+	if _, ok := cloneEnzymes["AatII"]; !ok {
+		t.Errorf("Expected commercially available AatII")
+	}
+	if _, ok := cloneEnzymes["AaaI"]; ok {
+		t.Errorf("AaaI is not commercially available")
+	}
+}
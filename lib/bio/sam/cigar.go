@@ -0,0 +1,453 @@
+package sam
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+CIGAR strings and coordinate arithmetic
+
+A CIGAR string describes how a read aligns to the reference as a list of
+operations, each with a length. The operations are defined in section 1.4.6
+of the SAM specification:
+
+	Op	Description						Consumes query	Consumes reference
+	M	alignment match (can be a mismatch)		yes				yes
+	I	insertion to the reference				yes				no
+	D	deletion from the reference				no				yes
+	N	skipped region from the reference		no				yes
+	S	soft clipping (clipped sequence in SEQ)	yes				no
+	H	hard clipping (not in SEQ)				no				no
+	P	padding (silent deletion from padded reference)	no		no
+	=	sequence match							yes				yes
+	X	sequence mismatch						yes				yes
+
+Query positions are 0-based indexes into SEQ, and reference positions are
+0-based coordinates on the reference, so the first aligned base of an
+alignment is at reference position POS-1.
+
+******************************************************************************/
+
+// cigarOperations are the valid CIGAR operations.
+const cigarOperations = "MIDNSHP=X"
+
+// CigarOperation is a single operation of a CIGAR string, like 8S.
+type CigarOperation struct {
+	Length int
+	Type   byte // One of M, I, D, N, S, H, P, =, or X.
+}
+
+// ConsumesQuery returns whether the operation consumes bases of the query
+// sequence.
+func (operation CigarOperation) ConsumesQuery() bool {
+	switch operation.Type {
+	case 'M', 'I', 'S', '=', 'X':
+		return true
+	}
+	return false
+}
+
+// ConsumesReference returns whether the operation consumes bases of the
+// reference sequence.
+func (operation CigarOperation) ConsumesReference() bool {
+	switch operation.Type {
+	case 'M', 'D', 'N', '=', 'X':
+		return true
+	}
+	return false
+}
+
+// Cigar is a parsed CIGAR string. An unavailable CIGAR (*) is an empty
+// Cigar.
+type Cigar []CigarOperation
+
+// ParseCigar parses a CIGAR string.
+func ParseCigar(cigar string) (Cigar, error) {
+	if cigar == "*" || cigar == "" {
+		return nil, nil
+	}
+	var operations Cigar
+	start := 0
+	for i := 0; i < len(cigar); i++ {
+		character := cigar[i]
+		if '0' <= character && character <= '9' {
+			continue
+		}
+		if !strings.Contains(cigarOperations, string(character)) || i == start {
+			return nil, fmt.Errorf("invalid CIGAR %s: unexpected %q at position %d", cigar, character, i)
+		}
+		length, err := strconv.Atoi(cigar[start:i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIGAR %s: %w", cigar, err)
+		}
+		operations = append(operations, CigarOperation{Length: length, Type: character})
+		start = i + 1
+	}
+	if start != len(cigar) {
+		return nil, fmt.Errorf("invalid CIGAR %s: must end with an operation", cigar)
+	}
+	return operations, nil
+}
+
+// String returns the CIGAR string, or * for an empty Cigar.
+func (cigar Cigar) String() string {
+	if len(cigar) == 0 {
+		return "*"
+	}
+	var sb strings.Builder
+	for _, operation := range cigar {
+		sb.WriteString(strconv.Itoa(operation.Length))
+		sb.WriteByte(operation.Type)
+	}
+	return sb.String()
+}
+
+// ReferenceLength returns the number of reference bases the alignment spans.
+func (cigar Cigar) ReferenceLength() int {
+	var length int
+	for _, operation := range cigar {
+		if operation.ConsumesReference() {
+			length += operation.Length
+		}
+	}
+	return length
+}
+
+// QueryLength returns the number of query bases of the alignment, including
+// soft clipped bases. It is the expected length of SEQ.
+func (cigar Cigar) QueryLength() int {
+	var length int
+	for _, operation := range cigar {
+		if operation.ConsumesQuery() {
+			length += operation.Length
+		}
+	}
+	return length
+}
+
+// clips returns the lengths of the clipping of the given type at each end of
+// the alignment. Hard clips are always the outermost operations, so they are
+// skipped when looking for soft clips.
+func (cigar Cigar) clips(clipType byte) (left int, right int) {
+	for i := 0; i < len(cigar) && (cigar[i].Type == 'H' || cigar[i].Type == 'S'); i++ {
+		if cigar[i].Type == clipType {
+			left += cigar[i].Length
+		}
+	}
+	for i := len(cigar) - 1; i >= 0 && (cigar[i].Type == 'H' || cigar[i].Type == 'S'); i-- {
+		if cigar[i].Type == clipType {
+			right += cigar[i].Length
+		}
+	}
+	if left+right > 0 && cigar.onlyClips() {
+		// A fully clipped alignment would count every clip at both ends.
+		right = 0
+	}
+	return left, right
+}
+
+// onlyClips returns whether every operation is a clip.
+func (cigar Cigar) onlyClips() bool {
+	for _, operation := range cigar {
+		if operation.Type != 'H' && operation.Type != 'S' {
+			return false
+		}
+	}
+	return true
+}
+
+// SoftClips returns the number of soft clipped bases at the start and end of
+// the alignment.
+func (cigar Cigar) SoftClips() (left int, right int) {
+	return cigar.clips('S')
+}
+
+// HardClips returns the number of hard clipped bases at the start and end of
+// the alignment.
+func (cigar Cigar) HardClips() (left int, right int) {
+	return cigar.clips('H')
+}
+
+// ReferenceOffset returns the offset from the start of the alignment of the
+// reference base aligned to a query position. It returns false if the query
+// position is inserted, soft clipped, or outside of the query.
+func (cigar Cigar) ReferenceOffset(queryPosition int) (int, bool) {
+	if queryPosition < 0 {
+		return 0, false
+	}
+	var query, reference int
+	for _, operation := range cigar {
+		consumesQuery, consumesReference := operation.ConsumesQuery(), operation.ConsumesReference()
+		if consumesQuery && queryPosition < query+operation.Length {
+			if !consumesReference {
+				return 0, false
+			}
+			return reference + queryPosition - query, true
+		}
+		if consumesQuery {
+			query += operation.Length
+		}
+		if consumesReference {
+			reference += operation.Length
+		}
+	}
+	return 0, false
+}
+
+// QueryPosition returns the query position aligned to a reference base, given
+// as an offset from the start of the alignment. It returns false if the
+// reference base is deleted, skipped, or outside of the alignment.
+func (cigar Cigar) QueryPosition(referenceOffset int) (int, bool) {
+	var query, reference int
+	for _, operation := range cigar {
+		consumesQuery, consumesReference := operation.ConsumesQuery(), operation.ConsumesReference()
+		if consumesReference && referenceOffset < reference+operation.Length {
+			if !consumesQuery || referenceOffset < reference {
+				return 0, false
+			}
+			return query + referenceOffset - reference, true
+		}
+		if consumesQuery {
+			query += operation.Length
+		}
+		if consumesReference {
+			reference += operation.Length
+		}
+	}
+	return 0, false
+}
+
+// Cigar parses the CIGAR string of the alignment.
+func (alignment *Alignment) Cigar() (Cigar, error) {
+	return ParseCigar(alignment.CIGAR)
+}
+
+// ReferenceStart returns the 0-based reference position of the first aligned
+// base.
+func (alignment *Alignment) ReferenceStart() int {
+	return int(alignment.POS) - 1
+}
+
+// ReferenceEnd returns the 0-based, exclusive reference position of the end
+// of the alignment, so that the alignment spans
+// [ReferenceStart(), ReferenceEnd()).
+func (alignment *Alignment) ReferenceEnd() (int, error) {
+	cigar, err := alignment.Cigar()
+	if err != nil {
+		return 0, err
+	}
+	return alignment.ReferenceStart() + cigar.ReferenceLength(), nil
+}
+
+// ReferencePosition returns the 0-based reference position aligned to a
+// query position. It returns false if the query position is not aligned to
+// the reference.
+func (alignment *Alignment) ReferencePosition(queryPosition int) (int, bool, error) {
+	cigar, err := alignment.Cigar()
+	if err != nil {
+		return 0, false, err
+	}
+	offset, ok := cigar.ReferenceOffset(queryPosition)
+	if !ok {
+		return 0, false, nil
+	}
+	return alignment.ReferenceStart() + offset, true, nil
+}
+
+// QueryPosition returns the query position aligned to a 0-based reference
+// position. It returns false if the reference position is not aligned to the
+// query.
+func (alignment *Alignment) QueryPosition(referencePosition int) (int, bool, error) {
+	cigar, err := alignment.Cigar()
+	if err != nil {
+		return 0, false, err
+	}
+	position, ok := cigar.QueryPosition(referencePosition - alignment.ReferenceStart())
+	return position, ok, nil
+}
+
+/******************************************************************************
+
+Aligned pairs
+
+******************************************************************************/
+
+// AlignedPair is a query base, a reference base, or both, at one step of an
+// alignment. Positions are -1 when the step does not consume the query
+// (deletions and skips) or the reference (insertions and soft clips).
+type AlignedPair struct {
+	QueryPosition     int
+	ReferencePosition int
+	Operation         byte // The CIGAR operation of the pair.
+	QueryBase         byte // The base in SEQ, or 0 if there is none.
+	ReferenceBase     byte // The reference base from the MD tag, or 0 if it is not known.
+}
+
+// AlignedPairIterator iterates over the aligned pairs of an alignment. It is
+// created with Alignment.AlignedPairs.
+type AlignedPairIterator struct {
+	cigar     Cigar
+	sequence  string
+	reference []byte // Reference bases from the MD tag. 0 means the base matches the query.
+	operation int    // Index of the current CIGAR operation.
+	step      int    // Step within the current CIGAR operation.
+	query     int
+	position  int
+	mdIndex   int
+}
+
+// AlignedPairs returns an iterator over every query and reference base of
+// the alignment, in order, skipping hard clips and padding. If the alignment
+// has an MD tag, reference bases of matches, mismatches, and deletions are
+// reconstructed from it.
+func (alignment *Alignment) AlignedPairs() (*AlignedPairIterator, error) {
+	cigar, err := alignment.Cigar()
+	if err != nil {
+		return nil, err
+	}
+	iterator := &AlignedPairIterator{cigar: cigar, position: alignment.ReferenceStart()}
+	if alignment.SEQ != "*" {
+		if len(alignment.SEQ) != cigar.QueryLength() {
+			return nil, fmt.Errorf("SEQ has length %d but CIGAR %s has query length %d", len(alignment.SEQ), alignment.CIGAR, cigar.QueryLength())
+		}
+		iterator.sequence = alignment.SEQ
+	}
+	md, err := alignment.GetString("MD")
+	switch {
+	case errors.Is(err, ErrOptionalNotFound):
+	case err != nil:
+		return nil, err
+	default:
+		iterator.reference, err = expandMD(md)
+		if err != nil {
+			return nil, err
+		}
+		var mdLength int
+		for _, operation := range cigar {
+			switch operation.Type {
+			case 'M', '=', 'X', 'D':
+				mdLength += operation.Length
+			}
+		}
+		if mdLength != len(iterator.reference) {
+			return nil, fmt.Errorf("MD tag %s covers %d reference bases but CIGAR %s has %d", md, len(iterator.reference), alignment.CIGAR, mdLength)
+		}
+	}
+	return iterator, nil
+}
+
+// Next returns the next aligned pair. It returns io.EOF at the end of the
+// alignment.
+func (iterator *AlignedPairIterator) Next() (AlignedPair, error) {
+	for iterator.operation < len(iterator.cigar) && iterator.step >= iterator.cigar[iterator.operation].Length {
+		iterator.operation++
+		iterator.step = 0
+	}
+	if iterator.operation >= len(iterator.cigar) {
+		return AlignedPair{}, io.EOF
+	}
+	operation := iterator.cigar[iterator.operation]
+	iterator.step++
+	if operation.Type == 'H' || operation.Type == 'P' {
+		iterator.step = operation.Length
+		return iterator.Next()
+	}
+
+	pair := AlignedPair{QueryPosition: -1, ReferencePosition: -1, Operation: operation.Type}
+	if operation.ConsumesQuery() {
+		pair.QueryPosition = iterator.query
+		if iterator.sequence != "" {
+			pair.QueryBase = iterator.sequence[iterator.query]
+		}
+		iterator.query++
+	}
+	if operation.ConsumesReference() {
+		pair.ReferencePosition = iterator.position
+		iterator.position++
+		if iterator.reference != nil && operation.Type != 'N' {
+			pair.ReferenceBase = iterator.reference[iterator.mdIndex]
+			if pair.ReferenceBase == 0 {
+				pair.ReferenceBase = pair.QueryBase
+			}
+			iterator.mdIndex++
+		}
+	}
+	return pair, nil
+}
+
+// ReferenceSequence reconstructs the reference sequence spanned by the
+// alignment from SEQ, CIGAR, and the MD tag. Skipped regions (N) are not
+// described by the MD tag, so they cannot be reconstructed.
+func (alignment *Alignment) ReferenceSequence() (string, error) {
+	if _, ok := alignment.GetOptional("MD"); !ok {
+		return "", fmt.Errorf("cannot reconstruct reference: MD: %w", ErrOptionalNotFound)
+	}
+	if alignment.SEQ == "*" {
+		return "", errors.New("cannot reconstruct reference: SEQ is not stored")
+	}
+	iterator, err := alignment.AlignedPairs()
+	if err != nil {
+		return "", err
+	}
+	var reference strings.Builder
+	for {
+		pair, err := iterator.Next()
+		if err != nil {
+			break
+		}
+		switch {
+		case pair.Operation == 'N':
+			return "", errors.New("cannot reconstruct reference across skipped regions")
+		case pair.ReferencePosition != -1:
+			reference.WriteByte(pair.ReferenceBase)
+		}
+	}
+	return reference.String(), nil
+}
+
+// expandMD expands an MD tag, like 10A5^AC6, to one byte per reference base
+// of matches, mismatches, and deletions. Matching bases are 0, since they
+// are the same as the query.
+func expandMD(md string) ([]byte, error) {
+	var reference []byte
+	for i := 0; i < len(md); {
+		switch character := md[i]; {
+		case '0' <= character && character <= '9':
+			start := i
+			for i < len(md) && '0' <= md[i] && md[i] <= '9' {
+				i++
+			}
+			matches, err := strconv.Atoi(md[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid MD tag %s: %w", md, err)
+			}
+			reference = append(reference, make([]byte, matches)...)
+		case character == '^':
+			i++
+			start := i
+			for i < len(md) && isMDBase(md[i]) {
+				reference = append(reference, md[i])
+				i++
+			}
+			if i == start {
+				return nil, fmt.Errorf("invalid MD tag %s: empty deletion at position %d", md, start)
+			}
+		case isMDBase(character):
+			reference = append(reference, character)
+			i++
+		default:
+			return nil, fmt.Errorf("invalid MD tag %s: unexpected %q at position %d", md, character, i)
+		}
+	}
+	return reference, nil
+}
+
+// isMDBase returns whether a character is a reference base in an MD tag.
+func isMDBase(character byte) bool {
+	return ('A' <= character && character <= 'Z') || ('a' <= character && character <= 'z')
+}
@@ -0,0 +1,156 @@
+package sam
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseCigar(t *testing.T) {
+	cigar, err := ParseCigar("5H3S4M2I3M1D2N4M2S")
+	if err != nil {
+		t.Fatalf("Failed to parse CIGAR: %s", err)
+	}
+	if len(cigar) != 9 || cigar[1] != (CigarOperation{Length: 3, Type: 'S'}) {
+		t.Errorf("Wrong operations: %v", cigar)
+	}
+	if cigar.String() != "5H3S4M2I3M1D2N4M2S" {
+		t.Errorf("Wrong CIGAR string: %s", cigar)
+	}
+	if length := cigar.ReferenceLength(); length != 14 {
+		t.Errorf("Expected reference length 14, got %d", length)
+	}
+	if length := cigar.QueryLength(); length != 18 {
+		t.Errorf("Expected query length 18, got %d", length)
+	}
+	if left, right := cigar.SoftClips(); left != 3 || right != 2 {
+		t.Errorf("Expected soft clips 3, 2, got %d, %d", left, right)
+	}
+	if left, right := cigar.HardClips(); left != 5 || right != 0 {
+		t.Errorf("Expected hard clips 5, 0, got %d, %d", left, right)
+	}
+	if left, right := Cigar([]CigarOperation{{10, 'S'}}).SoftClips(); left != 10 || right != 0 {
+		t.Errorf("Expected soft clips 10, 0, got %d, %d", left, right)
+	}
+
+	if cigar, err := ParseCigar("*"); err != nil || len(cigar) != 0 || cigar.String() != "*" {
+		t.Errorf("Failed to parse empty CIGAR: %v, %v", cigar, err)
+	}
+	for _, invalid := range []string{"M", "10", "10M5", "10Q", "1M M"} {
+		if _, err := ParseCigar(invalid); err == nil {
+			t.Errorf("Expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestCigarPositions(t *testing.T) {
+	cigar, _ := ParseCigar("2S3M1I2M2D2M")
+	for _, test := range []struct {
+		query     int
+		reference int
+		ok        bool
+	}{
+		{-1, 0, false}, {0, 0, false}, {2, 0, true}, {4, 2, true}, {5, 0, false}, {6, 3, true}, {8, 7, true}, {10, 0, false},
+	} {
+		reference, ok := cigar.ReferenceOffset(test.query)
+		if ok != test.ok || reference != test.reference {
+			t.Errorf("ReferenceOffset(%d) = %d, %t; expected %d, %t", test.query, reference, ok, test.reference, test.ok)
+		}
+		if ok {
+			if query, ok := cigar.QueryPosition(reference); !ok || query != test.query {
+				t.Errorf("QueryPosition(%d) = %d, %t; expected %d", reference, query, ok, test.query)
+			}
+		}
+	}
+	for _, deleted := range []int{-1, 5, 6, 9} {
+		if _, ok := cigar.QueryPosition(deleted); ok {
+			t.Errorf("Expected reference offset %d to be unaligned", deleted)
+		}
+	}
+
+	alignment := Alignment{POS: 101, CIGAR: "2S3M1I2M2D2M"}
+	if end, _ := alignment.ReferenceEnd(); end != 109 {
+		t.Errorf("Expected reference end 109, got %d", end)
+	}
+	if position, ok, _ := alignment.ReferencePosition(6); !ok || position != 103 {
+		t.Errorf("Expected reference position 103, got %d, %t", position, ok)
+	}
+	if query, ok, _ := alignment.QueryPosition(103); !ok || query != 6 {
+		t.Errorf("Expected query position 6, got %d, %t", query, ok)
+	}
+}
+
+func TestAlignedPairs(t *testing.T) {
+	// Reference:   ACGT--ACGTAAC
+	// Query:     ttACCTGGACG--AC
+	alignment := Alignment{POS: 1, CIGAR: "2S4M2I3M2D2M1H", SEQ: "TTACCTGGACGAC", Optionals: []Optional{{Tag: "MD", Type: 'Z', Data: "2G4^TA2"}}}
+	iterator, err := alignment.AlignedPairs()
+	if err != nil {
+		t.Fatalf("Failed to create iterator: %s", err)
+	}
+	var pairs []string
+	for {
+		pair, err := iterator.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			break
+		}
+		pairs = append(pairs, fmt.Sprintf("%d:%d:%c:%c:%c", pair.QueryPosition, pair.ReferencePosition, pair.Operation, max(pair.QueryBase, '-'), max(pair.ReferenceBase, '-')))
+	}
+	expected := "0:-1:S:T:- 1:-1:S:T:- 2:0:M:A:A 3:1:M:C:C 4:2:M:C:G 5:3:M:T:T 6:-1:I:G:- 7:-1:I:G:- 8:4:M:A:A 9:5:M:C:C 10:6:M:G:G -1:7:D:-:T -1:8:D:-:A 11:9:M:A:A 12:10:M:C:C"
+	if strings.Join(pairs, " ") != expected {
+		t.Errorf("Expected pairs:\n%s\nGot:\n%s", expected, strings.Join(pairs, " "))
+	}
+
+	reference, err := alignment.ReferenceSequence()
+	if err != nil || reference != "ACGTACGTAAC" {
+		t.Errorf("Expected reference ACGTACGTAAC, got %s (%v)", reference, err)
+	}
+
+	// Without an MD tag, reference bases are unknown.
+	alignment.Optionals = nil
+	iterator, _ = alignment.AlignedPairs()
+	for pair, err := iterator.Next(); err == nil; pair, err = iterator.Next() {
+		if pair.ReferenceBase != 0 {
+			t.Errorf("Expected unknown reference base, got %c", pair.ReferenceBase)
+		}
+	}
+	if _, err := alignment.ReferenceSequence(); !errors.Is(err, ErrOptionalNotFound) {
+		t.Errorf("Expected ErrOptionalNotFound, got %v", err)
+	}
+
+	for _, invalid := range []Alignment{
+		{POS: 1, CIGAR: "4M", SEQ: "ACG"},
+		{POS: 1, CIGAR: "4Q", SEQ: "ACGT"},
+		{POS: 1, CIGAR: "4M", SEQ: "ACGT", Optionals: []Optional{{Tag: "MD", Type: 'Z', Data: "3"}}},
+		{POS: 1, CIGAR: "4M", SEQ: "ACGT", Optionals: []Optional{{Tag: "MD", Type: 'Z', Data: "2^1"}}},
+		{POS: 1, CIGAR: "4M", SEQ: "ACGT", Optionals: []Optional{{Tag: "MD", Type: 'i', Data: "4"}}},
+	} {
+		if _, err := invalid.AlignedPairs(); err == nil {
+			t.Errorf("Expected error for %+v", invalid)
+		}
+	}
+	spliced := Alignment{POS: 1, CIGAR: "2M5N2M", SEQ: "ACGT", Optionals: []Optional{{Tag: "MD", Type: 'Z', Data: "4"}}}
+	if _, err := spliced.ReferenceSequence(); err == nil {
+		t.Errorf("Expected error reconstructing spliced reference")
+	}
+}
+
+func ExampleAlignment_AlignedPairs() {
+	alignment := Alignment{POS: 100, CIGAR: "1S3M1D2M", SEQ: "TACTTG", Optionals: []Optional{{Tag: "MD", Type: 'Z', Data: "1G1^A2"}}}
+	iterator, _ := alignment.AlignedPairs()
+	for pair, err := iterator.Next(); err == nil; pair, err = iterator.Next() {
+		if pair.QueryBase != 0 && pair.ReferenceBase != 0 && pair.QueryBase != pair.ReferenceBase {
+			fmt.Printf("%c>%c at %d\n", pair.ReferenceBase, pair.QueryBase, pair.ReferencePosition)
+		}
+	}
+	reference, _ := alignment.ReferenceSequence()
+	fmt.Println(reference)
+	// Output:
+	// G>C at 100
+	// AGTATG
+}
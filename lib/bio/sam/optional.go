@@ -0,0 +1,395 @@
+package sam
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+Typed optional fields
+
+Optional fields are stored as raw strings so that parsing is fast and writing
+is lossless, but most of the time we want them as typed values. The getters
+and setters below convert between the raw and typed values, following the
+types in section 1.5 of the SAM specification:
+
+	A	[!-~]							Printable character
+	i	[-+]?[0-9]+						Signed integer
+	f	[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?	Single-precision floating number
+	Z	[ !-~]*							Printable string, including space
+	H	([0-9A-F][0-9A-F])*				Byte array in the Hex format
+	B	[cCsSiIf](,[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?)*	Integer or numeric array
+
+Arrays of type B have a subtype for the size of their values: c (int8),
+C (uint8), s (int16), S (uint16), i (int32), I (uint32), or f (float32).
+
+******************************************************************************/
+
+// ErrOptionalNotFound is returned by the optional getters when an alignment
+// does not have the requested tag.
+var ErrOptionalNotFound = errors.New("optional field not found")
+
+var (
+	optionalTagRegex   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]$`)
+	optionalIntRegex   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	optionalFloatRegex = regexp.MustCompile(`^[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?$`)
+	optionalHexRegex   = regexp.MustCompile(`^([0-9A-F][0-9A-F])*$`)
+)
+
+// arraySubtypeRanges are the ranges of the integer subtypes of B arrays.
+var arraySubtypeRanges = map[byte][2]int64{
+	'c': {math.MinInt8, math.MaxInt8},
+	'C': {0, math.MaxUint8},
+	's': {math.MinInt16, math.MaxInt16},
+	'S': {0, math.MaxUint16},
+	'i': {math.MinInt32, math.MaxInt32},
+	'I': {0, math.MaxUint32},
+}
+
+// String returns the optional field as it is written in a SAM file, as
+// TAG:TYPE:DATA.
+func (optional Optional) String() string {
+	return fmt.Sprintf("%s:%c:%s", optional.Tag, optional.Type, optional.Data)
+}
+
+// Validate checks that the data of the optional field matches its type.
+func (optional Optional) Validate() error {
+	if !optionalTagRegex.MatchString(optional.Tag) {
+		return fmt.Errorf("Invalid optional tag %q: must match %s", optional.Tag, optionalTagRegex)
+	}
+	var err error
+	switch optional.Type {
+	case 'A':
+		if len(optional.Data) != 1 || optional.Data[0] < '!' || optional.Data[0] > '~' {
+			err = errors.New("must be a single printable character")
+		}
+	case 'i':
+		_, err = parseOptionalInt(optional.Data)
+	case 'f':
+		_, err = parseOptionalFloat(optional.Data)
+	case 'Z':
+		for _, character := range []byte(optional.Data) {
+			if character < ' ' || character > '~' {
+				err = fmt.Errorf("contains non-printable character %q", character)
+				break
+			}
+		}
+	case 'H':
+		if !optionalHexRegex.MatchString(optional.Data) {
+			err = errors.New("must be pairs of uppercase hex digits")
+		}
+	case 'B':
+		subtype, values, splitErr := splitArray(optional.Data)
+		err = splitErr
+		for _, value := range values {
+			if err != nil {
+				break
+			}
+			if subtype == 'f' {
+				_, err = parseOptionalFloat(value)
+			} else {
+				_, err = parseArrayInt(subtype, value)
+			}
+		}
+	default:
+		err = fmt.Errorf("unknown type %q, must be one of A, i, f, Z, H, or B", optional.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("Invalid optional %s:%c: %w", optional.Tag, optional.Type, err)
+	}
+	return nil
+}
+
+// parseOptionalInt parses the data of an i optional. BAM files can store
+// unsigned 32 bit integers, so the range is [-2^31, 2^32).
+func parseOptionalInt(data string) (int, error) {
+	if !optionalIntRegex.MatchString(data) {
+		return 0, fmt.Errorf("%q is not an integer", data)
+	}
+	value, err := strconv.ParseInt(data, 10, 64)
+	if err != nil || value < math.MinInt32 || value > math.MaxUint32 {
+		return 0, fmt.Errorf("%q is out of range [-2147483648, 4294967295]", data)
+	}
+	return int(value), nil
+}
+
+// parseOptionalFloat parses the data of an f optional, or a value of a B:f
+// array.
+func parseOptionalFloat(data string) (float64, error) {
+	if !optionalFloatRegex.MatchString(data) {
+		return 0, fmt.Errorf("%q is not a number", data)
+	}
+	value, err := strconv.ParseFloat(data, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a single-precision number: %w", data, err)
+	}
+	return value, nil
+}
+
+// parseArrayInt parses a value of an integer B array, checking that it fits
+// in the subtype.
+func parseArrayInt(subtype byte, data string) (int, error) {
+	if !optionalIntRegex.MatchString(data) {
+		return 0, fmt.Errorf("%q is not an integer", data)
+	}
+	bounds := arraySubtypeRanges[subtype]
+	value, err := strconv.ParseInt(data, 10, 64)
+	if err != nil || value < bounds[0] || value > bounds[1] {
+		return 0, fmt.Errorf("%q is out of range [%d, %d] of subtype %c", data, bounds[0], bounds[1], subtype)
+	}
+	return int(value), nil
+}
+
+// splitArray splits the data of a B optional into its subtype and values.
+func splitArray(data string) (byte, []string, error) {
+	values := strings.Split(data, ",")
+	subtype := values[0]
+	if len(subtype) != 1 || !strings.Contains("cCsSiIf", subtype) {
+		return 0, nil, fmt.Errorf("invalid array subtype %q, must be one of c, C, s, S, i, I, or f", subtype)
+	}
+	if len(values) == 2 && values[1] == "" {
+		// Arrays of length 0 are written with a trailing comma by some tools.
+		return subtype[0], nil, nil
+	}
+	return subtype[0], values[1:], nil
+}
+
+// GetOptional returns the optional field with the given tag.
+func (alignment *Alignment) GetOptional(tag string) (Optional, bool) {
+	for _, optional := range alignment.Optionals {
+		if optional.Tag == tag {
+			return optional, true
+		}
+	}
+	return Optional{}, false
+}
+
+// getOptional returns the optional field with the given tag, checking that
+// it is one of the given types.
+func (alignment *Alignment) getOptional(tag string, types string) (Optional, error) {
+	optional, ok := alignment.GetOptional(tag)
+	if !ok {
+		return Optional{}, fmt.Errorf("%s: %w", tag, ErrOptionalNotFound)
+	}
+	if !strings.ContainsRune(types, optional.Type) {
+		return Optional{}, fmt.Errorf("optional %s has type %c, not %s", tag, optional.Type, strings.Join(strings.Split(types, ""), " or "))
+	}
+	return optional, nil
+}
+
+// GetInt returns the value of an integer (i) optional field, like NM or AS.
+func (alignment *Alignment) GetInt(tag string) (int, error) {
+	optional, err := alignment.getOptional(tag, "i")
+	if err != nil {
+		return 0, err
+	}
+	value, err := parseOptionalInt(optional.Data)
+	if err != nil {
+		return 0, fmt.Errorf("optional %s: %w", tag, err)
+	}
+	return value, nil
+}
+
+// GetFloat returns the value of a floating point (f) optional field, like
+// the de field of minimap2.
+func (alignment *Alignment) GetFloat(tag string) (float64, error) {
+	optional, err := alignment.getOptional(tag, "f")
+	if err != nil {
+		return 0, err
+	}
+	value, err := parseOptionalFloat(optional.Data)
+	if err != nil {
+		return 0, fmt.Errorf("optional %s: %w", tag, err)
+	}
+	return value, nil
+}
+
+// GetString returns the value of a string (Z) or character (A) optional
+// field, like MD or SA.
+func (alignment *Alignment) GetString(tag string) (string, error) {
+	optional, err := alignment.getOptional(tag, "ZA")
+	if err != nil {
+		return "", err
+	}
+	return optional.Data, nil
+}
+
+// GetByteArray returns the value of a hex (H) optional field, or of a B
+// array with the c or C subtype.
+func (alignment *Alignment) GetByteArray(tag string) ([]byte, error) {
+	optional, err := alignment.getOptional(tag, "HB")
+	if err != nil {
+		return nil, err
+	}
+	if optional.Type == 'H' {
+		value, err := hex.DecodeString(optional.Data)
+		if err != nil {
+			return nil, fmt.Errorf("optional %s: %w", tag, err)
+		}
+		return value, nil
+	}
+	subtype, values, err := splitArray(optional.Data)
+	if err != nil {
+		return nil, fmt.Errorf("optional %s: %w", tag, err)
+	}
+	if subtype != 'c' && subtype != 'C' {
+		return nil, fmt.Errorf("optional %s is an array of subtype %c, not c or C", tag, subtype)
+	}
+	bytes := make([]byte, len(values))
+	for i, value := range values {
+		number, err := parseArrayInt(subtype, value)
+		if err != nil {
+			return nil, fmt.Errorf("optional %s: %w", tag, err)
+		}
+		bytes[i] = byte(number)
+	}
+	return bytes, nil
+}
+
+// GetIntArray returns the values of a B array with an integer subtype, like
+// the per-base modification probabilities in ML.
+func (alignment *Alignment) GetIntArray(tag string) ([]int, error) {
+	optional, err := alignment.getOptional(tag, "B")
+	if err != nil {
+		return nil, err
+	}
+	subtype, values, err := splitArray(optional.Data)
+	if err != nil {
+		return nil, fmt.Errorf("optional %s: %w", tag, err)
+	}
+	if subtype == 'f' {
+		return nil, fmt.Errorf("optional %s is a float array, not an integer array", tag)
+	}
+	integers := make([]int, len(values))
+	for i, value := range values {
+		integers[i], err = parseArrayInt(subtype, value)
+		if err != nil {
+			return nil, fmt.Errorf("optional %s: %w", tag, err)
+		}
+	}
+	return integers, nil
+}
+
+// GetFloatArray returns the values of a B array with the f subtype.
+func (alignment *Alignment) GetFloatArray(tag string) ([]float64, error) {
+	optional, err := alignment.getOptional(tag, "B")
+	if err != nil {
+		return nil, err
+	}
+	subtype, values, err := splitArray(optional.Data)
+	if err != nil {
+		return nil, fmt.Errorf("optional %s: %w", tag, err)
+	}
+	if subtype != 'f' {
+		return nil, fmt.Errorf("optional %s is an array of subtype %c, not f", tag, subtype)
+	}
+	floats := make([]float64, len(values))
+	for i, value := range values {
+		floats[i], err = parseOptionalFloat(value)
+		if err != nil {
+			return nil, fmt.Errorf("optional %s: %w", tag, err)
+		}
+	}
+	return floats, nil
+}
+
+// setOptional validates an optional field, then replaces the field with the
+// same tag, or appends it if there is none.
+func (alignment *Alignment) setOptional(optional Optional) error {
+	if err := optional.Validate(); err != nil {
+		return err
+	}
+	for i := range alignment.Optionals {
+		if alignment.Optionals[i].Tag == optional.Tag {
+			alignment.Optionals[i] = optional
+			return nil
+		}
+	}
+	alignment.Optionals = append(alignment.Optionals, optional)
+	return nil
+}
+
+// SetInt sets an integer (i) optional field.
+func (alignment *Alignment) SetInt(tag string, value int) error {
+	return alignment.setOptional(Optional{Tag: tag, Type: 'i', Data: strconv.Itoa(value)})
+}
+
+// SetFloat sets a floating point (f) optional field. Values are stored with
+// single precision, using the fewest digits that read back as the same
+// value.
+func (alignment *Alignment) SetFloat(tag string, value float64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("Invalid optional %s:f: %v cannot be written in SAM", tag, value)
+	}
+	return alignment.setOptional(Optional{Tag: tag, Type: 'f', Data: strconv.FormatFloat(value, 'g', -1, 32)})
+}
+
+// SetString sets a string (Z) optional field.
+func (alignment *Alignment) SetString(tag string, value string) error {
+	return alignment.setOptional(Optional{Tag: tag, Type: 'Z', Data: value})
+}
+
+// SetChar sets a character (A) optional field.
+func (alignment *Alignment) SetChar(tag string, value byte) error {
+	return alignment.setOptional(Optional{Tag: tag, Type: 'A', Data: string(value)})
+}
+
+// SetByteArray sets a hex (H) optional field.
+func (alignment *Alignment) SetByteArray(tag string, value []byte) error {
+	return alignment.setOptional(Optional{Tag: tag, Type: 'H', Data: strings.ToUpper(hex.EncodeToString(value))})
+}
+
+// SetIntArray sets a B array optional field with an integer subtype, which
+// must be one of c, C, s, S, i, or I.
+func (alignment *Alignment) SetIntArray(tag string, subtype byte, values []int) error {
+	if _, ok := arraySubtypeRanges[subtype]; !ok {
+		return fmt.Errorf("Invalid optional %s:B: invalid integer array subtype %q", tag, subtype)
+	}
+	var data strings.Builder
+	data.WriteByte(subtype)
+	for _, value := range values {
+		data.WriteString("," + strconv.Itoa(value))
+	}
+	return alignment.setOptional(Optional{Tag: tag, Type: 'B', Data: data.String()})
+}
+
+// SetFloatArray sets a B array optional field with the f subtype.
+func (alignment *Alignment) SetFloatArray(tag string, values []float64) error {
+	var data strings.Builder
+	data.WriteByte('f')
+	for _, value := range values {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return fmt.Errorf("Invalid optional %s:B: %v cannot be written in SAM", tag, value)
+		}
+		data.WriteString("," + strconv.FormatFloat(value, 'g', -1, 32))
+	}
+	return alignment.setOptional(Optional{Tag: tag, Type: 'B', Data: data.String()})
+}
+
+// RemoveOptional removes the optional field with the given tag. It returns
+// false if the alignment does not have the tag.
+func (alignment *Alignment) RemoveOptional(tag string) bool {
+	for i, optional := range alignment.Optionals {
+		if optional.Tag == tag {
+			alignment.Optionals = append(alignment.Optionals[:i], alignment.Optionals[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// parseOptional parses a single TAG:TYPE:DATA optional field. The data may
+// itself contain colons, like in the SA tag or comments.
+func parseOptional(text string) (Optional, error) {
+	values := strings.SplitN(text, ":", 3)
+	if len(values) != 3 || len(values[1]) != 1 {
+		return Optional{}, fmt.Errorf("optional field %q must be TAG:TYPE:DATA", text)
+	}
+	return Optional{Tag: values[0], Type: rune(values[1][0]), Data: values[2]}, nil
+}
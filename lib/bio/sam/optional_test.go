@@ -0,0 +1,154 @@
+package sam
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOptionalGetters(t *testing.T) {
+	line := "read1\t4\t*\t0\t0\t4M\t*\t0\t0\tACGT\tIIII\tNM:i:-3\tde:f:0.0345\ttp:A:P\tSA:Z:chr1,100,+,4M,60,0;\tCO:Z:a: b\tXH:H:1AE301\tML:B:C,255,0,12\tXs:B:s,-300,2\tXf:B:f,1.5,-2e-3\tXe:B:i,\n"
+	parser, _, err := NewParser(strings.NewReader(line), DefaultMaxLineSize)
+	if err != nil {
+		t.Fatalf("Failed to create parser: %s", err)
+	}
+	alignment, err := parser.Next()
+	if err != nil {
+		t.Fatalf("Failed to parse: %s", err)
+	}
+	if err := alignment.Validate(); err != nil {
+		t.Errorf("Failed to validate: %s", err)
+	}
+
+	if value, err := alignment.GetInt("NM"); err != nil || value != -3 {
+		t.Errorf("Expected NM -3, got %d (%v)", value, err)
+	}
+	if value, err := alignment.GetFloat("de"); err != nil || value < 0.03449 || value > 0.03451 {
+		t.Errorf("Expected de 0.0345, got %f (%v)", value, err)
+	}
+	if value, err := alignment.GetString("tp"); err != nil || value != "P" {
+		t.Errorf("Expected tp P, got %s (%v)", value, err)
+	}
+	// String data may contain colons.
+	if value, err := alignment.GetString("CO"); err != nil || value != "a: b" {
+		t.Errorf("Expected CO 'a: b', got %q (%v)", value, err)
+	}
+	if value, err := alignment.GetString("SA"); err != nil || value != "chr1,100,+,4M,60,0;" {
+		t.Errorf("Wrong SA %q (%v)", value, err)
+	}
+	if value, err := alignment.GetByteArray("XH"); err != nil || !bytes.Equal(value, []byte{0x1a, 0xe3, 0x01}) {
+		t.Errorf("Wrong XH %v (%v)", value, err)
+	}
+	if value, err := alignment.GetByteArray("ML"); err != nil || !bytes.Equal(value, []byte{255, 0, 12}) {
+		t.Errorf("Wrong ML %v (%v)", value, err)
+	}
+	if value, err := alignment.GetIntArray("Xs"); err != nil || len(value) != 2 || value[0] != -300 || value[1] != 2 {
+		t.Errorf("Wrong Xs %v (%v)", value, err)
+	}
+	if value, err := alignment.GetFloatArray("Xf"); err != nil || len(value) != 2 || value[0] != 1.5 {
+		t.Errorf("Wrong Xf %v (%v)", value, err)
+	}
+	if value, err := alignment.GetIntArray("Xe"); err != nil || len(value) != 0 {
+		t.Errorf("Wrong Xe %v (%v)", value, err)
+	}
+
+	// Missing tags and wrong types are errors.
+	if _, err := alignment.GetInt("XX"); !errors.Is(err, ErrOptionalNotFound) {
+		t.Errorf("Expected ErrOptionalNotFound, got %v", err)
+	}
+	for _, err := range []error{
+		func() error { _, err := alignment.GetInt("de"); return err }(),
+		func() error { _, err := alignment.GetFloat("NM"); return err }(),
+		func() error { _, err := alignment.GetString("NM"); return err }(),
+		func() error { _, err := alignment.GetByteArray("Xs"); return err }(),
+		func() error { _, err := alignment.GetIntArray("Xf"); return err }(),
+		func() error { _, err := alignment.GetFloatArray("ML"); return err }(),
+	} {
+		if err == nil || errors.Is(err, ErrOptionalNotFound) {
+			t.Errorf("Expected type error, got %v", err)
+		}
+	}
+
+	// Written alignments are identical to the input.
+	var buffer bytes.Buffer
+	_, _ = alignment.WriteTo(&buffer)
+	if buffer.String() != line {
+		t.Errorf("Round trip does not match. Got:\n%s", buffer.String())
+	}
+}
+
+func TestOptionalSetters(t *testing.T) {
+	var alignment Alignment
+	if err := alignment.SetInt("NM", 4); err != nil {
+		t.Fatalf("Failed to set NM: %s", err)
+	}
+	_ = alignment.SetFloat("de", 0.1)
+	_ = alignment.SetString("CO", "a comment: with colons")
+	_ = alignment.SetChar("tp", 'P')
+	_ = alignment.SetByteArray("XH", []byte{0x1a, 0xe3})
+	_ = alignment.SetIntArray("ML", 'C', []int{255, 0})
+	_ = alignment.SetFloatArray("Xf", []float64{0.1, 2})
+	// Setting an existing tag replaces it in place.
+	_ = alignment.SetInt("NM", 5)
+
+	var written []string
+	for _, optional := range alignment.Optionals {
+		written = append(written, optional.String())
+	}
+	expected := "NM:i:5 de:f:0.1 CO:Z:a comment: with colons tp:A:P XH:H:1AE3 ML:B:C,255,0 Xf:B:f,0.1,2"
+	if strings.Join(written, " ") != expected {
+		t.Errorf("Expected %s, got %s", expected, strings.Join(written, " "))
+	}
+	if value, _ := alignment.GetFloat("de"); float32(value) != float32(0.1) {
+		t.Errorf("Float did not round trip: %v", value)
+	}
+
+	for _, err := range []error{
+		alignment.SetInt("N", 1),
+		alignment.SetInt("1N", 1),
+		alignment.SetInt("XI", 1<<32),
+		alignment.SetString("XZ", "tab\tseparated"),
+		alignment.SetChar("XA", ' '),
+		alignment.SetIntArray("XB", 'c', []int{128}),
+		alignment.SetIntArray("XB", 'S', []int{-1}),
+		alignment.SetIntArray("XB", 'f', []int{1}),
+	} {
+		if err == nil {
+			t.Errorf("Expected error setting invalid optional")
+		}
+	}
+	if len(alignment.Optionals) != 7 {
+		t.Errorf("Invalid optionals should not be set, got %v", alignment.Optionals)
+	}
+
+	if !alignment.RemoveOptional("de") || alignment.RemoveOptional("de") {
+		t.Errorf("Failed to remove de")
+	}
+	if _, ok := alignment.GetOptional("de"); ok || len(alignment.Optionals) != 6 {
+		t.Errorf("de was not removed")
+	}
+}
+
+func TestParseOptionalError(t *testing.T) {
+	for _, optional := range []string{"NM", "NM:i", "NM:ii:1"} {
+		parser, _, _ := NewParser(strings.NewReader("read1\t0\t*\t0\t0\t*\t*\t0\t0\t*\t*\t"+optional+"\n"), DefaultMaxLineSize)
+		if _, err := parser.Next(); err == nil {
+			t.Errorf("Expected error parsing optional %q", optional)
+		}
+	}
+}
+
+func ExampleAlignment_GetInt() {
+	file := strings.NewReader("read1\t0\t*\t0\t0\t*\t*\t0\t0\tACGT\tIIII\tNM:i:2\tMM:Z:C+m,0;\tML:B:C,230\n")
+	parser, _, _ := NewParser(file, DefaultMaxLineSize)
+	alignment, _ := parser.Next()
+
+	editDistance, _ := alignment.GetInt("NM")
+	probabilities, _ := alignment.GetIntArray("ML")
+	_ = alignment.SetInt("NM", editDistance+1)
+
+	fmt.Println(editDistance, probabilities, alignment.Optionals[0])
+	// Output: 2 [230] NM:i:3
+}
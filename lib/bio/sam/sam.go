@@ -267,7 +267,7 @@ func (alignment *Alignment) WriteTo(w io.Writer) (int64, error) {
 	var sb strings.Builder
 	_, _ = sb.WriteString(fmt.Sprintf("%s\t%d\t%s\t%d\t%d\t%s\t%s\t%d\t%d\t%s\t%s", alignment.QNAME, alignment.FLAG, alignment.RNAME, alignment.POS, alignment.MAPQ, alignment.CIGAR, alignment.RNEXT, alignment.PNEXT, alignment.TLEN, alignment.SEQ, alignment.QUAL))
 	for _, optional := range alignment.Optionals {
-		_, _ = sb.WriteString(fmt.Sprintf("\t%s:%c:%s", optional.Tag, optional.Type, optional.Data))
+		_, _ = sb.WriteString("\t" + optional.String())
 	}
 	_, _ = sb.WriteString("\n")
 	newWrittenBytes, err := w.Write([]byte(sb.String()))
@@ -275,7 +275,7 @@ func (alignment *Alignment) WriteTo(w io.Writer) (int64, error) {
 }
 
 // Alignment_Validate validates an alignment as valid, given the REGEXP/range
-// defined in the SAM document. Not implemented yet.
+// defined in the SAM document.
 func (alignment *Alignment) Validate() error {
 	/* The following rules apply:
 
@@ -290,6 +290,8 @@ func (alignment *Alignment) Validate() error {
 	9 TLEN	Int		[−231 + 1, 231 − 1]			observed Template LENgth
 	10 SEQ	String	\*|[A-Za-z=.]+				segment SEQuence
 	11 QUAL	String	[!-~]+						ASCII of Phred-scaled base QUALity+33
+
+	Optional fields must match their type, and each tag may only appear once.
 	*/
 	// 1. Validate QNAME
 	qnameRegex := `^[!-?A-~]{1,254}$`
@@ -343,9 +345,102 @@ func (alignment *Alignment) Validate() error {
 		return errors.New("Invalid QUAL: must match " + qualRegex)
 	}
 
+	// 12. Validate optional fields
+	tags := make(map[string]bool, len(alignment.Optionals))
+	for _, optional := range alignment.Optionals {
+		if err := optional.Validate(); err != nil {
+			return err
+		}
+		if tags[optional.Tag] {
+			return fmt.Errorf("Invalid optional %s: tag must only appear once", optional.Tag)
+		}
+		tags[optional.Tag] = true
+	}
+
 	return nil
 }
 
+// newHeader returns a Header with all maps and slices initialized.
+func newHeader() Header {
+	return Header{
+		HD: make(map[string]string),
+		SQ: []map[string]string{},
+		RG: []map[string]string{},
+		PG: []map[string]string{},
+		CO: []string{},
+	}
+}
+
+// parseLine parses a single header line (one beginning with @) into the
+// header.
+func (header *Header) parseLine(line string) error {
+	values := strings.Split(line, "\t")
+	// CO lines are unique in that they are just strings. So we try to parse them
+	// first. We include the entire comment line for these.
+	if values[0] == "@CO" {
+		header.CO = append(header.CO, line)
+		return nil
+	}
+
+	// Every other line has an identical form ( TAG:DATA ), so we can parse that
+	// first and then just apply it to the respect top level tag. Only the
+	// first colon splits TAG from DATA, since DATA (for example, @PG CL) may
+	// itself contain colons.
+	genericMap := make(map[string]string)
+	for _, value := range values[1:] {
+		valueSplit := strings.SplitN(value, ":", 2)
+		if len(valueSplit) != 2 {
+			return fmt.Errorf("Expected TAG:DATA pair, got: %s", value)
+		}
+		genericMap[valueSplit[0]] = valueSplit[1]
+	}
+	switch values[0] {
+	case "@HD":
+		header.HD = genericMap
+	case "@SQ":
+		header.SQ = append(header.SQ, genericMap)
+	case "@RG":
+		header.RG = append(header.RG, genericMap)
+	case "@PG":
+		header.PG = append(header.PG, genericMap)
+	default:
+		return fmt.Errorf("Should contain @HD, @SQ, @RG, @PG or @CO as top level tags, but they weren't found. Line text: %s", line)
+	}
+	return nil
+}
+
+// isHDLine returns whether a header line is an @HD line.
+func isHDLine(line string) bool {
+	return line == "@HD" || strings.HasPrefix(line, "@HD\t")
+}
+
+// ParseHeader parses the text of a SAM header, such as the text header
+// stored at the beginning of a BAM file. Unlike NewParser, ParseHeader does
+// not require the @HD line to be present.
+func ParseHeader(text string) (Header, error) {
+	header := newHeader()
+	var hdParsed bool
+	for lineNumber, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] != '@' {
+			return Header{}, fmt.Errorf("Line %d is not a header line. Line text: %s", lineNumber+1, line)
+		}
+		if isHDLine(line) {
+			if hdParsed {
+				return Header{}, fmt.Errorf("Line %d is a second @HD line. There must be only one @HD line. Line text: %s", lineNumber+1, line)
+			}
+			hdParsed = true
+		}
+		if err := header.parseLine(line); err != nil {
+			return Header{}, fmt.Errorf("Line %d had error: %w", lineNumber+1, err)
+		}
+	}
+	return header, nil
+}
+
 // Parser is a sam file parser that provide sample control over reading sam
 // alignments. It should be initialized with NewParser.
 type Parser struct {
@@ -371,14 +466,8 @@ func NewParser(r io.Reader, maxLineSize int) (*Parser, Header, error) {
 	parser := &Parser{
 		reader: *bufio.NewReaderSize(r, maxLineSize),
 	}
-	var header Header
+	header := newHeader()
 	var hdParsed bool
-	// Initialize header maps
-	header.HD = make(map[string]string)
-	header.SQ = []map[string]string{}
-	header.RG = []map[string]string{}
-	header.PG = []map[string]string{}
-	header.CO = []string{}
 
 	// We need to first read the header before returning the parser to the
 	// user for analyzing alignments.
@@ -408,49 +497,17 @@ func NewParser(r io.Reader, maxLineSize int) (*Parser, Header, error) {
 			parser.firstLine = line
 			break
 		}
-		values := strings.Split(line, "\t")
-		if len(values) < 1 {
-			return parser, Header{}, fmt.Errorf("Line %d should contain at least 1 value. Got: %d. Line text: %s", parser.line, len(values), line)
-		}
-
-		// If we haven't parsed HD, it is always the first line: lets parse it.
-		if !hdParsed {
-			if values[0] != "@HD" {
-				return parser, Header{}, fmt.Errorf("First line (%d) should always contain @HD first. Line text: %s", parser.line, line)
-			}
-			// Now parse the rest of the HD header
-			for _, value := range values[1:] {
-				valueSplit := strings.Split(value, ":")
-				header.HD[valueSplit[0]] = valueSplit[1]
-			}
-			hdParsed = true
-			continue
+		// If we haven't parsed HD, it is always the first line, and there is
+		// only one.
+		if !hdParsed && !isHDLine(line) {
+			return parser, Header{}, fmt.Errorf("First line (%d) should always contain @HD first. Line text: %s", parser.line, line)
 		}
-
-		// CO lines are unique in that they are just strings. So we try to parse them
-		// first. We include the entire comment line for these.
-		if values[0] == "@CO" {
-			header.CO = append(header.CO, line)
-			continue
-		}
-
-		// HD/CO lines have been successfully parsed, now we work on SQ, RG, and PG.
-		// Luckily, each one has an identical form ( TAG:DATA ), so we can parse that
-		// first and then just apply it to the respect top level tag.
-		genericMap := make(map[string]string)
-		for _, value := range values[1:] {
-			valueSplit := strings.Split(value, ":")
-			genericMap[valueSplit[0]] = valueSplit[1]
+		if hdParsed && isHDLine(line) {
+			return parser, Header{}, fmt.Errorf("Line %d is a second @HD line. There must be only one @HD line. Line text: %s", parser.line, line)
 		}
-		switch values[0] {
-		case "@SQ":
-			header.SQ = append(header.SQ, genericMap)
-		case "@RG":
-			header.RG = append(header.RG, genericMap)
-		case "@PG":
-			header.PG = append(header.PG, genericMap)
-		default:
-			return parser, Header{}, fmt.Errorf("Line %d should contain @SQ, @RG, @PG or @CO as top level tags, but they weren't found. Line text: %s", parser.line, line)
+		hdParsed = true
+		if err := header.parseLine(line); err != nil {
+			return parser, Header{}, fmt.Errorf("Line %d had error: %w", parser.line, err)
 		}
 	}
 	parser.FileHeader = header
@@ -524,8 +581,11 @@ func (p *Parser) Next() (Alignment, error) {
 
 	var optionals []Optional
 	for _, value := range values[11:] {
-		valueSplit := strings.Split(value, ":")
-		optionals = append(optionals, Optional{Tag: valueSplit[0], Type: rune(valueSplit[1][0]), Data: valueSplit[2]})
+		optional, err := parseOptional(value)
+		if err != nil {
+			return alignment, fmt.Errorf("Line %d had error: %s", p.line, err)
+		}
+		optionals = append(optionals, optional)
 	}
 	alignment.Optionals = optionals
 	return alignment, nil
@@ -123,6 +123,20 @@ func TestValidate(t *testing.T) {
 			func(a *Alignment) { a.QUAL = "qual string with lower case or invalid characters" },
 			"Invalid QUAL",
 		},
+		{ // Invalid optional data
+			func(a *Alignment) { a.Optionals = []Optional{{Tag: "NM", Type: 'i', Data: "1.5"}} },
+			"Invalid optional NM:i",
+		},
+		{ // Invalid optional type
+			func(a *Alignment) { a.Optionals = []Optional{{Tag: "NM", Type: 'x', Data: "1"}} },
+			"Invalid optional NM:x",
+		},
+		{ // Duplicate optional tag
+			func(a *Alignment) {
+				a.Optionals = []Optional{{Tag: "NM", Type: 'i', Data: "1"}, {Tag: "NM", Type: 'i', Data: "2"}}
+			},
+			"Invalid optional NM",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -296,3 +310,22 @@ func TestPrimary(t *testing.T) {
 		})
 	}
 }
+
+func TestDuplicateHD(t *testing.T) {
+	text := "@HD\tVN:1.6\tSO:coordinate\n@SQ\tSN:chr1\tLN:100\n@HD\tVN:1.6\tSO:unsorted\n"
+	if _, err := ParseHeader(text); err == nil {
+		t.Errorf("ParseHeader should fail on a second @HD line")
+	}
+	_, _, err := NewParser(strings.NewReader(text+"read1\t0\tchr1\t1\t60\t4M\t*\t0\t0\tATGC\t*\n"), DefaultMaxLineSize)
+	if err == nil {
+		t.Errorf("NewParser should fail on a second @HD line")
+	}
+
+	header, err := ParseHeader("@HD\tVN:1.6\tSO:coordinate\n@SQ\tSN:chr1\tLN:100\n")
+	if err != nil {
+		t.Fatalf("Got error: %s", err)
+	}
+	if header.HD["SO"] != "coordinate" {
+		t.Errorf("Expected SO coordinate, got %s", header.HD["SO"])
+	}
+}
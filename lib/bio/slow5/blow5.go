@@ -0,0 +1,605 @@
+package slow5
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/koeng101/dnadesign/lib/bio/slow5/svb"
+)
+
+/******************************************************************************
+Oct 16, 2026
+
+blow5 parser and writer begin here. Specification below:
+https://hasindu2008.github.io/slow5specs/slow5-v1.0.0.pdf
+
+blow5 is the binary version of slow5. It holds the same header and reads, but
+numbers are stored as little endian binary values instead of text, and each
+read can be compressed. A blow5 file is laid out like this:
+
+	magic number     "BLOW5\x01"
+	version          3 uint8s, major.minor.patch
+	record press     uint8, the compression of each record
+	num read groups  uint32
+	signal press     uint8, the compression of raw signals (version >= 0.2.0)
+	padding          zeros, up to byte 64
+	header size      uint32
+	header           the slow5 header, without #slow5_version and #num_read_groups
+	records          uint64 size, then the (compressed) record
+	eof marker       "5WOLB"
+
+Records are compressed one by one, so that any read can be decompressed on its
+own. Raw signals are usually compressed with svb-zd: the differences between
+consecutive signals are zigzag encoded and then compressed with StreamVByte.
+Since nanopore signal changes slowly, most differences fit in a single byte,
+which is where most of the space saving of blow5 comes from.
+
+Records can also be compressed with zstd by slow5tools. There is no zstd
+implementation in the Go standard library, so zstd blow5 files can't be read
+or written here. Convert them with `slow5tools view -c zlib` first.
+
+Records have the columns of the #read_id line of the header. Auxiliary
+columns that Read has no field for are skipped, using their types from the
+#char* line. Arrays, like char* and float*, are stored after their uint64
+length, and svb-zd raw signals after the uint64 length of the compressed
+signal. The tests only use blow5 files written by this package, so this
+layout has not been checked against files written by slow5tools.
+
+******************************************************************************/
+
+// Compression is a compression method of blow5 records or raw signals. The
+// values are the ones used in blow5 headers.
+type Compression uint8
+
+const (
+	CompressionNone  Compression = 0 // no compression
+	CompressionZlib  Compression = 1 // zlib record compression
+	CompressionSvbZd Compression = 2 // StreamVByte zigzag delta raw signal compression
+	CompressionZstd  Compression = 3 // zstd record compression. Not supported.
+)
+
+// ErrZstdUnsupported is returned when reading or writing blow5 files with zstd
+// record compression.
+var ErrZstdUnsupported = errors.New("zstd compressed blow5 records are not supported. Use zlib instead")
+
+var (
+	blow5Magic     = []byte{'B', 'L', 'O', 'W', '5', 1}
+	blow5EOFMarker = []byte{'5', 'W', 'O', 'L', 'B'}
+	blow5Version   = [3]uint8{0, 2, 0}
+)
+
+// blow5HeaderSize is the size of the fixed part of blow5 headers, before
+// the header size.
+const blow5HeaderSize = 64
+
+// Blow5Parser is a parser of blow5 files. It is initialized with
+// NewBlow5Parser.
+type Blow5Parser struct {
+	reader            *bufio.Reader
+	header            Header
+	headerMap         map[int]string
+	columnTypes       map[int]string
+	endReasonMap      map[int]string
+	recordCompression Compression
+	signalCompression Compression
+	offset            int64 // position of the next record in the file
+}
+
+// NewBlow5Parser parses the header of a blow5 file, and returns a parser of
+// its reads.
+func NewBlow5Parser(r io.Reader) (*Blow5Parser, error) {
+	parser := &Blow5Parser{reader: bufio.NewReader(r)}
+	fixedHeader := make([]byte, blow5HeaderSize+4)
+	if _, err := io.ReadFull(parser.reader, fixedHeader); err != nil {
+		return parser, fmt.Errorf("Failed to read blow5 header: %w", err)
+	}
+	if !bytes.Equal(fixedHeader[:len(blow5Magic)], blow5Magic) {
+		return parser, fmt.Errorf("Not a blow5 file. Got magic number %q", fixedHeader[:len(blow5Magic)])
+	}
+	version := fmt.Sprintf("%d.%d.%d", fixedHeader[6], fixedHeader[7], fixedHeader[8])
+	parser.recordCompression = Compression(fixedHeader[9])
+	numReadGroups := binary.LittleEndian.Uint32(fixedHeader[10:14])
+	// Signal compression was added in version 0.2.0. Before that, this byte
+	// is padding, which is always zero.
+	parser.signalCompression = Compression(fixedHeader[14])
+	if err := checkCompression(parser.recordCompression, parser.signalCompression); err != nil {
+		return parser, err
+	}
+
+	headerText := make([]byte, binary.LittleEndian.Uint32(fixedHeader[blow5HeaderSize:]))
+	if _, err := io.ReadFull(parser.reader, headerText); err != nil {
+		return parser, fmt.Errorf("Failed to read blow5 header text: %w", err)
+	}
+	parser.offset = int64(len(fixedHeader) + len(headerText))
+
+	// The text of the header is a slow5 header without its first two lines,
+	// which are stored in the fixed part of the blow5 header.
+	slow5Header := fmt.Sprintf("#slow5_version\t%s\n#num_read_groups\t%d\n%s", version, numReadGroups, headerText)
+	headerParser, err := NewParser(strings.NewReader(slow5Header), len(slow5Header)+1)
+	if err != nil {
+		return parser, fmt.Errorf("Failed to parse blow5 header text: %w", err)
+	}
+	parser.header = headerParser.header
+	parser.headerMap = headerParser.headerMap
+	parser.columnTypes = headerParser.columnTypes
+	parser.endReasonMap = headerParser.endReasonMap
+	return parser, nil
+}
+
+// checkCompression returns an error for unknown or unsupported compression
+// methods.
+func checkCompression(recordCompression, signalCompression Compression) error {
+	switch recordCompression {
+	case CompressionNone, CompressionZlib:
+	case CompressionZstd:
+		return ErrZstdUnsupported
+	default:
+		return fmt.Errorf("Unknown blow5 record compression %d", recordCompression)
+	}
+	switch signalCompression {
+	case CompressionNone, CompressionSvbZd:
+	default:
+		return fmt.Errorf("Unknown blow5 signal compression %d", signalCompression)
+	}
+	return nil
+}
+
+// Header returns the header of the blow5 file.
+func (parser *Blow5Parser) Header() (Header, error) {
+	return parser.header, nil
+}
+
+// Next parses the next read of the blow5 file. It returns io.EOF after the
+// last read.
+func (parser *Blow5Parser) Next() (Read, error) {
+	offset, record, err := parser.nextRecord()
+	if err != nil {
+		return Read{}, err
+	}
+	read, err := parser.parseRecord(record)
+	if err != nil {
+		return Read{}, fmt.Errorf("Failed to parse blow5 record at byte %d: %w", offset, err)
+	}
+	return read, nil
+}
+
+// nextRecord reads the next record of the blow5 file, without decompressing
+// it. It returns the position of the record in the file, including its size.
+func (parser *Blow5Parser) nextRecord() (int64, []byte, error) {
+	// The eof marker is shorter than the size of a record, so it has to be
+	// checked before reading the size.
+	start, _ := parser.reader.Peek(len(blow5EOFMarker))
+	if bytes.Equal(start, blow5EOFMarker) {
+		return parser.offset, nil, io.EOF
+	}
+	offset := parser.offset
+	var sizeBytes [8]byte
+	if _, err := io.ReadFull(parser.reader, sizeBytes[:]); err != nil {
+		if err == io.EOF {
+			return offset, nil, fmt.Errorf("Blow5 file ended without an eof marker: %w", io.ErrUnexpectedEOF)
+		}
+		return offset, nil, err
+	}
+	record := make([]byte, binary.LittleEndian.Uint64(sizeBytes[:]))
+	if _, err := io.ReadFull(parser.reader, record); err != nil {
+		return offset, nil, fmt.Errorf("Failed to read blow5 record at byte %d: %w", offset, err)
+	}
+	parser.offset += int64(len(sizeBytes) + len(record))
+	return offset, record, nil
+}
+
+// decompressRecord decompresses a blow5 record.
+func (parser *Blow5Parser) decompressRecord(record []byte) ([]byte, error) {
+	if parser.recordCompression != CompressionZlib {
+		return record, nil
+	}
+	zlibReader, err := zlib.NewReader(bytes.NewReader(record))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zlibReader)
+}
+
+// parseRecord decompresses and parses a single blow5 record.
+func (parser *Blow5Parser) parseRecord(record []byte) (Read, error) {
+	record, err := parser.decompressRecord(record)
+	if err != nil {
+		return Read{}, err
+	}
+	decoder := recordDecoder{data: record}
+	newRead := Read{EndReasonMap: parser.header.HeaderValues[0].EndReasonHeaderMap}
+	for fieldIndex := 0; fieldIndex < len(parser.headerMap); fieldIndex++ {
+		field := parser.headerMap[fieldIndex]
+		switch field {
+		case "read_id":
+			newRead.ReadID = string(decoder.bytes(int(decoder.uint16())))
+		case "read_group":
+			newRead.ReadGroupID = decoder.uint32()
+		case "digitisation":
+			newRead.Digitisation = decoder.float64()
+		case "offset":
+			newRead.Offset = decoder.float64()
+		case "range":
+			newRead.Range = decoder.float64()
+		case "sampling_rate":
+			newRead.SamplingRate = decoder.float64()
+		case "len_raw_signal":
+			newRead.LenRawSignal = decoder.uint64()
+		case "raw_signal":
+			if parser.signalCompression == CompressionSvbZd {
+				compressed := decoder.bytes(int(decoder.uint64()))
+				rawSignal, err := svbZdDecompress(compressed, int(newRead.LenRawSignal))
+				if err != nil {
+					return Read{}, err
+				}
+				newRead.RawSignal = rawSignal
+				continue
+			}
+			signal := decoder.bytes(int(newRead.LenRawSignal) * 2)
+			newRead.RawSignal = make([]int16, len(signal)/2)
+			for i := range newRead.RawSignal {
+				newRead.RawSignal[i] = int16(binary.LittleEndian.Uint16(signal[i*2:]))
+			}
+		// Missing auxiliary values are stored as the maximum value of their
+		// type, or NaN. They are read as zero, like "." in slow5 files.
+		case "start_time":
+			if startTime := decoder.uint64(); startTime != math.MaxUint64 {
+				newRead.StartTime = startTime
+			}
+		case "read_number":
+			if readNumber := int32(decoder.uint32()); readNumber != math.MaxInt32 {
+				newRead.ReadNumber = readNumber
+			}
+		case "start_mux":
+			if startMux := decoder.uint8(); startMux != math.MaxUint8 {
+				newRead.StartMux = startMux
+			}
+		case "median_before":
+			if medianBefore := decoder.float64(); !math.IsNaN(medianBefore) {
+				newRead.MedianBefore = medianBefore
+			}
+		case "end_reason":
+			endReason := decoder.uint8()
+			if endReason == math.MaxUint8 {
+				continue
+			}
+			if _, ok := parser.endReasonMap[int(endReason)]; !ok {
+				return Read{}, fmt.Errorf("End reason out of range. Got '%d' in read %s. Cannot find valid enum reason", endReason, newRead.ReadID)
+			}
+			newRead.EndReason = parser.endReasonMap[int(endReason)]
+		case "channel_number":
+			newRead.ChannelNumber = string(decoder.bytes(int(decoder.uint64())))
+		default:
+			// Other auxiliary fields, like those added by newer versions of
+			// MinKNOW, are skipped using the type of their column.
+			if err := decoder.skip(parser.columnTypes[fieldIndex]); err != nil {
+				return Read{}, fmt.Errorf("Unknown field to parser '%s' found in blow5 header: %w", field, err)
+			}
+		}
+	}
+	if decoder.err != nil {
+		return Read{}, decoder.err
+	}
+	if len(decoder.data) != 0 {
+		return Read{}, fmt.Errorf("Record of read %s has %d extra bytes", newRead.ReadID, len(decoder.data))
+	}
+	return newRead, nil
+}
+
+// recordDecoder reads little endian values from a blow5 record. Reading
+// past the end of the record sets err, and returns zero values.
+type recordDecoder struct {
+	data []byte
+	err  error
+}
+
+func (decoder *recordDecoder) bytes(n int) []byte {
+	if decoder.err != nil || n < 0 || n > len(decoder.data) {
+		if decoder.err == nil {
+			decoder.err = fmt.Errorf("Record is too short: %w", io.ErrUnexpectedEOF)
+		}
+		return make([]byte, 8)
+	}
+	value := decoder.data[:n]
+	decoder.data = decoder.data[n:]
+	return value
+}
+
+func (decoder *recordDecoder) uint8() uint8   { return decoder.bytes(1)[0] }
+func (decoder *recordDecoder) uint16() uint16 { return binary.LittleEndian.Uint16(decoder.bytes(2)) }
+func (decoder *recordDecoder) uint32() uint32 { return binary.LittleEndian.Uint32(decoder.bytes(4)) }
+func (decoder *recordDecoder) uint64() uint64 { return binary.LittleEndian.Uint64(decoder.bytes(8)) }
+func (decoder *recordDecoder) float64() float64 {
+	return math.Float64frombits(decoder.uint64())
+}
+
+// skip skips a value of a slow5 column type, such as uint32_t, or an array of
+// one, such as char* or float*, which is stored after its uint64 length.
+func (decoder *recordDecoder) skip(columnType string) error {
+	if elementType, isArray := strings.CutSuffix(columnType, "*"); isArray {
+		size, ok := columnTypeSizes[elementType]
+		if !ok {
+			return fmt.Errorf("unknown column type %q", columnType)
+		}
+		decoder.bytes(int(decoder.uint64()) * size)
+		return nil
+	}
+	size, ok := columnTypeSizes[columnType]
+	if strings.HasPrefix(columnType, "enum{") {
+		size, ok = 1, true
+	}
+	if !ok {
+		return fmt.Errorf("unknown column type %q", columnType)
+	}
+	decoder.bytes(size)
+	return nil
+}
+
+// columnTypeSizes are the sizes in bytes of slow5 column types in blow5
+// records.
+var columnTypeSizes = map[string]int{
+	"char": 1, "int8_t": 1, "uint8_t": 1,
+	"int16_t": 2, "uint16_t": 2,
+	"int32_t": 4, "uint32_t": 4, "float": 4,
+	"int64_t": 8, "uint64_t": 8, "double": 8,
+}
+
+// Blow5Writer writes reads to a blow5 file. It is initialized with
+// NewBlow5Writer, and must be closed with Close to write the eof marker.
+type Blow5Writer struct {
+	writer            io.Writer
+	header            Header
+	columns           []string // columns of the #read_id line of the header
+	recordCompression Compression
+	signalCompression Compression
+}
+
+// NewBlow5Writer writes the header of a blow5 file to w, and returns a writer
+// for its reads. Reads are usually written with CompressionZlib record
+// compression and CompressionSvbZd signal compression, which is the default
+// of slow5tools.
+func NewBlow5Writer(w io.Writer, header Header, recordCompression, signalCompression Compression) (*Blow5Writer, error) {
+	writer := &Blow5Writer{writer: w, header: header, recordCompression: recordCompression, signalCompression: signalCompression}
+	if err := checkCompression(recordCompression, signalCompression); err != nil {
+		return writer, err
+	}
+	if len(header.HeaderValues) == 0 {
+		return writer, errors.New("Blow5 header must have at least one read group")
+	}
+
+	// The header text is the slow5 header without its first two lines.
+	var slow5Header bytes.Buffer
+	if _, err := header.WriteTo(&slow5Header); err != nil {
+		return writer, err
+	}
+	headerLines := strings.SplitN(slow5Header.String(), "\n", 3)
+	headerText := headerLines[len(headerLines)-1]
+
+	// records are written in the order of the columns of the header.
+	headerParser, err := NewParser(bytes.NewReader(slow5Header.Bytes()), slow5Header.Len()+1)
+	if err != nil {
+		return writer, fmt.Errorf("Failed to parse the columns of the blow5 header: %w", err)
+	}
+	for columnIndex := 0; columnIndex < len(headerParser.headerMap); columnIndex++ {
+		writer.columns = append(writer.columns, headerParser.headerMap[columnIndex])
+	}
+
+	fixedHeader := make([]byte, blow5HeaderSize+4)
+	copy(fixedHeader, blow5Magic)
+	copy(fixedHeader[6:9], blow5Version[:])
+	fixedHeader[9] = byte(recordCompression)
+	binary.LittleEndian.PutUint32(fixedHeader[10:14], uint32(len(header.HeaderValues)))
+	fixedHeader[14] = byte(signalCompression)
+	binary.LittleEndian.PutUint32(fixedHeader[blow5HeaderSize:], uint32(len(headerText)))
+	_, err = w.Write(append(fixedHeader, headerText...))
+	return writer, err
+}
+
+// Write writes a read to the blow5 file.
+func (writer *Blow5Writer) Write(read Read) error {
+	record, err := writer.buildRecord(read)
+	if err != nil {
+		return err
+	}
+	sizeBytes := binary.LittleEndian.AppendUint64(nil, uint64(len(record)))
+	_, err = writer.writer.Write(append(sizeBytes, record...))
+	return err
+}
+
+// Close writes the eof marker of the blow5 file. It does not close the
+// underlying io.Writer.
+func (writer *Blow5Writer) Close() error {
+	_, err := writer.writer.Write(blow5EOFMarker)
+	return err
+}
+
+// buildRecord builds the (compressed) record of a read. Fields are written in
+// the order of the columns of the header.
+func (writer *Blow5Writer) buildRecord(read Read) ([]byte, error) {
+	if len(read.ReadID) > math.MaxUint16 {
+		return nil, fmt.Errorf("Read id %s... is longer than %d characters", read.ReadID[:32], math.MaxUint16)
+	}
+	endReasonMap := read.EndReasonMap
+	if endReasonMap == nil {
+		endReasonMap = writer.header.HeaderValues[0].EndReasonHeaderMap
+	}
+	endReason, ok := endReasonMap[read.EndReason]
+	if !ok && read.EndReason != "" {
+		return nil, fmt.Errorf("Read %s has end reason %s, which isn't in the end reasons of the header", read.ReadID, read.EndReason)
+	}
+
+	var record []byte
+	for _, column := range writer.columns {
+		switch column {
+		case "read_id":
+			record = binary.LittleEndian.AppendUint16(record, uint16(len(read.ReadID)))
+			record = append(record, read.ReadID...)
+		case "read_group":
+			record = binary.LittleEndian.AppendUint32(record, read.ReadGroupID)
+		case "digitisation":
+			record = binary.LittleEndian.AppendUint64(record, math.Float64bits(read.Digitisation))
+		case "offset":
+			record = binary.LittleEndian.AppendUint64(record, math.Float64bits(read.Offset))
+		case "range":
+			record = binary.LittleEndian.AppendUint64(record, math.Float64bits(read.Range))
+		case "sampling_rate":
+			record = binary.LittleEndian.AppendUint64(record, math.Float64bits(read.SamplingRate))
+		case "len_raw_signal":
+			// the length of the signal is always the actual number of
+			// signals, since it is needed to decode them.
+			record = binary.LittleEndian.AppendUint64(record, uint64(len(read.RawSignal)))
+		case "raw_signal":
+			if writer.signalCompression == CompressionSvbZd {
+				compressed := svbZdCompress(read.RawSignal)
+				record = binary.LittleEndian.AppendUint64(record, uint64(len(compressed)))
+				record = append(record, compressed...)
+				continue
+			}
+			for _, signal := range read.RawSignal {
+				record = binary.LittleEndian.AppendUint16(record, uint16(signal))
+			}
+		case "start_time":
+			record = binary.LittleEndian.AppendUint64(record, read.StartTime)
+		case "read_number":
+			record = binary.LittleEndian.AppendUint32(record, uint32(read.ReadNumber))
+		case "start_mux":
+			record = append(record, read.StartMux)
+		case "median_before":
+			record = binary.LittleEndian.AppendUint64(record, math.Float64bits(read.MedianBefore))
+		case "end_reason":
+			record = append(record, uint8(endReason))
+		case "channel_number":
+			record = binary.LittleEndian.AppendUint64(record, uint64(len(read.ChannelNumber)))
+			record = append(record, read.ChannelNumber...)
+		default:
+			return nil, fmt.Errorf("Column %s of the blow5 header has no field in Read", column)
+		}
+	}
+
+	if writer.recordCompression == CompressionZlib {
+		var compressed bytes.Buffer
+		zlibWriter := zlib.NewWriter(&compressed)
+		if _, err := zlibWriter.Write(record); err != nil {
+			return nil, err
+		}
+		if err := zlibWriter.Close(); err != nil {
+			return nil, err
+		}
+		record = compressed.Bytes()
+	}
+	return record, nil
+}
+
+// svbZdCompress compresses raw signal with svb-zd: the difference of each
+// signal from the previous one is zigzag encoded, so that small negative
+// differences are small numbers, and then compressed with StreamVByte. The
+// result starts with the number of signals as a uint32.
+func svbZdCompress(rawSignal []int16) []byte {
+	zigzag := make([]uint32, len(rawSignal))
+	var previous int32
+	for i, signal := range rawSignal {
+		delta := int32(signal) - previous
+		zigzag[i] = uint32(delta<<1) ^ uint32(delta>>31)
+		previous = int32(signal)
+	}
+	return svb.NewFromUint32(zigzag).Bytes()
+}
+
+// svbZdDecompress decompresses raw signal compressed with svbZdCompress.
+func svbZdDecompress(compressed []byte, lenRawSignal int) ([]int16, error) {
+	if len(compressed) < 4 {
+		return nil, errors.New("Compressed raw signal is too short")
+	}
+	count := int(binary.LittleEndian.Uint32(compressed))
+	if count != lenRawSignal {
+		return nil, fmt.Errorf("Compressed raw signal has %d signals, but len_raw_signal is %d", count, lenRawSignal)
+	}
+	masks := compressed[4:]
+	if len(masks) < (count+3)/4 {
+		return nil, errors.New("Compressed raw signal is too short")
+	}
+	data := masks[(count+3)/4:]
+	masks = masks[:(count+3)/4]
+	// The data of each value is 1 to 4 bytes long, as given by its 2 bit code
+	// in the masks.
+	dataLength := 0
+	for i := 0; i < count; i++ {
+		dataLength += int(masks[i/4]>>((i%4)*2)&3) + 1
+	}
+	if len(data) != dataLength {
+		return nil, fmt.Errorf("Compressed raw signal has %d data bytes, expected %d", len(data), dataLength)
+	}
+	zigzag := make([]uint32, count)
+	svb.Uint32Decode32(masks, data, zigzag)
+	rawSignal := make([]int16, count)
+	var previous int32
+	for i, value := range zigzag {
+		previous += int32(value>>1) ^ -int32(value&1)
+		rawSignal[i] = int16(previous)
+	}
+	return rawSignal, nil
+}
+
+/******************************************************************************
+
+slow5 <-> blow5 conversion
+
+******************************************************************************/
+
+// Slow5ToBlow5 converts a slow5 file to a blow5 file, compressing records
+// and raw signals with the given methods.
+func Slow5ToBlow5(r io.Reader, w io.Writer, maxLineSize int, recordCompression, signalCompression Compression) error {
+	parser, err := NewParser(r, maxLineSize)
+	if err != nil {
+		return err
+	}
+	header, _ := parser.Header()
+	writer, err := NewBlow5Writer(w, header, recordCompression, signalCompression)
+	if err != nil {
+		return err
+	}
+	for {
+		read, err := parser.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		if err := writer.Write(read); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// Blow5ToSlow5 converts a blow5 file to a slow5 file.
+func Blow5ToSlow5(r io.Reader, w io.Writer) error {
+	parser, err := NewBlow5Parser(r)
+	if err != nil {
+		return err
+	}
+	header, _ := parser.Header()
+	if _, err := header.WriteTo(w); err != nil {
+		return err
+	}
+	for {
+		read, err := parser.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if _, err := read.WriteTo(w); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package slow5
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func readSlow5(t *testing.T, path string) (Header, []Read) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	parser, err := NewParser(file, maxLineSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _ := parser.Header()
+	var reads []Read
+	for {
+		read, err := parser.Next()
+		if errors.Is(err, io.EOF) {
+			return header, reads
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		reads = append(reads, read)
+	}
+}
+
+func readBlow5(t *testing.T, r io.Reader) (Header, []Read) {
+	t.Helper()
+	parser, err := NewBlow5Parser(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _ := parser.Header()
+	var reads []Read
+	for {
+		read, err := parser.Next()
+		if errors.Is(err, io.EOF) {
+			return header, reads
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		reads = append(reads, read)
+	}
+}
+
+func TestBlow5RoundTrip(t *testing.T) {
+	header, reads := readSlow5(t, "data/example.slow5")
+	for _, compression := range [][2]Compression{
+		{CompressionNone, CompressionNone},
+		{CompressionZlib, CompressionNone},
+		{CompressionNone, CompressionSvbZd},
+		{CompressionZlib, CompressionSvbZd},
+	} {
+		var blow5 bytes.Buffer
+		writer, err := NewBlow5Writer(&blow5, header, compression[0], compression[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, read := range reads {
+			if err := writer.Write(read); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		data := blow5.Bytes()
+		if !bytes.HasPrefix(data, blow5Magic) || !bytes.HasSuffix(data, blow5EOFMarker) {
+			t.Errorf("compression %v: missing magic number or eof marker", compression)
+		}
+		if data[9] != byte(compression[0]) || data[14] != byte(compression[1]) || binary.LittleEndian.Uint32(data[10:]) != 1 {
+			t.Errorf("compression %v: unexpected fixed header %v", compression, data[:15])
+		}
+
+		blow5Header, blow5Reads := readBlow5(t, &blow5)
+		if diff := cmp.Diff(header, blow5Header); diff != "" {
+			t.Errorf("compression %v: header changed (-want +got):\n%s", compression, diff)
+		}
+		if diff := cmp.Diff(reads, blow5Reads); diff != "" {
+			t.Errorf("compression %v: reads changed (-want +got):\n%s", compression, diff)
+		}
+	}
+}
+
+func TestSlow5Blow5Conversion(t *testing.T) {
+	example, err := os.ReadFile("data/example.slow5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blow5 bytes.Buffer
+	if err := Slow5ToBlow5(bytes.NewReader(example), &blow5, maxLineSize, CompressionZlib, CompressionSvbZd); err != nil {
+		t.Fatal(err)
+	}
+	if blow5.Len() >= len(example) {
+		t.Errorf("blow5 file (%d bytes) should be smaller than slow5 file (%d bytes)", blow5.Len(), len(example))
+	}
+	var slow5 bytes.Buffer
+	if err := Blow5ToSlow5(&blow5, &slow5); err != nil {
+		t.Fatal(err)
+	}
+	if slow5.String() != string(example) {
+		t.Errorf("slow5 file changed after conversion to blow5 and back")
+	}
+}
+
+func TestSvbZd(t *testing.T) {
+	rawSignal := []int16{430, 472, 463, -32768, 32767, 0}
+	compressed := svbZdCompress(rawSignal)
+	// 430 is zigzag encoded as 860, which takes 2 bytes, and 472-430=42 as
+	// 84, which takes 1.
+	if binary.LittleEndian.Uint32(compressed) != 6 || compressed[4]&0b1111 != 0b0001 || !bytes.Equal(compressed[6:9], []byte{0x5c, 0x03, 84}) {
+		t.Errorf("unexpected svb-zd compression %v", compressed)
+	}
+	decompressed, err := svbZdDecompress(compressed, len(rawSignal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(rawSignal, decompressed); diff != "" {
+		t.Errorf("signal changed after svb-zd compression (-want +got):\n%s", diff)
+	}
+	if _, err := svbZdDecompress(compressed, 5); err == nil {
+		t.Errorf("expected an error for a wrong signal length")
+	}
+	if _, err := svbZdDecompress(compressed[:len(compressed)-1], 6); err == nil {
+		t.Errorf("expected an error for truncated data")
+	}
+}
+
+func TestBlow5Errors(t *testing.T) {
+	header, reads := readSlow5(t, "data/example.slow5")
+	if _, err := NewBlow5Writer(io.Discard, header, CompressionZstd, CompressionSvbZd); !errors.Is(err, ErrZstdUnsupported) {
+		t.Errorf("expected ErrZstdUnsupported, got %v", err)
+	}
+	if _, err := NewBlow5Parser(bytes.NewReader(make([]byte, 100))); err == nil {
+		t.Errorf("expected an error for a missing magic number")
+	}
+
+	var blow5 bytes.Buffer
+	writer, _ := NewBlow5Writer(&blow5, header, CompressionZlib, CompressionSvbZd)
+	_ = writer.Write(reads[0])
+	// without Close, the file has no eof marker.
+	parser, err := NewBlow5Parser(&blow5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF for a missing eof marker, got %v", err)
+	}
+
+	read := reads[0]
+	read.EndReason = "not_an_end_reason"
+	if err := writer.Write(read); err == nil {
+		t.Errorf("expected an error for an unknown end reason")
+	}
+}
+
+func TestBlow5AuxiliaryFields(t *testing.T) {
+	// Newer versions of MinKNOW add auxiliary fields that don't have a field
+	// in Read, like num_minknow_events and the float array tracked_scaling.
+	headerText := "@run_id\trun\n" +
+		"#char*\tuint32_t\tdouble\tdouble\tdouble\tdouble\tuint64_t\tint16_t*\tuint64_t\tfloat*\tuint8_t\n" +
+		"#read_id\tread_group\tdigitisation\toffset\trange\tsampling_rate\tlen_raw_signal\traw_signal\tnum_minknow_events\ttracked_scaling\tstart_mux\n"
+	file := append([]byte{}, blow5Magic...)
+	file = append(file, 0, 2, 0, byte(CompressionNone))
+	file = binary.LittleEndian.AppendUint32(file, 1)
+	file = append(file, byte(CompressionNone))
+	file = append(file, make([]byte, blow5HeaderSize-len(file))...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(headerText)))
+	file = append(file, headerText...)
+
+	record := binary.LittleEndian.AppendUint16(nil, 2)
+	record = append(record, "r1"...)
+	record = binary.LittleEndian.AppendUint32(record, 0)
+	for _, value := range []float64{8192, 6, 1500, 4000} {
+		record = binary.LittleEndian.AppendUint64(record, math.Float64bits(value))
+	}
+	record = binary.LittleEndian.AppendUint64(record, 2)
+	record = binary.LittleEndian.AppendUint16(record, 500)
+	record = binary.LittleEndian.AppendUint16(record, 510)
+	record = binary.LittleEndian.AppendUint64(record, 7)
+	record = binary.LittleEndian.AppendUint64(record, 2)
+	record = binary.LittleEndian.AppendUint32(record, math.Float32bits(1.5))
+	record = binary.LittleEndian.AppendUint32(record, math.Float32bits(2.5))
+	record = append(record, 3)
+	file = binary.LittleEndian.AppendUint64(file, uint64(len(record)))
+	file = append(file, record...)
+	file = append(file, blow5EOFMarker...)
+
+	_, reads := readBlow5(t, bytes.NewReader(file))
+	if len(reads) != 1 || reads[0].ReadID != "r1" || len(reads[0].RawSignal) != 2 || reads[0].RawSignal[1] != 510 || reads[0].StartMux != 3 {
+		t.Errorf("Failed to parse read with unknown auxiliary fields: %+v", reads)
+	}
+
+	unknownType := bytes.Replace(file, []byte("\tfloat*\t"), []byte("\tfloat128*\t"), 1)
+	unknownType[blow5HeaderSize] += 3
+	parser, err := NewBlow5Parser(bytes.NewReader(unknownType))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.Next(); err == nil {
+		t.Errorf("Expected an error for an unknown column type")
+	}
+}
+
+func TestBlow5WriterColumnOrder(t *testing.T) {
+	header, reads := readSlow5(t, "data/example.slow5")
+	var blow5 bytes.Buffer
+	writer, err := NewBlow5Writer(&blow5, header, CompressionZlib, CompressionSvbZd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser, err := NewBlow5Parser(bytes.NewReader(blow5.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(writer.columns) != len(parser.headerMap) {
+		t.Fatalf("Writer has %d columns, header has %d", len(writer.columns), len(parser.headerMap))
+	}
+	for columnIndex, column := range writer.columns {
+		if parser.headerMap[columnIndex] != column {
+			t.Errorf("Column %d of the writer is %s, header has %s", columnIndex, column, parser.headerMap[columnIndex])
+		}
+	}
+
+	// Records follow the columns, whatever their order.
+	writer.columns[0], writer.columns[len(writer.columns)-1] = writer.columns[len(writer.columns)-1], writer.columns[0]
+	parser.headerMap[0], parser.headerMap[len(writer.columns)-1] = writer.columns[0], writer.columns[len(writer.columns)-1]
+	record, err := writer.buildRecord(reads[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := parser.parseRecord(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(reads[0], read); diff != "" {
+		t.Errorf("read changed (-want +got):\n%s", diff)
+	}
+
+	writer.columns = append(writer.columns, "num_minknow_events")
+	if _, err := writer.buildRecord(reads[0]); err == nil {
+		t.Errorf("Expected an error for a column without a field in Read")
+	}
+}
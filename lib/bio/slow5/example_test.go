@@ -1,6 +1,7 @@
 package slow5_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 
@@ -62,3 +63,32 @@ func ExampleSvbCompressRawSignal() {
 
 	// Output: [174 1 216 1 207 1 211 1 198 1]
 }
+
+func ExampleSlow5ToBlow5() {
+	file, _ := os.Open("data/example.slow5")
+	defer file.Close()
+
+	// Compress records with zlib and raw signals with svb-zd, like
+	// slow5tools does by default with zlib.
+	var blow5 bytes.Buffer
+	_ = slow5.Slow5ToBlow5(file, &blow5, 2*32*1024, slow5.CompressionZlib, slow5.CompressionSvbZd)
+
+	parser, _ := slow5.NewBlow5Parser(&blow5)
+	read, _ := parser.Next()
+	fmt.Println(read.RawSignal[0:10])
+	// Output: [430 472 463 467 454 465 463 450 450 449]
+}
+
+func ExampleIndexedReader_Get() {
+	file, _ := os.Open("data/example.slow5")
+	defer file.Close()
+
+	// Indexes are usually read from the .idx file next to the slow5 file
+	// with slow5.ReadIndex.
+	index, _ := slow5.BuildSlow5Index(file, 2*32*1024)
+	reader, _ := slow5.NewIndexedSlow5Reader(file, index, 2*32*1024)
+
+	read, _ := reader.Get("0026631e-33a3-49ab-aa22-3ab157d71f8b")
+	fmt.Println(read.RawSignal[0:10])
+	// Output: [430 472 463 467 454 465 463 450 450 449]
+}
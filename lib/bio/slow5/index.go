@@ -0,0 +1,315 @@
+package slow5
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+Oct 16, 2026
+
+slow5 and blow5 indexes begin here.
+
+Nanopore runs are huge, and getting a few reads out of them by parsing the
+whole file takes a long time. slow5tools indexes files with a .idx file next
+to them (example.blow5.idx for example.blow5), which holds the position of
+each read in the file. With an index, any read can be read directly from an
+io.ReaderAt, like an *os.File.
+
+Indexes are written in a binary format laid out like the .idx files of
+slow5tools. It has not been checked against indexes written by slow5tools,
+so build indexes of files with BuildSlow5Index or BuildBlow5Index rather than
+reading the ones slow5tools wrote. The layout is:
+
+	magic number  "SLOW5IDX\x01"
+	version       3 uint8s
+	padding       zeros, up to byte 64
+	entries       uint16 read id length, the read id, and the uint64 offset
+	              and uint64 size of the read in the file
+	eof marker    "XDI5WOLS"
+
+In slow5 files, an entry covers the line of a read, including its newline. In
+blow5 files, it covers the record of a read, including its size.
+
+Two parts of this layout are assumptions, which an index written by
+`slow5tools index` would settle: that the version is the version of the
+indexed file, and that entries of blow5 files start at the size of the
+record rather than after it.
+
+******************************************************************************/
+
+// ErrReadNotFound is returned when a read isn't in an index.
+var ErrReadNotFound = errors.New("read not found in index")
+
+var (
+	indexMagic     = []byte{'S', 'L', 'O', 'W', '5', 'I', 'D', 'X', 1}
+	indexEOFMarker = []byte{'X', 'D', 'I', '5', 'W', 'O', 'L', 'S'}
+)
+
+// indexHeaderSize is the size of the header of indexes.
+const indexHeaderSize = 64
+
+// IndexEntry is the position of a read in a slow5 or blow5 file.
+type IndexEntry struct {
+	ReadID string
+	Offset uint64
+	Size   uint64
+}
+
+// Index holds the positions of the reads of a slow5 or blow5 file. It is
+// built with BuildSlow5Index or BuildBlow5Index, or read from a .idx file with
+// ReadIndex.
+type Index struct {
+	Version [3]uint8 // version in the index header. Built indexes use the version of the indexed file, which isn't checked when reading.
+	Entries []IndexEntry
+	lookup  map[string]int
+}
+
+// Lookup returns the entry of a read.
+func (index *Index) Lookup(readID string) (IndexEntry, bool) {
+	if index.lookup == nil {
+		index.lookup = make(map[string]int, len(index.Entries))
+		for i, entry := range index.Entries {
+			index.lookup[entry.ReadID] = i
+		}
+	}
+	i, ok := index.lookup[readID]
+	if !ok {
+		return IndexEntry{}, false
+	}
+	return index.Entries[i], true
+}
+
+// add adds an entry to the index. Read ids must be unique.
+func (index *Index) add(entry IndexEntry) error {
+	if index.lookup == nil {
+		index.lookup = make(map[string]int)
+	}
+	if _, ok := index.lookup[entry.ReadID]; ok {
+		return fmt.Errorf("Duplicate read id %s", entry.ReadID)
+	}
+	index.lookup[entry.ReadID] = len(index.Entries)
+	index.Entries = append(index.Entries, entry)
+	return nil
+}
+
+// BuildSlow5Index builds the index of a slow5 file.
+func BuildSlow5Index(r io.Reader, maxLineSize int) (*Index, error) {
+	reader := bufio.NewReaderSize(r, maxLineSize)
+	index := &Index{}
+	var offset uint64
+	var lineNumber int
+	for {
+		lineBytes, err := reader.ReadSlice('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && len(lineBytes) > 0) {
+			if errors.Is(err, io.EOF) {
+				return index, nil
+			}
+			return index, fmt.Errorf("Failed to read line %d: %w", lineNumber+1, err)
+		}
+		lineNumber++
+		line := string(lineBytes)
+		switch {
+		case strings.HasPrefix(line, "#slow5_version"):
+			versionString := strings.TrimSpace(strings.TrimPrefix(line, "#slow5_version"))
+			version, err := parseVersion(versionString)
+			if err != nil {
+				return index, fmt.Errorf("Invalid slow5 version on line %d: %w", lineNumber, err)
+			}
+			index.Version = version
+		case strings.HasPrefix(line, "#") || strings.HasPrefix(line, "@") || strings.TrimSpace(line) == "":
+		default:
+			readID, _, _ := strings.Cut(line, "\t")
+			if err := index.add(IndexEntry{ReadID: readID, Offset: offset, Size: uint64(len(lineBytes))}); err != nil {
+				return index, fmt.Errorf("Line %d: %w", lineNumber, err)
+			}
+		}
+		offset += uint64(len(lineBytes))
+	}
+}
+
+// parseVersion parses a major.minor.patch version.
+func parseVersion(versionString string) ([3]uint8, error) {
+	var version [3]uint8
+	parts := strings.Split(versionString, ".")
+	if len(parts) != 3 {
+		return version, fmt.Errorf("Version %s is not major.minor.patch", versionString)
+	}
+	for i, part := range parts {
+		number, err := strconv.ParseUint(part, 10, 8)
+		if err != nil {
+			return version, err
+		}
+		version[i] = uint8(number)
+	}
+	return version, nil
+}
+
+// BuildBlow5Index builds the index of a blow5 file.
+func BuildBlow5Index(r io.Reader) (*Index, error) {
+	parser, err := NewBlow5Parser(r)
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{}
+	index.Version, err = parseVersion(parser.header.HeaderValues[0].Slow5Version)
+	if err != nil {
+		return index, err
+	}
+	for {
+		offset, record, err := parser.nextRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return index, nil
+			}
+			return index, err
+		}
+		// The read id is always the first field of a record, so only the
+		// record compression has to be undone to get it.
+		record, err = parser.decompressRecord(record)
+		if err != nil {
+			return index, fmt.Errorf("Failed to decompress blow5 record at byte %d: %w", offset, err)
+		}
+		decoder := recordDecoder{data: record}
+		readID := string(decoder.bytes(int(decoder.uint16())))
+		if decoder.err != nil {
+			return index, fmt.Errorf("Failed to read the read id of blow5 record at byte %d: %w", offset, decoder.err)
+		}
+		entry := IndexEntry{ReadID: readID, Offset: uint64(offset), Size: uint64(parser.offset - offset)}
+		if err := index.add(entry); err != nil {
+			return index, err
+		}
+	}
+}
+
+// ReadIndex reads a .idx index file.
+func ReadIndex(r io.Reader) (*Index, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, indexHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("Failed to read index header: %w", err)
+	}
+	if !bytes.Equal(header[:len(indexMagic)], indexMagic) {
+		return nil, fmt.Errorf("Not a slow5 index. Got magic number %q", header[:len(indexMagic)])
+	}
+	index := &Index{}
+	copy(index.Version[:], header[len(indexMagic):])
+	for {
+		start, _ := reader.Peek(len(indexEOFMarker))
+		if bytes.Equal(start, indexEOFMarker) {
+			return index, nil
+		}
+		var readIDLength uint16
+		if err := binary.Read(reader, binary.LittleEndian, &readIDLength); err != nil {
+			return index, fmt.Errorf("Index ended without an eof marker: %w", io.ErrUnexpectedEOF)
+		}
+		readID := make([]byte, readIDLength)
+		var position [2]uint64
+		if _, err := io.ReadFull(reader, readID); err != nil {
+			return index, fmt.Errorf("Failed to read index entry: %w", err)
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &position); err != nil {
+			return index, fmt.Errorf("Failed to read index entry of read %s: %w", readID, err)
+		}
+		if err := index.add(IndexEntry{ReadID: string(readID), Offset: position[0], Size: position[1]}); err != nil {
+			return index, err
+		}
+	}
+}
+
+// WriteTo writes the index as a .idx file.
+func (index *Index) WriteTo(w io.Writer) (int64, error) {
+	writer := bufio.NewWriter(w)
+	header := make([]byte, indexHeaderSize)
+	copy(header, indexMagic)
+	copy(header[len(indexMagic):], index.Version[:])
+	written, _ := writer.Write(header)
+	for _, entry := range index.Entries {
+		if len(entry.ReadID) > math.MaxUint16 {
+			return int64(written), fmt.Errorf("Read id %s... is longer than %d characters", entry.ReadID[:32], math.MaxUint16)
+		}
+		var buffer []byte
+		buffer = binary.LittleEndian.AppendUint16(buffer, uint16(len(entry.ReadID)))
+		buffer = append(buffer, entry.ReadID...)
+		buffer = binary.LittleEndian.AppendUint64(buffer, entry.Offset)
+		buffer = binary.LittleEndian.AppendUint64(buffer, entry.Size)
+		n, _ := writer.Write(buffer)
+		written += n
+	}
+	n, _ := writer.Write(indexEOFMarker)
+	written += n
+	return int64(written), writer.Flush()
+}
+
+// IndexedReader gets reads by read id from an indexed slow5 or blow5 file.
+// It is initialized with NewIndexedSlow5Reader or NewIndexedBlow5Reader.
+type IndexedReader struct {
+	reader    io.ReaderAt
+	index     *Index
+	header    Header
+	parseRead func(data []byte) (Read, error)
+}
+
+// NewIndexedSlow5Reader returns a reader of the reads of a slow5 file,
+// using its index.
+func NewIndexedSlow5Reader(r io.ReaderAt, index *Index, maxLineSize int) (*IndexedReader, error) {
+	parser, err := NewParser(io.NewSectionReader(r, 0, math.MaxInt64), maxLineSize)
+	if err != nil {
+		return nil, err
+	}
+	parseRead := func(data []byte) (Read, error) {
+		return parser.parseRead(string(data))
+	}
+	return &IndexedReader{reader: r, index: index, header: parser.header, parseRead: parseRead}, nil
+}
+
+// NewIndexedBlow5Reader returns a reader of the reads of a blow5 file,
+// using its index.
+func NewIndexedBlow5Reader(r io.ReaderAt, index *Index) (*IndexedReader, error) {
+	parser, err := NewBlow5Parser(io.NewSectionReader(r, 0, math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+	parseRead := func(data []byte) (Read, error) {
+		// entries include the size of the record.
+		if len(data) < 8 {
+			return Read{}, errors.New("Index entry is smaller than a blow5 record")
+		}
+		return parser.parseRecord(data[8:])
+	}
+	return &IndexedReader{reader: r, index: index, header: parser.header, parseRead: parseRead}, nil
+}
+
+// Header returns the header of the indexed file.
+func (reader *IndexedReader) Header() (Header, error) {
+	return reader.header, nil
+}
+
+// Get returns the read with the given read id. It returns ErrReadNotFound
+// if the read isn't in the index.
+func (reader *IndexedReader) Get(readID string) (Read, error) {
+	entry, ok := reader.index.Lookup(readID)
+	if !ok {
+		return Read{}, fmt.Errorf("%w: %s", ErrReadNotFound, readID)
+	}
+	data := make([]byte, entry.Size)
+	// ReadAt may return io.EOF along with the last read of the file.
+	if n, err := reader.reader.ReadAt(data, int64(entry.Offset)); n != len(data) {
+		return Read{}, fmt.Errorf("Failed to read %s: %w", readID, err)
+	}
+	read, err := reader.parseRead(data)
+	if err != nil {
+		return Read{}, fmt.Errorf("Failed to parse %s: %w", readID, err)
+	}
+	if read.ReadID != readID {
+		return Read{}, fmt.Errorf("Index entry of %s points to read %s. The index may be out of date", readID, read.ReadID)
+	}
+	return read, nil
+}
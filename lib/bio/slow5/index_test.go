@@ -0,0 +1,155 @@
+package slow5
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testReads returns the header of example.slow5 and a few reads made from
+// its read.
+func testReads(t *testing.T) (Header, []Read) {
+	t.Helper()
+	header, reads := readSlow5(t, "data/example.slow5")
+	var testReads []Read
+	for i := 0; i < 4; i++ {
+		read := reads[0]
+		read.ReadID = fmt.Sprintf("read-%d", i)
+		read.RawSignal = read.RawSignal[i*10 : i*10+100+i]
+		read.LenRawSignal = uint64(len(read.RawSignal))
+		testReads = append(testReads, read)
+	}
+	return header, testReads
+}
+
+func TestSlow5Index(t *testing.T) {
+	header, reads := testReads(t)
+	var file bytes.Buffer
+	_, _ = header.WriteTo(&file)
+	for _, read := range reads {
+		_, _ = read.WriteTo(&file)
+	}
+
+	index, err := BuildSlow5Index(bytes.NewReader(file.Bytes()), maxLineSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Version != [3]uint8{0, 2, 0} || len(index.Entries) != len(reads) {
+		t.Fatalf("unexpected index %+v", index)
+	}
+	reader, err := NewIndexedSlow5Reader(bytes.NewReader(file.Bytes()), index, maxLineSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testIndexedReader(t, reader, reads)
+}
+
+func TestBlow5Index(t *testing.T) {
+	header, reads := testReads(t)
+	var file bytes.Buffer
+	writer, _ := NewBlow5Writer(&file, header, CompressionZlib, CompressionSvbZd)
+	for _, read := range reads {
+		_ = writer.Write(read)
+	}
+	_ = writer.Close()
+
+	index, err := BuildBlow5Index(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first record starts right after the header.
+	headerSize := blow5HeaderSize + 4 + binary.LittleEndian.Uint32(file.Bytes()[blow5HeaderSize:])
+	if index.Entries[0].Offset != uint64(headerSize) {
+		t.Errorf("expected first record at byte %d, got %d", headerSize, index.Entries[0].Offset)
+	}
+	last := index.Entries[len(index.Entries)-1]
+	if last.Offset+last.Size != uint64(file.Len()-len(blow5EOFMarker)) {
+		t.Errorf("last record should end before the eof marker, got %+v", last)
+	}
+	reader, err := NewIndexedBlow5Reader(bytes.NewReader(file.Bytes()), index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testIndexedReader(t, reader, reads)
+}
+
+func testIndexedReader(t *testing.T, reader *IndexedReader, reads []Read) {
+	t.Helper()
+	// reads are fetched out of order, since they are read directly.
+	for i := len(reads) - 1; i >= 0; i-- {
+		read, err := reader.Get(reads[i].ReadID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(reads[i], read); diff != "" {
+			t.Errorf("unexpected read %s (-want +got):\n%s", reads[i].ReadID, diff)
+		}
+	}
+	if _, err := reader.Get("missing"); !errors.Is(err, ErrReadNotFound) {
+		t.Errorf("expected ErrReadNotFound, got %v", err)
+	}
+	header, _ := reader.Header()
+	if len(header.HeaderValues) != 1 {
+		t.Errorf("unexpected header %+v", header)
+	}
+}
+
+func TestIndexReadWrite(t *testing.T) {
+	index := &Index{Version: [3]uint8{0, 2, 0}}
+	_ = index.add(IndexEntry{ReadID: "read-0", Offset: 100, Size: 20})
+	_ = index.add(IndexEntry{ReadID: "read-1", Offset: 120, Size: 30})
+	var file bytes.Buffer
+	written, err := index.WriteTo(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := file.Bytes()
+	if written != int64(len(data)) || len(data) != indexHeaderSize+2*(2+6+16)+len(indexEOFMarker) {
+		t.Fatalf("unexpected index size %d", len(data))
+	}
+	if !bytes.HasPrefix(data, []byte("SLOW5IDX\x01\x00\x02\x00")) || !bytes.HasSuffix(data, []byte("XDI5WOLS")) {
+		t.Errorf("unexpected index header or eof marker")
+	}
+	firstEntry := data[indexHeaderSize : indexHeaderSize+24]
+	if binary.LittleEndian.Uint16(firstEntry) != 6 || string(firstEntry[2:8]) != "read-0" || binary.LittleEndian.Uint64(firstEntry[8:]) != 100 || binary.LittleEndian.Uint64(firstEntry[16:]) != 20 {
+		t.Errorf("unexpected first entry %v", firstEntry)
+	}
+
+	readIndex, err := ReadIndex(&file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(index.Entries, readIndex.Entries); diff != "" || readIndex.Version != index.Version {
+		t.Errorf("index changed after writing (-want +got):\n%s", diff)
+	}
+	entry, ok := readIndex.Lookup("read-1")
+	if !ok || entry.Offset != 120 {
+		t.Errorf("unexpected lookup %+v", entry)
+	}
+
+	if _, err := ReadIndex(bytes.NewReader(data[:len(data)-len(indexEOFMarker)])); err == nil {
+		t.Errorf("expected an error for a missing eof marker")
+	}
+	if err := index.add(IndexEntry{ReadID: "read-0"}); err == nil {
+		t.Errorf("expected an error for a duplicate read id")
+	}
+}
+
+func TestOutdatedIndex(t *testing.T) {
+	header, reads := testReads(t)
+	var file bytes.Buffer
+	_, _ = header.WriteTo(&file)
+	for _, read := range reads {
+		_, _ = read.WriteTo(&file)
+	}
+	index, _ := BuildSlow5Index(bytes.NewReader(file.Bytes()), maxLineSize)
+	index.Entries[0].Offset = index.Entries[1].Offset
+	reader, _ := NewIndexedSlow5Reader(bytes.NewReader(file.Bytes()), index, maxLineSize)
+	if _, err := reader.Get("read-0"); err == nil {
+		t.Errorf("expected an error for an index entry pointing to the wrong read")
+	}
+}
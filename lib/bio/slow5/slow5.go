@@ -1,8 +1,9 @@
 /*
-Package slow5 contains slow5 parsers and writers.
+Package slow5 contains slow5 and blow5 parsers and writers.
 
-Right now, only parsing slow5 files is supported. Support for writing and blow5
-coming soon.
+blow5 is the binary version of slow5, with compressed reads. blow5 files with
+zlib record compression and svb-zd raw signal compression can be read and
+written, and converted to and from slow5 files.
 
 slow5 is a file format alternative to fast5, which is the file format outputted
 by Oxford Nanopore sequencing devices. fast5 uses hdf5, which is a complex file
@@ -108,6 +109,7 @@ type Parser struct {
 	reader             bufio.Reader
 	line               uint
 	headerMap          map[int]string
+	columnTypes        map[int]string // types of the read columns, from the #char* line
 	endReasonMap       map[int]string
 	endReasonHeaderMap map[string]int
 	header             Header
@@ -165,7 +167,9 @@ func NewParser(r io.Reader, maxLineSize int) (*Parser, error) {
 		// Terminate if we hit the beginning of the raw read headers
 		// Get endReasonEnums. This is simply a string between enum{} that is used for the reasons that a read could have ended.
 		if values[0] == "#char*" {
-			for _, typeInfo := range values {
+			parser.columnTypes = make(map[int]string)
+			for typeIndex, typeInfo := range values {
+				parser.columnTypes[typeIndex] = strings.TrimPrefix(typeInfo, "#")
 				if strings.Contains(typeInfo, "enum") {
 					endReasonEnumsMinusPrefix := strings.TrimPrefix(typeInfo, "enum{")
 					endReasonEnumsMinusSuffix := strings.TrimSuffix(endReasonEnumsMinusPrefix, "}")
@@ -230,9 +234,12 @@ func (parser *Parser) Next() (Read, error) {
 		}
 	}
 	parser.line++
-	line := strings.TrimSpace(string(lineBytes))
+	return parser.parseRead(string(lineBytes))
+}
 
-	values := strings.Split(line, "\t")
+// parseRead parses the line of a read.
+func (parser *Parser) parseRead(line string) (Read, error) {
+	values := strings.Split(strings.TrimSpace(line), "\t")
 	// Reads have started.
 	// Once we have the read headers, start to parse the actual reads
 	var newRead Read
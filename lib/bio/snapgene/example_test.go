@@ -0,0 +1,26 @@
+package snapgene_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+	"github.com/koeng101/dnadesign/lib/bio/snapgene"
+)
+
+func Example_read() {
+	file, _ := os.Open("data/puc19_lacz.dna")
+	defer file.Close()
+	sequence, _ := snapgene.Parse(file)
+
+	fmt.Println(sequence.Meta.Locus.Name, sequence.Meta.Locus.SequenceLength, sequence.Meta.Locus.Circular)
+	for _, feature := range sequence.Features {
+		fmt.Println(feature.Type, feature.Attributes["label"][0], genbank.BuildLocationString(feature.Location))
+	}
+	// Output:
+	// pUC19_lacZ 134 true
+	// CDS lacZα 1..117
+	// misc_feature MCS complement(join(17..30,36..57))
+	// misc_feature ori junction join(120..134,1..5)
+	// primer_bind M13 rev complement(59..75)
+}
@@ -0,0 +1,438 @@
+/*
+Package snapgene provides a parser for SnapGene .dna files.
+
+SnapGene is a popular plasmid editor, and its .dna files are how many plasmid
+maps are shared. Unlike GenBank files, .dna files are binary: they are a list
+of packets, each starting with a one byte packet type and a four byte big
+endian length. The packets hold the sequence, and XML documents with the
+features, primers, and notes of the map.
+
+Since SnapGene maps hold the same data as GenBank files, this package parses
+them into genbank.Genbank structs, the same way that SnapGene exports GenBank
+files. Primers are added as primer_bind features. Packets of data that have no
+GenBank equivalent, like the history of the map, are skipped.
+
+The format isn't documented by SnapGene beyond the packet structure, so this
+parser follows the reverse engineered description of Biopython's SnapGene
+parser:
+https://github.com/biopython/biopython/blob/master/Bio/SeqIO/SnapGeneIO.py
+
+The test file, data/puc19_lacz.dna, was built to that description rather
+than saved by SnapGene, so files from SnapGene may have packets or XML this
+parser has not seen.
+*/
+package snapgene
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+// Packet types of SnapGene files.
+const (
+	packetDNA      = 0x00
+	packetPrimers  = 0x05
+	packetNotes    = 0x06
+	packetCookie   = 0x09
+	packetFeatures = 0x0A
+)
+
+// cookie is the start of the first packet of every SnapGene file.
+const cookie = "SnapGene"
+
+// Flags of the DNA packet.
+const (
+	flagCircular = 0x01
+)
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// Header is a blank struct, needed for compatibility with bio parsers. It
+// contains nothing.
+type Header struct{}
+
+// WriteTo is a blank function, needed for compatibility with bio parsers. It
+// doesn't do anything.
+func (header *Header) WriteTo(w io.Writer) (int64, error) {
+	return 0, nil
+}
+
+// Parser is a SnapGene parser created on an io.Reader. SnapGene files hold a
+// single sequence.
+type Parser struct {
+	reader *bufio.Reader
+	done   bool
+}
+
+// NewParser returns a Parser that reads a SnapGene file from r.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{reader: bufio.NewReader(r)}
+}
+
+// Header returns an empty header, since SnapGene files have none.
+func (parser *Parser) Header() (Header, error) {
+	return Header{}, nil
+}
+
+// Next returns the sequence of the SnapGene file. It returns io.EOF once the
+// sequence has been read.
+func (parser *Parser) Next() (genbank.Genbank, error) {
+	if parser.done {
+		return genbank.Genbank{}, io.EOF
+	}
+	parser.done = true
+	return Parse(parser.reader)
+}
+
+// packet is a single packet of a SnapGene file.
+type packet struct {
+	kind byte
+	data []byte
+}
+
+// readPacket reads the next packet. It returns io.EOF at the end of the file.
+func readPacket(r io.Reader) (packet, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:1]); err != nil {
+		return packet{}, err
+	}
+	if _, err := io.ReadFull(r, header[1:]); err != nil {
+		return packet{}, fmt.Errorf("Failed to read length of packet 0x%02x: %w", header[0], io.ErrUnexpectedEOF)
+	}
+	data := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return packet{}, fmt.Errorf("Failed to read packet 0x%02x of %d bytes: %w", header[0], len(data), io.ErrUnexpectedEOF)
+	}
+	return packet{kind: header[0], data: data}, nil
+}
+
+// Parse parses a SnapGene file.
+func Parse(r io.Reader) (genbank.Genbank, error) {
+	sequence := genbank.Genbank{Meta: genbank.Meta{Other: make(map[string]string)}}
+	first, err := readPacket(r)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return sequence, fmt.Errorf("Empty SnapGene file: %w", io.ErrUnexpectedEOF)
+		}
+		return sequence, err
+	}
+	if first.kind != packetCookie || !strings.HasPrefix(string(first.data), cookie) {
+		return sequence, errors.New("Not a SnapGene file. The file should start with a SnapGene cookie packet")
+	}
+
+	// Features are parsed once the whole file is read, since their
+	// locations depend on the sequence.
+	var featurePackets, primerPackets [][]byte
+	var hasDNA bool
+	for {
+		packet, err := readPacket(r)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return sequence, err
+		}
+		switch packet.kind {
+		case packetDNA:
+			if len(packet.data) == 0 {
+				return sequence, errors.New("Empty DNA packet")
+			}
+			hasDNA = true
+			sequence.Meta.Locus.Circular = packet.data[0]&flagCircular != 0
+			sequence.Sequence = string(packet.data[1:])
+		case packetFeatures:
+			featurePackets = append(featurePackets, packet.data)
+		case packetPrimers:
+			primerPackets = append(primerPackets, packet.data)
+		case packetNotes:
+			if err := parseNotes(packet.data, &sequence.Meta); err != nil {
+				return sequence, err
+			}
+		}
+	}
+	if !hasDNA {
+		return sequence, errors.New("SnapGene file has no DNA packet. Protein and RNA files are not supported")
+	}
+
+	sequence.Meta.Locus.SequenceLength = strconv.Itoa(len(sequence.Sequence))
+	sequence.Meta.Locus.SequenceCoding = "bp"
+	sequence.Meta.Locus.MoleculeType = "DNA"
+	if sequence.Meta.Locus.Name == "" {
+		sequence.Meta.Locus.Name = "Exported"
+	}
+	if sequence.Meta.Definition == "" {
+		sequence.Meta.Definition = "."
+	}
+
+	var features []genbank.Feature
+	for _, data := range featurePackets {
+		packetFeatures, err := parseFeatures(data, len(sequence.Sequence), sequence.Meta.Locus.Circular)
+		if err != nil {
+			return sequence, err
+		}
+		features = append(features, packetFeatures...)
+	}
+	for _, data := range primerPackets {
+		primerFeatures, err := parsePrimers(data, len(sequence.Sequence), sequence.Meta.Locus.Circular)
+		if err != nil {
+			return sequence, err
+		}
+		features = append(features, primerFeatures...)
+	}
+	for i := range features {
+		_ = sequence.AddFeature(&features[i])
+	}
+	return sequence, nil
+}
+
+// decodeText decodes text values of SnapGene XML, which can be HTML.
+func decodeText(text string) string {
+	if strings.HasPrefix(text, "<html>") {
+		text = html.UnescapeString(htmlTagRegex.ReplaceAllString(text, ""))
+	}
+	return strings.TrimSpace(text)
+}
+
+// buildLocation converts a SnapGene range, like 1-100, to a gbk location
+// string. Ranges are 1-based and inclusive, and ranges that cross the origin
+// of circular sequences have a start after their end.
+func buildLocation(rangeString string, sequenceLength int, circular bool) (string, error) {
+	startString, endString, found := strings.Cut(rangeString, "-")
+	start, startErr := strconv.Atoi(startString)
+	end, endErr := strconv.Atoi(endString)
+	if !found || startErr != nil || endErr != nil || start < 1 || end > sequenceLength {
+		return "", fmt.Errorf("Invalid range %q in a sequence of %d bp", rangeString, sequenceLength)
+	}
+	if start <= end {
+		return fmt.Sprintf("%d..%d", start, end), nil
+	}
+	if !circular {
+		return "", fmt.Errorf("Range %q crosses the origin of a linear sequence", rangeString)
+	}
+	return fmt.Sprintf("%d..%d,1..%d", start, sequenceLength, end), nil
+}
+
+// buildFeatureLocation builds the location of a feature from the locations of
+// its segments.
+func buildFeatureLocation(segments []string, reverse bool) (genbank.Location, error) {
+	locationString := strings.Join(segments, ",")
+	if strings.Contains(locationString, ",") {
+		locationString = "join(" + locationString + ")"
+	}
+	if reverse {
+		locationString = "complement(" + locationString + ")"
+	}
+	return genbank.ParseLocation(locationString)
+}
+
+/******************************************************************************
+
+XML packets
+
+******************************************************************************/
+
+type featuresXML struct {
+	Features []featureXML `xml:"Feature"`
+}
+
+type featureXML struct {
+	Name           string         `xml:"name,attr"`
+	Type           string         `xml:"type,attr"`
+	Directionality string         `xml:"directionality,attr"`
+	Segments       []segmentXML   `xml:"Segment"`
+	Qualifiers     []qualifierXML `xml:"Q"`
+}
+
+type segmentXML struct {
+	Range string `xml:"range,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type qualifierXML struct {
+	Name   string     `xml:"name,attr"`
+	Values []valueXML `xml:"V"`
+}
+
+type valueXML struct {
+	Text   *string `xml:"text,attr"`
+	Predef *string `xml:"predef,attr"`
+	Int    *string `xml:"int,attr"`
+}
+
+// parseFeatures parses a features packet.
+func parseFeatures(data []byte, sequenceLength int, circular bool) ([]genbank.Feature, error) {
+	var parsed featuresXML
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("Failed to parse features: %w", err)
+	}
+	var features []genbank.Feature
+	for _, featureData := range parsed.Features {
+		feature := genbank.Feature{Type: featureData.Type, Attributes: genbank.NewMultiMap[string, string]()}
+		if feature.Type == "" {
+			feature.Type = "misc_feature"
+		}
+		var segments []string
+		for _, segment := range featureData.Segments {
+			// gaps are segments between parts of a feature.
+			if segment.Type == "gap" {
+				continue
+			}
+			location, err := buildLocation(segment.Range, sequenceLength, circular)
+			if err != nil {
+				return nil, fmt.Errorf("Feature %s: %w", featureData.Name, err)
+			}
+			segments = append(segments, location)
+		}
+		if len(segments) == 0 {
+			return nil, fmt.Errorf("Feature %s has no location", featureData.Name)
+		}
+		// directionality is 1 for forward features, 2 for reverse, 3 for
+		// both, and missing for features without a direction.
+		location, err := buildFeatureLocation(segments, featureData.Directionality == "2")
+		if err != nil {
+			return nil, fmt.Errorf("Feature %s: %w", featureData.Name, err)
+		}
+		feature.Location = location
+
+		// SnapGene shows the name of features, which is their label in
+		// GenBank files.
+		if featureData.Name != "" {
+			genbank.Put(feature.Attributes, "label", featureData.Name)
+		}
+		for _, qualifier := range featureData.Qualifiers {
+			for _, value := range qualifier.Values {
+				var text string
+				switch {
+				case value.Text != nil:
+					text = decodeText(*value.Text)
+				case value.Predef != nil:
+					text = decodeText(*value.Predef)
+				case value.Int != nil:
+					text = *value.Int
+				}
+				if qualifier.Name == "label" && text == featureData.Name {
+					continue
+				}
+				genbank.Put(feature.Attributes, qualifier.Name, text)
+			}
+		}
+		features = append(features, feature)
+	}
+	return features, nil
+}
+
+type primersXML struct {
+	Primers []primerXML `xml:"Primer"`
+}
+
+type primerXML struct {
+	Name         string           `xml:"name,attr"`
+	Sequence     string           `xml:"sequence,attr"`
+	Description  string           `xml:"description,attr"`
+	BindingSites []bindingSiteXML `xml:"BindingSite"`
+}
+
+type bindingSiteXML struct {
+	Location    string `xml:"location,attr"`
+	BoundStrand string `xml:"boundStrand,attr"`
+	Simplified  string `xml:"simplified,attr"`
+}
+
+// parsePrimers parses a primers packet into primer_bind features, one for
+// each binding site of each primer.
+func parsePrimers(data []byte, sequenceLength int, circular bool) ([]genbank.Feature, error) {
+	var parsed primersXML
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("Failed to parse primers: %w", err)
+	}
+	var features []genbank.Feature
+	for _, primer := range parsed.Primers {
+		seen := make(map[string]bool)
+		for _, site := range primer.BindingSites {
+			// Binding sites of primers are 0-based, unlike feature
+			// segments, so they are shifted to be 1-based.
+			startString, endString, _ := strings.Cut(site.Location, "-")
+			start, startErr := strconv.Atoi(startString)
+			end, endErr := strconv.Atoi(endString)
+			if startErr != nil || endErr != nil {
+				return nil, fmt.Errorf("Primer %s has invalid binding site %q", primer.Name, site.Location)
+			}
+			segment, err := buildLocation(fmt.Sprintf("%d-%d", start+1, end+1), sequenceLength, circular)
+			if err != nil {
+				return nil, fmt.Errorf("Primer %s: %w", primer.Name, err)
+			}
+			// Simplified binding sites repeat the other sites of a primer.
+			key := segment + site.BoundStrand
+			if site.Simplified == "1" && seen[key] {
+				continue
+			}
+			seen[key] = true
+			location, err := buildFeatureLocation(strings.Split(segment, ","), site.BoundStrand == "1")
+			if err != nil {
+				return nil, fmt.Errorf("Primer %s: %w", primer.Name, err)
+			}
+			feature := genbank.Feature{Type: "primer_bind", Location: location, Attributes: genbank.NewMultiMap[string, string]()}
+			if primer.Name != "" {
+				genbank.Put(feature.Attributes, "label", primer.Name)
+			}
+			if description := decodeText(primer.Description); description != "" {
+				genbank.Put(feature.Attributes, "note", description)
+			}
+			features = append(features, feature)
+		}
+	}
+	return features, nil
+}
+
+type notesXML struct {
+	Type            string `xml:"Type"`
+	Description     string `xml:"Description"`
+	AccessionNumber string `xml:"AccessionNumber"`
+	LastModified    string `xml:"LastModified"`
+	CustomMapLabel  string `xml:"CustomMapLabel"`
+	Organism        string `xml:"Organism"`
+	Comments        string `xml:"Comments"`
+}
+
+// parseNotes parses a notes packet into the metadata of the sequence.
+func parseNotes(data []byte, meta *genbank.Meta) error {
+	var notes notesXML
+	if err := xml.Unmarshal(data, &notes); err != nil {
+		return fmt.Errorf("Failed to parse notes: %w", err)
+	}
+	// SnapGene maps are either natural or synthetic sequences.
+	if notes.Type == "Synthetic" {
+		meta.Locus.GenbankDivision = "SYN"
+	} else {
+		meta.Locus.GenbankDivision = "UNA"
+	}
+	if notes.LastModified != "" {
+		date, err := time.Parse("2006.1.2", notes.LastModified)
+		if err != nil {
+			return fmt.Errorf("Invalid last modified date %q: %w", notes.LastModified, err)
+		}
+		meta.Locus.ModificationDate = strings.ToUpper(date.Format("02-Jan-2006"))
+	}
+	meta.Locus.Name = strings.ReplaceAll(decodeText(notes.CustomMapLabel), " ", "_")
+	meta.Accession = decodeText(notes.AccessionNumber)
+	meta.Definition = decodeText(notes.Description)
+	if organism := decodeText(notes.Organism); organism != "" {
+		meta.Source = organism
+		meta.Organism = organism
+	}
+	if comments := decodeText(notes.Comments); comments != "" {
+		meta.Other["COMMENT"] = comments
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package snapgene
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+func readFile(t *testing.T, path string) genbank.Genbank {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	sequence, err := Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sequence
+}
+
+// buildFile builds a SnapGene file out of packets of a type and data.
+func buildFile(packets ...any) []byte {
+	var file bytes.Buffer
+	for i := 0; i < len(packets); i += 2 {
+		data := []byte(packets[i+1].(string))
+		file.WriteByte(packets[i].(byte))
+		_ = binary.Write(&file, binary.BigEndian, uint32(len(data)))
+		file.Write(data)
+	}
+	return file.Bytes()
+}
+
+const testCookie = "SnapGene\x00\x01\x00\x0f\x00\x13"
+
+// TestParse parses puc19_lacz.dna, which was built by hand following
+// Biopython's description of the format. It is not a SnapGene export.
+func TestParse(t *testing.T) {
+	sequence := readFile(t, "data/puc19_lacz.dna")
+
+	expectedLocus := genbank.Locus{
+		Name:             "pUC19_lacZ",
+		SequenceLength:   "134",
+		MoleculeType:     "DNA",
+		GenbankDivision:  "SYN",
+		ModificationDate: "17-MAY-2016",
+		SequenceCoding:   "bp",
+		Circular:         true,
+	}
+	if diff := cmp.Diff(expectedLocus, sequence.Meta.Locus); diff != "" {
+		t.Errorf("unexpected locus (-want +got):\n%s", diff)
+	}
+	if sequence.Meta.Definition != "Start of the lacZα fragment of pUC19." {
+		t.Errorf("unexpected definition %q", sequence.Meta.Definition)
+	}
+	if sequence.Meta.Accession != "L09137" || sequence.Meta.Organism != "synthetic DNA construct" || sequence.Meta.Source != "synthetic DNA construct" {
+		t.Errorf("unexpected accession, organism or source in %+v", sequence.Meta)
+	}
+	if sequence.Meta.Other["COMMENT"] != "Made for tests." {
+		t.Errorf("unexpected comment %q", sequence.Meta.Other["COMMENT"])
+	}
+	if len(sequence.Sequence) != 134 || sequence.Sequence[:12] != "ATGACCATGATT" {
+		t.Errorf("unexpected sequence %s", sequence.Sequence)
+	}
+
+	expectedFeatures := []struct {
+		featureType string
+		location    string
+		attributes  map[string][]string
+	}{
+		{"CDS", "1..117", map[string][]string{
+			"label":       {"lacZα"},
+			"codon_start": {"1"},
+			"gene":        {"lacZ"},
+			"note":        {"LacZα fragment"},
+			"translation": {"MTMITPSLHACRSTLEDPRVPSSNSLAVVLQRRDWENPGVTQLN"},
+		}},
+		{"misc_feature", "complement(join(17..30,36..57))", map[string][]string{
+			"label": {"MCS"},
+			"note":  {"multiple cloning site", "pUC19"},
+		}},
+		{"misc_feature", "join(120..134,1..5)", map[string][]string{
+			"label": {"ori junction"},
+		}},
+		{"primer_bind", "complement(59..75)", map[string][]string{
+			"label": {"M13 rev"},
+			"note":  {"In lac operon"},
+		}},
+	}
+	if len(sequence.Features) != len(expectedFeatures) {
+		t.Fatalf("expected %d features, got %d", len(expectedFeatures), len(sequence.Features))
+	}
+	for i, expected := range expectedFeatures {
+		feature := sequence.Features[i]
+		if feature.Type != expected.featureType {
+			t.Errorf("feature %d: expected type %s, got %s", i, expected.featureType, feature.Type)
+		}
+		if location := genbank.BuildLocationString(feature.Location); location != expected.location {
+			t.Errorf("feature %d: expected location %s, got %s", i, expected.location, location)
+		}
+		if diff := cmp.Diff(expected.attributes, feature.Attributes); diff != "" {
+			t.Errorf("feature %d: unexpected attributes (-want +got):\n%s", i, diff)
+		}
+	}
+
+	// features crossing the origin keep their sequence.
+	origin, err := sequence.Features[2].GetSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origin != sequence.Sequence[119:]+sequence.Sequence[:5] {
+		t.Errorf("unexpected sequence of feature crossing the origin %s", origin)
+	}
+}
+
+func TestParser(t *testing.T) {
+	file, err := os.Open("data/puc19_lacz.dna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	parser := NewParser(file)
+	sequence, err := parser.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sequence.Meta.Locus.Name != "pUC19_lacZ" {
+		t.Errorf("unexpected sequence %s", sequence.Meta.Locus.Name)
+	}
+	if _, err := parser.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF after the sequence, got %v", err)
+	}
+}
+
+func TestParseMinimal(t *testing.T) {
+	// files without notes are linear sequences.
+	file := buildFile(byte(packetCookie), testCookie, byte(packetDNA), "\x00ATGC")
+	sequence, err := Parse(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sequence.Sequence != "ATGC" || sequence.Meta.Locus.Circular || sequence.Meta.Locus.Name != "Exported" || len(sequence.Features) != 0 {
+		t.Errorf("unexpected sequence %+v", sequence)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		file []byte
+	}{
+		{"empty", nil},
+		{"no cookie", buildFile(byte(packetDNA), "\x00ATGC")},
+		{"no dna", buildFile(byte(packetCookie), testCookie)},
+		{"truncated", buildFile(byte(packetCookie), testCookie, byte(packetDNA), "\x00ATGC")[:len(testCookie)+8]},
+		{"feature out of sequence", buildFile(byte(packetCookie), testCookie, byte(packetDNA), "\x00ATGC",
+			byte(packetFeatures), `<Features><Feature name="a" type="CDS"><Segment range="2-5"/></Feature></Features>`)},
+		{"feature crossing origin of linear sequence", buildFile(byte(packetCookie), testCookie, byte(packetDNA), "\x00ATGC",
+			byte(packetFeatures), `<Features><Feature name="a" type="CDS"><Segment range="3-1"/></Feature></Features>`)},
+		{"invalid notes", buildFile(byte(packetCookie), testCookie, byte(packetDNA), "\x00ATGC",
+			byte(packetNotes), `<Notes><LastModified>yesterday</LastModified></Notes>`)},
+	} {
+		if _, err := Parse(bytes.NewReader(test.file)); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
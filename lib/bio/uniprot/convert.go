@@ -0,0 +1,310 @@
+package uniprot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/koeng101/dnadesign/lib/bio/fasta"
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+/******************************************************************************
+
+Conversion to fasta and genbank begins here
+
+Entries are converted to fasta records with the headers of the fasta files of
+Uniprot, like:
+
+	sp|P42212|GFP_AEQVI Green fluorescent protein OS=Aequorea victoria OX=6100 GN=GFP PE=1 SV=1
+
+and to protein genbank records, like the GenPept records of NCBI. The
+features of Uniprot are mapped to the protein features of GenPept: domains and
+other regions are Region features, single residue annotations like active
+sites and modified residues (post-translational modifications) are Site
+features, disulfide bonds and cross-links are Bond features, and secondary
+structure is SecStr features.
+
+Spec: https://www.uniprot.org/help/fasta-headers
+
+******************************************************************************/
+
+// proteinExistence maps the protein existence of entries to the PE level of
+// fasta headers.
+var proteinExistence = map[Type]int{
+	"evidence at protein level":    1,
+	"evidence at transcript level": 2,
+	"inferred from homology":       3,
+	"predicted":                    4,
+	"uncertain":                    5,
+}
+
+// ProteinName returns the recommended name of the protein of an entry, or
+// its submitted name if it has no recommended name.
+func (entry *Entry) ProteinName() string {
+	name := entry.Protein.RecommendedName.FullName.Value
+	if name == "" && len(entry.Protein.SubmittedName) > 0 {
+		name = entry.Protein.SubmittedName[0].FullName.Value
+	}
+	return strings.TrimSpace(name)
+}
+
+// geneName returns the primary gene name of an entry, or its ordered locus or
+// ORF name if it has no gene name.
+func (entry *Entry) geneName() string {
+	if len(entry.Gene) == 0 {
+		return ""
+	}
+	for _, nameType := range []Type{"primary", "ordered locus", "ORF"} {
+		for _, name := range entry.Gene[0].Name {
+			if name.Type == nameType {
+				return name.Value
+			}
+		}
+	}
+	return ""
+}
+
+// scientificName returns the scientific name of an organism.
+func scientificName(organism OrganismType) string {
+	for _, name := range organism.Name {
+		if name.Type == "scientific" {
+			return name.Value
+		}
+	}
+	return ""
+}
+
+// taxonomyID returns the NCBI taxonomy ID of an organism.
+func taxonomyID(organism OrganismType) string {
+	for _, reference := range organism.DbReference {
+		if reference.Type == "NCBI Taxonomy" {
+			return reference.Id
+		}
+	}
+	return ""
+}
+
+// sequence returns the sequence of an entry without whitespace.
+func (entry *Entry) sequence() string {
+	return strings.Join(strings.Fields(entry.Sequence.Value), "")
+}
+
+// ToFasta converts an entry to a fasta record with a Uniprot fasta header.
+func (entry *Entry) ToFasta() fasta.Record {
+	database := "tr"
+	if entry.Dataset == "Swiss-Prot" {
+		database = "sp"
+	}
+	var accession, name string
+	if len(entry.Accession) > 0 {
+		accession = entry.Accession[0]
+	}
+	if len(entry.Name) > 0 {
+		name = entry.Name[0]
+	}
+	header := fmt.Sprintf("%s|%s|%s %s", database, accession, name, entry.ProteinName())
+	if entry.Sequence.Fragment != "" {
+		header += " (Fragment)"
+	}
+	if organism := scientificName(entry.Organism); organism != "" {
+		header += " OS=" + organism
+	}
+	if taxonomy := taxonomyID(entry.Organism); taxonomy != "" {
+		header += " OX=" + taxonomy
+	}
+	if gene := entry.geneName(); gene != "" {
+		header += " GN=" + gene
+	}
+	if level, ok := proteinExistence[entry.ProteinExistence.Type]; ok {
+		header += " PE=" + strconv.Itoa(level)
+	}
+	header += " SV=" + strconv.Itoa(entry.Sequence.Version)
+	return fasta.Record{Identifier: header, Sequence: entry.sequence()}
+}
+
+// genpeptFeature is the GenPept feature of a type of Uniprot feature.
+type genpeptFeature struct {
+	featureType string // type of the GenPept feature
+	qualifier   string // qualifier with the kind of feature, if any
+	value       string // value of the qualifier
+}
+
+// genpeptFeatures maps the types of Uniprot features to GenPept features.
+// Other types of features are Region features.
+var genpeptFeatures = map[Type]genpeptFeature{
+	"chain":                       {"mat_peptide", "", ""},
+	"peptide":                     {"mat_peptide", "", ""},
+	"propeptide":                  {"propeptide", "", ""},
+	"signal peptide":              {"sig_peptide", "", ""},
+	"transit peptide":             {"transit_peptide", "", ""},
+	"active site":                 {"Site", "site_type", "active"},
+	"binding site":                {"Site", "site_type", "binding"},
+	"metal ion-binding site":      {"Site", "site_type", "metal-binding"},
+	"site":                        {"Site", "site_type", "other"},
+	"modified residue":            {"Site", "site_type", "modified"},
+	"glycosylation site":          {"Site", "site_type", "glycosylation"},
+	"lipid moiety-binding region": {"Site", "site_type", "lipid-binding"},
+	"mutagenesis site":            {"Site", "site_type", "mutagenized"},
+	"disulfide bond":              {"Bond", "bond_type", "disulfide"},
+	"cross-link":                  {"Bond", "bond_type", "xlink"},
+	"helix":                       {"SecStr", "sec_str_type", "helix"},
+	"strand":                      {"SecStr", "sec_str_type", "sheet"},
+	"turn":                        {"SecStr", "sec_str_type", "turn"},
+}
+
+// featureLocation returns the genbank location of a Uniprot feature. It
+// returns false for features without a known location.
+func featureLocation(location LocationType, bond bool) (genbank.Location, bool) {
+	if location.Position.Position != 0 {
+		position := int(location.Position.Position)
+		return genbank.Location{Start: position - 1, End: position, GbkLocationString: strconv.Itoa(position)}, true
+	}
+	begin, end := int(location.Begin.Position), int(location.End.Position)
+	if begin == 0 || end == 0 {
+		return genbank.Location{}, false
+	}
+	// bonds are between two residues, not the residues between them.
+	if bond {
+		return genbank.Location{
+			Start: begin - 1,
+			End:   end,
+			Bond:  true,
+			SubLocations: []genbank.Location{
+				{Start: begin - 1, End: begin},
+				{Start: end - 1, End: end},
+			},
+			GbkLocationString: fmt.Sprintf("bond(%d,%d)", begin, end),
+		}, true
+	}
+	return genbank.Location{
+		Start:             begin - 1,
+		End:               end,
+		FivePrimePartial:  location.Begin.Status == "less than",
+		ThreePrimePartial: location.End.Status == "greater than",
+	}, true
+}
+
+// gbkDivisions are the GenBank divisions of taxa, in order of precedence.
+var gbkDivisions = []struct{ taxon, division string }{
+	{"Viruses", "VRL"},
+	{"Bacteria", "BCT"},
+	{"Archaea", "BCT"},
+	{"Primates", "PRI"},
+	{"Rodentia", "ROD"},
+	{"Mammalia", "MAM"},
+	{"Vertebrata", "VRT"},
+	{"Viridiplantae", "PLN"},
+	{"Fungi", "PLN"},
+	{"Eukaryota", "INV"},
+}
+
+// gbkDivision returns the GenBank division of an organism from its lineage.
+func gbkDivision(lineage []string) string {
+	for _, division := range gbkDivisions {
+		for _, taxon := range lineage {
+			if taxon == division.taxon {
+				return division.division
+			}
+		}
+	}
+	return "UNA"
+}
+
+// ToGenbank converts an entry to a protein genbank record, like a GenPept
+// record. Features that aren't on the canonical sequence of the entry, like
+// features of isoforms, are skipped.
+func (entry *Entry) ToGenbank() genbank.Genbank {
+	sequence := entry.sequence()
+	var accession, name string
+	if len(entry.Accession) > 0 {
+		accession = entry.Accession[0]
+	}
+	if len(entry.Name) > 0 {
+		name = entry.Name[0]
+	}
+	organism := scientificName(entry.Organism)
+	source := organism
+	for _, organismName := range entry.Organism.Name {
+		if organismName.Type == "common" {
+			source += " (" + organismName.Value + ")"
+			break
+		}
+	}
+	var keywords []string
+	for _, keyword := range entry.Keyword {
+		keywords = append(keywords, keyword.Value)
+	}
+	record := genbank.Genbank{
+		Meta: genbank.Meta{
+			Definition: entry.ProteinName() + ".",
+			Accession:  accession,
+			Version:    accession + "." + strconv.Itoa(entry.Sequence.Version),
+			Keywords:   strings.Join(keywords, "; ") + ".",
+			Organism:   organism,
+			Source:     source,
+			Taxonomy:   entry.Organism.Lineage.Taxon,
+			Locus: genbank.Locus{
+				Name:             name,
+				SequenceLength:   strconv.Itoa(len(sequence)),
+				SequenceCoding:   "aa",
+				GenbankDivision:  gbkDivision(entry.Organism.Lineage.Taxon),
+				ModificationDate: strings.ToUpper(entry.Modified.Format("02-Jan-2006")),
+			},
+			Other: map[string]string{"DBSOURCE": fmt.Sprintf("UniProtKB: locus %s, accession %s", name, accession)},
+		},
+		Sequence: sequence,
+	}
+
+	sourceFeature := genbank.Feature{Type: "source", Location: genbank.Location{End: len(sequence)}, Attributes: genbank.NewMultiMap[string, string]()}
+	genbank.Put(sourceFeature.Attributes, "organism", organism)
+	if taxonomy := taxonomyID(entry.Organism); taxonomy != "" {
+		genbank.Put(sourceFeature.Attributes, "db_xref", "taxon:"+taxonomy)
+	}
+	features := []genbank.Feature{sourceFeature}
+
+	proteinFeature := genbank.Feature{Type: "Protein", Location: genbank.Location{End: len(sequence)}, Attributes: genbank.NewMultiMap[string, string]()}
+	genbank.Put(proteinFeature.Attributes, "product", entry.ProteinName())
+	for _, ecNumber := range entry.Protein.RecommendedName.EcNumber {
+		genbank.Put(proteinFeature.Attributes, "EC_number", ecNumber.Value)
+	}
+	if gene := entry.geneName(); gene != "" {
+		genbank.Put(proteinFeature.Attributes, "gene", gene)
+	}
+	features = append(features, proteinFeature)
+
+	for _, uniprotFeature := range entry.Feature {
+		if uniprotFeature.Location.Sequence != "" {
+			continue
+		}
+		mapping, ok := genpeptFeatures[uniprotFeature.Type]
+		if !ok {
+			mapping = genpeptFeature{"Region", "region_name", string(uniprotFeature.Type)}
+		}
+		location, ok := featureLocation(uniprotFeature.Location, mapping.featureType == "Bond")
+		if !ok {
+			continue
+		}
+		feature := genbank.Feature{Type: mapping.featureType, Location: location, Attributes: genbank.NewMultiMap[string, string]()}
+		if mapping.qualifier != "" {
+			genbank.Put(feature.Attributes, mapping.qualifier, mapping.value)
+		}
+		note := uniprotFeature.Description
+		if uniprotFeature.Original != "" && len(uniprotFeature.Variation) > 0 {
+			note = strings.TrimSuffix(fmt.Sprintf("%s -> %s: %s", uniprotFeature.Original, strings.Join(uniprotFeature.Variation, ","), note), ": ")
+		}
+		if note != "" {
+			// peptides are named by their product.
+			if mapping.qualifier == "" {
+				genbank.Put(feature.Attributes, "product", note)
+			} else {
+				genbank.Put(feature.Attributes, "note", note)
+			}
+		}
+		features = append(features, feature)
+	}
+	for i := range features {
+		_ = record.AddFeature(&features[i])
+	}
+	return record
+}
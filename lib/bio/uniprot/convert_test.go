@@ -0,0 +1,127 @@
+package uniprot_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+	"github.com/koeng101/dnadesign/lib/bio/uniprot"
+)
+
+func gfpEntry(t *testing.T) uniprot.Entry {
+	t.Helper()
+	entry, err := uniprot.NewParser(bytes.NewReader(gfpXML)).Next()
+	if err != nil {
+		t.Fatalf("Failed to parse GFP entry: %s", err)
+	}
+	return entry
+}
+
+func TestToFasta(t *testing.T) {
+	entry := gfpEntry(t)
+	record := entry.ToFasta()
+	expectedIdentifier := "sp|P42212|GFP_AEQVI Green fluorescent protein OS=Aequorea victoria OX=6100 GN=GFP PE=1 SV=1"
+	if record.Identifier != expectedIdentifier {
+		t.Errorf("Expected identifier %q, got %q", expectedIdentifier, record.Identifier)
+	}
+	if len(record.Sequence) != 238 || !strings.HasPrefix(record.Sequence, "MSKGEELFTG") {
+		t.Errorf("Expected the 238 aa sequence of GFP, got %q", record.Sequence)
+	}
+
+	uniprotFile, err := os.Open("data/uniprot_sprot_mini.xml.gz")
+	if err != nil {
+		t.Fatalf("Failed to open file: %s", err)
+	}
+	defer uniprotFile.Close()
+	unzippedFile, _ := gzip.NewReader(uniprotFile)
+	entry, err = uniprot.NewParser(unzippedFile).Next()
+	if err != nil {
+		t.Fatalf("Failed to parse entry: %s", err)
+	}
+	record = entry.ToFasta()
+	expectedIdentifier = "sp|P0C9F0|1001R_ASFK5 Protein MGF 100-1R OS=African swine fever virus (isolate Pig/Kenya/KEN-50/1950) OX=561445 GN=Ken-018 PE=3 SV=1"
+	if record.Identifier != expectedIdentifier {
+		t.Errorf("Expected identifier %q, got %q", expectedIdentifier, record.Identifier)
+	}
+}
+
+func TestToFastaFragment(t *testing.T) {
+	entry := uniprot.Entry{
+		Dataset:   "TrEMBL",
+		Accession: []string{"A0A000"},
+		Name:      []string{"A0A000_ECOLX"},
+		Sequence:  uniprot.SequenceType{Value: "\n  MKV\n  LA\n", Fragment: "single", Version: 2},
+	}
+	entry.Protein.SubmittedName = append(entry.Protein.SubmittedName, uniprot.SubmittedName{FullName: uniprot.EvidencedStringType{Value: "Uncharacterized protein"}})
+	record := entry.ToFasta()
+	expectedIdentifier := "tr|A0A000|A0A000_ECOLX Uncharacterized protein (Fragment) SV=2"
+	if record.Identifier != expectedIdentifier {
+		t.Errorf("Expected identifier %q, got %q", expectedIdentifier, record.Identifier)
+	}
+	if record.Sequence != "MKVLA" {
+		t.Errorf("Expected sequence MKVLA, got %q", record.Sequence)
+	}
+}
+
+func TestToGenbank(t *testing.T) {
+	entry := gfpEntry(t)
+	record := entry.ToGenbank()
+	if record.Meta.Locus.Name != "GFP_AEQVI" || record.Meta.Locus.SequenceCoding != "aa" || record.Meta.Locus.GenbankDivision != "INV" {
+		t.Errorf("Unexpected locus %+v", record.Meta.Locus)
+	}
+	if record.Meta.Version != "P42212.1" {
+		t.Errorf("Expected version P42212.1, got %s", record.Meta.Version)
+	}
+
+	featureTypes := make(map[string]int)
+	for _, feature := range record.Features {
+		featureTypes[feature.Type]++
+	}
+	// 4 sequence variants and 5 sequence conflicts are regions.
+	for featureType, count := range map[string]int{"source": 1, "Protein": 1, "mat_peptide": 1, "Site": 55, "Bond": 1, "SecStr": 30, "Region": 9} {
+		if featureTypes[featureType] != count {
+			t.Errorf("Expected %d %s features, got %d", count, featureType, featureTypes[featureType])
+		}
+	}
+
+	for _, feature := range record.Features {
+		switch {
+		case feature.Type == "Bond":
+			if feature.Location.GbkLocationString != "bond(65,67)" || feature.Attributes["bond_type"][0] != "xlink" {
+				t.Errorf("Unexpected cross-link %+v", feature)
+			}
+		case feature.Type == "Site" && feature.Attributes["site_type"][0] == "modified":
+			sequence, _ := feature.GetSequence()
+			if sequence != "Y" || feature.Attributes["note"][0] != "(Z)-2,3-didehydrotyrosine" {
+				t.Errorf("Expected modified Y66, got %s %+v", sequence, feature)
+			}
+		case feature.Type == "mat_peptide":
+			sequence, _ := feature.GetSequence()
+			if sequence != record.Sequence || feature.Attributes["product"][0] != "Green fluorescent protein" {
+				t.Errorf("Expected chain of the whole protein, got %+v", feature)
+			}
+		}
+	}
+
+	// Protein genbank records can be written and read back.
+	var buffer bytes.Buffer
+	if _, err := record.WriteTo(&buffer); err != nil {
+		t.Fatalf("Failed to write genbank: %s", err)
+	}
+	if !strings.HasPrefix(buffer.String(), "LOCUS       GFP_AEQVI     238 aa") {
+		t.Errorf("Expected locus in amino acids, got %q", strings.SplitN(buffer.String(), "\n", 2)[0])
+	}
+	parsed, err := genbank.NewParser(&buffer, 1024*1024).Next()
+	if err != nil {
+		t.Fatalf("Failed to parse written genbank: %s", err)
+	}
+	if parsed.Sequence != strings.ToLower(record.Sequence) && parsed.Sequence != record.Sequence {
+		t.Errorf("Sequence changed when written and read back")
+	}
+	if len(parsed.Features) != len(record.Features) {
+		t.Errorf("Expected %d features, got %d", len(record.Features), len(parsed.Features))
+	}
+}
@@ -1,8 +1,11 @@
 package uniprot_test
 
 import (
+	"bytes"
 	"compress/gzip"
+	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/koeng101/dnadesign/lib/bio/uniprot"
@@ -21,3 +24,44 @@ func Example_basic() {
 	fmt.Println(entry.Accession[0])
 	// Output: P0C9F0
 }
+
+// This example shows how to index a uniprot data dump, so that uniprot.Get
+// reads entries out of it instead of using the Uniprot REST API.
+func Example_localIndex() {
+	uniprotFile, _ := os.Open("data/uniprot_sprot_mini.xml.gz")
+	defer uniprotFile.Close()
+	unzippedFile, _ := gzip.NewReader(uniprotFile)
+	// Entries are read at an offset, so the data dump must be decompressed.
+	// Large data dumps would be decompressed to a file instead.
+	data, _ := io.ReadAll(unzippedFile)
+
+	index, _ := uniprot.BuildIndex(bytes.NewReader(data))
+	uniprot.DefaultBackend = uniprot.NewIndexedReader(bytes.NewReader(data), index)
+	defer func() { uniprot.DefaultBackend = &uniprot.RESTBackend{} }()
+
+	entry, _ := uniprot.Get(context.Background(), "P0C9F0")
+	fmt.Println(len(index.Records), entry.Name[0])
+	// Output: 20 1001R_ASFK5
+}
+
+// This example shows how to convert a uniprot entry to a fasta record with a
+// Uniprot fasta header, and to a protein genbank record with the features of
+// the entry.
+func ExampleEntry_ToFasta() {
+	uniprotFile, _ := os.Open("data/P42212.xml")
+	defer uniprotFile.Close()
+	entry, _ := uniprot.NewParser(uniprotFile).Next()
+
+	record := entry.ToFasta()
+	fmt.Println(record.Identifier)
+
+	protein := entry.ToGenbank()
+	for _, feature := range protein.Features {
+		if feature.Type == "Bond" {
+			fmt.Println(feature.Location.GbkLocationString, feature.Attributes["note"][0])
+		}
+	}
+	// Output:
+	// sp|P42212|GFP_AEQVI Green fluorescent protein OS=Aequorea victoria OX=6100 GN=GFP PE=1 SV=1
+	// bond(65,67) 5-imidazolinone (Ser-Gly)
+}
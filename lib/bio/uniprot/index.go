@@ -0,0 +1,207 @@
+package uniprot
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+Uniprot index begins here
+
+Get fetches one entry at a time from the Uniprot REST API, which is too slow
+for bulk jobs and impossible on computers without internet access. Instead,
+Uniprot can be downloaded once as an XML data dump and indexed, so that any
+entry can be read directly out of the dump by its accession.
+
+The index is a tab-delimited file with one line per entry, like a samtools
+.fai index:
+
+	ACCESSION	OFFSET	LENGTH
+
+where OFFSET is the byte offset of the <entry> element in the XML file and
+LENGTH is the number of bytes of the element. Entries are indexed by their
+primary accession, which is their first accession.
+
+Data dumps are distributed gzipped (uniprot_sprot.xml.gz), and gzipped files
+can't be read at an offset. They can still be indexed while they are
+decompressed, since offsets are always offsets in the decompressed XML, but
+reading entries with an IndexedReader needs the decompressed file.
+
+******************************************************************************/
+
+// ErrNotFound is returned when an entry can't be found.
+var ErrNotFound = errors.New("uniprot entry not found")
+
+// uniprotNamespace is the XML namespace of Uniprot entries.
+const uniprotNamespace = "http://uniprot.org/uniprot"
+
+// IndexRecord is a single line of a Uniprot index, describing where a single
+// entry is found in a Uniprot XML file.
+type IndexRecord struct {
+	Accession string // Primary accession of the entry.
+	Offset    int64  // Byte offset of the entry in the file.
+	Length    int64  // Number of bytes of the entry.
+}
+
+// Index is an index of the entries of a Uniprot XML file.
+type Index struct {
+	Records []IndexRecord
+}
+
+// BuildIndex reads a Uniprot XML file and builds its index.
+func BuildIndex(r io.Reader) (Index, error) {
+	var index Index
+	decoder := xml.NewDecoder(bufio.NewReader(r))
+	accessions := make(map[string]bool)
+	var record *IndexRecord // the entry being read, if any
+	var depth int           // depth of elements inside the entry
+	var inAccession bool
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Index{}, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if record == nil {
+				if token.Name.Local == "entry" {
+					record = &IndexRecord{Offset: offset}
+					depth = 0
+				}
+				continue
+			}
+			depth++
+			inAccession = depth == 1 && token.Name.Local == "accession" && record.Accession == ""
+		case xml.CharData:
+			if inAccession {
+				record.Accession += strings.TrimSpace(string(token))
+			}
+		case xml.EndElement:
+			inAccession = false
+			if record == nil {
+				continue
+			}
+			if depth > 0 {
+				depth--
+				continue
+			}
+			record.Length = decoder.InputOffset() - record.Offset
+			if record.Accession == "" {
+				return Index{}, fmt.Errorf("entry at byte %d has no accession", record.Offset)
+			}
+			if accessions[record.Accession] {
+				return Index{}, fmt.Errorf("duplicate accession %q at byte %d", record.Accession, record.Offset)
+			}
+			accessions[record.Accession] = true
+			index.Records = append(index.Records, *record)
+			record = nil
+		}
+	}
+	if record != nil {
+		return Index{}, fmt.Errorf("entry at byte %d is not closed: %w", record.Offset, io.ErrUnexpectedEOF)
+	}
+	return index, nil
+}
+
+// ReadIndex reads a Uniprot index.
+func ReadIndex(r io.Reader) (Index, error) {
+	var index Index
+	scanner := bufio.NewScanner(r)
+	var lineNumber int
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if len(line) == 0 {
+			continue
+		}
+		values := strings.Split(line, "\t")
+		if len(values) < 3 {
+			return Index{}, fmt.Errorf("line %d: expected 3 tab-delimited values, got %d", lineNumber, len(values))
+		}
+		record := IndexRecord{Accession: values[0]}
+		for i, field := range []*int64{&record.Offset, &record.Length} {
+			value, err := strconv.ParseInt(values[i+1], 10, 64)
+			if err != nil {
+				return Index{}, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			*field = value
+		}
+		index.Records = append(index.Records, record)
+	}
+	return index, scanner.Err()
+}
+
+// WriteTo writes the index.
+func (index *Index) WriteTo(w io.Writer) (int64, error) {
+	var writtenBytes int64
+	for _, record := range index.Records {
+		newWrittenBytes, err := fmt.Fprintf(w, "%s\t%d\t%d\n", record.Accession, record.Offset, record.Length)
+		writtenBytes += int64(newWrittenBytes)
+		if err != nil {
+			return writtenBytes, err
+		}
+	}
+	return writtenBytes, nil
+}
+
+// IndexedReader provides random access to the entries of an indexed Uniprot
+// XML file. It should be initialized with NewIndexedReader. It is a Backend,
+// so it can be used as the DefaultBackend of Get.
+type IndexedReader struct {
+	reader  io.ReaderAt
+	records map[string]IndexRecord
+}
+
+// NewIndexedReader returns an IndexedReader that reads entries from r using
+// index. r must be the decompressed XML file.
+func NewIndexedReader(r io.ReaderAt, index Index) *IndexedReader {
+	records := make(map[string]IndexRecord, len(index.Records))
+	for _, record := range index.Records {
+		records[record.Accession] = record
+	}
+	return &IndexedReader{reader: r, records: records}
+}
+
+// Fetch returns the entry with the given primary accession.
+func (ir *IndexedReader) Fetch(accession string) (Entry, error) {
+	record, ok := ir.records[accession]
+	if !ok {
+		return Entry{}, fmt.Errorf("%w: %s is not in the index", ErrNotFound, accession)
+	}
+	section := io.NewSectionReader(ir.reader, record.Offset, record.Length)
+	decoder := xml.NewDecoder(section)
+	// Entries usually declare their namespace, but not if the namespace is
+	// only declared at the root of the file.
+	decoder.DefaultSpace = uniprotNamespace
+	parser := &Parser{decoder: decoder}
+	entry, err := parser.Next()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return Entry{}, fmt.Errorf("failed to read entry %s: %w", accession, err)
+	}
+	if len(entry.Accession) == 0 || entry.Accession[0] != accession {
+		return Entry{}, fmt.Errorf("index record of %s points to another entry. The index may be out of date", accession)
+	}
+	return entry, nil
+}
+
+// Get returns the entry with the given primary accession, like Fetch.
+func (ir *IndexedReader) Get(ctx context.Context, accession string) (Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return Entry{}, err
+	}
+	return ir.Fetch(accession)
+}
@@ -0,0 +1,165 @@
+package uniprot_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/koeng101/dnadesign/lib/bio/uniprot"
+)
+
+// readMini returns the decompressed uniprot_sprot_mini.xml.gz.
+func readMini(t *testing.T) []byte {
+	t.Helper()
+	file, err := os.Open("data/uniprot_sprot_mini.xml.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	unzippedFile, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(unzippedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestIndex(t *testing.T) {
+	data := readMini(t)
+	index, err := uniprot.BuildIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Records) != 20 {
+		t.Fatalf("expected 20 records, got %d", len(index.Records))
+	}
+	for _, record := range index.Records {
+		entryXML := string(data[record.Offset : record.Offset+record.Length])
+		if !strings.HasPrefix(entryXML, "<entry") || !strings.HasSuffix(entryXML, "</entry>") {
+			t.Errorf("record %+v does not cover an entry", record)
+		}
+	}
+
+	// every entry read through the index is the same as the parsed entry.
+	reader := uniprot.NewIndexedReader(bytes.NewReader(data), index)
+	parser := uniprot.NewParser(bytes.NewReader(data))
+	for _, record := range index.Records {
+		expected, _ := parser.Next()
+		entry, err := reader.Fetch(record.Accession)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, entry); diff != "" {
+			t.Errorf("entry %s changed (-want +got):\n%s", record.Accession, diff)
+		}
+	}
+	if _, err := reader.Fetch("P42212"); !errors.Is(err, uniprot.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestIndexReadWrite(t *testing.T) {
+	data := readMini(t)
+	index, _ := uniprot.BuildIndex(bytes.NewReader(data))
+	var buffer bytes.Buffer
+	if _, err := index.WriteTo(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buffer.String(), "P0C9F0\t") {
+		t.Errorf("unexpected index %s", buffer.String())
+	}
+	readIndex, err := uniprot.ReadIndex(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(index, readIndex); diff != "" {
+		t.Errorf("index changed after writing (-want +got):\n%s", diff)
+	}
+	if _, err := uniprot.ReadIndex(strings.NewReader("P0C9F0\t100\n")); err == nil {
+		t.Errorf("expected an error for a missing column")
+	}
+	if _, err := uniprot.ReadIndex(strings.NewReader("P0C9F0\t100\tlong\n")); err == nil {
+		t.Errorf("expected an error for an invalid length")
+	}
+}
+
+func TestIndexErrors(t *testing.T) {
+	data := readMini(t)
+	if _, err := uniprot.BuildIndex(bytes.NewReader(data[:len(data)/2])); err == nil {
+		t.Errorf("expected an error for a truncated file")
+	}
+	duplicate := `<uniprot><entry><accession>P1</accession></entry><entry><accession>P1</accession></entry></uniprot>`
+	if _, err := uniprot.BuildIndex(strings.NewReader(duplicate)); err == nil {
+		t.Errorf("expected an error for a duplicate accession")
+	}
+	if _, err := uniprot.BuildIndex(strings.NewReader(`<uniprot><entry><name>P1</name></entry></uniprot>`)); err == nil {
+		t.Errorf("expected an error for an entry without accession")
+	}
+
+	// an index of another file points to the wrong entries.
+	index, _ := uniprot.BuildIndex(bytes.NewReader(data))
+	index.Records[0].Offset = index.Records[1].Offset
+	reader := uniprot.NewIndexedReader(bytes.NewReader(data), index)
+	if _, err := reader.Fetch(index.Records[0].Accession); err == nil {
+		t.Errorf("expected an error for an index record pointing to the wrong entry")
+	}
+}
+
+func TestGetBackends(t *testing.T) {
+	ctx := context.Background()
+	// entries of files that only declare the uniprot namespace at the root
+	// are read in the namespace.
+	rootNamespace := bytes.Replace(gfpXML, []byte(` xmlns="http://uniprot.org/uniprot">`), []byte(">"), 1)
+	if bytes.Equal(rootNamespace, gfpXML) {
+		t.Fatal("expected the entry to declare its namespace")
+	}
+	index, _ := uniprot.BuildIndex(bytes.NewReader(rootNamespace))
+	reader := uniprot.NewIndexedReader(bytes.NewReader(rootNamespace), index)
+	originalBackend := uniprot.DefaultBackend
+	uniprot.DefaultBackend = reader
+	defer func() { uniprot.DefaultBackend = originalBackend }()
+	entry, err := uniprot.Get(ctx, "P42212")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Name[0] != "GFP_AEQVI" {
+		t.Errorf("expected GFP_AEQVI, got %s", entry.Name[0])
+	}
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := uniprot.Get(cancelledCtx, "P42212"); err == nil {
+		t.Errorf("expected an error for a cancelled context")
+	}
+
+	// REST backends can use their own client and URL.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/uniprotkb/P42212.xml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(gfpXML)
+	}))
+	defer server.Close()
+	backend := &uniprot.RESTBackend{BaseURL: server.URL + "/uniprotkb/", Client: server.Client()}
+	entry, err = backend.Get(ctx, "P42212")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Accession[0] != "P42212" {
+		t.Errorf("expected P42212, got %s", entry.Accession[0])
+	}
+	if _, err := backend.Get(ctx, "P00000"); !errors.Is(err, uniprot.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
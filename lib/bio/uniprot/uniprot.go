@@ -99,12 +99,37 @@ func (p *Parser) Next() (Entry, error) {
 // BaseURL encodes the base URL for the Uniprot REST API.
 var BaseURL string = "https://rest.uniprot.org/uniprotkb/"
 
-// Get gets a uniprot from its accessionID
+// Backend gets Uniprot entries by their accession.
+type Backend interface {
+	Get(ctx context.Context, accession string) (Entry, error)
+}
+
+// DefaultBackend is the Backend used by Get. It is the Uniprot REST API, but
+// can be set to an IndexedReader of a local data dump to use Get without
+// internet access.
+var DefaultBackend Backend = &RESTBackend{}
+
+// RESTBackend gets entries from the Uniprot REST API.
+type RESTBackend struct {
+	BaseURL string       // URL of the REST API. If empty, the package BaseURL is used.
+	Client  *http.Client // HTTP client of requests. If nil, http.DefaultClient is used.
+}
+
+// Get gets a uniprot entry from its accessionID with the DefaultBackend.
 func Get(ctx context.Context, accessionID string) (Entry, error) {
+	return DefaultBackend.Get(ctx, accessionID)
+}
+
+// Get gets a uniprot entry from its accessionID with the REST API.
+func (backend *RESTBackend) Get(ctx context.Context, accessionID string) (Entry, error) {
 	var entry Entry
 
 	// Parse the base URL
-	baseURL, err := url.Parse(BaseURL)
+	rawBaseURL := backend.BaseURL
+	if rawBaseURL == "" {
+		rawBaseURL = BaseURL
+	}
+	baseURL, err := url.Parse(rawBaseURL)
 	if err != nil {
 		return entry, err
 	}
@@ -116,8 +141,11 @@ func Get(ctx context.Context, accessionID string) (Entry, error) {
 	// the URL, no err is checked here.
 	req, _ := http.NewRequestWithContext(ctx, "GET", fullURL.String(), nil)
 
-	// Create a new HTTP client and send the request
-	client := &http.Client{}
+	// Send the request
+	client := backend.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return entry, err
@@ -125,6 +153,9 @@ func Get(ctx context.Context, accessionID string) (Entry, error) {
 	defer resp.Body.Close()
 
 	// Check for HTTP errors
+	if resp.StatusCode == http.StatusNotFound {
+		return entry, fmt.Errorf("%w: %s", ErrNotFound, accessionID)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return entry, fmt.Errorf("Got http status code: %d", resp.StatusCode)
 	}
@@ -0,0 +1,115 @@
+package uniref
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/koeng101/dnadesign/lib/bio/fasta"
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+/******************************************************************************
+
+Conversion to fasta and genbank begins here
+
+Entries are converted to fasta records of their representative member, with
+the headers of the fasta files of UniRef, like:
+
+	UniRef50_Q9K794 Putative AgrB-like protein n=2 Tax=Bacillus TaxID=86661 RepID=AGRB_BACHD
+
+and to protein genbank records. UniRef entries have no features, so the
+genbank records only have source and Protein features.
+
+Spec: https://www.uniprot.org/help/fasta-headers
+
+******************************************************************************/
+
+// property returns the value of the property of a type.
+func property(properties []Property, propertyType string) string {
+	for _, property := range properties {
+		if property.Type == propertyType {
+			return property.Value
+		}
+	}
+	return ""
+}
+
+// ClusterName returns the name of the cluster of an entry.
+func (e *Entry) ClusterName() string {
+	return strings.TrimPrefix(e.Name, "Cluster: ")
+}
+
+// sequence returns the sequence of the representative member of an entry
+// without whitespace.
+func (e *Entry) sequence() string {
+	if e.RepMember.Sequence == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(e.RepMember.Sequence.Value), "")
+}
+
+// ToFasta converts an entry to a fasta record of the sequence of its
+// representative member with a UniRef fasta header.
+func (e *Entry) ToFasta() fasta.Record {
+	header := e.ID + " " + e.ClusterName()
+	if members := property(e.Properties, "member count"); members != "" {
+		header += " n=" + members
+	}
+	if taxon := property(e.Properties, "common taxon"); taxon != "" {
+		header += " Tax=" + taxon
+	}
+	if taxonID := property(e.Properties, "common taxon ID"); taxonID != "" {
+		header += " TaxID=" + taxonID
+	}
+	// representative members from UniProtKB are named by their entry name,
+	// and members from UniParc by their UniParc ID.
+	repID := property(e.RepMember.DBRef.Properties, "UniProtKB ID")
+	if repID == "" {
+		repID = e.RepMember.DBRef.ID
+	}
+	if repID != "" {
+		header += " RepID=" + repID
+	}
+	return fasta.Record{Identifier: header, Sequence: e.sequence()}
+}
+
+// ToGenbank converts an entry to a protein genbank record of the sequence of
+// its representative member.
+func (e *Entry) ToGenbank() genbank.Genbank {
+	sequence := e.sequence()
+	taxon := property(e.Properties, "common taxon")
+	record := genbank.Genbank{
+		Meta: genbank.Meta{
+			Definition: e.ClusterName() + ".",
+			Accession:  e.ID,
+			Version:    e.ID,
+			Keywords:   ".",
+			Organism:   taxon,
+			Source:     taxon,
+			Locus: genbank.Locus{
+				Name:             e.ID,
+				SequenceLength:   strconv.Itoa(len(sequence)),
+				SequenceCoding:   "aa",
+				GenbankDivision:  "UNA",
+				ModificationDate: e.Updated,
+			},
+			Other: map[string]string{"DBSOURCE": e.RepMember.DBRef.Type + ": " + e.RepMember.DBRef.ID},
+		},
+		Sequence: sequence,
+	}
+	if updated, err := time.Parse("2006-01-02", e.Updated); err == nil {
+		record.Meta.Locus.ModificationDate = strings.ToUpper(updated.Format("02-Jan-2006"))
+	}
+
+	source := genbank.Feature{Type: "source", Location: genbank.Location{End: len(sequence)}, Attributes: genbank.NewMultiMap[string, string]()}
+	genbank.Put(source.Attributes, "organism", taxon)
+	if taxonID := property(e.Properties, "common taxon ID"); taxonID != "" {
+		genbank.Put(source.Attributes, "db_xref", "taxon:"+taxonID)
+	}
+	protein := genbank.Feature{Type: "Protein", Location: genbank.Location{End: len(sequence)}, Attributes: genbank.NewMultiMap[string, string]()}
+	genbank.Put(protein.Attributes, "product", e.ClusterName())
+	_ = record.AddFeature(&source)
+	_ = record.AddFeature(&protein)
+	return record
+}
@@ -0,0 +1,54 @@
+package uniref
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToFasta(t *testing.T) {
+	parser, err := NewParser(strings.NewReader(testData50))
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+	entry, err := parser.Next()
+	if err != nil {
+		t.Fatalf("Failed to parse entry: %v", err)
+	}
+	record := entry.ToFasta()
+	expectedIdentifier := "UniRef50_UPI002E2621C6 uncharacterized protein LOC134193701 n=1 Tax=Corticium candelabrum TaxID=121492 RepID=UPI002E2621C6"
+	if record.Identifier != expectedIdentifier {
+		t.Errorf("Expected identifier %q, got %q", expectedIdentifier, record.Identifier)
+	}
+	if record.Sequence != "MGR" {
+		t.Errorf("Expected sequence MGR, got %q", record.Sequence)
+	}
+
+	// Representative members from UniProtKB are named by their entry name.
+	entry.RepMember.DBRef = DBReference{Type: "UniProtKB ID", ID: "GFP_AEQVI", Properties: []Property{{Type: "UniProtKB accession", Value: "P42212"}, {Type: "UniProtKB ID", Value: "GFP_AEQVI"}}}
+	entry.RepMember.Sequence = nil
+	record = entry.ToFasta()
+	if !strings.HasSuffix(record.Identifier, " RepID=GFP_AEQVI") || record.Sequence != "" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+}
+
+func TestToGenbank(t *testing.T) {
+	parser, err := NewParser(strings.NewReader(testData50))
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+	entry, err := parser.Next()
+	if err != nil {
+		t.Fatalf("Failed to parse entry: %v", err)
+	}
+	record := entry.ToGenbank()
+	if record.Meta.Locus.Name != "UniRef50_UPI002E2621C6" || record.Meta.Locus.SequenceCoding != "aa" || record.Meta.Locus.ModificationDate != "29-MAY-2024" {
+		t.Errorf("Unexpected locus %+v", record.Meta.Locus)
+	}
+	if record.Meta.Organism != "Corticium candelabrum" || record.Sequence != "MGR" {
+		t.Errorf("Unexpected record %+v", record.Meta)
+	}
+	if len(record.Features) != 2 || record.Features[1].Attributes["product"][0] != "uncharacterized protein LOC134193701" {
+		t.Errorf("Unexpected features %+v", record.Features)
+	}
+}
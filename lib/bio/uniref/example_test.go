@@ -28,3 +28,16 @@ func Example() {
 	// Name: Cluster: uncharacterized protein LOC134193701
 	// Sequence Length: 49499
 }
+
+// This example shows how to convert UniRef entries to fasta records, like the
+// fasta files of UniRef.
+func ExampleEntry_ToFasta() {
+	file, _ := os.Open(filepath.Join("data", "uniref90_mini.xml"))
+	defer file.Close()
+	parser, _ := uniref.NewParser(file)
+	entry, _ := parser.Next()
+
+	record := entry.ToFasta()
+	fmt.Println(record.Identifier)
+	// Output: UniRef50_UPI002E2621C6 uncharacterized protein LOC134193701 n=1 Tax=Corticium candelabrum TaxID=121492 RepID=UPI002E2621C6
+}
@@ -0,0 +1,188 @@
+package uniref
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+UniRef index begins here
+
+Like Uniprot data dumps, UniRef data dumps are too big to parse to get a few
+clusters out of them. The index records where each entry is in the XML file,
+so that entries can be read directly by their id, like UniRef90_P42212.
+
+The index is a tab-delimited file with one line per entry:
+
+	ID	OFFSET	LENGTH
+
+where OFFSET is the byte offset of the <entry> element in the XML file and
+LENGTH is the number of bytes of the element. Offsets are offsets in the
+decompressed XML, so gzipped files can be indexed while they are
+decompressed, but entries can only be read from the decompressed file.
+
+******************************************************************************/
+
+// ErrNotFound is returned when an entry isn't in an index.
+var ErrNotFound = errors.New("uniref entry not found")
+
+// unirefNamespace is the XML namespace of UniRef entries.
+const unirefNamespace = "http://uniprot.org/uniref"
+
+// IndexRecord is a single line of a UniRef index, describing where a single
+// entry is found in a UniRef XML file.
+type IndexRecord struct {
+	ID     string // ID of the entry, like UniRef90_P42212.
+	Offset int64  // Byte offset of the entry in the file.
+	Length int64  // Number of bytes of the entry.
+}
+
+// Index is an index of the entries of a UniRef XML file.
+type Index struct {
+	Records []IndexRecord
+}
+
+// BuildIndex reads a UniRef XML file and builds its index.
+func BuildIndex(r io.Reader) (Index, error) {
+	var index Index
+	decoder := newDecoder(bufio.NewReader(r))
+	ids := make(map[string]bool)
+	var record *IndexRecord // the entry being read, if any
+	var depth int           // depth of elements inside the entry
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Index{}, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if record != nil {
+				depth++
+				continue
+			}
+			if token.Name.Local != "entry" {
+				continue
+			}
+			record = &IndexRecord{Offset: offset}
+			depth = 0
+			for _, attribute := range token.Attr {
+				if attribute.Name.Local == "id" {
+					record.ID = attribute.Value
+				}
+			}
+			if record.ID == "" {
+				return Index{}, fmt.Errorf("entry at byte %d has no id", offset)
+			}
+			if ids[record.ID] {
+				return Index{}, fmt.Errorf("duplicate id %q at byte %d", record.ID, offset)
+			}
+			ids[record.ID] = true
+		case xml.EndElement:
+			if record == nil {
+				continue
+			}
+			if depth > 0 {
+				depth--
+				continue
+			}
+			record.Length = decoder.InputOffset() - record.Offset
+			index.Records = append(index.Records, *record)
+			record = nil
+		}
+	}
+	if record != nil {
+		return Index{}, fmt.Errorf("entry %s is not closed: %w", record.ID, io.ErrUnexpectedEOF)
+	}
+	return index, nil
+}
+
+// ReadIndex reads a UniRef index.
+func ReadIndex(r io.Reader) (Index, error) {
+	var index Index
+	scanner := bufio.NewScanner(r)
+	var lineNumber int
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if len(line) == 0 {
+			continue
+		}
+		values := strings.Split(line, "\t")
+		if len(values) < 3 {
+			return Index{}, fmt.Errorf("line %d: expected 3 tab-delimited values, got %d", lineNumber, len(values))
+		}
+		record := IndexRecord{ID: values[0]}
+		for i, field := range []*int64{&record.Offset, &record.Length} {
+			value, err := strconv.ParseInt(values[i+1], 10, 64)
+			if err != nil {
+				return Index{}, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			*field = value
+		}
+		index.Records = append(index.Records, record)
+	}
+	return index, scanner.Err()
+}
+
+// WriteTo writes the index.
+func (index *Index) WriteTo(w io.Writer) (int64, error) {
+	var writtenBytes int64
+	for _, record := range index.Records {
+		newWrittenBytes, err := fmt.Fprintf(w, "%s\t%d\t%d\n", record.ID, record.Offset, record.Length)
+		writtenBytes += int64(newWrittenBytes)
+		if err != nil {
+			return writtenBytes, err
+		}
+	}
+	return writtenBytes, nil
+}
+
+// IndexedReader provides random access to the entries of an indexed UniRef
+// XML file. It should be initialized with NewIndexedReader.
+type IndexedReader struct {
+	reader  io.ReaderAt
+	records map[string]IndexRecord
+}
+
+// NewIndexedReader returns an IndexedReader that reads entries from r using
+// index. r must be the decompressed XML file.
+func NewIndexedReader(r io.ReaderAt, index Index) *IndexedReader {
+	records := make(map[string]IndexRecord, len(index.Records))
+	for _, record := range index.Records {
+		records[record.ID] = record
+	}
+	return &IndexedReader{reader: r, records: records}
+}
+
+// Fetch returns the entry with the given id.
+func (ir *IndexedReader) Fetch(id string) (Entry, error) {
+	record, ok := ir.records[id]
+	if !ok {
+		return Entry{}, fmt.Errorf("%w: %s is not in the index", ErrNotFound, id)
+	}
+	decoder := newDecoder(io.NewSectionReader(ir.reader, record.Offset, record.Length))
+	// The namespace of entries is declared at the root of the file.
+	decoder.DefaultSpace = unirefNamespace
+	parser := &Parser{decoder: decoder}
+	entry, err := parser.Next()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return Entry{}, fmt.Errorf("failed to read entry %s: %w", id, err)
+	}
+	if entry.ID != id {
+		return Entry{}, fmt.Errorf("index record of %s points to entry %s. The index may be out of date", id, entry.ID)
+	}
+	return entry, nil
+}
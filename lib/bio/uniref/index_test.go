@@ -0,0 +1,66 @@
+package uniref
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIndex(t *testing.T) {
+	data, err := os.ReadFile("data/uniref90_mini.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := BuildIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Records) != 2 || index.Records[1].ID != "UniRef50_UPI00358F51CD" {
+		t.Fatalf("unexpected index %+v", index)
+	}
+
+	reader := NewIndexedReader(bytes.NewReader(data), index)
+	parser, _ := NewParser(bytes.NewReader(data))
+	for _, record := range index.Records {
+		expected, _ := parser.Next()
+		entry, err := reader.Fetch(record.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, entry); diff != "" {
+			t.Errorf("entry %s changed (-want +got):\n%s", record.ID, diff)
+		}
+	}
+	if _, err := reader.Fetch("UniRef50_P42212"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	var buffer bytes.Buffer
+	_, _ = index.WriteTo(&buffer)
+	readIndex, err := ReadIndex(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(index, readIndex); diff != "" {
+		t.Errorf("index changed after writing (-want +got):\n%s", diff)
+	}
+}
+
+func TestIndexErrors(t *testing.T) {
+	for _, file := range []string{
+		testData50[:len(testData50)-20],
+		strings.Replace(testData50, ` id="UniRef50_UPI002E2621C6"`, "", 1),
+		strings.Replace(testData50, "</UniRef50>", testData50[strings.Index(testData50, "<entry"):], 1),
+	} {
+		if _, err := BuildIndex(strings.NewReader(file)); err == nil {
+			t.Errorf("expected an error for file %s", file)
+		}
+	}
+	if _, err := ReadIndex(strings.NewReader("UniRef50_P42212\tstart\t10\n")); err == nil {
+		t.Errorf("expected an error for an invalid offset")
+	}
+}
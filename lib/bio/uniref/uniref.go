@@ -97,6 +97,14 @@ type Parser struct {
 }
 
 func NewParser(r io.Reader) (*Parser, error) {
+	return &Parser{
+		decoder: newDecoder(r),
+	}, nil
+}
+
+// newDecoder returns an XML decoder of UniRef files, which are ISO-8859-1
+// encoded.
+func newDecoder(r io.Reader) *xml.Decoder {
 	decoder := xml.NewDecoder(r)
 	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
 		if strings.ToLower(charset) == "iso-8859-1" {
@@ -104,10 +112,7 @@ func NewParser(r io.Reader) (*Parser, error) {
 		}
 		return nil, fmt.Errorf("unsupported charset: %s", charset)
 	}
-
-	return &Parser{
-		decoder: decoder,
-	}, nil
+	return decoder
 }
 
 // Header returns an empty header since UniRef files don't have headers
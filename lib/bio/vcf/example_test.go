@@ -0,0 +1,32 @@
+package vcf_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/koeng101/dnadesign/lib/bio/vcf"
+)
+
+func ExampleNewParser() {
+	// example.vcf is the example from the VCF 4.2 specification.
+	file, _ := os.Open("data/example.vcf")
+	defer file.Close()
+	parser, _ := vcf.NewParser(file, vcf.DefaultMaxLineSize)
+	header, _ := parser.Header()
+
+	for {
+		record, err := parser.Next()
+		if err != nil {
+			// Break at EOF
+			break
+		}
+		if record.Passed() {
+			fmt.Println(record.Pos, record.Ref, record.Alt, header.Samples[0], record.Samples[0].Genotype)
+		}
+	}
+	// Output:
+	// 14370 G [A] NA00001 0|0
+	// 1110696 A [G T] NA00001 1|2
+	// 1230237 T [] NA00001 0|0
+	// 1234567 GTC [G GTCT] NA00001 0/1
+}
@@ -0,0 +1,770 @@
+/*
+Package vcf contains VCF parsers and writers.
+
+VCF (Variant Call Format) is a tab-delimited text format for storing
+variations of sequences against a reference, such as SNPs, insertions, and
+deletions. It is the output of variant callers like `bcftools call`, which
+we use in external/bcftools to find mutations in sequenced plasmids.
+
+A VCF file begins with a header of meta-information lines (beginning with
+##) that describe the file, including the definitions of INFO, FILTER, and
+FORMAT fields used in records. The final header line (beginning with #CHROM)
+names the columns, including the name of each sample. Each following line is
+a single variant record:
+
+	#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	NA00001
+	20	14370	rs6054257	G	A	29	PASS	NS=3;DP=14;AF=0.5;DB	GT:GQ:DP	0|0:48:1
+
+This package provides a parser and writer for working with VCF files.
+
+Spec: https://samtools.github.io/hts-specs/VCFv4.2.pdf
+*/
+package vcf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Missing is the value used in VCF files for missing data.
+const Missing = "."
+
+// DefaultMaxLineSize is the default maximum line size of the parser. Lines of
+// VCF files with thousands of samples can be very long, so this is larger
+// than the usual 64kB.
+const DefaultMaxLineSize int = 8 * 1024 * 1024
+
+/******************************************************************************
+
+Header
+
+******************************************************************************/
+
+// Header contains the meta-information lines and sample names of a VCF file.
+type Header struct {
+	FileFormat string       // File format version, such as VCFv4.2
+	Info       []Definition // ##INFO lines, defining the fields of a record's INFO column.
+	Filter     []Definition // ##FILTER lines, defining the filters used in a record's FILTER column.
+	Format     []Definition // ##FORMAT lines, defining the per-sample genotype fields.
+	Contig     []Definition // ##contig lines, defining the reference sequences.
+	Other      []MetaLine   // All other meta-information lines, in order.
+	Samples    []string     // Sample names, in the order of the sample columns.
+}
+
+// Definition is a structured meta-information line, such as an INFO,
+// FILTER, FORMAT, or contig definition. For example:
+//
+//	##INFO=<ID=DP,Number=1,Type=Integer,Description="Total Depth">
+type Definition struct {
+	ID          string
+	Number      string // An integer, or A (one per alternate allele), R (one per allele), G (one per genotype), or . (unknown).
+	Type        string // Integer, Float, Flag, Character, or String.
+	Description string
+	Other       []KeyValue // Any other key-value pairs, such as Source, Version, or a contig's length, in order.
+}
+
+// KeyValue is a single key-value pair.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// MetaLine is an unstructured meta-information line, such as
+// ##fileDate=20090805. Structured lines that are not INFO, FILTER, FORMAT, or
+// contig definitions are also stored as MetaLines, with the full <...>
+// value.
+type MetaLine struct {
+	Key   string
+	Value string
+}
+
+// Get returns the value of a key of the definition.
+func (definition *Definition) Get(key string) (string, bool) {
+	switch key {
+	case "ID":
+		return definition.ID, definition.ID != ""
+	case "Number":
+		return definition.Number, definition.Number != ""
+	case "Type":
+		return definition.Type, definition.Type != ""
+	case "Description":
+		return definition.Description, definition.Description != ""
+	}
+	for _, pair := range definition.Other {
+		if pair.Key == key {
+			return pair.Value, true
+		}
+	}
+	return "", false
+}
+
+// InfoDefinition returns the definition of an INFO field.
+func (header *Header) InfoDefinition(id string) (Definition, bool) {
+	return findDefinition(header.Info, id)
+}
+
+// FormatDefinition returns the definition of a FORMAT field.
+func (header *Header) FormatDefinition(id string) (Definition, bool) {
+	return findDefinition(header.Format, id)
+}
+
+// FilterDefinition returns the definition of a FILTER.
+func (header *Header) FilterDefinition(id string) (Definition, bool) {
+	return findDefinition(header.Filter, id)
+}
+
+func findDefinition(definitions []Definition, id string) (Definition, bool) {
+	for _, definition := range definitions {
+		if definition.ID == id {
+			return definition, true
+		}
+	}
+	return Definition{}, false
+}
+
+// parseDefinition parses the <...> value of a structured meta-information
+// line.
+func parseDefinition(value string) (Definition, error) {
+	var definition Definition
+	if !strings.HasPrefix(value, "<") || !strings.HasSuffix(value, ">") {
+		return definition, fmt.Errorf("structured meta-information must be enclosed in <>, got: %s", value)
+	}
+	value = value[1 : len(value)-1]
+	for len(value) > 0 {
+		equals := strings.IndexByte(value, '=')
+		if equals == -1 {
+			return definition, fmt.Errorf("expected key=value, got: %s", value)
+		}
+		key := value[:equals]
+		value = value[equals+1:]
+		var fieldValue string
+		if strings.HasPrefix(value, `"`) {
+			// Quoted values may contain commas and escaped quotes.
+			var builder strings.Builder
+			var closed bool
+			i := 1
+			for ; i < len(value); i++ {
+				if value[i] == '\\' && i+1 < len(value) {
+					i++
+					builder.WriteByte(value[i])
+					continue
+				}
+				if value[i] == '"' {
+					closed = true
+					break
+				}
+				builder.WriteByte(value[i])
+			}
+			if !closed {
+				return definition, fmt.Errorf("unterminated quote in value of %s", key)
+			}
+			fieldValue = builder.String()
+			value = value[i+1:]
+		} else {
+			comma := strings.IndexByte(value, ',')
+			if comma == -1 {
+				comma = len(value)
+			}
+			fieldValue = value[:comma]
+			value = value[comma:]
+		}
+		value = strings.TrimPrefix(value, ",")
+
+		switch key {
+		case "ID":
+			definition.ID = fieldValue
+		case "Number":
+			definition.Number = fieldValue
+		case "Type":
+			definition.Type = fieldValue
+		case "Description":
+			definition.Description = fieldValue
+		default:
+			definition.Other = append(definition.Other, KeyValue{Key: key, Value: fieldValue})
+		}
+	}
+	if definition.ID == "" {
+		return definition, errors.New("structured meta-information requires an ID")
+	}
+	return definition, nil
+}
+
+// quote quotes a value of a structured meta-information line.
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// String formats the definition as the <...> value of a meta-information
+// line.
+func (definition *Definition) String() string {
+	var builder strings.Builder
+	builder.WriteString("<ID=")
+	builder.WriteString(definition.ID)
+	if definition.Number != "" {
+		builder.WriteString(",Number=" + definition.Number)
+	}
+	if definition.Type != "" {
+		builder.WriteString(",Type=" + definition.Type)
+	}
+	if definition.Description != "" {
+		builder.WriteString(",Description=" + quote(definition.Description))
+	}
+	for _, pair := range definition.Other {
+		value := pair.Value
+		if pair.Key == "Source" || pair.Key == "Version" || strings.ContainsAny(value, ",\"<> ") {
+			value = quote(value)
+		}
+		builder.WriteString("," + pair.Key + "=" + value)
+	}
+	builder.WriteString(">")
+	return builder.String()
+}
+
+// WriteTo writes the header to an io.Writer, including the #CHROM column
+// header line.
+func (header *Header) WriteTo(w io.Writer) (int64, error) {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "##fileformat=%s\n", header.FileFormat)
+	for _, line := range header.Other {
+		fmt.Fprintf(&builder, "##%s=%s\n", line.Key, line.Value)
+	}
+	for _, group := range []struct {
+		key         string
+		definitions []Definition
+	}{{"contig", header.Contig}, {"INFO", header.Info}, {"FILTER", header.Filter}, {"FORMAT", header.Format}} {
+		for _, definition := range group.definitions {
+			fmt.Fprintf(&builder, "##%s=%s\n", group.key, definition.String())
+		}
+	}
+	builder.WriteString("#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO")
+	if len(header.Samples) > 0 {
+		builder.WriteString("\tFORMAT\t")
+		builder.WriteString(strings.Join(header.Samples, "\t"))
+	}
+	builder.WriteString("\n")
+	newWrittenBytes, err := w.Write([]byte(builder.String()))
+	return int64(newWrittenBytes), err
+}
+
+/******************************************************************************
+
+Records
+
+******************************************************************************/
+
+// Record is a single variant record (a data line) of a VCF file.
+type Record struct {
+	Chrom   string      // Name of the reference sequence.
+	Pos     int         // 1-based position of the first base of Ref.
+	ID      []string    // Identifiers of the variant, such as rs numbers. Empty if missing.
+	Ref     string      // Reference bases.
+	Alt     []string    // Alternate alleles. Empty if there are none.
+	Qual    float64     // Phred-scaled quality of the ALT assertion. NaN if missing.
+	Filter  []string    // PASS, or the filters that failed. Empty if missing.
+	Info    []InfoField // INFO fields, in order.
+	Format  []string    // Keys of the per-sample genotype fields, in order.
+	Samples []Sample    // Genotype fields of each sample, in the order of Header.Samples.
+}
+
+// InfoField is a single key-value pair of a record's INFO column. Flags have
+// an empty Value.
+type InfoField struct {
+	Key   string
+	Value string
+}
+
+// Sample contains the genotype fields of a single sample in a record.
+type Sample struct {
+	Genotype Genotype          // Parsed GT field. Empty if the record has no GT field.
+	Fields   map[string]string // All other fields, keyed by their FORMAT key.
+}
+
+// Genotype is a parsed GT field. Each allele is an index into the alleles of
+// the record: 0 is the reference, 1 is the first alternate allele, and so
+// on. Missing alleles are -1.
+type Genotype struct {
+	Alleles []int
+	Phased  bool
+}
+
+// ParseGenotype parses a GT field, such as 0/1 or 1|0.
+func ParseGenotype(text string) (Genotype, error) {
+	var genotype Genotype
+	genotype.Phased = strings.Contains(text, "|")
+	for _, allele := range strings.FieldsFunc(text, func(r rune) bool { return r == '/' || r == '|' }) {
+		if allele == Missing {
+			genotype.Alleles = append(genotype.Alleles, -1)
+			continue
+		}
+		index, err := strconv.Atoi(allele)
+		if err != nil || index < 0 {
+			return Genotype{}, fmt.Errorf("invalid allele %q in genotype %q", allele, text)
+		}
+		genotype.Alleles = append(genotype.Alleles, index)
+	}
+	if len(genotype.Alleles) == 0 {
+		return Genotype{}, fmt.Errorf("invalid genotype %q", text)
+	}
+	return genotype, nil
+}
+
+// String formats the genotype as a GT field.
+func (genotype Genotype) String() string {
+	separator := "/"
+	if genotype.Phased {
+		separator = "|"
+	}
+	alleles := make([]string, len(genotype.Alleles))
+	for i, allele := range genotype.Alleles {
+		if allele < 0 {
+			alleles[i] = Missing
+		} else {
+			alleles[i] = strconv.Itoa(allele)
+		}
+	}
+	return strings.Join(alleles, separator)
+}
+
+// Homozygous returns true if all called alleles of the genotype are the
+// same.
+func (genotype Genotype) Homozygous() bool {
+	if len(genotype.Alleles) == 0 {
+		return false
+	}
+	for _, allele := range genotype.Alleles {
+		if allele < 0 || allele != genotype.Alleles[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetInfo returns the value of an INFO field. Flags return an empty string
+// and true if they are set.
+func (record *Record) GetInfo(key string) (string, bool) {
+	for _, field := range record.Info {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+// Passed returns true if the record passed all filters.
+func (record *Record) Passed() bool {
+	return len(record.Filter) == 1 && record.Filter[0] == "PASS"
+}
+
+// joinOrMissing joins values with a separator, or returns "." if there are
+// none.
+func joinOrMissing(values []string, separator string) string {
+	if len(values) == 0 {
+		return Missing
+	}
+	return strings.Join(values, separator)
+}
+
+// splitOrMissing splits a value with a separator, returning nil if the value
+// is missing.
+func splitOrMissing(value string, separator string) []string {
+	if value == Missing {
+		return nil
+	}
+	return strings.Split(value, separator)
+}
+
+// WriteTo writes the record as a single VCF line.
+func (record *Record) WriteTo(w io.Writer) (int64, error) {
+	var builder strings.Builder
+	qual := Missing
+	if !math.IsNaN(record.Qual) {
+		qual = strconv.FormatFloat(record.Qual, 'f', -1, 64)
+	}
+	info := make([]string, len(record.Info))
+	for i, field := range record.Info {
+		info[i] = field.Key
+		if field.Value != "" {
+			info[i] += "=" + field.Value
+		}
+	}
+	fmt.Fprintf(&builder, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s", record.Chrom, record.Pos, joinOrMissing(record.ID, ";"), record.Ref, joinOrMissing(record.Alt, ","), qual, joinOrMissing(record.Filter, ";"), joinOrMissing(info, ";"))
+	if len(record.Format) > 0 {
+		builder.WriteString("\t")
+		builder.WriteString(strings.Join(record.Format, ":"))
+		for _, sample := range record.Samples {
+			values := make([]string, len(record.Format))
+			var last int // Trailing missing fields may be dropped.
+			for i, key := range record.Format {
+				value, ok := sample.Fields[key]
+				if key == "GT" && len(sample.Genotype.Alleles) > 0 {
+					value, ok = sample.Genotype.String(), true
+				}
+				if !ok {
+					value = Missing
+				} else {
+					last = i
+				}
+				values[i] = value
+			}
+			builder.WriteString("\t")
+			builder.WriteString(strings.Join(values[:last+1], ":"))
+		}
+	}
+	builder.WriteString("\n")
+	newWrittenBytes, err := w.Write([]byte(builder.String()))
+	return int64(newWrittenBytes), err
+}
+
+/******************************************************************************
+
+Typed fields
+
+INFO and FORMAT values are stored as text, since their types are defined in
+the header rather than the record. The typed getters below look up the
+definition of a field in the header, check its Type, split its values on
+commas, and check the number of values against its Number. Missing values
+within a list, like the second value of HQ=51,., are MissingInteger for
+integers and NaN for floats.
+
+******************************************************************************/
+
+// MissingInteger is the value of missing values of Integer fields.
+const MissingInteger = math.MinInt
+
+// ErrFieldNotFound is returned by the typed getters when a record or sample
+// doesn't have a field.
+var ErrFieldNotFound = errors.New("field not found")
+
+// expectedValues returns the number of values a field of a record should
+// have, or -1 if it can have any number of values.
+func (record *Record) expectedValues(definition Definition) int {
+	switch definition.Number {
+	case "A":
+		return len(record.Alt)
+	case "R":
+		return len(record.Alt) + 1
+	}
+	number, err := strconv.Atoi(definition.Number)
+	if err != nil {
+		// G depends on the ploidy of the sample, and . is unknown.
+		return -1
+	}
+	return number
+}
+
+// typedValues checks the type of a field against its definition, and returns
+// its values.
+func (record *Record) typedValues(definition Definition, found bool, id string, value string, types ...string) ([]string, error) {
+	if !found {
+		return nil, fmt.Errorf("%s is not defined in the header", id)
+	}
+	typeMatches := false
+	for _, fieldType := range types {
+		typeMatches = typeMatches || definition.Type == fieldType
+	}
+	if !typeMatches {
+		return nil, fmt.Errorf("%s has Type=%s, not %s", id, definition.Type, strings.Join(types, " or "))
+	}
+	values := strings.Split(value, ",")
+	if value == Missing {
+		values = []string{Missing}
+	}
+	// a single missing value stands for all of them.
+	if expected := record.expectedValues(definition); expected >= 0 && len(values) != expected && value != Missing {
+		return nil, fmt.Errorf("%s has Number=%s, so expected %d values, got %d: %s", id, definition.Number, expected, len(values), value)
+	}
+	return values, nil
+}
+
+// parseIntegers parses the values of an Integer field.
+func parseIntegers(id string, values []string) ([]int, error) {
+	integers := make([]int, len(values))
+	for i, value := range values {
+		if value == Missing {
+			integers[i] = MissingInteger
+			continue
+		}
+		integer, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Integer value %q of %s", value, id)
+		}
+		integers[i] = integer
+	}
+	return integers, nil
+}
+
+// parseFloats parses the values of a Float field.
+func parseFloats(id string, values []string) ([]float64, error) {
+	floats := make([]float64, len(values))
+	for i, value := range values {
+		if value == Missing {
+			floats[i] = math.NaN()
+			continue
+		}
+		float, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Float value %q of %s", value, id)
+		}
+		floats[i] = float
+	}
+	return floats, nil
+}
+
+// infoValues returns the values of an INFO field of one of the given types.
+func (record *Record) infoValues(header *Header, key string, types ...string) ([]string, error) {
+	value, ok := record.GetInfo(key)
+	if !ok {
+		return nil, fmt.Errorf("INFO %s: %w", key, ErrFieldNotFound)
+	}
+	definition, found := header.InfoDefinition(key)
+	return record.typedValues(definition, found, "INFO "+key, value, types...)
+}
+
+// GetInfoInt returns the values of an INFO field with Type=Integer.
+func (record *Record) GetInfoInt(header *Header, key string) ([]int, error) {
+	values, err := record.infoValues(header, key, "Integer")
+	if err != nil {
+		return nil, err
+	}
+	return parseIntegers("INFO "+key, values)
+}
+
+// GetInfoFloat returns the values of an INFO field with Type=Float or
+// Type=Integer.
+func (record *Record) GetInfoFloat(header *Header, key string) ([]float64, error) {
+	values, err := record.infoValues(header, key, "Float", "Integer")
+	if err != nil {
+		return nil, err
+	}
+	return parseFloats("INFO "+key, values)
+}
+
+// GetInfoString returns the values of an INFO field with Type=String or
+// Type=Character.
+func (record *Record) GetInfoString(header *Header, key string) ([]string, error) {
+	return record.infoValues(header, key, "String", "Character")
+}
+
+// GetInfoFlag returns whether an INFO field with Type=Flag is set.
+func (record *Record) GetInfoFlag(header *Header, key string) (bool, error) {
+	definition, found := header.InfoDefinition(key)
+	if !found {
+		return false, fmt.Errorf("INFO %s is not defined in the header", key)
+	}
+	if definition.Type != "Flag" {
+		return false, fmt.Errorf("INFO %s has Type=%s, not Flag", key, definition.Type)
+	}
+	_, ok := record.GetInfo(key)
+	return ok, nil
+}
+
+// sampleValues returns the values of a FORMAT field of a sample of one of the
+// given types.
+func (record *Record) sampleValues(header *Header, sampleIndex int, key string, types ...string) ([]string, error) {
+	if sampleIndex < 0 || sampleIndex >= len(record.Samples) {
+		return nil, fmt.Errorf("sample index %d out of range for %d samples", sampleIndex, len(record.Samples))
+	}
+	value, ok := record.Samples[sampleIndex].Fields[key]
+	if !ok {
+		return nil, fmt.Errorf("FORMAT %s of sample %d: %w", key, sampleIndex, ErrFieldNotFound)
+	}
+	definition, found := header.FormatDefinition(key)
+	return record.typedValues(definition, found, "FORMAT "+key, value, types...)
+}
+
+// GetSampleInt returns the values of a FORMAT field with Type=Integer of the
+// sample at sampleIndex.
+func (record *Record) GetSampleInt(header *Header, sampleIndex int, key string) ([]int, error) {
+	values, err := record.sampleValues(header, sampleIndex, key, "Integer")
+	if err != nil {
+		return nil, err
+	}
+	return parseIntegers("FORMAT "+key, values)
+}
+
+// GetSampleFloat returns the values of a FORMAT field with Type=Float or
+// Type=Integer of the sample at sampleIndex.
+func (record *Record) GetSampleFloat(header *Header, sampleIndex int, key string) ([]float64, error) {
+	values, err := record.sampleValues(header, sampleIndex, key, "Float", "Integer")
+	if err != nil {
+		return nil, err
+	}
+	return parseFloats("FORMAT "+key, values)
+}
+
+// GetSampleString returns the values of a FORMAT field with Type=String or
+// Type=Character of the sample at sampleIndex. The GT field is parsed into
+// Sample.Genotype instead.
+func (record *Record) GetSampleString(header *Header, sampleIndex int, key string) ([]string, error) {
+	return record.sampleValues(header, sampleIndex, key, "String", "Character")
+}
+
+/******************************************************************************
+
+Parser
+
+******************************************************************************/
+
+// Parser is a VCF parser. It should be initialized with NewParser.
+type Parser struct {
+	reader bufio.Reader
+	line   uint
+	header Header
+	atEOF  bool
+}
+
+// Header returns the parsed VCF header.
+func (parser *Parser) Header() (Header, error) {
+	return parser.header, nil
+}
+
+// NewParser creates a parser from an io.Reader for VCF data. The header is
+// parsed before the parser is returned. For VCF files with many samples,
+// you will want to increase the maxLineSize.
+func NewParser(r io.Reader, maxLineSize int) (*Parser, error) {
+	parser := &Parser{reader: *bufio.NewReaderSize(r, maxLineSize)}
+	var header Header
+	for {
+		lineBytes, err := parser.reader.ReadSlice('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && len(lineBytes) > 0) {
+			if errors.Is(err, io.EOF) {
+				return parser, fmt.Errorf("reached end of file before #CHROM header line: %w", io.ErrUnexpectedEOF)
+			}
+			return parser, err
+		}
+		parser.line++
+		line := strings.TrimRight(string(lineBytes), "\r\n")
+		if strings.HasPrefix(line, "#CHROM") {
+			columns := strings.Split(line, "\t")
+			if len(columns) < 8 {
+				return parser, fmt.Errorf("Line %d: #CHROM line must have at least 8 tab-delimited columns, got %d", parser.line, len(columns))
+			}
+			if len(columns) > 9 {
+				header.Samples = columns[9:]
+			}
+			break
+		}
+		if !strings.HasPrefix(line, "##") {
+			return parser, fmt.Errorf("Line %d: expected a meta-information line beginning with ##, got: %s", parser.line, line)
+		}
+		key, value, found := strings.Cut(line[2:], "=")
+		if !found {
+			return parser, fmt.Errorf("Line %d: meta-information line must be key=value, got: %s", parser.line, line)
+		}
+		if parser.line == 1 {
+			if key != "fileformat" {
+				return parser, fmt.Errorf("Line 1: first line must be ##fileformat, got: %s", line)
+			}
+			header.FileFormat = value
+			continue
+		}
+		var definitions *[]Definition
+		switch key {
+		case "INFO":
+			definitions = &header.Info
+		case "FILTER":
+			definitions = &header.Filter
+		case "FORMAT":
+			definitions = &header.Format
+		case "contig":
+			definitions = &header.Contig
+		default:
+			header.Other = append(header.Other, MetaLine{Key: key, Value: value})
+			continue
+		}
+		definition, err := parseDefinition(value)
+		if err != nil {
+			return parser, fmt.Errorf("Line %d: %w", parser.line, err)
+		}
+		*definitions = append(*definitions, definition)
+	}
+	parser.header = header
+	return parser, nil
+}
+
+// Next parses the next record from the parser. Returns an `io.EOF` upon EOF.
+func (parser *Parser) Next() (Record, error) {
+	var line string
+	for line == "" {
+		if parser.atEOF {
+			return Record{}, io.EOF
+		}
+		lineBytes, err := parser.reader.ReadSlice('\n')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return Record{}, err
+			}
+			parser.atEOF = true
+		}
+		parser.line++
+		line = strings.TrimRight(string(lineBytes), "\r\n")
+	}
+	record, err := parseRecord(line, len(parser.header.Samples))
+	if err != nil {
+		return Record{}, fmt.Errorf("Line %d had error: %w", parser.line, err)
+	}
+	return record, nil
+}
+
+// parseRecord parses a single VCF data line.
+func parseRecord(line string, sampleCount int) (Record, error) {
+	var record Record
+	values := strings.Split(line, "\t")
+	if len(values) < 8 {
+		return record, fmt.Errorf("must have at least 8 tab-delimited values, had %d", len(values))
+	}
+	if len(values) > 8 && len(values) != 9+sampleCount {
+		return record, fmt.Errorf("expected %d sample columns, had %d", sampleCount, len(values)-9)
+	}
+	record.Chrom = values[0]
+	pos, err := strconv.Atoi(values[1])
+	if err != nil {
+		return record, fmt.Errorf("invalid POS: %w", err)
+	}
+	record.Pos = pos
+	record.ID = splitOrMissing(values[2], ";")
+	record.Ref = values[3]
+	record.Alt = splitOrMissing(values[4], ",")
+	record.Qual = math.NaN()
+	if values[5] != Missing {
+		record.Qual, err = strconv.ParseFloat(values[5], 64)
+		if err != nil {
+			return record, fmt.Errorf("invalid QUAL: %w", err)
+		}
+	}
+	record.Filter = splitOrMissing(values[6], ";")
+	for _, field := range splitOrMissing(values[7], ";") {
+		key, value, _ := strings.Cut(field, "=")
+		record.Info = append(record.Info, InfoField{Key: key, Value: value})
+	}
+	if len(values) == 8 {
+		return record, nil
+	}
+
+	record.Format = strings.Split(values[8], ":")
+	for _, sampleValue := range values[9:] {
+		sample := Sample{Fields: make(map[string]string)}
+		fields := strings.Split(sampleValue, ":")
+		if len(fields) > len(record.Format) {
+			return record, fmt.Errorf("sample has %d fields, but FORMAT has %d keys", len(fields), len(record.Format))
+		}
+		for i, value := range fields {
+			key := record.Format[i]
+			if key == "GT" {
+				sample.Genotype, err = ParseGenotype(value)
+				if err != nil {
+					return record, err
+				}
+				continue
+			}
+			sample.Fields[key] = value
+		}
+		record.Samples = append(record.Samples, sample)
+	}
+	return record, nil
+}
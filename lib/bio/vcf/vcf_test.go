@@ -0,0 +1,245 @@
+package vcf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func parseAll(t *testing.T, r io.Reader) (Header, []Record) {
+	t.Helper()
+	parser, err := NewParser(r, DefaultMaxLineSize)
+	if err != nil {
+		t.Fatalf("Got error on new parser: %s", err)
+	}
+	header, _ := parser.Header()
+	var records []Record
+	for {
+		record, err := parser.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("Got unknown error: %s", err)
+			}
+			break
+		}
+		records = append(records, record)
+	}
+	return header, records
+}
+
+func TestParse(t *testing.T) {
+	file, err := os.Open("data/example.vcf")
+	if err != nil {
+		t.Fatalf("Failed to open example.vcf: %s", err)
+	}
+	defer file.Close()
+	header, records := parseAll(t, file)
+
+	if header.FileFormat != "VCFv4.2" {
+		t.Errorf("Expected VCFv4.2, got %s", header.FileFormat)
+	}
+	if len(header.Info) != 6 || len(header.Filter) != 2 || len(header.Format) != 4 || len(header.Contig) != 1 || len(header.Other) != 4 {
+		t.Errorf("Wrong number of header lines. Info: %d, Filter: %d, Format: %d, Contig: %d, Other: %d", len(header.Info), len(header.Filter), len(header.Format), len(header.Contig), len(header.Other))
+	}
+	db, ok := header.InfoDefinition("DB")
+	if !ok || db.Description != "dbSNP membership, build 129" || db.Type != "Flag" {
+		t.Errorf("Failed to parse DB definition with comma in description: %+v", db)
+	}
+	if species, _ := header.Contig[0].Get("species"); species != "Homo sapiens" {
+		t.Errorf("Expected contig species Homo sapiens, got %s", species)
+	}
+	if strings.Join(header.Samples, ",") != "NA00001,NA00002,NA00003" {
+		t.Errorf("Wrong samples: %v", header.Samples)
+	}
+
+	if len(records) != 5 {
+		t.Fatalf("Expected 5 records, got %d", len(records))
+	}
+	first := records[0]
+	if first.Chrom != "20" || first.Pos != 14370 || first.ID[0] != "rs6054257" || first.Ref != "G" || first.Alt[0] != "A" || first.Qual != 29 || !first.Passed() {
+		t.Errorf("Failed to parse first record: %+v", first)
+	}
+	if _, ok := first.GetInfo("DB"); !ok {
+		t.Errorf("Expected DB flag to be set")
+	}
+	if depth, _ := first.GetInfo("DP"); depth != "14" {
+		t.Errorf("Expected DP=14, got %s", depth)
+	}
+	if genotype := first.Samples[1].Genotype; !genotype.Phased || genotype.Alleles[0] != 1 || genotype.Alleles[1] != 0 {
+		t.Errorf("Failed to parse genotype 1|0: %+v", genotype)
+	}
+	if first.Samples[2].Fields["HQ"] != ".,." {
+		t.Errorf("Expected HQ .,., got %s", first.Samples[2].Fields["HQ"])
+	}
+	if records[1].ID != nil || records[1].Passed() || records[1].Filter[0] != "q10" {
+		t.Errorf("Failed to parse missing ID and failed filter: %+v", records[1])
+	}
+	if _, ok := records[1].Samples[2].Fields["HQ"]; ok {
+		t.Errorf("Dropped trailing fields should not be set")
+	}
+	if records[3].Alt != nil {
+		t.Errorf("Expected no alternate alleles, got %v", records[3].Alt)
+	}
+	if len(records[4].Alt) != 2 || !records[4].Samples[2].Genotype.Homozygous() {
+		t.Errorf("Failed to parse multiallelic record: %+v", records[4])
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("data/example.vcf")
+	if err != nil {
+		t.Fatalf("Failed to read example.vcf: %s", err)
+	}
+	header, records := parseAll(t, bytes.NewReader(data))
+	var buffer bytes.Buffer
+	if _, err := header.WriteTo(&buffer); err != nil {
+		t.Fatalf("Failed to write header: %s", err)
+	}
+	for _, record := range records {
+		if _, err := record.WriteTo(&buffer); err != nil {
+			t.Fatalf("Failed to write record: %s", err)
+		}
+	}
+	if buffer.String() != string(data) {
+		t.Errorf("Round trip does not match input. Got:\n%s", buffer.String())
+	}
+}
+
+func TestMissingValues(t *testing.T) {
+	file := "##fileformat=VCFv4.2\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\ts1\n1\t5\t.\tA\tT\t.\t.\t.\tGT:DP\t./.\n"
+	_, records := parseAll(t, strings.NewReader(file))
+	record := records[0]
+	if !math.IsNaN(record.Qual) || record.Filter != nil || record.Info != nil {
+		t.Errorf("Expected missing values, got %+v", record)
+	}
+	if genotype := record.Samples[0].Genotype; genotype.Alleles[0] != -1 || genotype.String() != "./." {
+		t.Errorf("Expected missing genotype, got %+v", genotype)
+	}
+	var buffer bytes.Buffer
+	_, _ = record.WriteTo(&buffer)
+	if buffer.String() != "1\t5\t.\tA\tT\t.\t.\t.\tGT:DP\t./.\n" {
+		t.Errorf("Wrong output for missing values: %q", buffer.String())
+	}
+}
+
+func TestDefinitionEscapes(t *testing.T) {
+	definition, err := parseDefinition(`<ID=X,Number=1,Type=String,Description="A \"quoted\", escaped description",Source="tool">`)
+	if err != nil {
+		t.Fatalf("Failed to parse definition: %s", err)
+	}
+	if definition.Description != `A "quoted", escaped description` {
+		t.Errorf("Failed to unescape description: %s", definition.Description)
+	}
+	if source, _ := definition.Get("Source"); source != "tool" {
+		t.Errorf("Expected Source tool, got %s", source)
+	}
+	if definition.String() != `<ID=X,Number=1,Type=String,Description="A \"quoted\", escaped description",Source="tool">` {
+		t.Errorf("Failed to escape definition: %s", definition.String())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	headerErrors := []struct {
+		name string
+		file string
+	}{
+		{"no fileformat", "##source=x\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n"},
+		{"no #CHROM line", "##fileformat=VCFv4.2\n"},
+		{"bad definition", "##fileformat=VCFv4.2\n##INFO=ID=DP\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n"},
+		{"unterminated quote", "##fileformat=VCFv4.2\n##INFO=<ID=DP,Description=\"x>\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n"},
+		{"short #CHROM line", "##fileformat=VCFv4.2\n#CHROM\tPOS\n"},
+		{"data before #CHROM", "##fileformat=VCFv4.2\n1\t5\t.\tA\tT\t.\t.\t.\n"},
+	}
+	for _, test := range headerErrors {
+		if _, err := NewParser(strings.NewReader(test.file), DefaultMaxLineSize); err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+
+	const header = "##fileformat=VCFv4.2\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\ts1\n"
+	recordErrors := []struct {
+		name string
+		line string
+	}{
+		{"too few columns", "1\t5\t.\tA\n"},
+		{"bad position", "1\tfive\t.\tA\tT\t.\t.\t.\tGT\t0/1\n"},
+		{"bad quality", "1\t5\t.\tA\tT\thigh\t.\t.\tGT\t0/1\n"},
+		{"wrong sample count", "1\t5\t.\tA\tT\t.\t.\t.\tGT\t0/1\t1/1\n"},
+		{"bad genotype", "1\t5\t.\tA\tT\t.\t.\t.\tGT\tA/T\n"},
+		{"too many sample fields", "1\t5\t.\tA\tT\t.\t.\t.\tGT\t0/1:5\n"},
+	}
+	for _, test := range recordErrors {
+		parser, err := NewParser(strings.NewReader(header+test.line), DefaultMaxLineSize)
+		if err != nil {
+			t.Fatalf("%s: unexpected header error: %s", test.name, err)
+		}
+		if _, err := parser.Next(); err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("%s: expected error, got %v", test.name, err)
+		}
+	}
+}
+
+func TestTypedFields(t *testing.T) {
+	file, err := os.Open("data/example.vcf")
+	if err != nil {
+		t.Fatalf("Failed to open example.vcf: %s", err)
+	}
+	defer file.Close()
+	header, records := parseAll(t, file)
+	first, multiallelic := records[0], records[2]
+
+	if depth, err := first.GetInfoInt(&header, "DP"); err != nil || len(depth) != 1 || depth[0] != 14 {
+		t.Errorf("Expected DP 14, got %v %v", depth, err)
+	}
+	if frequencies, err := multiallelic.GetInfoFloat(&header, "AF"); err != nil || len(frequencies) != 2 || frequencies[1] != 0.667 {
+		t.Errorf("Expected AF 0.333,0.667, got %v %v", frequencies, err)
+	}
+	if ancestral, err := multiallelic.GetInfoString(&header, "AA"); err != nil || ancestral[0] != "T" {
+		t.Errorf("Expected AA T, got %v %v", ancestral, err)
+	}
+	if set, err := first.GetInfoFlag(&header, "DB"); err != nil || !set {
+		t.Errorf("Expected DB to be set, got %v %v", set, err)
+	}
+	if set, err := records[1].GetInfoFlag(&header, "DB"); err != nil || set {
+		t.Errorf("Expected DB to be unset, got %v %v", set, err)
+	}
+	if _, err := records[3].GetInfoFloat(&header, "AF"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound for missing AF, got %v", err)
+	}
+	if _, err := first.GetInfoInt(&header, "AF"); err == nil {
+		t.Errorf("Expected error getting Float field AF as Integer")
+	}
+	if _, err := first.GetInfoFlag(&header, "DP"); err == nil {
+		t.Errorf("Expected error getting Integer field DP as Flag")
+	}
+
+	if quality, err := first.GetSampleInt(&header, 1, "GQ"); err != nil || quality[0] != 48 {
+		t.Errorf("Expected GQ 48, got %v %v", quality, err)
+	}
+	if haplotypeQuality, err := first.GetSampleInt(&header, 2, "HQ"); err != nil || len(haplotypeQuality) != 2 || haplotypeQuality[0] != MissingInteger {
+		t.Errorf("Expected missing HQ, got %v %v", haplotypeQuality, err)
+	}
+	if haplotypeQuality, err := first.GetSampleFloat(&header, 0, "HQ"); err != nil || haplotypeQuality[1] != 51 {
+		t.Errorf("Expected HQ 51,51, got %v %v", haplotypeQuality, err)
+	}
+	if _, err := records[1].GetSampleInt(&header, 2, "HQ"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound for dropped HQ, got %v", err)
+	}
+	if _, err := first.GetSampleInt(&header, 3, "GQ"); err == nil {
+		t.Errorf("Expected error for out of range sample")
+	}
+}
+
+func TestTypedFieldErrors(t *testing.T) {
+	file := "##fileformat=VCFv4.2\n##INFO=<ID=AC,Number=A,Type=Integer,Description=\"x\">\n##INFO=<ID=BQ,Number=1,Type=Integer,Description=\"x\">\n#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\n1\t5\t.\tA\tT,G\t.\t.\tAC=1;BQ=high;XX=1\n"
+	header, records := parseAll(t, strings.NewReader(file))
+	for key, name := range map[string]string{"AC": "wrong number of values", "BQ": "invalid integer", "XX": "undefined field"} {
+		if _, err := records[0].GetInfoInt(&header, key); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -41,8 +41,9 @@ Let's build some DNA!
 
 # Keoni
 
-PS: We do NOT (yet) handle restriction enzymes which recognize one site but cut
-in multiple places (Type IIG enzymes) such as BcgI.
+PS: Restriction enzymes which recognize one site but cut on both sides of it
+(Type IIG enzymes), such as BcgI, excise their site as a small fragment.
+CutWithEnzyme returns that fragment, unless cutting is directional.
 */
 package clone
 
@@ -80,22 +81,35 @@ type Fragment struct {
 	ReverseOverhang string
 }
 
-// Enzyme is a struct that represents restriction enzymes.
+// Enzyme is a struct that represents restriction enzymes. Enzymes that cut on
+// both sides of their recognition site, like BcgI, also cut UpstreamSkip bases
+// before the site, with an overhang of UpstreamOverheadLength bases.
 type Enzyme struct {
-	Name            string
-	RegexpFor       *regexp.Regexp
-	RegexpRev       *regexp.Regexp
-	Skip            int
-	OverheadLength  int
-	RecognitionSite string
+	Name                   string
+	RegexpFor              *regexp.Regexp
+	RegexpRev              *regexp.Regexp
+	Skip                   int
+	OverheadLength         int
+	RecognitionSite        string
+	UpstreamSkip           int
+	UpstreamOverheadLength int
 }
 
+// cutsBothSides returns whether an enzyme cuts on both sides of its
+// recognition site.
+func (enzyme Enzyme) cutsBothSides() bool {
+	return enzyme.UpstreamSkip != 0 || enzyme.UpstreamOverheadLength != 0
+}
+
+// DefaultEnzymes are the enzymes CutWithEnzymeByName cuts with. Other
+// enzymes can be made with NewEnzyme and their REBASE recognition sequence,
+// and cut with by name with CutWithEnzymeByNameFrom.
 var DefaultEnzymes = map[string]Enzyme{
-	"BsaI":  {"BsaI", regexp.MustCompile("GGTCTC"), regexp.MustCompile("GAGACC"), 1, 4, "GGTCTC"},
-	"BbsI":  {"BbsI", regexp.MustCompile("GAAGAC"), regexp.MustCompile("GTCTTC"), 2, 4, "GAAGAC"},
-	"BtgZI": {"BtgZI", regexp.MustCompile("GCGATG"), regexp.MustCompile("CATCGC"), 10, 4, "GCGATG"},
-	"PaqCI": {"PaqCI", regexp.MustCompile("CACCTGC"), regexp.MustCompile("GCAGGTG"), 4, 4, "CACCTGC"},
-	"BsmBI": {"BsmBI", regexp.MustCompile("CGTCTC"), regexp.MustCompile("GAGACG"), 1, 4, "CGTCTC"},
+	"BsaI":  {"BsaI", regexp.MustCompile("GGTCTC"), regexp.MustCompile("GAGACC"), 1, 4, "GGTCTC", 0, 0},
+	"BbsI":  {"BbsI", regexp.MustCompile("GAAGAC"), regexp.MustCompile("GTCTTC"), 2, 4, "GAAGAC", 0, 0},
+	"BtgZI": {"BtgZI", regexp.MustCompile("GCGATG"), regexp.MustCompile("CATCGC"), 10, 4, "GCGATG", 0, 0},
+	"PaqCI": {"PaqCI", regexp.MustCompile("CACCTGC"), regexp.MustCompile("GCAGGTG"), 4, 4, "CACCTGC", 0, 0},
+	"BsmBI": {"BsmBI", regexp.MustCompile("CGTCTC"), regexp.MustCompile("GAGACG"), 1, 4, "CGTCTC", 0, 0},
 }
 
 /******************************************************************************
@@ -109,8 +123,15 @@ Base cloning functions begin here.
 // allows us to specify the enzyme by name. Set methylated flag to true if
 // there is lowercase methylated DNA as part of the sequence.
 func CutWithEnzymeByName(part Part, directional bool, name string, methylated bool) ([]Fragment, error) {
+	return CutWithEnzymeByNameFrom(DefaultEnzymes, part, directional, name, methylated)
+}
+
+// CutWithEnzymeByNameFrom is CutWithEnzymeByName with the enzyme looked up in
+// enzymes instead of DefaultEnzymes, like the enzymes of
+// rebase.CloneEnzymes.
+func CutWithEnzymeByNameFrom(enzymes map[string]Enzyme, part Part, directional bool, name string, methylated bool) ([]Fragment, error) {
 	// Get the enzyme from the enzyme map
-	enzyme, ok := DefaultEnzymes[name]
+	enzyme, ok := enzymes[name]
 	if !ok {
 		// Return an error if there was an error
 		return []Fragment{}, errors.New("enzyme not found")
@@ -119,10 +140,20 @@ func CutWithEnzymeByName(part Part, directional bool, name string, methylated bo
 	return CutWithEnzyme(part, directional, enzyme, methylated), nil
 }
 
+// minimumFragmentLength is the length of the longest fragments, with their
+// overhangs, that cutting drops. Minimum lengths (given oligos) for assembly
+// is 8 base pairs: https://doi.org/10.1186/1756-0500-3-291
+const minimumFragmentLength = 8
+
 // CutWithEnzyme cuts a given sequence with an enzyme represented by an Enzyme struct.
 // If there is methylated parts of the target DNA, set the "methylated" flag to
 // true and lowercase ONLY methylated DNA.
 func CutWithEnzyme(part Part, directional bool, enzyme Enzyme, methylated bool) []Fragment {
+	// Enzymes that cut on both sides of their site cut each site twice.
+	if enzyme.cutsBothSides() {
+		return cutBothSides(part, directional, enzyme, methylated)
+	}
+
 	var fragmentSequences []string
 
 	// Setup circular sequences
@@ -246,9 +277,7 @@ func CutWithEnzyme(part Part, directional bool, enzyme Enzyme, methylated bool)
 		}
 		// Convert fragment sequences into fragments
 		for _, fragmentsequence := range fragmentSequences {
-			// Minimum lengths (given oligos) for assembly is 8 base pairs
-			// https://doi.org/10.1186/1756-0500-3-291
-			if len(fragmentsequence) > 8 {
+			if len(fragmentsequence) > minimumFragmentLength {
 				fragmentSequence := fragmentsequence[enzyme.OverheadLength : len(fragmentsequence)-enzyme.OverheadLength]
 				forwardOverhang := fragmentsequence[:enzyme.OverheadLength]
 				reverseOverhang := fragmentsequence[len(fragmentsequence)-enzyme.OverheadLength:]
@@ -260,6 +289,141 @@ func CutWithEnzyme(part Part, directional bool, enzyme Enzyme, methylated bool)
 	return fragments
 }
 
+// siteCut is a cut of an enzyme at one of its recognition sites. The
+// overhang of the cut is sequence[start:end].
+type siteCut struct {
+	start    int
+	end      int
+	site     int  // index of the site of the cut, shared by both cuts of a site
+	left     bool // whether the cut is left of its site in the sequence
+	position int  // start of the site of the cut
+	forward  bool // whether the site of the cut is on the forward strand
+}
+
+// findCuts returns the cuts of an enzyme in a sequence, sorted by position.
+// Sites and cuts of circular sequences may cross the origin, and cuts past
+// the ends of linear sequences are skipped.
+func findCuts(sequence string, circular bool, enzyme Enzyme) []siteCut {
+	length := len(sequence)
+	if length == 0 {
+		return nil
+	}
+
+	// Sites across the origin of circular sequences are found by searching
+	// past the origin.
+	searchSequence := sequence
+	if circular {
+		searchSequence += sequence[:min(length, len(enzyme.RecognitionSite)-1)]
+	}
+	upstreamReach := enzyme.UpstreamSkip + enzyme.UpstreamOverheadLength
+	downstreamReach := enzyme.Skip + enzyme.OverheadLength
+
+	var cuts []siteCut
+	var site int
+	addCut := func(start int, end int, left bool, match []int, forward bool) {
+		cut := siteCut{start: start, end: end, site: site, left: left, position: match[0], forward: forward}
+		if circular {
+			cut.start = ((start % length) + length) % length
+			cut.end = cut.start + end - start
+			cuts = append(cuts, cut)
+			return
+		}
+		// Linear sequences can't be cut past their ends.
+		if start >= 0 && end <= length {
+			cuts = append(cuts, cut)
+		}
+	}
+	for _, match := range enzyme.RegexpFor.FindAllStringIndex(searchSequence, -1) {
+		if match[0] >= length {
+			continue
+		}
+		if enzyme.cutsBothSides() {
+			addCut(match[0]-upstreamReach, match[0]-enzyme.UpstreamSkip, true, match, true)
+		}
+		addCut(match[1]+enzyme.Skip, match[1]+downstreamReach, false, match, true)
+		site++
+	}
+	// Palindromic sites are found by RegexpFor on both strands.
+	if !checks.IsPalindromic(enzyme.RecognitionSite) {
+		for _, match := range enzyme.RegexpRev.FindAllStringIndex(searchSequence, -1) {
+			if match[0] >= length {
+				continue
+			}
+			addCut(match[0]-downstreamReach, match[0]-enzyme.Skip, true, match, false)
+			if enzyme.cutsBothSides() {
+				addCut(match[1]+enzyme.UpstreamSkip, match[1]+upstreamReach, false, match, false)
+			}
+			site++
+		}
+	}
+	sort.SliceStable(cuts, func(i, j int) bool {
+		return cuts[i].start < cuts[j].start
+	})
+	return cuts
+}
+
+// cutBothSides cuts a sequence with an enzyme that cuts on both sides of its
+// recognition site, like CutWithEnzyme. Each site gives a cut before and
+// after the site, so the site is excised as a small fragment. If cutting is
+// directional, the fragments that keep a site are removed, like the
+// fragments of directional Type IIS enzymes, along with the ends of linear
+// sequences. Otherwise, the ends of linear sequences have no overhang on
+// their outer side, like the fragments of a single cut of CutWithEnzyme.
+// Fragments of minimumFragmentLength or less, with their overhangs, are
+// dropped, like the fragments of CutWithEnzyme.
+func cutBothSides(part Part, directional bool, enzyme Enzyme, methylated bool) []Fragment {
+	sequence := part.Sequence
+	if !methylated {
+		sequence = strings.ToUpper(sequence)
+	}
+	length := len(sequence)
+	cuts := findCuts(sequence, part.Circular, enzyme)
+	if len(cuts) == 0 {
+		return nil
+	}
+
+	// Overhangs of circular sequences may cross the origin.
+	doubleSequence := sequence
+	if part.Circular {
+		doubleSequence = sequence + sequence
+	}
+	var fragments []Fragment
+	addFragment := func(fragment Fragment) {
+		if len(fragment.ForwardOverhang)+len(fragment.Sequence)+len(fragment.ReverseOverhang) > minimumFragmentLength {
+			fragments = append(fragments, fragment)
+		}
+	}
+	if !part.Circular && !directional {
+		addFragment(Fragment{sequence[:cuts[0].start], "", sequence[cuts[0].start:cuts[0].end]})
+	}
+	for cutIndex, cut := range cuts {
+		var nextCut siteCut
+		switch {
+		case cutIndex+1 < len(cuts):
+			nextCut = cuts[cutIndex+1]
+		case part.Circular:
+			nextCut = cuts[0]
+			nextCut.start, nextCut.end = nextCut.start+length, nextCut.end+length
+		default:
+			continue
+		}
+		// Cuts too close to each other leave no fragment between them.
+		if cut.end > nextCut.start || nextCut.end > len(doubleSequence) {
+			continue
+		}
+		// The fragment between the cuts of a site is the excised site.
+		if directional && cut.left && cut.site == nextCut.site {
+			continue
+		}
+		addFragment(Fragment{doubleSequence[cut.end:nextCut.start], doubleSequence[cut.start:cut.end], doubleSequence[nextCut.start:nextCut.end]})
+	}
+	if !part.Circular && !directional {
+		lastCut := cuts[len(cuts)-1]
+		addFragment(Fragment{sequence[lastCut.end:], sequence[lastCut.start:lastCut.end], ""})
+	}
+	return fragments
+}
+
 // Ligate simulates ligations. It assumes that fragments can only be ligated
 // in a single way (no 2 fragments with the same overhangs), and also assumes
 // the first fragment WILL be used in the ligation reaction. This function
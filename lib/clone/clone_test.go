@@ -14,6 +14,24 @@ func TestCutWithEnzymeByName(t *testing.T) {
 	}
 }
 
+func TestCutWithEnzymeByNameFrom(t *testing.T) {
+	ecoRI, err := NewEnzyme("EcoRI", "G^AATTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enzymes := map[string]Enzyme{"EcoRI": ecoRI}
+	fragments, err := CutWithEnzymeByNameFrom(enzymes, Part{Sequence: "AAAAAAAAAAGAATTCTTTTTTTTTT"}, false, "EcoRI", false)
+	if err != nil || len(fragments) != 2 || fragments[0].ForwardOverhang != "AATT" {
+		t.Errorf("Failed to cut with EcoRI from an enzyme map: %+v %v", fragments, err)
+	}
+	if _, err := CutWithEnzymeByNameFrom(enzymes, popen, true, "BsaI", false); err == nil {
+		t.Errorf("CutWithEnzymeByNameFrom should only look up enzymes in the given map")
+	}
+	if _, ok := DefaultEnzymes["EcoRI"]; ok {
+		t.Errorf("CutWithEnzymeByNameFrom should not change DefaultEnzymes")
+	}
+}
+
 func TestCutWithEnzyme(t *testing.T) {
 	var sequence Part
 	bsai := "GGTCTCAATGC"
@@ -0,0 +1,212 @@
+package clone
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+/******************************************************************************
+
+Restriction maps and digests begin here.
+
+When designing plasmids, you want to know where every enzyme cuts: enzymes
+that cut once (unique cutters) are where new parts can be cloned in, enzymes
+that don't cut at all can be used in the flanks of new parts, and digests with
+one or two enzymes that give distinct bands on a gel are how plasmids are
+checked after they are built.
+
+A RestrictionMap has the sites of a set of enzymes in a part, like
+DefaultEnzymes or the commercially available enzymes of REBASE. Digest cuts a
+part with any number of enzymes at once, like a double digest, and Bands
+predicts the bands of the fragments of a digest on a gel next to a ladder.
+
+Fragment lengths are the distances between cuts. Cuts are at the start of
+their overhangs, so fragment lengths are the lengths of the fragments with
+their forward overhang.
+
+******************************************************************************/
+
+// DefaultBandResolution is the relative difference in size under which
+// fragments run as a single band on a gel.
+var DefaultBandResolution = 0.05
+
+// Site is a recognition site of an enzyme in a part.
+type Site struct {
+	Enzyme   string
+	Position int   // start of the recognition site in the part
+	Forward  bool  // whether the site is on the forward strand
+	Cuts     []int // positions of the cuts of the site, at the start of their overhangs
+}
+
+// FindSites returns the recognition sites of an enzyme in a part, sorted by
+// position. Sites of circular parts may cross the origin. Sites near the ends
+// of linear parts may have fewer cuts, since the enzyme can't cut past the
+// ends of the part.
+func FindSites(part Part, enzyme Enzyme) []Site {
+	cuts := findCuts(strings.ToUpper(part.Sequence), part.Circular, enzyme)
+	siteIndexes := make(map[int]int) // index of each site in sites
+	var sites []Site
+	for _, cut := range cuts {
+		siteIndex, ok := siteIndexes[cut.site]
+		if !ok {
+			siteIndex = len(sites)
+			siteIndexes[cut.site] = siteIndex
+			sites = append(sites, Site{Enzyme: enzyme.Name, Position: cut.position, Forward: cut.forward})
+		}
+		sites[siteIndex].Cuts = append(sites[siteIndex].Cuts, cut.start)
+	}
+	sort.SliceStable(sites, func(i, j int) bool {
+		return sites[i].Position < sites[j].Position
+	})
+	return sites
+}
+
+// RestrictionMap is the recognition sites of enzymes in a part, by enzyme
+// name. Enzymes that don't cut the part have no sites.
+type RestrictionMap map[string][]Site
+
+// NewRestrictionMap returns the restriction map of a part for a set of
+// enzymes, like DefaultEnzymes.
+func NewRestrictionMap(part Part, enzymes map[string]Enzyme) RestrictionMap {
+	restrictionMap := make(RestrictionMap, len(enzymes))
+	for name, enzyme := range enzymes {
+		restrictionMap[name] = FindSites(part, enzyme)
+	}
+	return restrictionMap
+}
+
+// Cutters returns the names of the enzymes with a number of sites in the
+// part, sorted by name.
+func (restrictionMap RestrictionMap) Cutters(sites int) []string {
+	var names []string
+	for name, enzymeSites := range restrictionMap {
+		if len(enzymeSites) == sites {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UniqueCutters returns the names of the enzymes with a single site in the
+// part, sorted by name.
+func (restrictionMap RestrictionMap) UniqueCutters() []string {
+	return restrictionMap.Cutters(1)
+}
+
+// AbsentCutters returns the names of the enzymes without sites in the part,
+// sorted by name.
+func (restrictionMap RestrictionMap) AbsentCutters() []string {
+	return restrictionMap.Cutters(0)
+}
+
+// DigestFragment is a fragment of a digest.
+type DigestFragment struct {
+	Start       int    // cut at the start of the fragment
+	End         int    // cut at the end of the fragment, before Start for fragments across the origin
+	Length      int    // length of the fragment
+	StartEnzyme string // enzyme that cut the start of the fragment, if any
+	EndEnzyme   string // enzyme that cut the end of the fragment, if any
+}
+
+// Digest cuts a part with enzymes, like a single or double digest, and
+// returns its fragments in order. Linear parts give one more fragment than
+// they have cuts, and circular parts give as many fragments as they have
+// cuts. Circular parts without cuts give no fragments.
+func Digest(part Part, enzymes ...Enzyme) []DigestFragment {
+	sequence := strings.ToUpper(part.Sequence)
+	length := len(sequence)
+	type enzymeCut struct {
+		position int
+		enzyme   string
+	}
+	var cuts []enzymeCut
+	cutPositions := make(map[int]bool)
+	for _, enzyme := range enzymes {
+		for _, cut := range findCuts(sequence, part.Circular, enzyme) {
+			// Enzymes with the same cut, like isoschizomers, cut once.
+			if !cutPositions[cut.start] {
+				cutPositions[cut.start] = true
+				cuts = append(cuts, enzymeCut{cut.start, enzyme.Name})
+			}
+		}
+	}
+	sort.SliceStable(cuts, func(i, j int) bool {
+		return cuts[i].position < cuts[j].position
+	})
+
+	var fragments []DigestFragment
+	if !part.Circular {
+		start := enzymeCut{}
+		for _, cut := range cuts {
+			fragments = append(fragments, DigestFragment{Start: start.position, End: cut.position, Length: cut.position - start.position, StartEnzyme: start.enzyme, EndEnzyme: cut.enzyme})
+			start = cut
+		}
+		return append(fragments, DigestFragment{Start: start.position, End: length, Length: length - start.position, StartEnzyme: start.enzyme})
+	}
+	for cutIndex, cut := range cuts {
+		nextCut := cuts[(cutIndex+1)%len(cuts)]
+		fragmentLength := nextCut.position - cut.position
+		if fragmentLength <= 0 {
+			fragmentLength += length
+		}
+		fragments = append(fragments, DigestFragment{Start: cut.position, End: nextCut.position, Length: fragmentLength, StartEnzyme: cut.enzyme, EndEnzyme: nextCut.enzyme})
+	}
+	return fragments
+}
+
+// Ladder is the sizes of the bands of a DNA ladder, in base pairs.
+type Ladder []int
+
+// Ladders of New England Biolabs.
+var (
+	Ladder1kb   = Ladder{10002, 8001, 6001, 5001, 4001, 3001, 2000, 1500, 1000, 517, 500}
+	Ladder100bp = Ladder{1517, 1200, 1000, 900, 800, 700, 600, 517, 500, 400, 300, 200, 100}
+)
+
+// Band is a predicted band of a gel.
+type Band struct {
+	Size      int   // average size of the fragments of the band
+	Fragments []int // sizes of the fragments of the band, largest first
+	InRange   bool  // whether the band is within the sizes of the ladder
+}
+
+// Bands predicts the bands of the fragments of a digest on a gel, from the
+// top of the gel to the bottom. Fragments of sizes with a relative difference
+// under resolution, like DefaultBandResolution, run as a single band. Bands
+// outside of the sizes of the ladder can't be sized, and short fragments may
+// run off the gel.
+func Bands(fragments []DigestFragment, ladder Ladder, resolution float64) []Band {
+	sizes := make([]int, len(fragments))
+	for i, fragment := range fragments {
+		sizes[i] = fragment.Length
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+
+	var bands []Band
+	for _, size := range sizes {
+		if len(bands) > 0 {
+			band := &bands[len(bands)-1]
+			if float64(band.Fragments[0]-size) < resolution*float64(band.Fragments[0]) {
+				band.Fragments = append(band.Fragments, size)
+				continue
+			}
+		}
+		bands = append(bands, Band{Fragments: []int{size}})
+	}
+
+	smallest, largest := math.MaxInt, 0
+	for _, size := range ladder {
+		smallest, largest = min(smallest, size), max(largest, size)
+	}
+	for i := range bands {
+		var total int
+		for _, size := range bands[i].Fragments {
+			total += size
+		}
+		bands[i].Size = int(math.Round(float64(total) / float64(len(bands[i].Fragments))))
+		bands[i].InRange = bands[i].Size >= smallest && bands[i].Size <= largest
+	}
+	return bands
+}
@@ -0,0 +1,123 @@
+package clone
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/koeng101/dnadesign/lib/bio/genbank"
+)
+
+func puc19(t *testing.T) Part {
+	t.Helper()
+	file, err := os.Open("../bio/genbank/data/puc19.gbk")
+	if err != nil {
+		t.Fatalf("Failed to open pUC19: %s", err)
+	}
+	defer file.Close()
+	record, err := genbank.NewParser(file, 1024*1024).Next()
+	if err != nil {
+		t.Fatalf("Failed to parse pUC19: %s", err)
+	}
+	return Part{record.Sequence, true}
+}
+
+func testEnzymes(t *testing.T) map[string]Enzyme {
+	t.Helper()
+	enzymes := make(map[string]Enzyme)
+	for name, recognitionSequence := range map[string]string{"EcoRI": "G^AATTC", "HindIII": "A^AGCTT", "ScaI": "AGT^ACT", "PvuI": "CGAT^CG", "NotI": "GC^GGCCGC", "BcgI": "(10/12)CGANNNNNNTGC(12/10)"} {
+		enzyme, err := NewEnzyme(name, recognitionSequence)
+		if err != nil {
+			t.Fatalf("Failed to make %s: %s", name, err)
+		}
+		enzymes[name] = enzyme
+	}
+	return enzymes
+}
+
+func TestRestrictionMap(t *testing.T) {
+	part := puc19(t)
+	enzymes := testEnzymes(t)
+	for name, enzyme := range DefaultEnzymes {
+		enzymes[name] = enzyme
+	}
+	restrictionMap := NewRestrictionMap(part, enzymes)
+
+	if uniqueCutters := restrictionMap.UniqueCutters(); !reflect.DeepEqual(uniqueCutters, []string{"BcgI", "BsaI", "EcoRI", "HindIII", "ScaI"}) {
+		t.Errorf("Unexpected unique cutters %v", uniqueCutters)
+	}
+	if absentCutters := restrictionMap.AbsentCutters(); !reflect.DeepEqual(absentCutters, []string{"BbsI", "BtgZI", "NotI", "PaqCI"}) {
+		t.Errorf("Unexpected absent cutters %v", absentCutters)
+	}
+	if twoCutters := restrictionMap.Cutters(2); !reflect.DeepEqual(twoCutters, []string{"BsmBI", "PvuI"}) {
+		t.Errorf("Unexpected two cutters %v", twoCutters)
+	}
+	expectedSites := map[string][]Site{
+		"EcoRI": {{"EcoRI", 682, true, []int{683}}},
+		"BsmBI": {{"BsmBI", 1027, true, []int{1034}}, {"BsmBI", 1081, false, []int{1076}}},
+		"BcgI":  {{"BcgI", 1543, false, []int{1531, 1565}}},
+	}
+	for name, sites := range expectedSites {
+		if !reflect.DeepEqual(restrictionMap[name], sites) {
+			t.Errorf("Expected %s sites %+v, got %+v", name, sites, restrictionMap[name])
+		}
+	}
+}
+
+func TestFindSitesAcrossOrigin(t *testing.T) {
+	ecoRI := testEnzymes(t)["EcoRI"]
+	part := Part{"ATTCTTTTTTTTTTTTTTTTTTTTTTTTTTTTTTGA", true}
+	sites := FindSites(part, ecoRI)
+	if len(sites) != 1 || sites[0].Position != 34 || sites[0].Cuts[0] != 35 {
+		t.Errorf("Expected EcoRI site across the origin, got %+v", sites)
+	}
+	// Linear parts aren't cut across their ends.
+	part.Circular = false
+	if sites := FindSites(part, ecoRI); len(sites) != 0 {
+		t.Errorf("Expected no sites, got %+v", sites)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	part := puc19(t)
+	enzymes := testEnzymes(t)
+
+	// EcoRI and HindIII cut out the multiple cloning site of pUC19.
+	fragments := Digest(part, enzymes["EcoRI"], enzymes["HindIII"])
+	expected := []DigestFragment{{632, 683, 51, "HindIII", "EcoRI"}, {683, 632, 2635, "EcoRI", "HindIII"}}
+	if !reflect.DeepEqual(fragments, expected) {
+		t.Errorf("Expected fragments %+v, got %+v", expected, fragments)
+	}
+
+	// A single cut linearizes circular parts.
+	fragments = Digest(part, enzymes["EcoRI"])
+	if len(fragments) != 1 || fragments[0].Length != 2686 {
+		t.Errorf("Expected linearized pUC19, got %+v", fragments)
+	}
+	if fragments := Digest(part, enzymes["NotI"]); len(fragments) != 0 {
+		t.Errorf("Expected no fragments, got %+v", fragments)
+	}
+
+	// Linear parts keep their ends.
+	fragments = Digest(Part{part.Sequence, false}, enzymes["EcoRI"], enzymes["EcoRI"])
+	expected = []DigestFragment{{0, 683, 683, "", "EcoRI"}, {683, 2686, 2003, "EcoRI", ""}}
+	if !reflect.DeepEqual(fragments, expected) {
+		t.Errorf("Expected fragments %+v, got %+v", expected, fragments)
+	}
+	if fragments := Digest(Part{part.Sequence, false}); len(fragments) != 1 || fragments[0].Length != 2686 {
+		t.Errorf("Expected uncut part, got %+v", fragments)
+	}
+}
+
+func TestBands(t *testing.T) {
+	fragments := []DigestFragment{{Length: 110}, {Length: 1790}, {Length: 786}, {Length: 1750}}
+	bands := Bands(fragments, Ladder1kb, DefaultBandResolution)
+	expected := []Band{{1770, []int{1790, 1750}, true}, {786, []int{786}, true}, {110, []int{110}, false}}
+	if !reflect.DeepEqual(bands, expected) {
+		t.Errorf("Expected bands %+v, got %+v", expected, bands)
+	}
+	bands = Bands(fragments, Ladder100bp, 0)
+	if len(bands) != 4 || bands[0].InRange || !bands[3].InRange {
+		t.Errorf("Unexpected bands %+v", bands)
+	}
+}
@@ -0,0 +1,181 @@
+package clone
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/koeng101/dnadesign/lib/transform"
+)
+
+/******************************************************************************
+
+Enzymes from recognition sequences begin here.
+
+DefaultEnzymes only has the Type IIS enzymes used for GoldenGate, but REBASE
+lists the recognition sequence of every known restriction enzyme, written from
+5' to 3' with the cut marked in one of two ways. Enzymes that cut inside of
+their recognition site have the cut of the top strand marked with ^:
+
+	EcoRI  G^AATTC
+
+and the bottom strand is cut at the same position of the bottom strand, so
+EcoRI leaves the 5' overhang AATT. Enzymes that cut outside of their
+recognition site, like Type IIS enzymes, have the cuts of the top and bottom
+strand after the end of the site in parentheses:
+
+	BsaI   GGTCTC(1/5)
+
+so BsaI cuts the top strand 1 base after GGTCTC and the bottom strand 5 bases
+after GGTCTC, leaving a 4 base 5' overhang. Negative cuts are inside of the
+recognition site, counting back from its end.
+
+Recognition sequences use IUPAC codes for degenerate bases, like N in
+GACNNNN^NNGTC.
+
+Enzymes that cut on both sides of their recognition site (Type IIG enzymes),
+like BcgI, also have the cuts before the site in parentheses, counted back
+from the start of the site:
+
+	BcgI   (10/12)CGANNNNNNTGC(12/10)
+
+so BcgI excises its site as a 34 base fragment with 2 base 3' overhangs.
+
+Overhangs of 3' overhang enzymes, like PstI CTGCA^G, are the sequence of the
+top strand between the two cuts, like the overhangs of 5' overhang enzymes,
+so they are only compatible with overhangs of the same sequence. Blunt
+enzymes have overhangs of 0 bases.
+
+******************************************************************************/
+
+// ErrUnknownCut is returned for recognition sequences without a known cut.
+var ErrUnknownCut = errors.New("cut site of enzyme is unknown")
+
+// iupacRegexp maps the IUPAC codes of recognition sequences to regular
+// expressions of the bases they match.
+var iupacRegexp = map[rune]string{
+	'A': "A",
+	'C': "C",
+	'G': "G",
+	'T': "T",
+	'R': "[AG]",
+	'Y': "[CT]",
+	'M': "[AC]",
+	'K': "[GT]",
+	'S': "[CG]",
+	'W': "[AT]",
+	'B': "[CGT]",
+	'D': "[AGT]",
+	'H': "[ACT]",
+	'V': "[ACG]",
+	'N': "[ACGT]",
+}
+
+// recognitionSiteRegexp returns the regular expression of a recognition site.
+func recognitionSiteRegexp(site string) (*regexp.Regexp, error) {
+	var expression strings.Builder
+	for _, base := range site {
+		baseExpression, ok := iupacRegexp[base]
+		if !ok {
+			return nil, fmt.Errorf("Invalid base %q in recognition site %s", base, site)
+		}
+		expression.WriteString(baseExpression)
+	}
+	return regexp.Compile(expression.String())
+}
+
+// NewEnzyme returns an Enzyme from its name and its recognition sequence in
+// REBASE notation, like G^AATTC, GGTCTC(1/5) or (10/12)CGANNNNNNTGC(12/10). It returns ErrUnknownCut for
+// recognition sequences without a cut, like GATC, or with an unknown cut.
+func NewEnzyme(name string, recognitionSequence string) (Enzyme, error) {
+	sequence := strings.ToUpper(strings.Join(strings.Fields(recognitionSequence), ""))
+	if sequence == "" || strings.Contains(sequence, "?") {
+		return Enzyme{}, fmt.Errorf("%s: %w", name, ErrUnknownCut)
+	}
+	if strings.Contains(sequence, ",") {
+		return Enzyme{}, fmt.Errorf("%s has more than one recognition sequence: %s", name, recognitionSequence)
+	}
+
+	// cuts are positions in the top strand from the start of the site.
+	var site string
+	var topCut, bottomCut int
+	var upstreamTopCut, upstreamBottomCut int
+	switch {
+	case strings.HasPrefix(sequence, "("):
+		// enzymes that cut on both sides of their site, like BcgI
+		// (10/12)CGANNNNNNTGC(12/10), have the cuts before the site counted
+		// back from the start of the site.
+		closeParenthesis := strings.Index(sequence, ")")
+		if closeParenthesis == -1 {
+			return Enzyme{}, fmt.Errorf("%s has unbalanced parentheses: %s", name, recognitionSequence)
+		}
+		top, bottom, err := parseCuts(sequence[1:closeParenthesis])
+		if err != nil {
+			return Enzyme{}, fmt.Errorf("%s has invalid cuts: %w", name, err)
+		}
+		upstreamTopCut, upstreamBottomCut = -top, -bottom
+		sequence = sequence[closeParenthesis+1:]
+		if !strings.HasSuffix(sequence, ")") {
+			return Enzyme{}, fmt.Errorf("%s has no cuts after its recognition site: %s", name, recognitionSequence)
+		}
+		fallthrough
+	case strings.HasSuffix(sequence, ")"):
+		openParenthesis := strings.LastIndex(sequence, "(")
+		if openParenthesis == -1 {
+			return Enzyme{}, fmt.Errorf("%s has unbalanced parentheses: %s", name, recognitionSequence)
+		}
+		site = sequence[:openParenthesis]
+		top, bottom, err := parseCuts(sequence[openParenthesis+1 : len(sequence)-1])
+		if err != nil {
+			return Enzyme{}, fmt.Errorf("%s has invalid cuts: %w", name, err)
+		}
+		topCut, bottomCut = len(site)+top, len(site)+bottom
+	case strings.Count(sequence, "^") == 1:
+		topCut = strings.Index(sequence, "^")
+		site = strings.Replace(sequence, "^", "", 1)
+		bottomCut = len(site) - topCut
+	default:
+		return Enzyme{}, fmt.Errorf("%s: %w", name, ErrUnknownCut)
+	}
+
+	regexpFor, err := recognitionSiteRegexp(site)
+	if err != nil {
+		return Enzyme{}, fmt.Errorf("%s: %w", name, err)
+	}
+	regexpRev, err := recognitionSiteRegexp(transform.ReverseComplement(site))
+	if err != nil {
+		return Enzyme{}, fmt.Errorf("%s: %w", name, err)
+	}
+	// Skip is counted from the end of the recognition site to the first cut,
+	// so it is negative for enzymes that cut inside of their site.
+	return Enzyme{
+		Name:                   name,
+		RegexpFor:              regexpFor,
+		RegexpRev:              regexpRev,
+		Skip:                   min(topCut, bottomCut) - len(site),
+		OverheadLength:         max(topCut, bottomCut) - min(topCut, bottomCut),
+		RecognitionSite:        site,
+		UpstreamSkip:           -max(upstreamTopCut, upstreamBottomCut),
+		UpstreamOverheadLength: max(upstreamTopCut, upstreamBottomCut) - min(upstreamTopCut, upstreamBottomCut),
+	}, nil
+}
+
+// parseCuts parses the cuts of the top and bottom strand of a recognition
+// sequence, like 1/5.
+func parseCuts(cuts string) (int, int, error) {
+	topAndBottom := strings.Split(cuts, "/")
+	if len(topAndBottom) != 2 {
+		return 0, 0, fmt.Errorf("expected top/bottom cuts, got %s", cuts)
+	}
+	top, err := strconv.Atoi(topAndBottom[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	bottom, err := strconv.Atoi(topAndBottom[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return top, bottom, nil
+}
@@ -0,0 +1,169 @@
+package clone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewEnzyme(t *testing.T) {
+	// Type IIS enzymes from REBASE should be the same as DefaultEnzymes.
+	for name, recognitionSequence := range map[string]string{"BsaI": "GGTCTC(1/5)", "BbsI": "GAAGAC(2/6)", "BtgZI": "GCGATG(10/14)", "PaqCI": "CACCTGC(4/8)", "BsmBI": "CGTCTC(1/5)"} {
+		enzyme, err := NewEnzyme(name, recognitionSequence)
+		if err != nil {
+			t.Errorf("Failed to make %s: %s", name, err)
+			continue
+		}
+		expected := DefaultEnzymes[name]
+		if enzyme.Name != expected.Name || enzyme.RegexpFor.String() != expected.RegexpFor.String() || enzyme.RegexpRev.String() != expected.RegexpRev.String() || enzyme.Skip != expected.Skip || enzyme.OverheadLength != expected.OverheadLength || enzyme.RecognitionSite != expected.RecognitionSite {
+			t.Errorf("Expected %+v, got %+v", expected, enzyme)
+		}
+	}
+
+	tests := []struct {
+		name                string
+		recognitionSequence string
+		site                string
+		skip                int
+		overhangLength      int
+	}{
+		{"EcoRI", "G^AATTC", "GAATTC", -5, 4},
+		{"PstI", "CTGCA^G", "CTGCAG", -5, 4},
+		{"EcoRV", "GAT^ATC", "GATATC", -3, 0},
+		{"AasI", "GACNNNN^NNGTC", "GACNNNNNNGTC", -7, 2},
+		{"BsrDI", "GCAATG(2/0)", "GCAATG", 0, 2},
+		{"BbvCI", "CCTCAGC(-5/-2)", "CCTCAGC", -5, 3},
+		{"AccI", "gt^mkac", "GTMKAC", -4, 2},
+	}
+	for _, test := range tests {
+		enzyme, err := NewEnzyme(test.name, test.recognitionSequence)
+		if err != nil {
+			t.Errorf("Failed to make %s: %s", test.name, err)
+			continue
+		}
+		if enzyme.RecognitionSite != test.site || enzyme.Skip != test.skip || enzyme.OverheadLength != test.overhangLength {
+			t.Errorf("%s: expected site %s, skip %d and overhang length %d, got %s, %d and %d", test.name, test.site, test.skip, test.overhangLength, enzyme.RecognitionSite, enzyme.Skip, enzyme.OverheadLength)
+		}
+	}
+}
+
+func TestNewEnzymeErrors(t *testing.T) {
+	for _, recognitionSequence := range []string{"GATC", "?", "", "GGTCTC(1/5", "GGTCTC(1)", "GGTCTC(a/5)", "GGTCTC(1/b)", "G^AA^TTC", "GAXTTC(1/5)", "GGTCTC(1/5),CGTCTC(1/5)", "(8/13)GACNNNNNNTGG", "(8/13GACNNNNNNTGG(12/7)", "(8)GACNNNNNNTGG(12/7)"} {
+		_, err := NewEnzyme("EcoFake", recognitionSequence)
+		if err == nil {
+			t.Errorf("NewEnzyme should fail on %q", recognitionSequence)
+		}
+	}
+	_, err := NewEnzyme("EcoFake", "GATC")
+	if !errors.Is(err, ErrUnknownCut) {
+		t.Errorf("Expected ErrUnknownCut, got %s", err)
+	}
+}
+
+func TestCutWithNewEnzyme(t *testing.T) {
+	// EcoRI leaves 5' AATT overhangs.
+	ecoRI, _ := NewEnzyme("EcoRI", "G^AATTC")
+	fragments := CutWithEnzyme(Part{"AAAAAAAAAAGAATTCTTTTTTTTTT", false}, false, ecoRI, false)
+	if len(fragments) != 2 {
+		t.Fatalf("Expected 2 fragments, got %d", len(fragments))
+	}
+	if fragments[0].Sequence != "CTTTTTTTTTT" || fragments[0].ForwardOverhang != "AATT" || fragments[1].Sequence != "AAAAAAAAAAG" || fragments[1].ReverseOverhang != "AATT" {
+		t.Errorf("Unexpected EcoRI fragments %+v", fragments)
+	}
+
+	// BsaJI has a degenerate site.
+	bsaJI, _ := NewEnzyme("BsaJI", "C^CNNGG")
+	fragments = CutWithEnzyme(Part{"ATATATATATCCATGGATATATATAT", false}, false, bsaJI, false)
+	if len(fragments) != 2 || fragments[0].ForwardOverhang != "CATG" {
+		t.Errorf("Unexpected BsaJI fragments %+v", fragments)
+	}
+
+	// Non-palindromic enzymes cut sites on both strands, and both leave
+	// the same overhang.
+	bbvCI, _ := NewEnzyme("BbvCI", "CC^TCAGC")
+	fragments = CutWithEnzyme(Part{"ATATATATATCCTCAGCATATATATATATATGCTGAGGATATATATAT", false}, false, bbvCI, false)
+	if len(fragments) != 1 || fragments[0].ForwardOverhang != "TCA" || fragments[0].ReverseOverhang != "TGA" || fragments[0].Sequence != "GCATATATATATATATGC" {
+		t.Errorf("Unexpected BbvCI fragments %+v", fragments)
+	}
+}
+
+func TestCutBothSides(t *testing.T) {
+	bcgI, err := NewEnzyme("BcgI", "(10/12)CGANNNNNNTGC(12/10)")
+	if err != nil {
+		t.Fatalf("Failed to make BcgI: %s", err)
+	}
+	if bcgI.UpstreamSkip != 10 || bcgI.UpstreamOverheadLength != 2 || bcgI.Skip != 10 || bcgI.OverheadLength != 2 {
+		t.Errorf("Unexpected BcgI %+v", bcgI)
+	}
+
+	// BcgI excises its site as a 34 base fragment with 2 base 3' overhangs.
+	left := "ATTGTTAGTAGGTATTATGTATTGTAAGTA"
+	site := "CGACTAGCTTGC"
+	right := "TTGAGTTAGTTTGTGATTTGTTGAAGTTTG"
+	sequence := left + site + right
+	start, end := len(left), len(left)+len(site)
+	fragments := CutWithEnzyme(Part{sequence, false}, false, bcgI, false)
+	expected := []Fragment{
+		{sequence[:start-12], "", sequence[start-12 : start-10]},
+		{sequence[start-10 : end+10], sequence[start-12 : start-10], sequence[end+10 : end+12]},
+		{sequence[end+12:], sequence[end+10 : end+12], ""},
+	}
+	if len(fragments) != len(expected) {
+		t.Fatalf("Expected %d fragments, got %+v", len(expected), fragments)
+	}
+	for i := range expected {
+		if fragments[i] != expected[i] {
+			t.Errorf("Expected fragment %+v, got %+v", expected[i], fragments[i])
+		}
+	}
+	if excised := fragments[1]; len(excised.Sequence+excised.ReverseOverhang) != 34 || excised.Sequence[10:22] != site {
+		t.Errorf("Expected BcgI to excise a 34 base fragment with its site, got %+v", excised)
+	}
+	// Directional cutting removes the excised site and the ends of linear
+	// sequences.
+	if fragments := CutWithEnzyme(Part{sequence, false}, true, bcgI, false); len(fragments) != 0 {
+		t.Errorf("Expected no fragments, got %+v", fragments)
+	}
+	// Sites too close to the ends of linear sequences are only cut on one
+	// side.
+	if fragments := CutWithEnzyme(Part{sequence[start-5:], false}, false, bcgI, false); len(fragments) != 2 || fragments[0].Sequence != sequence[start-5:end+10] {
+		t.Errorf("Expected site to be cut after its end, got %+v", fragments)
+	}
+
+	// Like CutWithEnzyme, fragments of 8 bases or less with their overhangs
+	// are dropped: the 2 base end before the first site, and the 2 base
+	// fragment between the sites.
+	shortFragments := left[16:] + site + right[:26] + site + right
+	fragments = CutWithEnzyme(Part{shortFragments, false}, false, bcgI, false)
+	if len(fragments) != 3 || fragments[0].Sequence[10:22] != site || fragments[1].Sequence[10:22] != site || fragments[2].ReverseOverhang != "" {
+		t.Errorf("Expected two excised sites and the end of the sequence, got %+v", fragments)
+	}
+
+	// BaeI leaves 5 base 3' overhangs. Its site is reversed here, and crosses
+	// the origin of a circular sequence.
+	baeI, err := NewEnzyme("BaeI", "(10/15)ACNNNNGTAYC(12/7)")
+	if err != nil {
+		t.Fatalf("Failed to make BaeI: %s", err)
+	}
+	site = "GATACTTCAGT" // reverse complement of ACTGAAGTATC
+	sequence = left + site + right
+	start, end = len(left), len(left)+len(site)
+	excised := Fragment{sequence[start-7 : end+10], sequence[start-12 : start-7], sequence[end+10 : end+15]}
+	backbone := Fragment{sequence[end+15:] + sequence[:start-12], sequence[end+10 : end+15], sequence[start-12 : start-7]}
+	rotated := sequence[start+5:] + sequence[:start+5]
+	fragments = CutWithEnzyme(Part{rotated, true}, false, baeI, false)
+	if len(fragments) != 2 {
+		t.Fatalf("Expected 2 fragments, got %+v", fragments)
+	}
+	if fragments[0] != backbone || fragments[1] != excised {
+		t.Errorf("Expected fragments %+v and %+v, got %+v", backbone, excised, fragments)
+	}
+	fragments = CutWithEnzyme(Part{rotated, true}, true, baeI, false)
+	if len(fragments) != 1 || fragments[0] != backbone {
+		t.Errorf("Expected only the backbone, got %+v", fragments)
+	}
+
+	// Methylated sites aren't cut.
+	if fragments := CutWithEnzyme(Part{left + "cgactagcttgc" + right, false}, false, bcgI, true); len(fragments) != 0 {
+		t.Errorf("Expected no fragments, got %+v", fragments)
+	}
+}
@@ -0,0 +1,30 @@
+package signal_test
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/koeng101/dnadesign/lib/bio/slow5"
+	"github.com/koeng101/dnadesign/lib/sequencing/signal"
+)
+
+func Example() {
+	file, _ := os.Open("../../bio/slow5/data/example.slow5")
+	defer file.Close()
+	parser, _ := slow5.NewParser(file, 2*32*1024)
+	read, _ := parser.Next()
+
+	// Convert the signal to picoamps, and find where the strand starts and
+	// ends in the normalized signal.
+	picoamps := signal.ToPicoamps(read)
+	normalized, _ := signal.Normalize(picoamps)
+	start, end := signal.Trim(normalized, signal.DefaultTrimOptions)
+
+	events := signal.Segment(picoamps[start:end], signal.DefaultSegmentOptions)
+	stats := signal.Summarize(read)
+	fmt.Printf("%d samples (%.2fs), median %.1f pA\n", stats.Samples, stats.Duration, stats.Median)
+	fmt.Printf("strand from sample %d to %d, %d events\n", start, end, len(events))
+	// Output:
+	// 5347 samples (1.34s), median 80.5 pA
+	// strand from sample 1370 to 5347, 302 events
+}
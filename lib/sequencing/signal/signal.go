@@ -0,0 +1,372 @@
+/*
+Package signal contains functions to process raw nanopore signal.
+
+Nanopore sequencers measure the electrical current through a pore while a DNA
+or RNA strand moves through it. The current depends on the few bases inside
+the pore, so the signal is a series of steps ("events"), each lasting a few
+samples. Basecallers turn this signal into sequence, but the signal itself is
+useful for quality control and simple squiggle analysis: a run with drifting
+current, short reads, or lots of open pore time is a run with problems.
+
+slow5.Read stores the signal as raw integers from the analog to digital
+converter of the sequencer. This package converts them to picoamps (pA),
+normalizes them, trims the open pore and adapter current at the start of
+reads, segments the signal into events, and summarizes it.
+*/
+package signal
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/koeng101/dnadesign/lib/bio/slow5"
+)
+
+// MADScale scales the median absolute deviation (MAD) to the standard
+// deviation of normally distributed values.
+const MADScale = 1.4826
+
+// ErrConstantSignal is returned when normalizing a signal whose values are
+// all the same, which can't be scaled.
+var ErrConstantSignal = errors.New("signal has no variation")
+
+// ToPicoamps converts the raw signal of a read to picoamps.
+func ToPicoamps(read slow5.Read) []float64 {
+	scale := read.Range / read.Digitisation
+	picoamps := make([]float64, len(read.RawSignal))
+	for i, raw := range read.RawSignal {
+		picoamps[i] = (float64(raw) + read.Offset) * scale
+	}
+	return picoamps
+}
+
+// Median returns the median of values. It returns NaN for no values.
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return (sorted[middle-1] + sorted[middle]) / 2
+}
+
+// MAD returns the median absolute deviation of values from their median. It
+// is a measure of spread that, unlike the standard deviation, isn't thrown
+// off by the spikes common in nanopore signal.
+func MAD(values []float64) float64 {
+	median := Median(values)
+	deviations := make([]float64, len(values))
+	for i, value := range values {
+		deviations[i] = math.Abs(value - median)
+	}
+	return Median(deviations)
+}
+
+// Normalize scales signal to a median of 0 and a scaled MAD of 1, which puts
+// reads from different pores and runs on the same scale. Basecallers
+// normalize reads this way.
+func Normalize(signal []float64) ([]float64, error) {
+	median := Median(signal)
+	scale := MAD(signal) * MADScale
+	if scale == 0 || math.IsNaN(scale) {
+		return nil, ErrConstantSignal
+	}
+	normalized := make([]float64, len(signal))
+	for i, value := range signal {
+		normalized[i] = (value - median) / scale
+	}
+	return normalized, nil
+}
+
+/******************************************************************************
+
+Trimming
+
+Reads start with the adapter, whose current is much higher than the current of
+DNA since it has a motor protein and a leader that move through the pore
+quickly, and often a bit of open pore current before the strand is captured.
+Reads can also end with open pore current, after the strand leaves the pore.
+Both are above the rest of the normalized signal, so they are found as windows
+with many samples above a threshold, following the trimming of the Dorado
+basecaller.
+
+******************************************************************************/
+
+// TrimOptions are the parameters of Trim. Signal must be normalized.
+type TrimOptions struct {
+	WindowSize  int     // size of the windows that are checked
+	Threshold   float64 // normalized signal above which a sample is open pore or adapter
+	MinElements int     // number of samples above Threshold for a window to be trimmed
+	MinTrim     int     // number of samples always trimmed from the start
+	MaxSamples  int     // the start of reads is only searched in the first MaxSamples samples
+	MaxTrim     float64 // maximum fraction of the read trimmed from the start
+}
+
+// DefaultTrimOptions are the trimming parameters of Dorado.
+var DefaultTrimOptions = TrimOptions{
+	WindowSize:  40,
+	Threshold:   2.4,
+	MinElements: 3,
+	MinTrim:     10,
+	MaxSamples:  8000,
+	MaxTrim:     0.3,
+}
+
+// Trim returns the start and end of the strand in a normalized signal,
+// without the adapter and open pore current at the start and the open pore
+// current at the end. The strand is signal[start:end].
+func Trim(normalized []float64, options TrimOptions) (int, int) {
+	if options.WindowSize <= 0 {
+		return 0, len(normalized)
+	}
+	start := trimStart(normalized, options)
+	end := len(normalized)
+	// open pore current at the end of the read is trimmed window by window,
+	// as long as most of the window is open pore.
+	for end-options.WindowSize > start {
+		var above int
+		for _, value := range normalized[end-options.WindowSize : end] {
+			if value > options.Threshold {
+				above++
+			}
+		}
+		if above*2 <= options.WindowSize {
+			break
+		}
+		end -= options.WindowSize
+	}
+	return start, end
+}
+
+// trimStart returns the end of the adapter and open pore current at the
+// start of a normalized signal. It looks for the first window with high
+// current, and returns the end of the first window after it that ends below
+// the threshold.
+func trimStart(normalized []float64, options TrimOptions) int {
+	minTrim := min(options.MinTrim, len(normalized))
+	samples := min(options.MaxSamples, len(normalized)) - minTrim
+	windows := samples / options.WindowSize
+	seenPeak := false
+	for window := 0; window < windows; window++ {
+		start := window*options.WindowSize + minTrim
+		end := start + options.WindowSize
+		var above int
+		for _, value := range normalized[start:end] {
+			if value > options.Threshold {
+				above++
+			}
+		}
+		if above > options.MinElements || seenPeak {
+			seenPeak = true
+			if normalized[end-1] > options.Threshold {
+				continue
+			}
+			if end >= samples || float64(end) >= options.MaxTrim*float64(len(normalized)) {
+				return minTrim
+			}
+			return end
+		}
+	}
+	return minTrim
+}
+
+/******************************************************************************
+
+Event segmentation
+
+Events are found by comparing the signal in two windows on either side of each
+sample with a t-test: the t statistic is high where the mean current changes.
+Two window sizes are used, since short windows find short events but are
+noisy, and long windows are reliable but miss short events. Boundaries between
+events are the peaks of the t statistic, following the event detection of the
+Scrappie basecaller.
+
+******************************************************************************/
+
+// Event is a segment of signal with a constant mean current.
+type Event struct {
+	Start  int // index of the first sample of the event
+	Length int
+	Mean   float64
+	StdDev float64
+}
+
+// SegmentOptions are the parameters of Segment.
+type SegmentOptions struct {
+	ShortWindow    int     // size of the windows of the short t-test
+	LongWindow     int     // size of the windows of the long t-test
+	ShortThreshold float64 // t statistic of the short t-test needed for a boundary
+	LongThreshold  float64 // t statistic of the long t-test needed for a boundary
+	PeakHeight     float64 // drop in t statistic after a peak needed for it to be a boundary
+}
+
+// DefaultSegmentOptions find events in signal in picoamps sampled at 4kHz,
+// where DNA events last about 10 samples. The long t-test has a lower
+// threshold, since its windows average out more noise, which lets it find
+// small changes in current that the short t-test misses.
+var DefaultSegmentOptions = SegmentOptions{
+	ShortWindow:    3,
+	LongWindow:     6,
+	ShortThreshold: 4.0,
+	LongThreshold:  3.0,
+	PeakHeight:     0.2,
+}
+
+// TStatistics returns the t statistic of the difference between the means
+// of the window windowSize samples before and after each sample. Samples
+// without full windows on both sides are 0.
+func TStatistics(signal []float64, windowSize int) []float64 {
+	tstat := make([]float64, len(signal))
+	if windowSize <= 0 || len(signal) < 2*windowSize {
+		return tstat
+	}
+	sum := make([]float64, len(signal)+1)
+	sumSquares := make([]float64, len(signal)+1)
+	for i, value := range signal {
+		sum[i+1] = sum[i] + value
+		sumSquares[i+1] = sumSquares[i] + value*value
+	}
+	window := float64(windowSize)
+	for i := windowSize; i <= len(signal)-windowSize; i++ {
+		mean1 := (sum[i] - sum[i-windowSize]) / window
+		mean2 := (sum[i+windowSize] - sum[i]) / window
+		variance := (sumSquares[i]-sumSquares[i-windowSize])/window - mean1*mean1 +
+			(sumSquares[i+windowSize]-sumSquares[i])/window - mean2*mean2
+		// windows of identical values have no variance.
+		variance = max(variance, math.SmallestNonzeroFloat32)
+		tstat[i] = math.Abs(mean2-mean1) / math.Sqrt(variance/window)
+	}
+	return tstat
+}
+
+// detector finds peaks in the t statistic of one window size.
+type detector struct {
+	tstat     []float64
+	window    int
+	threshold float64
+	maskedTo  int
+	peakPos   int
+	peakValue float64
+	validPeak bool
+}
+
+func (detector *detector) reset() {
+	detector.peakPos = -1
+	detector.peakValue = math.MaxFloat64
+	detector.validPeak = false
+}
+
+// Segment splits a signal into events.
+func Segment(signal []float64, options SegmentOptions) []Event {
+	short := &detector{tstat: TStatistics(signal, options.ShortWindow), window: options.ShortWindow, threshold: options.ShortThreshold}
+	long := &detector{tstat: TStatistics(signal, options.LongWindow), window: options.LongWindow, threshold: options.LongThreshold}
+	short.reset()
+	long.reset()
+	boundaries := []int{0}
+	for i := range signal {
+		for _, detector := range []*detector{short, long} {
+			if detector.maskedTo >= i {
+				continue
+			}
+			value := detector.tstat[i]
+			if detector.peakPos == -1 {
+				// Look for the bottom of a valley, then for a rise high
+				// enough to be a peak.
+				if value < detector.peakValue {
+					detector.peakValue = value
+				} else if value-detector.peakValue > options.PeakHeight {
+					detector.peakValue = value
+					detector.peakPos = i
+				}
+				continue
+			}
+			if value > detector.peakValue {
+				detector.peakValue = value
+				detector.peakPos = i
+			}
+			// peaks of the short detector take precedence over the long one.
+			if detector == short && detector.peakValue > detector.threshold {
+				long.maskedTo = detector.peakPos + detector.window
+				long.reset()
+			}
+			if detector.peakValue-value > options.PeakHeight && detector.peakValue > detector.threshold {
+				detector.validPeak = true
+			}
+			if detector.validPeak && i-detector.peakPos > detector.window/2 {
+				if detector.peakPos > boundaries[len(boundaries)-1] {
+					boundaries = append(boundaries, detector.peakPos)
+				}
+				detector.reset()
+				detector.peakValue = value
+			}
+		}
+	}
+	if len(signal) > boundaries[len(boundaries)-1] {
+		boundaries = append(boundaries, len(signal))
+	}
+
+	events := make([]Event, 0, len(boundaries)-1)
+	for i := 1; i < len(boundaries); i++ {
+		start, end := boundaries[i-1], boundaries[i]
+		mean, stdDev := meanStdDev(signal[start:end])
+		events = append(events, Event{Start: start, Length: end - start, Mean: mean, StdDev: stdDev})
+	}
+	return events
+}
+
+// meanStdDev returns the mean and population standard deviation of values.
+func meanStdDev(values []float64) (float64, float64) {
+	var sum, sumSquares float64
+	for _, value := range values {
+		sum += value
+		sumSquares += value * value
+	}
+	count := float64(len(values))
+	mean := sum / count
+	return mean, math.Sqrt(max(sumSquares/count-mean*mean, 0))
+}
+
+/******************************************************************************
+
+Summary statistics
+
+******************************************************************************/
+
+// Stats are summary statistics of the signal of a read, in picoamps.
+type Stats struct {
+	Samples  int
+	Duration float64 // seconds
+	Mean     float64
+	StdDev   float64
+	Median   float64
+	MAD      float64
+	Min      float64
+	Max      float64
+}
+
+// Summarize returns summary statistics of the signal of a read.
+func Summarize(read slow5.Read) Stats {
+	picoamps := ToPicoamps(read)
+	stats := Stats{Samples: len(picoamps), Min: math.NaN(), Max: math.NaN(), Mean: math.NaN(), StdDev: math.NaN(), Median: math.NaN(), MAD: math.NaN()}
+	if read.SamplingRate > 0 {
+		stats.Duration = float64(len(picoamps)) / read.SamplingRate
+	}
+	if len(picoamps) == 0 {
+		return stats
+	}
+	stats.Mean, stats.StdDev = meanStdDev(picoamps)
+	stats.Median = Median(picoamps)
+	stats.MAD = MAD(picoamps)
+	stats.Min, stats.Max = picoamps[0], picoamps[0]
+	for _, value := range picoamps {
+		stats.Min = min(stats.Min, value)
+		stats.Max = max(stats.Max, value)
+	}
+	return stats
+}
@@ -0,0 +1,154 @@
+package signal
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/koeng101/dnadesign/lib/bio/slow5"
+)
+
+func exampleRead(t *testing.T) slow5.Read {
+	t.Helper()
+	file, err := os.Open("../../bio/slow5/data/example.slow5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	parser, err := slow5.NewParser(file, 2*32*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := parser.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return read
+}
+
+func TestToPicoamps(t *testing.T) {
+	read := slow5.Read{RawSignal: []int16{0, 100, -16}, Digitisation: 8192, Offset: 16, Range: 1489.52832}
+	picoamps := ToPicoamps(read)
+	expected := []float64{16 * 1489.52832 / 8192, 116 * 1489.52832 / 8192, 0}
+	for i := range expected {
+		if math.Abs(picoamps[i]-expected[i]) > 1e-9 {
+			t.Errorf("expected %f pA, got %f", expected[i], picoamps[i])
+		}
+	}
+}
+
+func TestMedianMAD(t *testing.T) {
+	tests := []struct {
+		values []float64
+		median float64
+		mad    float64
+	}{
+		{[]float64{3, 1, 2}, 2, 1},
+		{[]float64{1, 2, 3, 4}, 2.5, 1},
+		{[]float64{1, 1, 2, 2, 4, 6, 9}, 2, 1},
+	}
+	for _, test := range tests {
+		if median := Median(test.values); median != test.median {
+			t.Errorf("expected median %f of %v, got %f", test.median, test.values, median)
+		}
+		if mad := MAD(test.values); mad != test.mad {
+			t.Errorf("expected MAD %f of %v, got %f", test.mad, test.values, mad)
+		}
+	}
+	if !math.IsNaN(Median(nil)) {
+		t.Errorf("expected NaN median of no values")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	normalized, err := Normalize([]float64{1, 2, 3, 4, 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if normalized[2] != 0 || math.Abs(normalized[3]-1/MADScale) > 1e-12 {
+		t.Errorf("unexpected normalized signal %v", normalized)
+	}
+	if _, err := Normalize([]float64{5, 5, 5}); !errors.Is(err, ErrConstantSignal) {
+		t.Errorf("expected ErrConstantSignal, got %v", err)
+	}
+}
+
+// steps returns a noisy signal with the given levels, each lasting length
+// samples.
+func steps(levels []float64, length int, noise float64) []float64 {
+	random := rand.New(rand.NewSource(1))
+	var signal []float64
+	for _, level := range levels {
+		for i := 0; i < length; i++ {
+			signal = append(signal, level+random.NormFloat64()*noise)
+		}
+	}
+	return signal
+}
+
+func TestTrim(t *testing.T) {
+	// 200 samples of adapter current, then the strand, then 120 samples of
+	// open pore.
+	signal := append(steps([]float64{5}, 200, 0.2), steps([]float64{0, 1, -1, 0.5, -0.5}, 400, 0.3)...)
+	signal = append(signal, steps([]float64{6}, 120, 0.2)...)
+	start, end := Trim(signal, DefaultTrimOptions)
+	if start < 200 || start > 250 {
+		t.Errorf("expected the strand to start after the adapter at 200, got %d", start)
+	}
+	if end != 2200 {
+		t.Errorf("expected the strand to end before the open pore at 2200, got %d", end)
+	}
+
+	// signal without an adapter is only trimmed by MinTrim.
+	start, end = Trim(steps([]float64{0, 1, -1}, 1000, 0.3), DefaultTrimOptions)
+	if start != DefaultTrimOptions.MinTrim || end != 3000 {
+		t.Errorf("expected only minimal trimming, got %d-%d", start, end)
+	}
+	if start, end := Trim(signal, TrimOptions{}); start != 0 || end != len(signal) {
+		t.Errorf("expected no trimming without a window size, got %d-%d", start, end)
+	}
+}
+
+func TestSegment(t *testing.T) {
+	levels := []float64{80, 95, 70, 110, 90, 60, 85, 92, 75, 100}
+	signal := steps(levels, 12, 1)
+	events := Segment(signal, DefaultSegmentOptions)
+	if len(events) != len(levels) {
+		t.Fatalf("expected %d events, got %d: %+v", len(levels), len(events), events)
+	}
+	for i, event := range events {
+		if event.Start < i*12-1 || event.Start > i*12+1 {
+			t.Errorf("expected event %d to start at %d, got %d", i, i*12, event.Start)
+		}
+		if math.Abs(event.Mean-levels[i]) > 3 {
+			t.Errorf("expected event %d mean near %f, got %f", i, levels[i], event.Mean)
+		}
+	}
+	var total int
+	for _, event := range events {
+		total += event.Length
+	}
+	if total != len(signal) {
+		t.Errorf("events should cover the signal, got %d of %d samples", total, len(signal))
+	}
+	if events := Segment(nil, DefaultSegmentOptions); len(events) != 0 {
+		t.Errorf("expected no events for no signal, got %v", events)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	read := exampleRead(t)
+	stats := Summarize(read)
+	if stats.Samples != len(read.RawSignal) || math.Abs(stats.Duration-float64(len(read.RawSignal))/4000) > 1e-12 {
+		t.Errorf("unexpected samples or duration %+v", stats)
+	}
+	if !(stats.Min <= stats.Median && stats.Median <= stats.Max) || stats.MAD <= 0 || stats.StdDev <= 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	empty := Summarize(slow5.Read{SamplingRate: 4000})
+	if empty.Samples != 0 || !math.IsNaN(empty.Mean) {
+		t.Errorf("unexpected stats of an empty read %+v", empty)
+	}
+}